@@ -0,0 +1,276 @@
+// Package client calls the trippy HTTP API for a player.
+//
+// The client holds the JWT of the player and replaces it with the one of every round played,
+// so that the chips of the next round are the balance after the last one.
+// Requests which fail on the way are sent again with the same Idempotency-Key,
+// so that a round is never played twice.
+package client
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+)
+
+const (
+	_DEFAULT_RETRIES    = 3
+	_DEFAULT_RETRY_WAIT = 200 * time.Millisecond
+	_DEFAULT_TIMEOUT    = 30 * time.Second
+
+	_HEADER_IDEMPOTENCY_KEY = "Idempotency-Key"
+	_HEADER_RETRY_AFTER     = "Retry-After"
+)
+
+// Client plays the rounds of one player, one at a time
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+	retries    int           // Times a failed request is sent again
+	retryWait  time.Duration // Wait before the first retry, doubled for every retry
+
+	mu    sync.Mutex // Rounds are played one at a time, each with the token of the last one
+	token string
+}
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithHTTPClient sends the requests with the HTTP client, eg. for TLS client certificates
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithRetries sends a failed request again up to retries times,
+// waiting wait before the first retry and twice as long for every next one.
+// A Retry-After of the server is waited for if it is longer.
+func WithRetries(retries int, wait time.Duration) Option {
+	return func(c *Client) {
+		c.retries = retries
+		c.retryWait = wait
+	}
+}
+
+// New creates a client of the API at baseURL, eg. https://trippy.example.com, for the player of the token
+func New(baseURL, token string, opts ...Option) *Client {
+	c := &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: &http.Client{Timeout: _DEFAULT_TIMEOUT},
+		retries:    _DEFAULT_RETRIES,
+		retryWait:  _DEFAULT_RETRY_WAIT,
+		token:      token,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Token returns the JWT of the player, with the balance after the last round played
+func (c *Client) Token() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.token
+}
+
+// SetToken replaces the JWT of the player, eg. after chips were bought
+func (c *Client) SetToken(token string) {
+	c.mu.Lock()
+	c.token = token
+	c.mu.Unlock()
+}
+
+// SignToken creates the JWT of a player, signed with the API key of the server
+func SignToken(claims Claims, apiKey []byte) (string, error) {
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(apiKey)
+}
+
+// Spin plays a round on the machine and returns the v1 response
+func (c *Client) Spin(ctx context.Context, machine string) (*SpinResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.token == "" {
+		return nil, ErrNoToken
+	}
+	var resp SpinResponse
+	if err := c.post(ctx, "/api/machines/"+url.PathEscape(machine)+"/spins", []byte(c.token), &resp); err != nil {
+		return nil, err
+	}
+	c.token = resp.JWT
+	return &resp, nil
+}
+
+// SpinV2 plays a round on the machine and returns the v2 response
+func (c *Client) SpinV2(ctx context.Context, machine string) (*SpinResponseV2, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.token == "" {
+		return nil, ErrNoToken
+	}
+	var resp SpinResponseV2
+	if err := c.post(ctx, "/api/v2/machines/"+url.PathEscape(machine)+"/spins", []byte(c.token), &resp); err != nil {
+		return nil, err
+	}
+	c.token = resp.JWT
+	return &resp, nil
+}
+
+// Autoplay plays up to rounds rounds on the machine, until a stop condition is met
+func (c *Client) Autoplay(ctx context.Context, machine string, rounds int, stop AutoplayStop) (*AutoplayResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.token == "" {
+		return nil, ErrNoToken
+	}
+	body, err := json.Marshal(autoplayRequest{JWT: c.token, Rounds: rounds, Stop: stop})
+	if err != nil {
+		return nil, err
+	}
+	var resp AutoplayResponse
+	if err = c.post(ctx, "/api/v2/machines/"+url.PathEscape(machine)+"/autoplay", body, &resp); err != nil {
+		return nil, err
+	}
+	c.token = resp.JWT
+	return &resp, nil
+}
+
+// Pick reveals the prize at the position, from 1, in the bonus game of the machine.
+// The prize is paid as a round of its own, the response holds the game after the pick.
+func (c *Client) Pick(ctx context.Context, machine, game string, position int) (*SpinResponseV2, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.token == "" {
+		return nil, ErrNoToken
+	}
+	body, err := json.Marshal(pickRequest{JWT: c.token, Pick: position})
+	if err != nil {
+		return nil, err
+	}
+	var resp SpinResponseV2
+	path := "/api/v2/machines/" + url.PathEscape(machine) + "/bonus/" + url.PathEscape(game) + "/picks"
+	if err = c.post(ctx, path, body, &resp); err != nil {
+		return nil, err
+	}
+	c.token = resp.JWT
+	return &resp, nil
+}
+
+// Jackpots returns the current amount of the progressive jackpots, only those of the machine if set
+func (c *Client) Jackpots(ctx context.Context, machine string) ([]JackpotValue, error) {
+	path := "/api/v2/jackpots"
+	if machine != "" {
+		path = path + "?machine=" + url.QueryEscape(machine)
+	}
+	var resp jackpotsResponse
+	if err := c.request(ctx, http.MethodGet, path, nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Jackpots, nil
+}
+
+// post sends the body and decodes the response in resp
+func (c *Client) post(ctx context.Context, path string, body []byte, resp interface{}) error {
+	return c.request(ctx, http.MethodPost, path, body, resp)
+}
+
+// request sends the request and decodes the response in resp.
+// The request is retried with the same idempotency key while it fails with a retryable error.
+func (c *Client) request(ctx context.Context, method, path string, body []byte, resp interface{}) error {
+	key := newIdempotencyKey()
+	wait := c.retryWait
+	for attempt := 0; ; attempt++ {
+		retryAfter, err := c.send(ctx, method, path, key, body, resp)
+		if err == nil {
+			return nil
+		}
+		if attempt >= c.retries || !retryable(ctx, err) {
+			return err
+		}
+		if retryAfter > wait {
+			wait = retryAfter
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(wait):
+		}
+		wait = wait * 2
+	}
+}
+
+// send makes one attempt of a request. On failure it returns the Retry-After of the server, if any.
+func (c *Client) send(ctx context.Context, method, path, key string, body []byte, resp interface{}) (time.Duration, error) {
+	req, err := http.NewRequest(method, c.baseURL+path, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set(_HEADER_IDEMPOTENCY_KEY, key)
+
+	httpResp, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer httpResp.Body.Close()
+	data, err := ioutil.ReadAll(httpResp.Body)
+	if err != nil {
+		return 0, err
+	}
+
+	if httpResp.StatusCode != http.StatusOK {
+		seconds, _ := strconv.Atoi(httpResp.Header.Get(_HEADER_RETRY_AFTER))
+		return time.Duration(seconds) * time.Second, decodeError(httpResp.StatusCode, data)
+	}
+	if err = json.Unmarshal(data, resp); err != nil {
+		return 0, fmt.Errorf("Unable to decode response of [Path:%s] [Error:%s]", path, err)
+	}
+	return 0, nil
+}
+
+// decodeError reads the error envelope of the API, or keeps the body as the message
+func decodeError(status int, data []byte) *Error {
+	var envelope struct {
+		Error *Error `json:"error"`
+	}
+	if err := json.Unmarshal(data, &envelope); err != nil || envelope.Error == nil {
+		return &Error{Status: status, Message: strings.TrimSpace(string(data))}
+	}
+	envelope.Error.Status = status
+	return envelope.Error
+}
+
+// retryable reports whether a request which failed with err can be sent again.
+// Requests which did not get a response are retried, the idempotency key prevents a second round.
+func retryable(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	switch e := err.(type) {
+	case *Error:
+		return e.retryable()
+	case *url.Error:
+		return true
+	}
+	return false
+}
+
+func newIdempotencyKey() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,225 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"trippy/jackpot"
+	"trippy/server"
+)
+
+const (
+	_TEST_API_KEY = "secret"
+	_TEST_MACHINE = "atkins-diet"
+)
+
+var (
+	testServerOnce sync.Once
+	testServer     *httptest.Server
+)
+
+// newTestServer serves the router of the server, initialized as the trippy command does
+func newTestServer(t *testing.T) *httptest.Server {
+	testServerOnce.Do(func() {
+		keyPath := filepath.Join(t.TempDir(), "api.key")
+		if err := ioutil.WriteFile(keyPath, []byte(_TEST_API_KEY), 0600); err != nil {
+			t.Fatalf("Unable to write API key [Error:%s]", err)
+		}
+		cfg := server.DefaultConfig()
+		cfg.APIKeyPath = keyPath
+		cfg.RateLimits = nil
+		cfg.Log.Level = "error"
+		cfg.Jackpots = []jackpot.Config{{Name: "progressive", Machines: []string{_TEST_MACHINE}, Contribution: 10, Seed: 1000,
+			Trigger: &jackpot.Trigger{Symbol: "ATKINS", Count: 5, Line: 1, MaxBet: true}}}
+		s := &server.Server{Config: cfg}
+		if err := s.Initialize(); err != nil {
+			t.Fatalf("Unable to initialize server [Error:%s]", err)
+		}
+		testServer = httptest.NewServer(s.Handler())
+	})
+	return testServer
+}
+
+func newToken(t *testing.T, claims Claims) string {
+	token, err := SignToken(claims, []byte(_TEST_API_KEY))
+	if err != nil {
+		t.Fatalf("Unable to sign token [Error:%s]", err)
+	}
+	return token
+}
+
+// flakyTransport loses the responses of the first requests, once the server played them
+type flakyTransport struct {
+	lose     int
+	requests int
+	lost     [][]byte // Bodies of the responses lost
+}
+
+func (ft *flakyTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	ft.requests++
+	resp, err := http.DefaultTransport.RoundTrip(r)
+	if err != nil || ft.requests > ft.lose {
+		return resp, err
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	ft.lost = append(ft.lost, body)
+	return nil, errors.New("connection reset by peer")
+}
+
+func TestSpinsChainTokens(t *testing.T) {
+	ts := newTestServer(t)
+	c := New(ts.URL, newToken(t, Claims{UID: "123", Chips: 1000, Bet: 1}))
+
+	balance := 1000
+	for i := 0; i < 5; i++ {
+		resp, err := c.SpinV2(context.Background(), _TEST_MACHINE)
+		if err != nil {
+			t.Fatalf("Expected:[nil] Got:[%s]", err)
+		}
+		if resp.BalanceBefore != balance || resp.BalanceAfter != balance-resp.Wager+resp.Total || len(resp.Spins) == 0 {
+			t.Fatalf("Expected the balance of the last round:[%d] Got:[%+v]", balance, resp)
+		}
+		if c.Token() != resp.JWT {
+			t.Errorf("Expected the token of the round")
+		}
+		balance = resp.BalanceAfter
+	}
+
+	resp, err := c.Spin(context.Background(), _TEST_MACHINE)
+	if err != nil || resp.JWT == "" || c.Token() != resp.JWT || len(resp.Spins) == 0 {
+		t.Errorf("Expected a v1 round with its token. Got:[%+v] [Error:%v]", resp, err)
+	}
+}
+
+func TestAutoplay(t *testing.T) {
+	ts := newTestServer(t)
+	c := New(ts.URL, newToken(t, Claims{UID: "123", Chips: 1000, Bet: 1}))
+
+	resp, err := c.Autoplay(context.Background(), _TEST_MACHINE, 5, AutoplayStop{LossLimit: 500})
+	if err != nil {
+		t.Fatalf("Expected:[nil] Got:[%s]", err)
+	}
+	if resp.Played != len(resp.Rounds) || resp.BalanceAfter != 1000-resp.Wager+resp.Total || c.Token() != resp.JWT {
+		t.Errorf("Unexpected autoplay [Played:%d Rounds:%d Balance:%d]", resp.Played, len(resp.Rounds), resp.BalanceAfter)
+	}
+}
+
+func TestJackpots(t *testing.T) {
+	ts := newTestServer(t)
+	c := New(ts.URL, newToken(t, Claims{UID: "123", Chips: 1000, Bet: 1}))
+
+	resp, err := c.SpinV2(context.Background(), _TEST_MACHINE)
+	if err != nil {
+		t.Fatalf("Expected:[nil] Got:[%s]", err)
+	}
+	if len(resp.JackpotValues) != 1 || resp.JackpotValues[0].Amount < 1000 {
+		t.Fatalf("Expected the jackpot of the machine Got:[%+v]", resp.JackpotValues)
+	}
+	values, err := c.Jackpots(context.Background(), "")
+	if err != nil || len(values) != 1 || values[0].Amount < resp.JackpotValues[0].Amount {
+		t.Errorf("Expected the jackpot at least:[%d] Got:[%+v] [Error:%v]", resp.JackpotValues[0].Amount, values, err)
+	}
+	if _, err = c.Jackpots(context.Background(), "unknown"); err == nil || err.(*Error).Code != UNKNOWN_MACHINE {
+		t.Errorf("Expected:[%s] Got:[%v]", UNKNOWN_MACHINE, err)
+	}
+}
+
+// The default machine has no bonus game, picks are not found
+func TestPickUnknownGame(t *testing.T) {
+	ts := newTestServer(t)
+	c := New(ts.URL, newToken(t, Claims{UID: "123", Chips: 1000, Bet: 1}))
+	if _, err := c.Pick(context.Background(), _TEST_MACHINE, "unknown", 1); err == nil || err.(*Error).Code != BONUS_NOT_FOUND {
+		t.Errorf("Expected:[%s] Got:[%v]", BONUS_NOT_FOUND, err)
+	}
+}
+
+// A round whose response is lost is replayed by the server, not played again
+func TestRetryReplaysRound(t *testing.T) {
+	ts := newTestServer(t)
+	transport := &flakyTransport{lose: 2}
+	c := New(ts.URL, newToken(t, Claims{UID: "123", Chips: 1000, Bet: 1}),
+		WithHTTPClient(&http.Client{Transport: transport}), WithRetries(3, time.Millisecond))
+
+	resp, err := c.SpinV2(context.Background(), _TEST_MACHINE)
+	if err != nil {
+		t.Fatalf("Expected:[nil] Got:[%s]", err)
+	}
+	if transport.requests != 3 || len(transport.lost) != 2 {
+		t.Fatalf("Expected:[3] requests Got:[%d]", transport.requests)
+	}
+	for _, lost := range transport.lost {
+		if !bytes.Contains(lost, []byte(resp.Round)) {
+			t.Errorf("Expected the round lost:[%s] Got:[%s]", lost, resp.Round)
+		}
+	}
+	if resp.BalanceBefore != 1000 {
+		t.Errorf("Expected a single round. Balance before:[%d]", resp.BalanceBefore)
+	}
+}
+
+type errorSample struct {
+	claims   Claims
+	machine  string
+	code     ErrorCode
+	status   int
+	requests int
+}
+
+func testError(t *testing.T, ts *httptest.Server, sample errorSample) {
+	transport := &flakyTransport{}
+	c := New(ts.URL, newToken(t, sample.claims),
+		WithHTTPClient(&http.Client{Transport: transport}), WithRetries(2, time.Millisecond))
+	token := c.Token()
+
+	_, err := c.SpinV2(context.Background(), sample.machine)
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || !IsCode(err, sample.code) || apiErr.Status != sample.status {
+		t.Errorf("Expected:[%s %d] Got:[%v]", sample.code, sample.status, err)
+	}
+	if transport.requests != sample.requests {
+		t.Errorf("[Code:%s] Expected:[%d] requests Got:[%d]", sample.code, sample.requests, transport.requests)
+	}
+	if c.Token() != token {
+		t.Errorf("Expected the token to be kept after an error")
+	}
+}
+
+func TestErrors(t *testing.T) {
+	ts := newTestServer(t)
+	samples := []errorSample{
+		{Claims{UID: "123", Chips: 1, Bet: 1}, _TEST_MACHINE, INSUFFICIENT_CHIPS, http.StatusBadRequest, 1},
+		{Claims{UID: "123", Chips: 1000, Bet: 1}, "unknown", UNKNOWN_MACHINE, http.StatusBadRequest, 1},
+		{Claims{UID: "123", Chips: 1000, Bet: 1, Expiry: 1}, _TEST_MACHINE, TOKEN_EXPIRED, http.StatusUnauthorized, 1},
+	}
+	for _, sample := range samples {
+		testError(t, ts, sample)
+	}
+
+	if _, err := New(ts.URL, "").SpinV2(context.Background(), _TEST_MACHINE); err != ErrNoToken {
+		t.Errorf("Expected:[%s] Got:[%v]", ErrNoToken, err)
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	for err, expected := range map[error]bool{
+		&Error{Status: http.StatusTooManyRequests, Code: RATE_LIMITED}:   true,
+		&Error{Status: http.StatusServiceUnavailable, Code: UNAVAILABLE}: true,
+		&Error{Status: http.StatusServiceUnavailable, Code: MAINTENANCE}: false,
+		&Error{Status: http.StatusBadGateway}:                            true,
+		&Error{Status: http.StatusBadRequest, Code: INSUFFICIENT_CHIPS}:  false,
+		errors.New("Unable to decode response"):                          false,
+	} {
+		if got := retryable(context.Background(), err); got != expected {
+			t.Errorf("[Error:%s] Expected:[%t] Got:[%t]", err, expected, got)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrorCode is the stable identifier of an API error
+type ErrorCode string
+
+// Codes of the API errors the clients usually handle, see the server error catalogue for all of them
+const (
+	INVALID_REQUEST         ErrorCode = "INVALID_REQUEST"
+	INVALID_TOKEN           ErrorCode = "INVALID_TOKEN"
+	TOKEN_EXPIRED           ErrorCode = "TOKEN_EXPIRED"
+	INVALID_BET             ErrorCode = "INVALID_BET"
+	INSUFFICIENT_CHIPS      ErrorCode = "INSUFFICIENT_CHIPS"
+	UNKNOWN_MACHINE         ErrorCode = "UNKNOWN_MACHINE"
+	MACHINE_DISABLED        ErrorCode = "MACHINE_DISABLED"
+	RATE_LIMITED            ErrorCode = "RATE_LIMITED"
+	IDEMPOTENCY_KEY_REUSED  ErrorCode = "IDEMPOTENCY_KEY_REUSED"
+	IDEMPOTENCY_IN_PROGRESS ErrorCode = "IDEMPOTENCY_IN_PROGRESS"
+	SPIN_FAILED             ErrorCode = "SPIN_FAILED"
+	UNAVAILABLE             ErrorCode = "UNAVAILABLE"
+	MAINTENANCE             ErrorCode = "MAINTENANCE"
+	BONUS_NOT_FOUND         ErrorCode = "BONUS_NOT_FOUND"
+	INVALID_PICK            ErrorCode = "INVALID_PICK"
+	INTERNAL                ErrorCode = "INTERNAL"
+)
+
+// retryableCodes are the errors of the requests which can succeed if sent again
+var retryableCodes = map[ErrorCode]bool{
+	RATE_LIMITED:            true,
+	IDEMPOTENCY_IN_PROGRESS: true,
+	SPIN_FAILED:             true,
+	UNAVAILABLE:             true,
+	INTERNAL:                true,
+}
+
+var ErrNoToken = errors.New("Client has no token")
+
+// Error is an error responded by the API
+type Error struct {
+	Status  int                    `json:"-"` // HTTP status
+	Code    ErrorCode              `json:"code"`
+	Message string                 `json:"message"`
+	Details map[string]interface{} `json:"details,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s [Status:%d]", e.Code, e.Message, e.Status)
+}
+
+// IsCode reports whether err is an API error with the code
+func IsCode(err error, code ErrorCode) bool {
+	var apiErr *Error
+	return errors.As(err, &apiErr) && apiErr.Code == code
+}
+
+// retryable reports whether the request can succeed if sent again.
+// Errors without a code come from proxies in front of the API.
+func (e *Error) retryable() bool {
+	return retryableCodes[e.Code] || (e.Code == "" && e.Status >= 500)
+}
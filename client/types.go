@@ -0,0 +1,185 @@
+package client
+
+// Types of the API responses, see the server package for their documentation
+
+// SpinResponse is the v1 response of a round
+type SpinResponse struct {
+	Total    int          `json:"total"` // Jackpots won included
+	Spins    []Spin       `json:"spins"`
+	Jackpots []JackpotWin `json:"jackpots"`
+	JWT      string       `json:"jwt"`
+}
+
+type Spin struct {
+	Type  string    `json:"type"` // main, free or jackpot
+	Total int       `json:"total"`
+	Stops []int     `json:"stops"`
+	Lines []WinLine `json:"lines"`
+}
+
+type WinLine struct {
+	Index  int    `json:"index"`  // Number of the line
+	Symbol int    `json:"symbol"` // Symbol paid
+	Name   string `json:"name"`   // Name of the symbol paid
+	Count  int    `json:"count"`  // Number of symbols paid
+	Payout int    `json:"payout"` // Payout of the line
+}
+
+// SpinResponseV2 is the v2 response of a round
+type SpinResponseV2 struct {
+	Version       int      `json:"version"`
+	Round         string   `json:"round"` // ID of the round, found in the server logs
+	Machine       string   `json:"machine"`
+	Wager         int      `json:"wager"`
+	Total         int      `json:"total"`
+	FreeSpins     int      `json:"free_spins"` // Free spins awarded in the round
+	BalanceBefore int      `json:"balance_before"`
+	BalanceAfter  int      `json:"balance_after"`
+	Spins         []SpinV2 `json:"spins"`
+	JWT           string   `json:"jwt,omitempty"` // Only in the rounds played alone
+
+	Jackpots      []JackpotWin   `json:"jackpots"`       // Jackpots won, included in the total
+	JackpotValues []JackpotValue `json:"jackpot_values"` // Jackpots of the machine after the round
+	Bonus         *BonusGame     `json:"bonus"`          // Bonus game launched or picked by the round
+}
+
+type SpinV2 struct {
+	Type        string           `json:"type"`
+	Total       int              `json:"total"`
+	Multiplier  int              `json:"multiplier"`
+	Stops       []int            `json:"stops"`
+	Grid        [][]Symbol       `json:"grid"` // Visible window, Grid[row][reel]
+	Lines       []WinLineV2      `json:"lines"`
+	Scatters    int              `json:"scatters"`
+	FreeSpins   int              `json:"free_spins"`  // Free spins awarded by this spin
+	Jackpot     string           `json:"jackpot"`     // Mystery jackpot awarded by a jackpot spin
+	Wilds       []Wild           `json:"wilds"`       // Cells turned wild by the wild behaviours
+	Multipliers []WildMultiplier `json:"multipliers"` // Multipliers carried by the wilds of the grid
+	Coins       []Coin           `json:"coins"`       // Coins held after a hold spin
+	Respins     int              `json:"respins"`     // Respins left after a hold spin
+}
+
+// Coin is a coin held in a cell of the grid, with its credits in chips
+type Coin struct {
+	Position
+	Value int `json:"value"`
+}
+
+// WildMultiplier is the multiplier carried by the wild of a cell of the grid
+type WildMultiplier struct {
+	Position
+	Multiplier int `json:"multiplier"`
+}
+
+// Wild are the cells of the grid turned wild by a behaviour: expanding, sticky or walking
+type Wild struct {
+	Behaviour string     `json:"behaviour"`
+	Cells     []Position `json:"cells"`
+}
+
+// BonusGame is a pick game launched by a round, its prizes are won with Client.Pick
+type BonusGame struct {
+	ID     string       `json:"id"`
+	Prizes int          `json:"prizes"` // Hidden prizes to pick from
+	Picks  int          `json:"picks"`  // Picks left
+	Won    int          `json:"won"`    // Chips won by the picks so far
+	Over   bool         `json:"over"`
+	Reveal []BonusPrize `json:"reveal"` // Prizes picked, all of them once the game is over
+}
+
+type BonusPrize struct {
+	Position int  `json:"position"` // From 1
+	Prize    int  `json:"prize"`
+	Picked   bool `json:"picked"`
+}
+
+type WinLineV2 struct {
+	Index          int        `json:"index"`
+	Symbol         Symbol     `json:"symbol"`
+	Count          int        `json:"count"`
+	Payout         int        `json:"payout"`
+	Multiplier     int        `json:"multiplier"`      // Free spins and wilds multipliers of the line
+	WildMultiplier int        `json:"wild_multiplier"` // Multiplier of the wilds paid, part of the multiplier
+	Positions      []Position `json:"positions"`       // Cells of the pay line, the first Count are paid
+	Symbols        []Symbol   `json:"symbols"`         // Symbols paid
+}
+
+type Symbol struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	Code string `json:"code"` // Short code displaying the symbol
+	Kind string `json:"kind"` // regular, wild, scatter, bonus, coin or blank
+}
+
+// Position is a cell of the visible window, numbered from 1
+type Position struct {
+	Reel int `json:"reel"`
+	Row  int `json:"row"`
+}
+
+// JackpotWin is a progressive jackpot won in a round
+type JackpotWin struct {
+	Jackpot string `json:"jackpot"`
+	Amount  int    `json:"amount"`
+	Reason  string `json:"reason"` // combination or must_hit_by
+}
+
+type JackpotValue struct {
+	Jackpot   string   `json:"jackpot"`
+	Amount    int      `json:"amount"`
+	MustHitBy int      `json:"must_hit_by"` // Amount the jackpot is won before exceeding, 0 if none
+	Machines  []string `json:"machines"`    // Machines contributing to the jackpot
+}
+
+type jackpotsResponse struct {
+	Jackpots []JackpotValue `json:"jackpots"`
+}
+
+// AutoplayStop are the conditions stopping an autoplay before all its rounds are played, unset if 0
+type AutoplayStop struct {
+	BalanceBelow   int  `json:"balance_below,omitempty"`
+	SingleWinAbove int  `json:"single_win_above,omitempty"`
+	FreeSpins      bool `json:"free_spins,omitempty"`
+	LossLimit      int  `json:"loss_limit,omitempty"`
+}
+
+type pickRequest struct {
+	JWT  string `json:"jwt"`
+	Pick int    `json:"pick"`
+}
+
+type autoplayRequest struct {
+	JWT    string       `json:"jwt"`
+	Rounds int          `json:"rounds"`
+	Stop   AutoplayStop `json:"stop"`
+}
+
+type AutoplayResponse struct {
+	Version       int              `json:"version"`
+	Machine       string           `json:"machine"`
+	Requested     int              `json:"requested"`
+	Played        int              `json:"played"`
+	StopReason    string           `json:"stop_reason"`
+	Wager         int              `json:"wager"` // Chips wagered in all the rounds
+	Total         int              `json:"total"` // Chips paid in all the rounds
+	BalanceBefore int              `json:"balance_before"`
+	BalanceAfter  int              `json:"balance_after"`
+	Rounds        []SpinResponseV2 `json:"rounds"`
+	JWT           string           `json:"jwt"`
+}
+
+// Claims are the player and the bet in the JWT of the API
+type Claims struct {
+	UID   string `json:"uid"`
+	Chips int    `json:"chips"`
+	Bet   int    `json:"bet"`             // Coins per line
+	Denom int    `json:"denom,omitempty"` // Coin value, machine default if 0
+	Lines int    `json:"lines,omitempty"` // Active pay lines, all lines if 0
+
+	Expiry int64 `json:"exp,omitempty"` // Unix time after which the token is rejected, never if 0
+}
+
+// Valid adheres to the jwt.Claims interface, the server checks the expiry
+func (c Claims) Valid() error {
+	return nil
+}
@@ -0,0 +1,364 @@
+// Package jackpot runs progressive jackpots shared by several machines.
+// A part of every wager of the participating machines is added to the pool, which is won
+// on a combination of symbols or once it reaches a random amount below its must-hit-by ceiling.
+// Mystery jackpots are tiered jackpots of a single machine, see MysteryMachine.
+package jackpot
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"trippy/slotmachine"
+)
+
+// Pools are kept in thousandths of a chip, the contributions being fractions of the wagers
+const _SCALE = 1000
+
+// Reasons of a win
+const (
+	COMBINATION = "combination"
+	MUST_HIT_BY = "must_hit_by"
+	MYSTERY     = "mystery" // Drawn at random, see MysteryMachine
+)
+
+// Config is a jackpot and the machines contributing to it
+type Config struct {
+	Name         string   `json:"name"`
+	Machines     []string `json:"machines"`     // Machines contributing to the pool and able to win it
+	Contribution float64  `json:"contribution"` // Percentage of every wager added to the pool, eg. 1.5
+	Seed         int      `json:"seed"`         // Chips the pool starts from after a win
+	MustHitBy    int      `json:"must_hit_by"`  // Chips the pool is won before exceeding, never if 0
+	Trigger      *Trigger `json:"trigger"`      // Combination winning the pool, none if nil
+}
+
+// Trigger is a combination of a main spin winning the pool
+type Trigger struct {
+	Symbol string `json:"symbol"`  // Name of the paid symbol in the catalogue of the machine
+	Count  int    `json:"count"`   // Symbols paid on the line, at least
+	Line   int    `json:"line"`    // Pay line, any line if 0
+	MaxBet bool   `json:"max_bet"` // Only with the maximum coins per line
+}
+
+// Validate checks the jackpot and reports all the problems found
+func (c Config) Validate() error {
+	var errs []string
+	addErr := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Sprintf(format, args...))
+	}
+	if c.Name == "" {
+		addErr("name is empty")
+	}
+	if len(c.Machines) == 0 {
+		addErr("machines is empty")
+	}
+	if c.Contribution <= 0 || c.Contribution > 100 {
+		addErr("contribution:[%g] must be greater than 0 and at most 100", c.Contribution)
+	}
+	if c.Seed < 0 {
+		addErr("seed:[%d] is negative", c.Seed)
+	}
+	if c.MustHitBy != 0 && c.MustHitBy <= c.Seed {
+		addErr("must_hit_by:[%d] must be greater than seed:[%d]", c.MustHitBy, c.Seed)
+	}
+	if c.Trigger == nil && c.MustHitBy == 0 {
+		addErr("a trigger or must_hit_by is required")
+	}
+	if t := c.Trigger; t != nil {
+		if t.Symbol == "" {
+			addErr("trigger: symbol is empty")
+		}
+		if t.Count < 1 {
+			addErr("trigger: count:[%d] must be at least 1", t.Count)
+		}
+		if t.Line < 0 {
+			addErr("trigger: line:[%d] is negative", t.Line)
+		}
+	}
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// matches returns true if a main spin of the results pays the combination
+func (t *Trigger) matches(results []slotmachine.SpinResult, maxBet bool) bool {
+	if t == nil || (t.MaxBet && !maxBet) {
+		return false
+	}
+	for _, result := range results {
+		if result.Type != slotmachine.MAIN_SPIN {
+			continue
+		}
+		for _, winLine := range result.WinLines {
+			if winLine.Name == t.Symbol && winLine.Count >= t.Count && (t.Line == 0 || winLine.Index == t.Line) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// State is the state of a pool, persisted by the store
+type State struct {
+	Value   int64 `json:"value"`  // Thousandths of a chip
+	HitAt   int64 `json:"hit_at"` // Value the pool is won at, thousandths of a chip, 0 without must-hit-by
+	Wins    int   `json:"wins"`
+	LastWin *Win  `json:"last_win,omitempty"`
+}
+
+// Win is a jackpot won in a round
+type Win struct {
+	Jackpot string    `json:"jackpot"`
+	Amount  int       `json:"amount"` // Chips won
+	Reason  string    `json:"reason"` // combination, must_hit_by or mystery
+	Machine string    `json:"machine"`
+	Round   string    `json:"round"`
+	Time    time.Time `json:"time"`
+}
+
+// Value is the current amount of a jackpot
+type Value struct {
+	Jackpot   string   `json:"jackpot"`
+	Amount    int      `json:"amount"`                // Chips
+	MustHitBy int      `json:"must_hit_by,omitempty"` // Chips the pool is won before exceeding
+	Machines  []string `json:"machines"`
+}
+
+// Play is a round played on a machine
+type Play struct {
+	Round   string
+	Machine string
+	Wager   int  // Chips wagered
+	MaxBet  bool // Maximum coins per line
+	Results []slotmachine.SpinResult
+}
+
+type pool struct {
+	cfg      Config
+	machines map[string]bool
+	state    State
+}
+
+// Pools are the jackpots served.
+// They are safe for concurrent use, all the pools of a round being updated at once.
+type Pools struct {
+	mu    sync.Mutex
+	pools []*pool
+	rand  *rand.Rand
+	store Store // nil if the pools are kept in memory only
+	dirty bool  // Contributions not saved yet
+	err   error // Error of the last save, nil once a save succeeds
+	stop  chan struct{}
+
+	closeOnce sync.Once
+}
+
+// New returns the pools of the jackpots, restored from the store if it has them
+func New(cfgs []Config, store Store) (*Pools, error) {
+	p := &Pools{
+		rand:  rand.New(rand.NewSource(time.Now().UnixNano())),
+		store: store,
+		stop:  make(chan struct{}),
+	}
+	states := make(map[string]State)
+	if store != nil {
+		var err error
+		if states, err = store.Load(); err != nil {
+			return nil, err
+		}
+	}
+	names := make(map[string]bool)
+	for _, cfg := range cfgs {
+		if err := cfg.Validate(); err != nil {
+			return nil, fmt.Errorf("Invalid jackpot [Jackpot:%s] %s", cfg.Name, err)
+		}
+		if names[cfg.Name] {
+			return nil, fmt.Errorf("Jackpot:[%s] is configured twice", cfg.Name)
+		}
+		names[cfg.Name] = true
+
+		pl := &pool{cfg: cfg, machines: make(map[string]bool)}
+		for _, machine := range cfg.Machines {
+			pl.machines[machine] = true
+		}
+		if state, ok := states[cfg.Name]; ok {
+			pl.state = restore(p.rand, state, cfg.MustHitBy)
+		} else {
+			p.reset(pl, int64(cfg.Seed)*_SCALE)
+		}
+		p.pools = append(p.pools, pl)
+	}
+	return p, nil
+}
+
+// reset starts the pool from value and draws the value it must be won at
+func (p *Pools) reset(pl *pool, value int64) {
+	pl.state.Value = value
+	pl.state.HitAt = hitAt(p.rand, value, pl.cfg.MustHitBy)
+}
+
+// restore returns the state saved for a pool.
+// The ceiling may have changed since it was saved, the value it is won at is drawn again if it is above.
+func restore(r *rand.Rand, state State, mustHitBy int) State {
+	if max := int64(mustHitBy) * _SCALE; max == 0 {
+		state.HitAt = 0
+	} else if state.HitAt == 0 || state.HitAt > max {
+		state.HitAt = hitAt(r, state.Value, mustHitBy)
+	}
+	return state
+}
+
+// hitAt draws the value a pool starting from value is won at, below its ceiling, 0 without ceiling
+func hitAt(r *rand.Rand, value int64, mustHitBy int) int64 {
+	if mustHitBy == 0 {
+		return 0
+	}
+	max := int64(mustHitBy) * _SCALE
+	if value >= max {
+		return max
+	}
+	return value + 1 + r.Int63n(max-value)
+}
+
+// contribution returns the part of the wager added to a pool, in thousandths of a chip
+func contribution(wager int, percent float64) int64 {
+	return int64(math.Round(float64(wager) * percent * _SCALE / 100))
+}
+
+// award returns the chips won from a pool and the value it starts again from.
+// The pool never pays above its ceiling, the rest starts the next one with the fractions of a chip.
+func award(value int64, seed, mustHitBy int) (int, int64) {
+	won := value
+	if max := int64(mustHitBy) * _SCALE; max > 0 && won > max {
+		won = max
+	}
+	amount := int(won / _SCALE)
+	return amount, int64(seed)*_SCALE + value - int64(amount)*_SCALE
+}
+
+// Play adds the contribution of the round to the pools of the machine and returns the jackpots won.
+// The wins are saved at once, an error means they were won but not saved.
+func (p *Pools) Play(play Play) ([]Win, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var wins []Win
+	for _, pl := range p.pools {
+		if !pl.machines[play.Machine] {
+			continue
+		}
+		pl.state.Value = pl.state.Value + contribution(play.Wager, pl.cfg.Contribution)
+		p.dirty = true
+
+		var reason string
+		if pl.cfg.Trigger.matches(play.Results, play.MaxBet) {
+			reason = COMBINATION
+		} else if pl.state.HitAt > 0 && pl.state.Value >= pl.state.HitAt {
+			reason = MUST_HIT_BY
+		} else {
+			continue
+		}
+		amount, next := award(pl.state.Value, pl.cfg.Seed, pl.cfg.MustHitBy)
+		win := Win{
+			Jackpot: pl.cfg.Name,
+			Amount:  amount,
+			Reason:  reason,
+			Machine: play.Machine,
+			Round:   play.Round,
+			Time:    time.Now().UTC(),
+		}
+		p.reset(pl, next)
+		pl.state.Wins++
+		pl.state.LastWin = &win
+		wins = append(wins, win)
+	}
+	if len(wins) == 0 {
+		return nil, nil
+	}
+	return wins, p.save()
+}
+
+// Values returns the current amount of the jackpots of the machine, all of them if machine is empty
+func (p *Pools) Values(machine string) []Value {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var values []Value
+	for _, pl := range p.pools {
+		if machine != "" && !pl.machines[machine] {
+			continue
+		}
+		machines := append([]string(nil), pl.cfg.Machines...)
+		sort.Strings(machines)
+		values = append(values, Value{
+			Jackpot:   pl.cfg.Name,
+			Amount:    int(pl.state.Value / _SCALE),
+			MustHitBy: pl.cfg.MustHitBy,
+			Machines:  machines,
+		})
+	}
+	return values
+}
+
+// Flush saves the contributions not saved yet
+func (p *Pools) Flush() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if !p.dirty {
+		return nil
+	}
+	return p.save()
+}
+
+// save saves the state of every pool, p.mu must be held
+func (p *Pools) save() error {
+	if p.store == nil {
+		p.dirty = false
+		return nil
+	}
+	states := make(map[string]State, len(p.pools))
+	for _, pl := range p.pools {
+		states[pl.cfg.Name] = pl.state
+	}
+	if p.err = p.store.Save(states); p.err != nil {
+		return p.err
+	}
+	p.dirty = false
+	return nil
+}
+
+// Err returns the error of the last save, nil if the contributions were saved since
+func (p *Pools) Err() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.err
+}
+
+// Persist flushes the contributions every interval until the pools are closed.
+// Errors are sent to onError, the next flush trying again.
+func (p *Pools) Persist(interval time.Duration, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := p.Flush(); err != nil && onError != nil {
+				onError(err)
+			}
+		case <-p.stop:
+			return
+		}
+	}
+}
+
+// Close stops Persist and flushes the contributions, it can be called more than once
+func (p *Pools) Close() error {
+	p.closeOnce.Do(func() { close(p.stop) })
+	return p.Flush()
+}
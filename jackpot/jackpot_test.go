@@ -0,0 +1,216 @@
+package jackpot
+
+import (
+	"errors"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"trippy/slotmachine"
+)
+
+func atkinsLine(index int) []slotmachine.SpinResult {
+	return []slotmachine.SpinResult{{
+		Type:     slotmachine.MAIN_SPIN,
+		WinLines: []slotmachine.WinLine{{Index: index, Name: "ATKINS", Count: 5}},
+	}}
+}
+
+func progressive() Config {
+	return Config{
+		Name:         "progressive",
+		Machines:     []string{"atkins-diet"},
+		Contribution: 10,
+		Seed:         100,
+		Trigger:      &Trigger{Symbol: "ATKINS", Count: 5, Line: 1, MaxBet: true},
+	}
+}
+
+type playSample struct {
+	name    string
+	play    Play
+	wins    int
+	amount  int
+	reason  string
+	balance int // Chips of the pool after the round
+}
+
+var playSamples = []playSample{
+	{name: "contribution", play: Play{Machine: "atkins-diet", Wager: 25}, balance: 102},
+	{name: "other machine", play: Play{Machine: "other", Wager: 25, MaxBet: true, Results: atkinsLine(1)}, balance: 100},
+	{name: "not max bet", play: Play{Machine: "atkins-diet", Wager: 25, Results: atkinsLine(1)}, balance: 102},
+	{name: "other line", play: Play{Machine: "atkins-diet", Wager: 25, MaxBet: true, Results: atkinsLine(2)}, balance: 102},
+	{name: "combination", play: Play{Machine: "atkins-diet", Wager: 25, MaxBet: true, Results: atkinsLine(1)},
+		wins: 1, amount: 102, reason: COMBINATION, balance: 100},
+}
+
+func TestPlay(t *testing.T) {
+	for _, sample := range playSamples {
+		testPlay(t, sample)
+	}
+}
+
+func testPlay(t *testing.T, sample playSample) {
+	pools, err := New([]Config{progressive()}, nil)
+	if err != nil {
+		t.Fatalf("[Sample:%s] Expected:[nil] Got:[%s]", sample.name, err)
+	}
+	wins, err := pools.Play(sample.play)
+	if err != nil {
+		t.Errorf("[Sample:%s] Expected:[nil] Got:[%s]", sample.name, err)
+	}
+	if len(wins) != sample.wins {
+		t.Fatalf("[Sample:%s] Expected:[%d] wins Got:[%v]", sample.name, sample.wins, wins)
+	}
+	if len(wins) > 0 && (wins[0].Amount != sample.amount || wins[0].Reason != sample.reason) {
+		t.Errorf("[Sample:%s] Expected:[%d %s] Got:[%d %s]", sample.name, sample.amount, sample.reason, wins[0].Amount, wins[0].Reason)
+	}
+	if values := pools.Values(""); values[0].Amount != sample.balance {
+		t.Errorf("[Sample:%s] Expected:[%d] Got:[%d]", sample.name, sample.balance, values[0].Amount)
+	}
+}
+
+// The pool is won before exceeding its ceiling, whatever its random hit point
+func TestMustHitBy(t *testing.T) {
+	cfg := progressive()
+	cfg.Trigger = nil
+	cfg.MustHitBy = 150
+	for i := 0; i < 20; i++ {
+		pools, err := New([]Config{cfg}, nil)
+		if err != nil {
+			t.Fatalf("Expected:[nil] Got:[%s]", err)
+		}
+		var wins []Win
+		for spins := 0; len(wins) == 0; spins++ {
+			if spins > 51 {
+				t.Fatalf("Expected a win before:[%d] Got:[%d] chips", cfg.MustHitBy, pools.Values("")[0].Amount)
+			}
+			wins, _ = pools.Play(Play{Machine: "atkins-diet", Wager: 10})
+		}
+		if wins[0].Reason != MUST_HIT_BY || wins[0].Amount <= cfg.Seed || wins[0].Amount > cfg.MustHitBy {
+			t.Errorf("Expected a win in:[%d %d] Got:[%+v]", cfg.Seed, cfg.MustHitBy, wins[0])
+		}
+	}
+}
+
+// Contributions of fractions of a chip are kept
+func TestFractions(t *testing.T) {
+	cfg := progressive()
+	cfg.Contribution = 0.5
+	pools, _ := New([]Config{cfg}, nil)
+	for i := 0; i < 100; i++ {
+		pools.Play(Play{Machine: "atkins-diet", Wager: 1})
+	}
+	if amount := pools.Values("atkins-diet")[0].Amount; amount != 100 {
+		t.Errorf("Expected:[100] Got:[%d]", amount)
+	}
+	pools.Play(Play{Machine: "atkins-diet", Wager: 100})
+	if amount := pools.Values("atkins-diet")[0].Amount; amount != 101 {
+		t.Errorf("Expected:[101] Got:[%d]", amount)
+	}
+}
+
+func TestConcurrentPlay(t *testing.T) {
+	pools, _ := New([]Config{progressive()}, nil)
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				pools.Play(Play{Machine: "atkins-diet", Wager: 10})
+			}
+		}()
+	}
+	wg.Wait()
+	if amount := pools.Values("")[0].Amount; amount != 1100 {
+		t.Errorf("Expected:[1100] Got:[%d]", amount)
+	}
+}
+
+func TestFileStore(t *testing.T) {
+	store := NewFileStore(filepath.Join(t.TempDir(), "jackpots.json"))
+	pools, err := New([]Config{progressive()}, store)
+	if err != nil {
+		t.Fatalf("Expected:[nil] Got:[%s]", err)
+	}
+	pools.Play(Play{Machine: "atkins-diet", Wager: 500})
+	go pools.Persist(time.Hour, nil)
+	if err = pools.Close(); err != nil {
+		t.Fatalf("Expected:[nil] Got:[%s]", err)
+	}
+	if err = pools.Close(); err != nil {
+		t.Fatalf("Expected a second close to be ignored Got:[%s]", err)
+	}
+
+	restored, err := New([]Config{progressive()}, store)
+	if err != nil {
+		t.Fatalf("Expected:[nil] Got:[%s]", err)
+	}
+	if amount := restored.Values("")[0].Amount; amount != 150 {
+		t.Errorf("Expected:[150] Got:[%d]", amount)
+	}
+
+	// Wins are saved at once
+	wins, err := restored.Play(Play{Machine: "atkins-diet", MaxBet: true, Results: atkinsLine(1)})
+	if err != nil || len(wins) != 1 {
+		t.Fatalf("Expected a win Got:[%v %v]", wins, err)
+	}
+	states, _ := store.Load()
+	if state := states["progressive"]; state.Wins != 1 || state.Value != 100*_SCALE || state.LastWin == nil {
+		t.Errorf("Expected the win saved Got:[%+v]", state)
+	}
+}
+
+// failingStore fails to save until it is fixed
+type failingStore struct {
+	failing bool
+}
+
+func (fs *failingStore) Load() (map[string]State, error) { return nil, nil }
+
+func (fs *failingStore) Save(map[string]State) error {
+	if fs.failing {
+		return errors.New("disk full")
+	}
+	return nil
+}
+
+func TestSaveError(t *testing.T) {
+	store := &failingStore{failing: true}
+	pools, _ := New([]Config{progressive()}, store)
+	pools.Play(Play{Machine: "atkins-diet", Wager: 500})
+	if err := pools.Flush(); err == nil || pools.Err() != err {
+		t.Errorf("Expected the save error Got:[%v %v]", err, pools.Err())
+	}
+	store.failing = false
+	if err := pools.Flush(); err != nil || pools.Err() != nil {
+		t.Errorf("Expected:[nil] Got:[%v %v]", err, pools.Err())
+	}
+}
+
+type configSample struct {
+	name  string
+	cfg   func(*Config)
+	valid bool
+}
+
+var configSamples = []configSample{
+	{name: "valid", cfg: func(c *Config) {}, valid: true},
+	{name: "no machines", cfg: func(c *Config) { c.Machines = nil }},
+	{name: "no contribution", cfg: func(c *Config) { c.Contribution = 0 }},
+	{name: "ceiling below seed", cfg: func(c *Config) { c.MustHitBy = 50 }},
+	{name: "never won", cfg: func(c *Config) { c.Trigger = nil }},
+	{name: "trigger without count", cfg: func(c *Config) { c.Trigger.Count = 0 }},
+}
+
+func TestValidate(t *testing.T) {
+	for _, sample := range configSamples {
+		cfg := progressive()
+		sample.cfg(&cfg)
+		if err := cfg.Validate(); (err == nil) != sample.valid {
+			t.Errorf("[Sample:%s] Expected valid:[%t] Got:[%v]", sample.name, sample.valid, err)
+		}
+	}
+}
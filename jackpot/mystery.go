@@ -0,0 +1,363 @@
+package jackpot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"trippy/logger"
+	"trippy/slotmachine"
+)
+
+// Levels of the default mystery jackpots
+const (
+	MINI  = "mini"
+	MINOR = "minor"
+	MAJOR = "major"
+	GRAND = "grand"
+)
+
+// MysteryConfig are tiered jackpots of a machine, awarded at random after its spins.
+// An eligible round awards a level with Probability, the level being drawn by weight,
+// and every level is won before exceeding its must-hit-by ceiling.
+type MysteryConfig struct {
+	Probability float64        `json:"probability"` // Chance of an eligible round to award a level
+	MinWager    int            `json:"min_wager"`   // Chips wagered for a round to be eligible, every round if 0
+	Levels      []MysteryLevel `json:"levels"`      // The default levels if empty
+}
+
+// MysteryLevel is a tier of the mystery jackpots
+type MysteryLevel struct {
+	Name      string  `json:"name"`
+	Seed      int     `json:"seed"`        // Chips the level starts from after a win
+	Increment float64 `json:"increment"`   // Percentage of every eligible wager added to the level
+	MustHitBy int     `json:"must_hit_by"` // Chips the level is won before exceeding, never if 0
+	Weight    int     `json:"weight"`      // Weight of the level in the random draw, never drawn if 0
+}
+
+// DefaultMysteryLevels are the mini, minor, major and grand jackpots
+func DefaultMysteryLevels() []MysteryLevel {
+	return []MysteryLevel{
+		{Name: MINI, Seed: 10, Increment: 0.5, MustHitBy: 50, Weight: 60},
+		{Name: MINOR, Seed: 50, Increment: 0.3, MustHitBy: 250, Weight: 30},
+		{Name: MAJOR, Seed: 500, Increment: 0.15, MustHitBy: 2500, Weight: 9},
+		{Name: GRAND, Seed: 5000, Increment: 0.05, MustHitBy: 25000, Weight: 1},
+	}
+}
+
+// levels returns the levels configured, the default ones if none is
+func (c MysteryConfig) levels() []MysteryLevel {
+	if len(c.Levels) == 0 {
+		return DefaultMysteryLevels()
+	}
+	return c.Levels
+}
+
+// Validate checks the mystery jackpots and reports all the problems found
+func (c MysteryConfig) Validate() error {
+	var errs []string
+	addErr := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Sprintf(format, args...))
+	}
+	if c.Probability < 0 || c.Probability > 1 {
+		addErr("probability:[%g] must be between 0 and 1", c.Probability)
+	}
+	if c.MinWager < 0 {
+		addErr("min_wager:[%d] is negative", c.MinWager)
+	}
+	var weights int
+	names := make(map[string]bool)
+	for i, level := range c.levels() {
+		if level.Name == "" {
+			addErr("levels[%d]: name is empty", i)
+		}
+		if names[level.Name] {
+			addErr("levels[%d]: name:[%s] is used twice", i, level.Name)
+		}
+		names[level.Name] = true
+		if level.Seed < 0 {
+			addErr("levels[%d]: seed:[%d] is negative", i, level.Seed)
+		}
+		if level.Increment < 0 || level.Increment > 100 {
+			addErr("levels[%d]: increment:[%g] must be between 0 and 100", i, level.Increment)
+		}
+		if level.MustHitBy != 0 && level.MustHitBy <= level.Seed {
+			addErr("levels[%d]: must_hit_by:[%d] must be greater than seed:[%d]", i, level.MustHitBy, level.Seed)
+		}
+		if level.Weight < 0 {
+			addErr("levels[%d]: weight:[%d] is negative", i, level.Weight)
+		}
+		if level.MustHitBy == 0 && (level.Weight == 0 || c.Probability == 0) {
+			addErr("levels[%d]: level:[%s] is never won, it needs a weight drawn or must_hit_by", i, level.Name)
+		}
+		weights = weights + level.Weight
+	}
+	if c.Probability > 0 && weights == 0 {
+		addErr("levels: no level has a weight to be drawn")
+	}
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+type mysteryLevel struct {
+	cfg   MysteryLevel
+	state State
+}
+
+// MysteryJackpots are the levels of the mystery jackpots of a machine, persisted by the store.
+// They outlive the machines wrapped, a machine reloaded keeps playing for the same levels.
+type MysteryJackpots struct {
+	cfg       MysteryConfig
+	mu        sync.Mutex
+	rand      *rand.Rand
+	levels    []*mysteryLevel
+	weight    int   // Sum of the weights of the levels
+	store     Store // nil if the levels are kept in memory only
+	dirty     bool  // Increments not saved yet
+	err       error // Error of the last save, nil once a save succeeds
+	stop      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewMysteryJackpots returns the levels of the mystery jackpots, restored from the store if it has them
+func NewMysteryJackpots(cfg MysteryConfig, store Store) (*MysteryJackpots, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("Invalid mystery jackpots %s", err)
+	}
+	states := make(map[string]State)
+	if store != nil {
+		var err error
+		if states, err = store.Load(); err != nil {
+			return nil, err
+		}
+	}
+	j := &MysteryJackpots{
+		cfg:   cfg,
+		rand:  rand.New(rand.NewSource(time.Now().UnixNano())),
+		store: store,
+		stop:  make(chan struct{}),
+	}
+	for _, cfg := range cfg.levels() {
+		level := &mysteryLevel{cfg: cfg}
+		if state, ok := states[cfg.Name]; ok {
+			level.state = restore(j.rand, state, cfg.MustHitBy)
+		} else {
+			level.state.Value = int64(cfg.Seed) * _SCALE
+			level.state.HitAt = hitAt(j.rand, level.state.Value, cfg.MustHitBy)
+		}
+		j.levels = append(j.levels, level)
+		j.weight = j.weight + cfg.Weight
+	}
+	return j, nil
+}
+
+// play adds the increments of the wager to the levels and returns the levels won.
+// The wins are saved at once, an error means they were won but not saved.
+func (j *MysteryJackpots) play(wager int) ([]Win, error) {
+	if wager < j.cfg.MinWager {
+		return nil, nil
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	won := make(map[*mysteryLevel]bool)
+	for _, level := range j.levels {
+		level.state.Value = level.state.Value + contribution(wager, level.cfg.Increment)
+		if level.state.HitAt > 0 && level.state.Value >= level.state.HitAt {
+			won[level] = true
+		}
+	}
+	j.dirty = true
+	if j.weight > 0 && j.rand.Float64() < j.cfg.Probability {
+		draw := j.rand.Intn(j.weight)
+		for _, level := range j.levels {
+			if draw < level.cfg.Weight {
+				won[level] = true
+				break
+			}
+			draw = draw - level.cfg.Weight
+		}
+	}
+
+	var wins []Win
+	for _, level := range j.levels {
+		if !won[level] {
+			continue
+		}
+		amount, next := award(level.state.Value, level.cfg.Seed, level.cfg.MustHitBy)
+		win := Win{Jackpot: level.cfg.Name, Amount: amount, Reason: MYSTERY, Time: time.Now().UTC()}
+		level.state.Value = next
+		level.state.HitAt = hitAt(j.rand, next, level.cfg.MustHitBy)
+		level.state.Wins++
+		level.state.LastWin = &win
+		wins = append(wins, win)
+	}
+	if len(wins) == 0 {
+		return nil, nil
+	}
+	return wins, j.save()
+}
+
+// Values returns the current amount of the levels
+func (j *MysteryJackpots) Values() []Value {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	values := make([]Value, len(j.levels))
+	for i, level := range j.levels {
+		values[i] = Value{Jackpot: level.cfg.Name, Amount: int(level.state.Value / _SCALE), MustHitBy: level.cfg.MustHitBy}
+	}
+	return values
+}
+
+// Flush saves the increments not saved yet
+func (j *MysteryJackpots) Flush() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if !j.dirty {
+		return nil
+	}
+	return j.save()
+}
+
+// save saves the state of every level, j.mu must be held
+func (j *MysteryJackpots) save() error {
+	if j.store == nil {
+		j.dirty = false
+		return nil
+	}
+	states := make(map[string]State, len(j.levels))
+	for _, level := range j.levels {
+		states[level.cfg.Name] = level.state
+	}
+	if j.err = j.store.Save(states); j.err != nil {
+		return j.err
+	}
+	j.dirty = false
+	return nil
+}
+
+// Err returns the error of the last save, nil if the increments were saved since
+func (j *MysteryJackpots) Err() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.err
+}
+
+// Persist flushes the increments every interval until the levels are closed.
+// Errors are sent to onError, the next flush trying again.
+func (j *MysteryJackpots) Persist(interval time.Duration, onError func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := j.Flush(); err != nil && onError != nil {
+				onError(err)
+			}
+		case <-j.stop:
+			return
+		}
+	}
+}
+
+// Close stops Persist and flushes the increments, it can be called more than once
+func (j *MysteryJackpots) Close() error {
+	j.closeOnce.Do(func() { close(j.stop) })
+	return j.Flush()
+}
+
+// MysteryMachine adds mystery jackpots to the rounds of any machine.
+// A level won is a JACKPOT_SPIN result appended to the spins of the round, its pay added to the payout.
+// The increments are taken from the wager of the round carried by the context, see slotmachine.WithWager.
+type MysteryMachine struct {
+	slotmachine.SlotMachine
+
+	jackpots *MysteryJackpots
+}
+
+// NewMysteryMachine wraps the machine with the levels of the mystery jackpots
+func NewMysteryMachine(machine slotmachine.SlotMachine, jackpots *MysteryJackpots) *MysteryMachine {
+	return &MysteryMachine{SlotMachine: machine, jackpots: jackpots}
+}
+
+// Spin plays the round on the machine, then the mystery jackpots if the round is eligible
+func (m *MysteryMachine) Spin(ctx context.Context, bet slotmachine.Bet) (int, []slotmachine.SpinResult, error) {
+	payout, results, err := m.SlotMachine.Spin(ctx, bet)
+	if err != nil {
+		return payout, results, err
+	}
+	log := logger.FromContext(ctx)
+	wins, err := m.jackpots.play(slotmachine.RoundWager(ctx, bet))
+	if err != nil {
+		// The levels are paid, they are saved again by the next flush
+		log.Error("Unable to save mystery jackpots", "err", err)
+	}
+	for _, win := range wins {
+		log.Info("Mystery jackpot won", "jackpot", win.Jackpot, "amount", win.Amount)
+		result := slotmachine.SpinResult{
+			Type:       slotmachine.JACKPOT_SPIN,
+			Stops:      []int{},
+			Window:     [][]slotmachine.Symbol{},
+			Pay:        win.Amount,
+			Multiplier: 1,
+			Jackpot:    win.Jackpot,
+		}
+		slotmachine.NotifySpin(ctx, result)
+		results = append(results, result)
+		payout = payout + win.Amount
+	}
+	return payout, results, nil
+}
+
+// JackpotValues returns the current amount of the levels
+func (m *MysteryMachine) JackpotValues() []Value {
+	return m.jackpots.Values()
+}
+
+// Unwrap returns the machine wrapped, whose capabilities are found with slotmachine.Capability
+func (m *MysteryMachine) Unwrap() slotmachine.SlotMachine {
+	return m.SlotMachine
+}
+
+// TheoreticalRTP returns the RTP of the machine wrapped with the increments of the levels, for eligible rounds.
+// The seeds are paid by the operator, they are not part of it.
+func (m *MysteryMachine) TheoreticalRTP() (float64, error) {
+	calculator, ok := slotmachine.Capability[interface{ TheoreticalRTP() (float64, error) }](m.SlotMachine)
+	if !ok {
+		return 0, errors.New("Machine does not compute the theoretical RTP")
+	}
+	rtp, err := calculator.TheoreticalRTP()
+	if err != nil {
+		return 0, err
+	}
+	for _, level := range m.jackpots.levels {
+		rtp = rtp + level.cfg.Increment/100
+	}
+	return rtp, nil
+}
+
+// SimulatedRTP returns the RTP of the machine wrapped found by simulation, with the increments of the levels
+func (m *MysteryMachine) SimulatedRTP(rounds int) (slotmachine.RTPEstimate, error) {
+	simulator, ok := slotmachine.Capability[interface {
+		SimulatedRTP(rounds int) (slotmachine.RTPEstimate, error)
+	}](m.SlotMachine)
+	if !ok {
+		return slotmachine.RTPEstimate{}, errors.New("Machine does not simulate its RTP")
+	}
+	estimate, err := simulator.SimulatedRTP(rounds)
+	if err != nil {
+		return estimate, err
+	}
+	for _, level := range m.jackpots.levels {
+		estimate.RTP = estimate.RTP + level.cfg.Increment/100
+		estimate.Low = estimate.Low + level.cfg.Increment/100
+		estimate.High = estimate.High + level.cfg.Increment/100
+	}
+	return estimate, nil
+}
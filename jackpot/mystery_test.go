@@ -0,0 +1,162 @@
+package jackpot
+
+import (
+	"context"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"trippy/slotmachine"
+)
+
+// flatMachine wagers the coins of the bet and pays nothing
+type flatMachine struct{}
+
+func (flatMachine) Wager(bet slotmachine.Bet, chips int) (int, error) {
+	return bet.Coins, nil
+}
+
+func (flatMachine) Spin(ctx context.Context, bet slotmachine.Bet) (int, []slotmachine.SpinResult, error) {
+	return 0, []slotmachine.SpinResult{{Type: slotmachine.MAIN_SPIN}}, nil
+}
+
+// Every level is won before its ceiling, and the draw awards all of them
+func TestMysteryLevels(t *testing.T) {
+	levels, err := NewMysteryJackpots(MysteryConfig{Probability: 0.05}, nil)
+	if err != nil {
+		t.Fatalf("Expected:[nil] Got:[%s]", err)
+	}
+	machine := NewMysteryMachine(flatMachine{}, levels)
+	won := make(map[string]int)
+	for i := 0; i < 20000; i++ {
+		before := machine.JackpotValues()
+		payout, results, err := machine.Spin(slotmachine.WithWager(context.Background(), 100), slotmachine.Bet{Coins: 100})
+		if err != nil {
+			t.Fatalf("Expected:[nil] Got:[%s]", err)
+		}
+		var paid int
+		for _, result := range results[1:] {
+			if result.Type != slotmachine.JACKPOT_SPIN || result.Jackpot == "" {
+				t.Fatalf("Expected a jackpot spin Got:[%+v]", result)
+			}
+			for _, value := range before {
+				if value.Jackpot == result.Jackpot && (result.Pay < value.Amount || result.Pay > value.MustHitBy) {
+					t.Errorf("Expected:[%s] between [%d %d] Got:[%d]", value.Jackpot, value.Amount, value.MustHitBy, result.Pay)
+				}
+			}
+			won[result.Jackpot]++
+			paid = paid + result.Pay
+		}
+		if payout != paid {
+			t.Fatalf("Expected the jackpots in the payout:[%d] Got:[%d]", paid, payout)
+		}
+	}
+	for _, level := range DefaultMysteryLevels() {
+		if won[level.Name] == 0 {
+			t.Errorf("Expected:[%s] won Got:[%v]", level.Name, won)
+		}
+	}
+	if won[MINI] < won[MINOR] || won[MINOR] < won[GRAND] {
+		t.Errorf("Expected the lower levels won more often Got:[%v]", won)
+	}
+}
+
+func TestMysteryMinWager(t *testing.T) {
+	levels, _ := NewMysteryJackpots(MysteryConfig{Probability: 1, MinWager: 10}, nil)
+	machine := NewMysteryMachine(flatMachine{}, levels)
+	if _, results, _ := machine.Spin(slotmachine.WithWager(context.Background(), 9), slotmachine.Bet{Coins: 9}); len(results) != 1 {
+		t.Errorf("Expected no jackpot below the minimum wager Got:[%+v]", results)
+	}
+	if values := machine.JackpotValues(); values[0].Amount != 10 {
+		t.Errorf("Expected no increment below the minimum wager Got:[%+v]", values[0])
+	}
+	if _, results, _ := machine.Spin(slotmachine.WithWager(context.Background(), 10), slotmachine.Bet{Coins: 10}); len(results) != 2 {
+		t.Errorf("Expected a jackpot drawn Got:[%+v]", results)
+	}
+	// Without a wager in the context, the total of the bet is wagered
+	if _, results, _ := machine.Spin(context.Background(), slotmachine.Bet{Coins: 2, Denom: 1, Lines: 5}); len(results) != 2 {
+		t.Errorf("Expected a jackpot drawn for the total of the bet Got:[%+v]", results)
+	}
+}
+
+// limitedMachine describes its bets, a capability of its own
+type limitedMachine struct {
+	flatMachine
+}
+
+func (limitedMachine) Limits() slotmachine.BetLimits {
+	return slotmachine.BetLimits{MinBet: 1, MaxBet: 5}
+}
+
+// The capabilities of the machine wrapped are found through the wrapper
+func TestMysteryCapabilities(t *testing.T) {
+	levels, _ := NewMysteryJackpots(MysteryConfig{}, nil)
+	machine := NewMysteryMachine(limitedMachine{}, levels)
+	limited, ok := slotmachine.Capability[interface{ Limits() slotmachine.BetLimits }](machine)
+	if !ok || limited.Limits().MaxBet != 5 {
+		t.Errorf("Expected the limits of the machine wrapped Got:[%t]", ok)
+	}
+	if _, ok = slotmachine.Capability[interface {
+		BonusGame() *slotmachine.BonusRules
+	}](machine); ok {
+		t.Errorf("Expected no bonus game")
+	}
+	if _, ok = slotmachine.Capability[interface{ JackpotValues() []Value }](machine); !ok {
+		t.Errorf("Expected the levels of the wrapper")
+	}
+}
+
+// The levels are saved by the store and restored with their increments, whatever the machine they wrap
+func TestMysteryStore(t *testing.T) {
+	store := NewFileStore(filepath.Join(t.TempDir(), "mystery.json"))
+	// Hardly ever drawn, never hit by
+	cfg := MysteryConfig{Probability: 1e-12, Levels: []MysteryLevel{{Name: MINI, Seed: 10, Increment: 0.5, Weight: 1}}}
+	levels, err := NewMysteryJackpots(cfg, store)
+	if err != nil {
+		t.Fatalf("Expected:[nil] Got:[%s]", err)
+	}
+	ctx := slotmachine.WithWager(context.Background(), 1000)
+	for i := 0; i < 3; i++ {
+		NewMysteryMachine(flatMachine{}, levels).Spin(ctx, slotmachine.Bet{Coins: 1000})
+	}
+	values := levels.Values()
+	if values[0].Amount != 10+3*5 {
+		t.Errorf("Expected the increments of 3 rounds Got:[%+v]", values[0])
+	}
+	// Closed twice, by the reload of a machine and the server stopping
+	if err = levels.Close(); err != nil {
+		t.Fatalf("Expected:[nil] Got:[%s]", err)
+	}
+	if err = levels.Close(); err != nil {
+		t.Fatalf("Expected:[nil] Got:[%s]", err)
+	}
+
+	restored, err := NewMysteryJackpots(cfg, store)
+	if err != nil || !reflect.DeepEqual(restored.Values(), values) {
+		t.Errorf("Expected:[%+v] Got:[%+v] [Error:%v]", values, restored.Values(), err)
+	}
+}
+
+type mysterySample struct {
+	name  string
+	cfg   MysteryConfig
+	valid bool
+}
+
+var mysterySamples = []mysterySample{
+	{name: "default levels", cfg: MysteryConfig{Probability: 0.01}, valid: true},
+	{name: "must-hit-by only", cfg: MysteryConfig{}, valid: true},
+	{name: "probability above 1", cfg: MysteryConfig{Probability: 2}},
+	{name: "level never won", cfg: MysteryConfig{Levels: []MysteryLevel{{Name: MINI, Seed: 10, Increment: 1, Weight: 1}}}},
+	{name: "levels without weight", cfg: MysteryConfig{Probability: 0.01, Levels: []MysteryLevel{{Name: MINI, Seed: 10, MustHitBy: 20}}}},
+	{name: "ceiling below seed", cfg: MysteryConfig{Levels: []MysteryLevel{{Name: MINI, Seed: 10, MustHitBy: 5}}}},
+	{name: "same name", cfg: MysteryConfig{Levels: []MysteryLevel{{Name: MINI, MustHitBy: 5}, {Name: MINI, MustHitBy: 5}}}},
+}
+
+func TestMysteryValidate(t *testing.T) {
+	for _, sample := range mysterySamples {
+		if err := sample.cfg.Validate(); (err == nil) != sample.valid {
+			t.Errorf("[Sample:%s] Expected valid:[%t] Got:[%v]", sample.name, sample.valid, err)
+		}
+	}
+}
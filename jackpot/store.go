@@ -0,0 +1,67 @@
+package jackpot
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// Store persists the state of the pools, by jackpot name
+type Store interface {
+	Load() (map[string]State, error)
+	Save(map[string]State) error
+}
+
+// FileStore keeps the pools in a JSON file
+type FileStore struct {
+	path string
+}
+
+func NewFileStore(path string) *FileStore {
+	return &FileStore{path: path}
+}
+
+// Load reads the pools, none if the file does not exist yet
+func (fs *FileStore) Load() (map[string]State, error) {
+	states := make(map[string]State)
+	data, err := ioutil.ReadFile(fs.path)
+	if os.IsNotExist(err) {
+		return states, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read jackpots [File:%s] [Error:%s]", fs.path, err)
+	}
+	if err = json.Unmarshal(data, &states); err != nil {
+		return nil, fmt.Errorf("Unable to parse jackpots [File:%s] [Error:%s]", fs.path, err)
+	}
+	return states, nil
+}
+
+// Save writes the pools to a temporary file renamed over the previous one,
+// a crash never leaves the file half written
+func (fs *FileStore) Save(states map[string]State) error {
+	data, err := json.MarshalIndent(states, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(fs.path), filepath.Base(fs.path)+".tmp")
+	if err != nil {
+		return fmt.Errorf("Unable to save jackpots [File:%s] [Error:%s]", fs.path, err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err = tmp.Write(data); err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), fs.path)
+	}
+	if err != nil {
+		return fmt.Errorf("Unable to save jackpots [File:%s] [Error:%s]", fs.path, err)
+	}
+	return nil
+}
@@ -0,0 +1,230 @@
+// Package logger writes levelled, structured log lines in logfmt or JSON.
+//
+// A Logger is safe for concurrent use. Loggers derived with With share the
+// output of their parent and add their fields to every line they write.
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type Level int
+
+const (
+	DEBUG Level = iota
+	INFO
+	WARN
+	ERROR
+)
+
+var levelNames = map[Level]string{
+	DEBUG: "debug",
+	INFO:  "info",
+	WARN:  "warn",
+	ERROR: "error",
+}
+
+func (l Level) String() string {
+	if name, ok := levelNames[l]; ok {
+		return name
+	}
+	return strconv.Itoa(int(l))
+}
+
+// ParseLevel returns the level for its name, eg. "debug" or "WARN"
+func ParseLevel(name string) (Level, error) {
+	for level, levelName := range levelNames {
+		if strings.EqualFold(name, levelName) {
+			return level, nil
+		}
+	}
+	return INFO, fmt.Errorf("Unknown log level:[%s]", name)
+}
+
+type Format string
+
+const (
+	LOGFMT Format = "logfmt"
+	JSON   Format = "json"
+)
+
+// ParseFormat returns the format for its name, "logfmt" or "json"
+func ParseFormat(name string) (Format, error) {
+	switch Format(strings.ToLower(name)) {
+	case LOGFMT:
+		return LOGFMT, nil
+	case JSON:
+		return JSON, nil
+	}
+	return LOGFMT, fmt.Errorf("Unknown log format:[%s]", name)
+}
+
+// output is shared by a logger and all the loggers derived from it
+type output struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+type Logger struct {
+	out    *output
+	level  Level
+	format Format
+	fields []interface{} // Key value pairs added to every line
+}
+
+func New(w io.Writer, level Level, format Format) *Logger {
+	return &Logger{
+		out:    &output{w: w},
+		level:  level,
+		format: format,
+	}
+}
+
+// Discard returns a logger which writes nothing
+func Discard() *Logger {
+	return New(ioutil.Discard, ERROR+1, LOGFMT)
+}
+
+var (
+	defaultMu     sync.RWMutex
+	defaultLogger = New(os.Stdout, INFO, LOGFMT)
+)
+
+// Default returns the logger used when none has been injected
+func Default() *Logger {
+	defaultMu.RLock()
+	defer defaultMu.RUnlock()
+	return defaultLogger
+}
+
+// SetDefault replaces the logger used when none has been injected
+func SetDefault(l *Logger) {
+	defaultMu.Lock()
+	defaultLogger = l
+	defaultMu.Unlock()
+}
+
+// With returns a logger which adds the key value pairs to every line
+func (l *Logger) With(keyvals ...interface{}) *Logger {
+	fields := make([]interface{}, 0, len(l.fields)+len(keyvals))
+	fields = append(fields, l.fields...)
+	fields = append(fields, keyvals...)
+	return &Logger{
+		out:    l.out,
+		level:  l.level,
+		format: l.format,
+		fields: fields,
+	}
+}
+
+// Enabled reports whether lines of the level are written
+func (l *Logger) Enabled(level Level) bool {
+	return level >= l.level
+}
+
+func (l *Logger) Debug(msg string, keyvals ...interface{}) { l.log(DEBUG, msg, keyvals) }
+func (l *Logger) Info(msg string, keyvals ...interface{})  { l.log(INFO, msg, keyvals) }
+func (l *Logger) Warn(msg string, keyvals ...interface{})  { l.log(WARN, msg, keyvals) }
+func (l *Logger) Error(msg string, keyvals ...interface{}) { l.log(ERROR, msg, keyvals) }
+
+func (l *Logger) log(level Level, msg string, keyvals []interface{}) {
+	if !l.Enabled(level) {
+		return
+	}
+	all := make([]interface{}, 0, 6+len(l.fields)+len(keyvals))
+	all = append(all, "ts", time.Now().UTC().Format(time.RFC3339Nano), "level", level.String(), "msg", msg)
+	all = append(all, l.fields...)
+	all = append(all, keyvals...)
+	if len(all)%2 != 0 {
+		all = append(all, "MISSING")
+	}
+
+	var buf bytes.Buffer
+	if l.format == JSON {
+		writeJSON(&buf, all)
+	} else {
+		writeLogfmt(&buf, all)
+	}
+
+	l.out.mu.Lock()
+	l.out.w.Write(buf.Bytes())
+	l.out.mu.Unlock()
+}
+
+func writeLogfmt(buf *bytes.Buffer, keyvals []interface{}) {
+	for i := 0; i < len(keyvals); i += 2 {
+		if i > 0 {
+			buf.WriteByte(' ')
+		}
+		buf.WriteString(fmt.Sprint(keyvals[i]))
+		buf.WriteByte('=')
+		value := valueString(keyvals[i+1])
+		if value == "" || strings.ContainsAny(value, " =\"\t\n") {
+			value = strconv.Quote(value)
+		}
+		buf.WriteString(value)
+	}
+	buf.WriteByte('\n')
+}
+
+func writeJSON(buf *bytes.Buffer, keyvals []interface{}) {
+	buf.WriteByte('{')
+	for i := 0; i < len(keyvals); i += 2 {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, _ := json.Marshal(fmt.Sprint(keyvals[i]))
+		buf.Write(key)
+		buf.WriteByte(':')
+
+		var value interface{} = keyvals[i+1]
+		if err, ok := value.(error); ok {
+			value = err.Error()
+		}
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			encoded, _ = json.Marshal(fmt.Sprint(value))
+		}
+		buf.Write(encoded)
+	}
+	buf.WriteString("}\n")
+}
+
+func valueString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case error:
+		return v.Error()
+	case fmt.Stringer:
+		return v.String()
+	}
+	return fmt.Sprint(value)
+}
+
+type ctxKey struct{}
+
+// NewContext returns a context carrying the logger
+func NewContext(ctx context.Context, l *Logger) context.Context {
+	return context.WithValue(ctx, ctxKey{}, l)
+}
+
+// FromContext returns the logger carried by the context, or the default logger
+func FromContext(ctx context.Context) *Logger {
+	if ctx != nil {
+		if l, ok := ctx.Value(ctxKey{}).(*Logger); ok {
+			return l
+		}
+	}
+	return Default()
+}
@@ -0,0 +1,87 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+var (
+	levelSamples = []levelSample{
+		{name: "debug", level: DEBUG},
+		{name: "INFO", level: INFO},
+		{name: "Warn", level: WARN},
+		{name: "error", level: ERROR},
+		{name: "verbose", level: INFO, errExpected: true},
+	}
+)
+
+type levelSample struct {
+	name        string
+	level       Level
+	errExpected bool
+}
+
+func TestParseLevel(t *testing.T) {
+	for _, sample := range levelSamples {
+		level, err := ParseLevel(sample.name)
+		if (err != nil) != sample.errExpected {
+			t.Errorf("Name:[%s] Expected error:[%t] Got:[%s]", sample.name, sample.errExpected, err)
+			continue
+		}
+		if level != sample.level {
+			t.Errorf("Name:[%s] Expected:[%s] Got:[%s]", sample.name, sample.level, level)
+		}
+	}
+}
+
+func TestLevelFilter(t *testing.T) {
+	var buf bytes.Buffer
+	l := New(&buf, WARN, LOGFMT)
+	l.Debug("hidden")
+	l.Info("hidden")
+	l.Warn("shown")
+	l.Error("shown")
+	if lines := strings.Count(buf.String(), "\n"); lines != 2 {
+		t.Errorf("Expected:[2 lines] Got:[%d] Output:[%s]", lines, buf.String())
+	}
+}
+
+func TestLogfmt(t *testing.T) {
+	var buf bytes.Buffer
+	l := New(&buf, DEBUG, LOGFMT).With("round", "abc")
+	l.Info("spin done", "pay", 10, "err", errors.New("bad thing"))
+	line := buf.String()
+	for _, expected := range []string{`level=info`, `msg="spin done"`, `round=abc`, `pay=10`, `err="bad thing"`} {
+		if !strings.Contains(line, expected) {
+			t.Errorf("Expected:[%s] in Line:[%s]", expected, line)
+		}
+	}
+}
+
+func TestJSON(t *testing.T) {
+	var buf bytes.Buffer
+	l := New(&buf, DEBUG, JSON).With("round", "abc")
+	l.Warn("low balance", "chips", 5, "odd")
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &fields); err != nil {
+		t.Fatalf("Line:[%s] is not JSON [Error:%s]", buf.String(), err)
+	}
+	if fields["level"] != "warn" || fields["round"] != "abc" || fields["chips"] != float64(5) || fields["odd"] != "MISSING" {
+		t.Errorf("Unexpected fields. Got:[%v]", fields)
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	l := Discard()
+	if FromContext(NewContext(context.Background(), l)) != l {
+		t.Errorf("Expected the logger in the context")
+	}
+	if FromContext(context.Background()) != Default() {
+		t.Errorf("Expected the default logger")
+	}
+}
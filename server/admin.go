@@ -0,0 +1,333 @@
+package server
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"trippy/logger"
+	"trippy/slotmachine"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+const (
+	_HEADER_AUTHORIZATION  = "Authorization"
+	_BEARER_PREFIX         = "Bearer "
+	_RTP_SIMULATION_ROUNDS = 100000 // Rounds simulated for the RTP of the machines not modelled
+)
+
+type respMachine struct {
+	Name       string   `json:"name"`
+	Engine     string   `json:"engine"`
+	Enabled    bool     `json:"enabled"`
+	Definition string   `json:"definition,omitempty"` // Empty for the engine default
+	RTP        *respRTP `json:"rtp,omitempty"`
+}
+
+// respRTP compares the return to player of the machine definition with the one observed since the server started
+type respRTP struct {
+	Theoretical float64 `json:"theoretical"`
+	Observed    float64 `json:"observed"`
+	Rounds      int     `json:"rounds"`
+	Wagered     int     `json:"wagered"`
+	Paid        int     `json:"paid"`
+	Error       string  `json:"error,omitempty"` // Why the theoretical RTP is unknown
+
+	// Simulated is the RTP found by playing rounds when the theoretical RTP is unknown
+	Simulated *slotmachine.RTPEstimate `json:"simulated,omitempty"`
+}
+
+type respMaintenance struct {
+	Enabled bool       `json:"enabled"`
+	Message string     `json:"message,omitempty"`
+	Since   *time.Time `json:"since,omitempty"`
+}
+
+type reqMaintenance struct {
+	Enabled bool   `json:"enabled"`
+	Message string `json:"message"` // Sent to the players with the MAINTENANCE error
+}
+
+// rtpCalculator is implemented by the machines which know their theoretical RTP
+type rtpCalculator interface {
+	TheoreticalRTP() (float64, error)
+}
+
+// rtpSimulator is implemented by the machines which estimate their RTP by playing rounds
+type rtpSimulator interface {
+	SimulatedRTP(rounds int) (slotmachine.RTPEstimate, error)
+}
+
+// rtpSimulations keeps the simulated RTP of the machines, a machine is simulated again once reloaded
+type rtpSimulations struct {
+	mu        sync.Mutex
+	estimates map[string]*rtpSimulation
+}
+
+// rtpSimulation is the simulation of a machine, the estimate is set once done is closed
+type rtpSimulation struct {
+	machine  slotmachine.SlotMachine
+	done     chan struct{}
+	estimate slotmachine.RTPEstimate
+	err      error
+}
+
+var simulations = rtpSimulations{estimates: make(map[string]*rtpSimulation)}
+
+// get returns the simulated RTP of the machine, simulating it on the first call.
+// The rounds are played without the lock: the calls for the machine in the meantime wait for the same
+// simulation, the other machines are not held up.
+func (s *rtpSimulations) get(name string, machine slotmachine.SlotMachine, simulator rtpSimulator) (slotmachine.RTPEstimate, error) {
+	s.mu.Lock()
+	simulation, ok := s.estimates[name]
+	if ok && simulation.machine == machine {
+		s.mu.Unlock()
+		<-simulation.done
+		return simulation.estimate, simulation.err
+	}
+	simulation = &rtpSimulation{machine: machine, done: make(chan struct{})}
+	s.estimates[name] = simulation
+	s.mu.Unlock()
+
+	defer close(simulation.done)
+	simulation.estimate, simulation.err = simulator.SimulatedRTP(_RTP_SIMULATION_ROUNDS)
+	return simulation.estimate, simulation.err
+}
+
+// maintenanceMode rejects the new rounds while it is enabled
+type maintenanceMode struct {
+	mu      sync.RWMutex
+	enabled bool
+	message string
+	since   time.Time
+}
+
+var maintenance = &maintenanceMode{}
+
+func (m *maintenanceMode) set(enabled bool, message string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if enabled && !m.enabled {
+		m.since = time.Now()
+	}
+	m.enabled = enabled
+	m.message = message
+}
+
+// active reports whether the maintenance is enabled, with the message for the players
+func (m *maintenanceMode) active() (bool, string) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.enabled, m.message
+}
+
+func (m *maintenanceMode) status() respMaintenance {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	status := respMaintenance{Enabled: m.enabled}
+	if m.enabled {
+		since := m.since
+		status.Message, status.Since = m.message, &since
+	}
+	return status
+}
+
+// adminAPI serves the operator endpoints under /admin/.
+// Every request must carry the admin key as a bearer token.
+type adminAPI struct {
+	key    string
+	logCfg LogConfig
+
+	mu sync.Mutex // Changes to the machines are made one at a time
+}
+
+func (a *adminAPI) register(router *httprouter.Router) {
+	router.GET("/admin/machines", a.auth(a.listMachines))
+	router.GET("/admin/machines/:machine", a.auth(a.getMachine))
+	router.POST("/admin/machines/:machine/enable", a.auth(a.enableMachine))
+	router.POST("/admin/machines/:machine/disable", a.auth(a.disableMachine))
+	router.POST("/admin/machines/:machine/reload", a.auth(a.reloadMachine))
+	router.GET("/admin/maintenance", a.auth(a.getMaintenance))
+	router.PUT("/admin/maintenance", a.auth(a.setMaintenance))
+}
+
+// auth rejects the requests without the admin key
+func (a *adminAPI) auth(next httprouter.Handle) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		header := r.Header.Get(_HEADER_AUTHORIZATION)
+		key := strings.TrimPrefix(header, _BEARER_PREFIX)
+		if !strings.HasPrefix(header, _BEARER_PREFIX) || subtle.ConstantTimeCompare([]byte(key), []byte(a.key)) != 1 {
+			logger.FromContext(r.Context()).Warn("Admin request blocked, invalid key", "path", r.URL.Path, "remote", r.RemoteAddr)
+			respondWithError(w, newAPIError(_ERR_UNAUTHORIZED, ""))
+			return
+		}
+		next(w, r, ps)
+	}
+}
+
+func (a *adminAPI) listMachines(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	cfgs := machines.allConfigs()
+	response := make([]respMachine, len(cfgs))
+	for i, cfg := range cfgs {
+		response[i] = newRespMachine(cfg)
+	}
+	writeResponse(w, http.StatusOK, response)
+}
+
+func (a *adminAPI) getMachine(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	cfg, ok := a.machineConfig(w, ps)
+	if !ok {
+		return
+	}
+	machine, found := machines.get(cfg.Name)
+	if !found {
+		// Disabled machines are created to compute their RTP, without being served
+		var err error
+		if machine, err = newMachine(cfg, a.logCfg); err != nil {
+			respondWithError(w, newAPIError(_ERR_INVALID_DEFINITION, err.Error()).withDetail("machine", cfg.Name))
+			return
+		}
+	}
+	response := newRespMachine(cfg)
+	response.RTP = machineRTP(cfg.Name, machine)
+	writeResponse(w, http.StatusOK, response)
+}
+
+func (a *adminAPI) enableMachine(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	cfg, ok := a.machineConfig(w, ps)
+	if !ok {
+		return
+	}
+	if !cfg.Enabled {
+		machine, err := newMachine(cfg, a.logCfg)
+		if err != nil {
+			respondWithError(w, newAPIError(_ERR_INVALID_DEFINITION, err.Error()).withDetail("machine", cfg.Name))
+			return
+		}
+		cfg.Enabled = true
+		machines.configure(cfg)
+		machines.set(cfg.Name, machine)
+		logger.FromContext(r.Context()).Info("Admin: Machine enabled", "machine", cfg.Name, "remote", r.RemoteAddr)
+	}
+	writeResponse(w, http.StatusOK, newRespMachine(cfg))
+}
+
+func (a *adminAPI) disableMachine(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	cfg, ok := a.machineConfig(w, ps)
+	if !ok {
+		return
+	}
+	if cfg.Enabled {
+		cfg.Enabled = false
+		machines.configure(cfg)
+		machines.remove(cfg.Name)
+		logger.FromContext(r.Context()).Info("Admin: Machine disabled", "machine", cfg.Name, "remote", r.RemoteAddr,
+			"machines_enabled", machines.count())
+	}
+	writeResponse(w, http.StatusOK, newRespMachine(cfg))
+}
+
+// reloadMachine loads the definition file of the machine again.
+// The rounds in play finish on the previous definition.
+func (a *adminAPI) reloadMachine(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	cfg, ok := a.machineConfig(w, ps)
+	if !ok {
+		return
+	}
+	log := logger.FromContext(r.Context())
+	machine, err := newMachine(cfg, a.logCfg)
+	if err != nil {
+		log.Warn("Admin: Machine reload failed, keeping the current definition", "machine", cfg.Name, "err", err)
+		respondWithError(w, newAPIError(_ERR_INVALID_DEFINITION, err.Error()).withDetail("machine", cfg.Name))
+		return
+	}
+	// A disabled machine is only checked, it is served with the new definition once enabled
+	if cfg.Enabled {
+		machines.set(cfg.Name, machine)
+	}
+	response := newRespMachine(cfg)
+	response.RTP = machineRTP(cfg.Name, machine)
+	log.Info("Admin: Machine reloaded", "machine", cfg.Name, "definition", cfg.Definition, "remote", r.RemoteAddr,
+		"rtp", response.RTP.Theoretical)
+	writeResponse(w, http.StatusOK, response)
+}
+
+func (a *adminAPI) getMaintenance(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	writeResponse(w, http.StatusOK, maintenance.status())
+}
+
+func (a *adminAPI) setMaintenance(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	var req reqMaintenance
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondWithError(w, newAPIError(_ERR_INVALID_REQUEST, fmt.Sprintf("Body is not a valid maintenance request [Error:%s]", err)))
+		return
+	}
+	maintenance.set(req.Enabled, req.Message)
+	logger.FromContext(r.Context()).Info("Admin: Maintenance mode set", "enabled", req.Enabled, "message", req.Message,
+		"remote", r.RemoteAddr)
+	writeResponse(w, http.StatusOK, maintenance.status())
+}
+
+// machineConfig returns the config of the machine in the path.
+// If it is unknown, the error is written to the response and ok is false.
+func (a *adminAPI) machineConfig(w http.ResponseWriter, ps httprouter.Params) (MachineConfig, bool) {
+	name := ps.ByName(_PARA_SPIN_MACHINE)
+	cfg, ok := machines.config(name)
+	if !ok {
+		respondWithError(w, newAPIError(_ERR_UNKNOWN_MACHINE, fmt.Sprintf("Unknown machine:[%s]", name)).
+			withDetail("machine", name))
+	}
+	return cfg, ok
+}
+
+func newRespMachine(cfg MachineConfig) respMachine {
+	return respMachine{
+		Name:       cfg.Name,
+		Engine:     cfg.Engine,
+		Enabled:    cfg.Enabled,
+		Definition: cfg.Definition,
+	}
+}
+
+// machineRTP reads the observed RTP of the machine from its metrics, and its theoretical RTP from the engine.
+// The RTP of the machines whose features the engine does not model is simulated, once per machine.
+func machineRTP(name string, machine slotmachine.SlotMachine) *respRTP {
+	rtp := &respRTP{
+		Rounds:  int(counterValue(roundsTotal, name)),
+		Wagered: int(counterValue(wageredTotal, name)),
+		Paid:    int(counterValue(paidTotal, name)),
+	}
+	if rtp.Wagered > 0 {
+		rtp.Observed = float64(rtp.Paid) / float64(rtp.Wagered)
+	}
+	calculator, ok := slotmachine.Capability[rtpCalculator](machine)
+	if !ok {
+		rtp.Error = "Engine does not compute the theoretical RTP"
+		return rtp
+	}
+	theoretical, err := calculator.TheoreticalRTP()
+	if err != nil {
+		rtp.Error = err.Error()
+
+		// The features the engine does not model are simulated
+		if simulator, ok := slotmachine.Capability[rtpSimulator](machine); ok {
+			if estimate, err := simulations.get(name, machine, simulator); err == nil {
+				rtp.Simulated = &estimate
+			}
+		}
+		return rtp
+	}
+	rtp.Theoretical = theoretical
+	return rtp
+}
@@ -0,0 +1,242 @@
+package server
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"trippy/slotmachine"
+	"trippy/slotmachine/engine/atkins"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+const _TEST_ADMIN_KEY = "admin-secret"
+
+type adminSample struct {
+	method string
+	path   string
+	body   string
+	key    string
+	status int
+}
+
+func adminRequest(router http.Handler, sample adminSample) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(sample.method, sample.path, strings.NewReader(sample.body))
+	if sample.key != "" {
+		r.Header.Set(_HEADER_AUTHORIZATION, _BEARER_PREFIX+sample.key)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, r)
+	return w
+}
+
+func testAdmin(t *testing.T, router http.Handler, sample adminSample) *httptest.ResponseRecorder {
+	w := adminRequest(router, sample)
+	if w.Code != sample.status {
+		t.Errorf("[%s %s] Expected:[%d] Got:[%d %s]", sample.method, sample.path, sample.status, w.Code, w.Body)
+	}
+	return w
+}
+
+func newAdminRouter(t *testing.T) http.Handler {
+	machines = newMachineRegistry()
+	maintenance = &maintenanceMode{}
+	def := filepath.Join(t.TempDir(), "atkins-diet.json")
+	data, err := ioutil.ReadFile("../slotmachine/engine/atkins/atkins-diet.json")
+	if err != nil {
+		t.Fatalf("Unable to read definition [Error:%s]", err)
+	}
+	if err = ioutil.WriteFile(def, data, 0600); err != nil {
+		t.Fatalf("Unable to write definition [Error:%s]", err)
+	}
+	admin := &adminAPI{key: _TEST_ADMIN_KEY}
+	for _, cfg := range []MachineConfig{
+		{Name: _ATKINS_DIET_MACHINE, Engine: _ENGINE_ATKINS, Enabled: true, Definition: def},
+		{Name: "atkins-classic", Engine: _ENGINE_ATKINS},
+	} {
+		machines.configure(cfg)
+		if cfg.Enabled {
+			machine, _ := newMachine(cfg, LogConfig{})
+			machines.set(cfg.Name, machine)
+		}
+	}
+	router := httprouter.New()
+	admin.register(router)
+	return router
+}
+
+func TestAdminAuth(t *testing.T) {
+	defer func(mr *machineRegistry) { machines = mr }(machines)
+	router := newAdminRouter(t)
+	samples := []adminSample{
+		{http.MethodGet, "/admin/machines", "", "", http.StatusUnauthorized},
+		{http.MethodGet, "/admin/machines", "", "wrong", http.StatusUnauthorized},
+		{http.MethodPut, "/admin/maintenance", `{"enabled":true}`, "", http.StatusUnauthorized},
+		{http.MethodGet, "/admin/machines", "", _TEST_ADMIN_KEY, http.StatusOK},
+	}
+	for _, sample := range samples {
+		testAdmin(t, router, sample)
+	}
+	if enabled, _ := maintenance.active(); enabled {
+		t.Errorf("Expected maintenance to be set only with the admin key")
+	}
+}
+
+func TestAdminMachines(t *testing.T) {
+	defer func(mr *machineRegistry) { machines = mr }(machines)
+	router := newAdminRouter(t)
+
+	var list []respMachine
+	w := testAdmin(t, router, adminSample{http.MethodGet, "/admin/machines", "", _TEST_ADMIN_KEY, http.StatusOK})
+	if err := json.NewDecoder(w.Body).Decode(&list); err != nil || len(list) != 2 || list[0].Name != "atkins-classic" || list[0].Enabled {
+		t.Errorf("Expected 2 machines ordered by name. Got:[%+v] [Error:%v]", list, err)
+	}
+
+	var machine respMachine
+	w = testAdmin(t, router, adminSample{http.MethodGet, "/admin/machines/atkins-diet", "", _TEST_ADMIN_KEY, http.StatusOK})
+	if err := json.NewDecoder(w.Body).Decode(&machine); err != nil || machine.RTP == nil || machine.RTP.Theoretical <= 0 {
+		t.Errorf("Expected the theoretical RTP. Got:[%+v] [Error:%v]", machine, err)
+	}
+
+	testAdmin(t, router, adminSample{http.MethodPost, "/admin/machines/atkins-diet/disable", "", _TEST_ADMIN_KEY, http.StatusOK})
+	w = playRequest(t, SpinV2, userClaims{UID: "123", Chips: 1000, Bet: 1})
+	if !strings.Contains(w.Body.String(), string(_ERR_MACHINE_DISABLED)) {
+		t.Errorf("Expected:[%s] Got:[%d %s]", _ERR_MACHINE_DISABLED, w.Code, w.Body)
+	}
+
+	testAdmin(t, router, adminSample{http.MethodPost, "/admin/machines/atkins-classic/enable", "", _TEST_ADMIN_KEY, http.StatusOK})
+	if _, ok := machines.get("atkins-classic"); !ok {
+		t.Errorf("Expected machine:[atkins-classic] to be served")
+	}
+	testAdmin(t, router, adminSample{http.MethodPost, "/admin/machines/unknown/enable", "", _TEST_ADMIN_KEY, http.StatusBadRequest})
+}
+
+func TestAdminReload(t *testing.T) {
+	defer func(mr *machineRegistry) { machines = mr }(machines)
+	router := newAdminRouter(t)
+	before, _ := machines.get(_ATKINS_DIET_MACHINE)
+
+	testAdmin(t, router, adminSample{http.MethodPost, "/admin/machines/atkins-diet/reload", "", _TEST_ADMIN_KEY, http.StatusOK})
+	after, _ := machines.get(_ATKINS_DIET_MACHINE)
+	if after == before {
+		t.Errorf("Expected the machine to be created again from its definition")
+	}
+
+	cfg, _ := machines.config(_ATKINS_DIET_MACHINE)
+	if err := ioutil.WriteFile(cfg.Definition, []byte("{"), 0600); err != nil {
+		t.Fatalf("Unable to write definition [Error:%s]", err)
+	}
+	testAdmin(t, router, adminSample{http.MethodPost, "/admin/machines/atkins-diet/reload", "", _TEST_ADMIN_KEY, http.StatusUnprocessableEntity})
+	if current, _ := machines.get(_ATKINS_DIET_MACHINE); current != after {
+		t.Errorf("Expected the current machine to be kept when the reload fails")
+	}
+
+	// A definition the spinner cannot play is refused with its problems
+	ragged := atkins.DefaultDefinition()
+	ragged.Reels = append(ragged.Reels[:3:3], ragged.Reels[3][:2])
+	data, _ := json.Marshal(ragged)
+	if err := ioutil.WriteFile(cfg.Definition, data, 0600); err != nil {
+		t.Fatalf("Unable to write definition [Error:%s]", err)
+	}
+	w := testAdmin(t, router, adminSample{http.MethodPost, "/admin/machines/atkins-diet/reload", "", _TEST_ADMIN_KEY, http.StatusUnprocessableEntity})
+	if !strings.Contains(w.Body.String(), "reels[3]") {
+		t.Errorf("Expected the problem of reels[3] Got:[%s]", w.Body)
+	}
+	if current, _ := machines.get(_ATKINS_DIET_MACHINE); current != after {
+		t.Errorf("Expected the current machine to be kept when the definition is invalid")
+	}
+}
+
+func TestMaintenance(t *testing.T) {
+	defer func(mr *machineRegistry) { machines = mr }(machines)
+	router := newAdminRouter(t)
+
+	testAdmin(t, router, adminSample{http.MethodPut, "/admin/maintenance", `{"enabled":true,"message":"Back at 10:00"}`, _TEST_ADMIN_KEY, http.StatusOK})
+	w := playRequest(t, SpinV2, userClaims{UID: "123", Chips: 1000, Bet: 1})
+	if w.Code != http.StatusServiceUnavailable || !strings.Contains(w.Body.String(), "Back at 10:00") {
+		t.Errorf("Expected:[%s] Got:[%d %s]", _ERR_MAINTENANCE, w.Code, w.Body)
+	}
+
+	testAdmin(t, router, adminSample{http.MethodPut, "/admin/maintenance", `{"enabled":false}`, _TEST_ADMIN_KEY, http.StatusOK})
+	if w = playRequest(t, SpinV2, userClaims{UID: "123", Chips: 1000, Bet: 1}); w.Code != http.StatusOK {
+		t.Errorf("Expected:[200] Got:[%d %s]", w.Code, w.Body)
+	}
+	testAdmin(t, router, adminSample{http.MethodPut, "/admin/maintenance", `enabled`, _TEST_ADMIN_KEY, http.StatusBadRequest})
+}
+
+// The RTP of the wilds the engine does not model is simulated once for the machine
+func TestSimulatedRTP(t *testing.T) {
+	walking := atkins.NewAtkinsDietMachine()
+	walking.Wilds.Walking = true
+	rtp := machineRTP("walking", walking)
+	if rtp.Error == "" || rtp.Simulated == nil || rtp.Simulated.Rounds != _RTP_SIMULATION_ROUNDS {
+		t.Fatalf("Expected the simulated RTP Got:[%+v]", rtp)
+	}
+	if rtp.Simulated.Low > rtp.Simulated.RTP || rtp.Simulated.High < rtp.Simulated.RTP || rtp.Simulated.Confidence != 0.95 {
+		t.Errorf("Expected the RTP within its interval Got:[%+v]", rtp.Simulated)
+	}
+	if again := machineRTP("walking", walking); !reflect.DeepEqual(again.Simulated, rtp.Simulated) {
+		t.Errorf("Expected the simulation kept:[%+v] Got:[%+v]", rtp.Simulated, again.Simulated)
+	}
+	if plain := machineRTP("plain", atkins.NewAtkinsDietMachine()); plain.Simulated != nil || plain.Theoretical <= 0 {
+		t.Errorf("Expected the theoretical RTP only Got:[%+v]", plain)
+	}
+}
+
+// blockingSimulator counts its simulations, which finish once release is closed
+type blockingSimulator struct {
+	calls   int32
+	release chan struct{}
+}
+
+func (bs *blockingSimulator) SimulatedRTP(rounds int) (slotmachine.RTPEstimate, error) {
+	atomic.AddInt32(&bs.calls, 1)
+	<-bs.release
+	return slotmachine.RTPEstimate{Rounds: rounds}, nil
+}
+
+// The calls during a simulation wait for it, the simulations of the other machines are not held up
+func TestSimulationsInFlight(t *testing.T) {
+	sims := rtpSimulations{estimates: make(map[string]*rtpSimulation)}
+	machine := atkins.NewAtkinsDietMachine()
+	blocking := &blockingSimulator{release: make(chan struct{})}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if estimate, err := sims.get("blocking", machine, blocking); err != nil || estimate.Rounds != _RTP_SIMULATION_ROUNDS {
+				t.Errorf("Expected:[%d rounds] Got:[%+v] [Error:%v]", _RTP_SIMULATION_ROUNDS, estimate, err)
+			}
+		}()
+	}
+
+	other := &blockingSimulator{release: make(chan struct{})}
+	close(other.release)
+	done := make(chan struct{})
+	go func() {
+		sims.get("other", atkins.NewAtkinsDietMachine(), other)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Expected the simulation of another machine not to wait")
+	}
+
+	close(blocking.release)
+	wg.Wait()
+	if calls := atomic.LoadInt32(&blocking.calls); calls != 1 {
+		t.Errorf("Expected:[1] simulation Got:[%d]", calls)
+	}
+}
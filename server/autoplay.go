@@ -0,0 +1,194 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"trippy/logger"
+	"trippy/slotmachine"
+	"trippy/slotmachine/engine/atkins"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+const (
+	_AUTOPLAY_MAX_ROUNDS = 100
+
+	// Reasons an autoplay stopped
+	_STOP_COMPLETED          = "completed"          // All the rounds requested were played
+	_STOP_BALANCE_BELOW      = "balance_below"      // Balance fell below stop.balance_below
+	_STOP_SINGLE_WIN_ABOVE   = "single_win_above"   // A round paid more than stop.single_win_above
+	_STOP_FREE_SPINS         = "free_spins"         // A round triggered free spins
+	_STOP_LOSS_LIMIT         = "loss_limit"         // The next round could lose more than stop.loss_limit
+	_STOP_INSUFFICIENT_CHIPS = "insufficient_chips" // Balance is below the wager
+	_STOP_UNAVAILABLE        = "unavailable"        // The server is in maintenance or shutting down
+	_STOP_CANCELLED          = "cancelled"          // The client went away
+	_STOP_ERROR              = "error"              // A round failed, see the server logs
+)
+
+type reqAutoplay struct {
+	JWT    string       `json:"jwt"`
+	Rounds int          `json:"rounds"` // Rounds to play, up to 100
+	Stop   autoplayStop `json:"stop"`
+}
+
+// autoplayStop are the conditions stopping an autoplay before all its rounds are played, unset if 0
+type autoplayStop struct {
+	BalanceBelow   int  `json:"balance_below,omitempty"`    // Stop once the balance is below this
+	SingleWinAbove int  `json:"single_win_above,omitempty"` // Stop once a round pays more than this
+	FreeSpins      bool `json:"free_spins,omitempty"`       // Stop once a round triggers free spins
+	LossLimit      int  `json:"loss_limit,omitempty"`       // Chips which can be lost at most, wagers minus payouts
+}
+
+type respAutoplay struct {
+	Version       int          `json:"version"`
+	Machine       string       `json:"machine"`
+	Requested     int          `json:"requested"`
+	Played        int          `json:"played"`
+	StopReason    string       `json:"stop_reason"`
+	Wager         int          `json:"wager"` // Chips wagered in all the rounds
+	Total         int          `json:"total"` // Chips paid in all the rounds
+	BalanceBefore int          `json:"balance_before"`
+	BalanceAfter  int          `json:"balance_after"`
+	Rounds        []respSpinV2 `json:"rounds"`
+	JWT           string       `json:"jwt"`
+}
+
+func (req reqAutoplay) validate() error {
+	if req.Rounds < 1 || req.Rounds > _AUTOPLAY_MAX_ROUNDS {
+		return newAPIError(_ERR_INVALID_REQUEST, fmt.Sprintf("rounds:[%d] must be between 1 and %d", req.Rounds, _AUTOPLAY_MAX_ROUNDS)).
+			withDetail("min", 1).
+			withDetail("max", _AUTOPLAY_MAX_ROUNDS)
+	}
+	if req.Stop.BalanceBelow < 0 || req.Stop.SingleWinAbove < 0 || req.Stop.LossLimit < 0 {
+		return newAPIError(_ERR_INVALID_REQUEST, "Stop conditions cannot be negative")
+	}
+	return nil
+}
+
+// Autoplay plays up to N rounds in a row for the user, until a stop condition is met.
+// The first round must be playable, the other ones stop the autoplay when they cannot be played.
+func Autoplay(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	log := logger.FromContext(r.Context())
+	machineName := ps.ByName(_PARA_SPIN_MACHINE)
+
+	body, err := readBody(w, r)
+	if err != nil {
+		respondWithMachineError(w, machineName, newAPIError(_ERR_INVALID_REQUEST, fmt.Sprintf("Reading body failed. [Error:%s]", err)))
+		return
+	}
+	var req reqAutoplay
+	if err = json.Unmarshal(body, &req); err != nil {
+		respondWithMachineError(w, machineName, newAPIError(_ERR_INVALID_REQUEST, fmt.Sprintf("Body is not a valid autoplay request [Error:%s]", err)))
+		return
+	}
+	if err = req.validate(); err != nil {
+		respondWithMachineError(w, machineName, err)
+		return
+	}
+	user, ok := requestUser(w, r, machineName, req.JWT)
+	if !ok {
+		return
+	}
+	machine, ok := requestMachine(w, machineName)
+	if !ok {
+		return
+	}
+
+	response := respAutoplay{
+		Version:       2,
+		Machine:       machineName,
+		Requested:     req.Rounds,
+		StopReason:    _STOP_COMPLETED,
+		BalanceBefore: user.Chips,
+		Rounds:        make([]respSpinV2, 0, req.Rounds),
+	}
+	for response.Played < req.Rounds {
+		stopReason, stop := autoplayStopBefore(r, machine, user, req.Stop, response)
+		if stop {
+			response.StopReason = stopReason
+			break
+		}
+
+		rnd, err := play(r.Context(), log, machineName, machine, user)
+		if err != nil {
+			if response.Played == 0 {
+				respondWithRoundError(w, machineName, err, rnd)
+				return
+			}
+			response.StopReason = autoplayStopOnError(err)
+			break
+		}
+		response.Played++
+		response.Wager = response.Wager + rnd.wager
+		response.Total = response.Total + rnd.payout
+		response.Rounds = append(response.Rounds, computeSpinResponseV2(rnd))
+		user.Chips = rnd.balanceAfter()
+
+		if stopReason, stop = autoplayStopAfter(rnd, req.Stop); stop {
+			response.StopReason = stopReason
+			break
+		}
+	}
+
+	response.BalanceAfter = user.Chips
+	response.JWT, err = createToken(user, []byte(apiKey))
+	if err != nil {
+		log.Error("Autoplay: Unable to create new JWT token", "uid", user.UID, "err", err)
+		respondWithMachineError(w, machineName, newAPIError(_ERR_INTERNAL, "Unable to generate new JWT"))
+		return
+	}
+	log.Info("Autoplay played", "uid", user.UID, "machine", machineName, "requested", req.Rounds,
+		"played", response.Played, "stop_reason", response.StopReason, "wager", response.Wager, "payout", response.Total, "balance_before", response.BalanceBefore, "balance_after", response.BalanceAfter)
+	writeResponse(w, http.StatusOK, response)
+}
+
+// autoplayStopBefore checks the conditions stopping the autoplay before the next round
+func autoplayStopBefore(r *http.Request, machine slotmachine.SlotMachine, user userClaims, stop autoplayStop, played respAutoplay) (string, bool) {
+	if r.Context().Err() != nil {
+		return _STOP_CANCELLED, true
+	}
+	if stop.LossLimit > 0 {
+		// Errors are reported when the round is played
+		wager, err := machine.Wager(user.bet(), user.Chips)
+		if err == nil && played.Wager-played.Total+wager > stop.LossLimit {
+			return _STOP_LOSS_LIMIT, true
+		}
+	}
+	// Checked by requestMachine for the first round
+	if enabled, _ := maintenance.active(); enabled && played.Played > 0 {
+		return _STOP_UNAVAILABLE, true
+	}
+	return "", false
+}
+
+// autoplayStopAfter checks the conditions stopping the autoplay after a round
+func autoplayStopAfter(rnd round, stop autoplayStop) (string, bool) {
+	if stop.FreeSpins {
+		for _, result := range rnd.results {
+			if result.FreeSpins > 0 {
+				return _STOP_FREE_SPINS, true
+			}
+		}
+	}
+	if stop.SingleWinAbove > 0 && rnd.payout > stop.SingleWinAbove {
+		return _STOP_SINGLE_WIN_ABOVE, true
+	}
+	if stop.BalanceBelow > 0 && rnd.balanceAfter() < stop.BalanceBelow {
+		return _STOP_BALANCE_BELOW, true
+	}
+	return "", false
+}
+
+// autoplayStopOnError is the reason an autoplay stopped on a round which could not be played
+func autoplayStopOnError(err error) string {
+	if errors.Is(err, atkins.ErrChipsInsufficient) {
+		return _STOP_INSUFFICIENT_CHIPS
+	}
+	if toAPIError(err).Code == _ERR_UNAVAILABLE {
+		return _STOP_UNAVAILABLE
+	}
+	return _STOP_ERROR
+}
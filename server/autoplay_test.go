@@ -0,0 +1,126 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"trippy/slotmachine"
+	"trippy/slotmachine/engine/atkins"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+const _FIXED_MACHINE = "fixed"
+
+// fixedMachine pays the same on every round, triggering free spins every few rounds
+type fixedMachine struct {
+	wager     int
+	payouts   []int // Payout of each round, the last one repeats
+	freeSpins int   // Rounds before free spins are triggered, never if 0
+	played    int
+}
+
+func (m *fixedMachine) Wager(bet slotmachine.Bet, chips int) (int, error) {
+	if m.wager > chips {
+		return m.wager, atkins.ErrChipsInsufficient
+	}
+	return m.wager, nil
+}
+
+func (m *fixedMachine) Spin(ctx context.Context, bet slotmachine.Bet) (int, []slotmachine.SpinResult, error) {
+	payout := m.payouts[len(m.payouts)-1]
+	if m.played < len(m.payouts) {
+		payout = m.payouts[m.played]
+	}
+	m.played++
+	result := slotmachine.SpinResult{Type: slotmachine.MAIN_SPIN, Pay: payout, Multiplier: 1}
+	if m.freeSpins > 0 && m.played%m.freeSpins == 0 {
+		result.FreeSpins = 10
+	}
+	return payout, []slotmachine.SpinResult{result}, nil
+}
+
+type autoplaySample struct {
+	machine  *fixedMachine
+	chips    int
+	rounds   int
+	stop     autoplayStop
+	status   int
+	played   int
+	reason   string
+	expected int // Balance after the autoplay
+}
+
+func testAutoplay(t *testing.T, sample autoplaySample) {
+	apiKey = "secret"
+	machines.set(_FIXED_MACHINE, sample.machine)
+	token, _ := createToken(userClaims{UID: "123", Chips: sample.chips, Bet: 1}, []byte(apiKey))
+	body, _ := json.Marshal(reqAutoplay{JWT: token, Rounds: sample.rounds, Stop: sample.stop})
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/api/v2/machines/fixed/autoplay", strings.NewReader(string(body)))
+	Autoplay(w, r, httprouter.Params{{Key: _PARA_SPIN_MACHINE, Value: _FIXED_MACHINE}})
+	if w.Code != sample.status {
+		t.Errorf("Expected:[%d] Got:[%d %s]", sample.status, w.Code, w.Body)
+		return
+	}
+	if w.Code != http.StatusOK {
+		return
+	}
+
+	var resp respAutoplay
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("Unable to decode response [Error:%s]", err)
+	}
+	if resp.Played != sample.played || len(resp.Rounds) != sample.played || resp.StopReason != sample.reason ||
+		resp.BalanceAfter != sample.expected {
+		t.Errorf("Expected:[Played:%d Reason:%s Balance:%d] Got:[Played:%d Reason:%s Balance:%d]",
+			sample.played, sample.reason, sample.expected, resp.Played, resp.StopReason, resp.BalanceAfter)
+	}
+	user, err := parseToken(resp.JWT, []byte(apiKey))
+	if err != nil || user.Chips != resp.BalanceAfter {
+		t.Errorf("Expected a token with the balance after. Got:[%+v] [Error:%v]", user, err)
+	}
+	for _, rnd := range resp.Rounds {
+		if rnd.JWT != "" {
+			t.Errorf("Expected a single token, not one per round")
+		}
+	}
+}
+
+func TestAutoplay(t *testing.T) {
+	samples := []autoplaySample{
+		{&fixedMachine{wager: 20, payouts: []int{10}}, 1000, 10, autoplayStop{}, http.StatusOK, 10, _STOP_COMPLETED, 900},
+		{&fixedMachine{wager: 20, payouts: []int{0}}, 1000, 100, autoplayStop{BalanceBelow: 950}, http.StatusOK, 3, _STOP_BALANCE_BELOW, 940},
+		{&fixedMachine{wager: 20, payouts: []int{0, 0, 500, 0}}, 1000, 10, autoplayStop{SingleWinAbove: 100}, http.StatusOK, 3, _STOP_SINGLE_WIN_ABOVE, 1440},
+		{&fixedMachine{wager: 20, payouts: []int{0}, freeSpins: 4}, 1000, 10, autoplayStop{FreeSpins: true}, http.StatusOK, 4, _STOP_FREE_SPINS, 920},
+		{&fixedMachine{wager: 20, payouts: []int{10}, freeSpins: 4}, 1000, 10, autoplayStop{}, http.StatusOK, 10, _STOP_COMPLETED, 900},
+		{&fixedMachine{wager: 20, payouts: []int{5}}, 1000, 10, autoplayStop{LossLimit: 50}, http.StatusOK, 3, _STOP_LOSS_LIMIT, 955},
+		{&fixedMachine{wager: 20, payouts: []int{5}}, 1000, 10, autoplayStop{LossLimit: 10}, http.StatusOK, 0, _STOP_LOSS_LIMIT, 1000},
+		{&fixedMachine{wager: 20, payouts: []int{0}}, 50, 10, autoplayStop{}, http.StatusOK, 2, _STOP_INSUFFICIENT_CHIPS, 10},
+		{&fixedMachine{wager: 20, payouts: []int{0}}, 10, 10, autoplayStop{}, http.StatusBadRequest, 0, "", 0},
+		{&fixedMachine{wager: 20, payouts: []int{0}}, 1000, 0, autoplayStop{}, http.StatusBadRequest, 0, "", 0},
+		{&fixedMachine{wager: 20, payouts: []int{0}}, 1000, _AUTOPLAY_MAX_ROUNDS + 1, autoplayStop{}, http.StatusBadRequest, 0, "", 0},
+		{&fixedMachine{wager: 20, payouts: []int{0}}, 1000, 10, autoplayStop{LossLimit: -1}, http.StatusBadRequest, 0, "", 0},
+	}
+	for _, sample := range samples {
+		testAutoplay(t, sample)
+	}
+}
+
+func TestBodyToken(t *testing.T) {
+	for body, expected := range map[string]string{
+		"eyJ.token":                      "eyJ.token",
+		`{"jwt":"eyJ.token","rounds":1}`: "eyJ.token",
+		`{"rounds":1}`:                   "",
+		`{"jwt":`:                        "",
+	} {
+		if got := bodyToken([]byte(body)); got != expected {
+			t.Errorf("[Body:%s] Expected:[%s] Got:[%s]", body, expected, got)
+		}
+	}
+}
@@ -0,0 +1,190 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+
+	"trippy/logger"
+	"trippy/slotmachine"
+	"trippy/slotmachine/bonus"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+const (
+	_BONUS_DIR = "bonus" // Directory of the games in the storage path
+
+	_PARA_BONUS_GAME = "game" // Path parameter of the bonus game picked
+)
+
+var bonusGames = bonus.NewGames(bonus.NewMemoryStore()) // Pick games waiting for the picks of the players
+
+// newBonusGames returns the games kept in the storage backend, in memory unless it is a file
+func newBonusGames(storage StorageConfig) (*bonus.Games, error) {
+	if storage.Backend != _STORAGE_FILE {
+		return bonus.NewGames(bonus.NewMemoryStore()), nil
+	}
+	store, err := bonus.NewFileStore(filepath.Join(storage.Path, _BONUS_DIR))
+	if err != nil {
+		return nil, err
+	}
+	return bonus.NewGames(store), nil
+}
+
+// bonusMachine is implemented by the machines with a pick game
+type bonusMachine interface {
+	BonusGame() *slotmachine.BonusRules
+}
+
+// startBonus launches the pick game of the machine if the spins of the round triggered it.
+// The prizes are won by the picks, in rounds of their own.
+func startBonus(log *logger.Logger, rnd *round) {
+	machine, ok := slotmachine.Capability[bonusMachine](rnd.machine)
+	if !ok || !bonus.Triggered(machine.BonusGame(), rnd.results) {
+		return
+	}
+	game, err := bonusGames.Start(bonus.Game{
+		ID:      newID(),
+		UID:     rnd.user.UID,
+		Machine: rnd.machineName,
+		Round:   rnd.id,
+		Wager:   rnd.wager,
+	}, *machine.BonusGame())
+	if err != nil {
+		log.Error("Unable to start bonus game", "err", err)
+		return
+	}
+	log.Info("Bonus game started", "game", game.ID, "prizes", len(game.Prizes), "picks", game.Picks)
+	rnd.bonus = &game
+}
+
+type reqBonusPick struct {
+	JWT  string `json:"jwt"`
+	Pick int    `json:"pick"` // Position of the prize, from 1
+}
+
+// BonusPick reveals the prize picked by the player in a bonus game.
+// The prize is paid as a round without wager, with a new JWT, and the game is in the response.
+// Picks are allowed in maintenance, the players finish the games they have won.
+func BonusPick(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	log := logger.FromContext(r.Context())
+	machineName := ps.ByName(_PARA_SPIN_MACHINE)
+	gameID := ps.ByName(_PARA_BONUS_GAME)
+
+	body, err := readBody(w, r)
+	if err != nil {
+		log.Warn("Bonus: Unable to read body", "err", err)
+		respondWithMachineError(w, machineName, newAPIError(_ERR_INVALID_REQUEST, fmt.Sprintf("Reading body failed. [Error:%s]", err)))
+		return
+	}
+	var req reqBonusPick
+	if err = json.Unmarshal(body, &req); err != nil {
+		log.Warn("Bonus: Unable to parse body", "err", err)
+		respondWithMachineError(w, machineName, newAPIError(_ERR_INVALID_REQUEST, "Body must be a JSON object with jwt and pick"))
+		return
+	}
+	user, ok := requestUser(w, r, machineName, req.JWT)
+	if !ok {
+		return
+	}
+	machine, found := getMachine(machineName)
+	if !found {
+		respondWithMachineError(w, machineName, newAPIError(_ERR_UNKNOWN_MACHINE, fmt.Sprintf("Unknown machine:[%s]", machineName)).
+			withDetail("machine", machineName))
+		return
+	}
+
+	rnd, err := pick(log, machineName, machine, user, gameID, req.Pick)
+	if err != nil {
+		respondWithMachineError(w, machineName, err)
+		return
+	}
+	user.Chips = rnd.balanceAfter()
+	rnd.token, err = createToken(user, []byte(apiKey))
+	if err != nil {
+		log.Error("Bonus: Unable to create new JWT token", "err", err)
+		respondWithMachineError(w, machineName, newAPIError(_ERR_INTERNAL, "Unable to generate new JWT"))
+		return
+	}
+	writeResponse(w, http.StatusOK, computeSpinResponseV2(rnd))
+}
+
+// pick reveals the prize of the game and returns the round paying it, without a new JWT
+func pick(log *logger.Logger, machineName string, machine slotmachine.SlotMachine, user userClaims, gameID string, position int) (round, error) {
+	if !rounds.begin() {
+		return round{}, newAPIError(_ERR_UNAVAILABLE, "")
+	}
+	defer rounds.end()
+
+	rnd := round{
+		id:          newID(),
+		machineName: machineName,
+		machine:     machine,
+		user:        user,
+	}
+	log = log.With("round", rnd.id, "uid", user.UID, "machine", machineName, "game", gameID)
+
+	game, err := bonusGames.Get(gameID, user.UID)
+	if err != nil {
+		return rnd, bonusError(log, gameID, err)
+	}
+	// The games of the other machines are not found
+	if game.Machine != machineName {
+		return rnd, bonusError(log, gameID, bonus.ErrGameNotFound)
+	}
+	game, prize, err := bonusGames.Pick(gameID, user.UID, position)
+	if err != nil {
+		return rnd, bonusError(log, gameID, err)
+	}
+	rnd.payout = prize
+	rnd.results = []slotmachine.SpinResult{{
+		Type:       slotmachine.BONUS_SPIN,
+		Stops:      []int{},
+		Window:     [][]slotmachine.Symbol{},
+		Pay:        prize,
+		Multiplier: 1,
+	}}
+	rnd.bonus = &game
+	observeBonusPick(machineName, prize)
+	history.add(rnd)
+
+	log.Info("Bonus prize picked", "pick", position, "prize", prize, "picks_left", game.Picks,
+		"balance_before", user.Chips, "balance_after", rnd.balanceAfter())
+	return rnd, nil
+}
+
+// bonusError returns the API error of a failed pick
+func bonusError(log *logger.Logger, gameID string, err error) error {
+	switch {
+	case errors.Is(err, bonus.ErrGameNotFound):
+		return newAPIError(_ERR_BONUS_NOT_FOUND, fmt.Sprintf("Bonus game:[%s] not found", gameID)).withDetail("game", gameID)
+	case errors.Is(err, bonus.ErrInvalidPick), errors.Is(err, bonus.ErrGameOver):
+		return newAPIError(_ERR_INVALID_PICK, err.Error()).withDetail("game", gameID)
+	}
+	log.Error("Bonus: Unable to play game", "err", err)
+	return newAPIError(_ERR_INTERNAL, "Unable to play bonus game")
+}
+
+// newBonusGame returns the game as the player sees it: the prizes picked, all of them once it is over
+func newBonusGame(game *bonus.Game) *bonusGame {
+	if game == nil {
+		return nil
+	}
+	resp := &bonusGame{
+		ID:     game.ID,
+		Prizes: len(game.Prizes),
+		Picks:  game.Picks,
+		Won:    game.Won,
+		Over:   game.Over(),
+		Reveal: []bonusPrize{},
+	}
+	for i, prize := range game.Prizes {
+		if game.Picked[i] || game.Over() {
+			resp.Reveal = append(resp.Reveal, bonusPrize{Position: i + 1, Prize: prize, Picked: game.Picked[i]})
+		}
+	}
+	return resp
+}
@@ -0,0 +1,110 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"trippy/slotmachine"
+	"trippy/slotmachine/bonus"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+const _CHEF = slotmachine.Symbol(12)
+
+// pickMachine launches its bonus game in every round, each prize paying twice the wager
+type pickMachine struct {
+	fixedMachine
+}
+
+func (m *pickMachine) Spin(ctx context.Context, bet slotmachine.Bet) (int, []slotmachine.SpinResult, error) {
+	result := slotmachine.SpinResult{Type: slotmachine.MAIN_SPIN, Multiplier: 1,
+		Window: [][]slotmachine.Symbol{{_CHEF, 1, 2}, {3, _CHEF, 4}, {5, 6, _CHEF}}}
+	return 0, []slotmachine.SpinResult{result}, nil
+}
+
+func (m *pickMachine) BonusGame() *slotmachine.BonusRules {
+	return &slotmachine.BonusRules{Symbol: _CHEF, Count: 3, Prizes: 3, Picks: 2,
+		Reveals: []slotmachine.BonusReveal{{Multiplier: 2, Weight: 1}}}
+}
+
+type pickSample struct {
+	uid   string
+	pick  int
+	code  int
+	err   errCode
+	total int
+}
+
+var pickSamples = []pickSample{
+	{uid: "123", pick: 1, code: http.StatusOK, total: 200},
+	{uid: "123", pick: 1, code: http.StatusBadRequest, err: _ERR_INVALID_PICK},
+	{uid: "123", pick: 4, code: http.StatusBadRequest, err: _ERR_INVALID_PICK},
+	{uid: "456", pick: 2, code: http.StatusNotFound, err: _ERR_BONUS_NOT_FOUND},
+	{uid: "123", pick: 3, code: http.StatusOK, total: 200},
+	{uid: "123", pick: 2, code: http.StatusNotFound, err: _ERR_BONUS_NOT_FOUND},
+}
+
+// The prizes are paid by the picks, with a new JWT, until the game is over
+func TestBonusPicks(t *testing.T) {
+	defer func(games *bonus.Games) { bonusGames = games }(bonusGames)
+	bonusGames = bonus.NewGames(bonus.NewMemoryStore())
+	apiKey = "secret"
+	machines.set(_FIXED_MACHINE, &pickMachine{fixedMachine{wager: 100}})
+	token, _ := createToken(userClaims{UID: "123", Chips: 1000, Bet: 1}, []byte(apiKey))
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/api/v2/machines/fixed/spins", strings.NewReader(token))
+	SpinV2(w, r, httprouter.Params{{Key: _PARA_SPIN_MACHINE, Value: _FIXED_MACHINE}})
+	var resp respSpinV2
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("Expected:[200] Got:[%d] [Error:%v]", w.Code, err)
+	}
+	if resp.Bonus == nil || resp.Bonus.Prizes != 3 || resp.Bonus.Picks != 2 || len(resp.Bonus.Reveal) != 0 {
+		t.Fatalf("Expected a bonus game with hidden prizes Got:[%+v]", resp.Bonus)
+	}
+
+	game, balance := resp.Bonus.ID, resp.BalanceAfter
+	for _, sample := range pickSamples {
+		balance = testBonusPick(t, sample, game, balance)
+	}
+}
+
+func testBonusPick(t *testing.T, sample pickSample, game string, balance int) int {
+	token, _ := createToken(userClaims{UID: sample.uid, Chips: balance, Bet: 1}, []byte(apiKey))
+	body := fmt.Sprintf(`{"jwt":%q,"pick":%d}`, token, sample.pick)
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/api/v2/machines/fixed/bonus/"+game+"/picks", strings.NewReader(body))
+	BonusPick(w, r, httprouter.Params{{Key: _PARA_SPIN_MACHINE, Value: _FIXED_MACHINE}, {Key: _PARA_BONUS_GAME, Value: game}})
+	if w.Code != sample.code {
+		t.Fatalf("[Pick:%d] Expected:[%d] Got:[%d %s]", sample.pick, sample.code, w.Code, w.Body)
+	}
+	if sample.err != "" {
+		if !strings.Contains(w.Body.String(), string(sample.err)) {
+			t.Errorf("[Pick:%d] Expected:[%s] Got:[%s]", sample.pick, sample.err, w.Body)
+		}
+		return balance
+	}
+
+	var resp respSpinV2
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("Expected:[nil] Got:[%s]", err)
+	}
+	if resp.Wager != 0 || resp.Total != sample.total || resp.BalanceAfter != balance+sample.total || resp.JWT == "" {
+		t.Errorf("[Pick:%d] Expected the prize:[%d] paid Got:[%+v]", sample.pick, sample.total, resp)
+	}
+	if len(resp.Spins) != 1 || resp.Spins[0].Type != slotmachine.BONUS_SPIN {
+		t.Errorf("[Pick:%d] Expected a bonus spin Got:[%+v]", sample.pick, resp.Spins)
+	}
+	// The prize picked is revealed, then all of them once the game is over
+	if resp.Bonus == nil || (resp.Bonus.Over && len(resp.Bonus.Reveal) != resp.Bonus.Prizes) ||
+		(!resp.Bonus.Over && len(resp.Bonus.Reveal) != 1) {
+		t.Errorf("[Pick:%d] Expected the prizes revealed Got:[%+v]", sample.pick, resp.Bonus)
+	}
+	return resp.BalanceAfter
+}
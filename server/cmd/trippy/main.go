@@ -1,9 +1,11 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"net/http"
 	_ "net/http/pprof"
+	"os"
 
 	"trippy/server"
 
@@ -12,27 +14,45 @@ import (
 
 func main() {
 
-	// Live profiling webserver
-	go func() {
-		fmt.Println("See Profile info at http://localhost:6060/debug/pprof/ ")
-		fmt.Println(http.ListenAndServe(":6060", nil))
-	}()
-
 	var (
 		err error
 	)
 
+	// Config: defaults < config file < env < flags
+	flags := server.NewFlags(flag.CommandLine)
+	flag.Parse()
+
+	config, err := server.LoadConfig(flags.ConfigPath)
+	if err != nil {
+		fmt.Printf("Unable to load config. [Error:%s]\n", err)
+		os.Exit(1)
+	}
+	if err = config.ApplyEnv(); err != nil {
+		fmt.Printf("Unable to read config from env. [Error:%s]\n", err)
+		os.Exit(1)
+	}
+	flags.Apply(&config)
+
+	// Live profiling webserver, serving the metrics away from the API
+	if config.DebugListen != "" {
+		http.Handle("/metrics", server.MetricsHandler())
+		go func() {
+			fmt.Printf("See Profile info at http://%s/debug/pprof/ and metrics at http://%s/metrics\n", config.DebugListen, config.DebugListen)
+			fmt.Println(http.ListenAndServe(config.DebugListen, nil))
+		}()
+	}
+
 	// Create New Server
-	var s server.Service = new(server.Server)
+	var s server.Service = &server.Server{Config: config}
 
 	// Initialize
 	if err = s.Initialize(); err != nil {
 		fmt.Printf("Unable to initialize server. [Error:%s]\n", err)
-		return
+		os.Exit(1)
 	}
 	// Start the server
 	if err = s.Start(); err != nil {
 		fmt.Printf("Unable to start server. [Error:%s]\n", err)
-		return
+		os.Exit(1)
 	}
 }
@@ -0,0 +1,474 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"trippy/jackpot"
+	"trippy/logger"
+)
+
+// Config is the configuration of the server.
+// It is layered, each layer overriding the previous one:
+// defaults, config file, env variables and command line flags.
+type Config struct {
+	Listen          string   `json:"listen"`           // Address of the API webserver
+	DebugListen     string   `json:"debug_listen"`     // Address of the pprof and metrics webserver, disabled if empty
+	ReadTimeout     Duration `json:"read_timeout"`     // Timeout to read a request
+	WriteTimeout    Duration `json:"write_timeout"`    // Timeout to write a response
+	IdleTimeout     Duration `json:"idle_timeout"`     // Timeout of idle keep-alive connections
+	ShutdownTimeout Duration `json:"shutdown_timeout"` // Timeout of the graceful shutdown
+	DrainTimeout    Duration `json:"drain_timeout"`    // Timeout for the rounds in play to finish before the shutdown
+	IdempotencyTTL  Duration `json:"idempotency_ttl"`  // Time the rounds played with an Idempotency-Key are kept for retries
+	APIKeyPath      string   `json:"api_key_path"`     // File with the key used to sign the JWTs
+	H2C             bool     `json:"h2c"`              // Accept HTTP/2 without TLS (prior knowledge) for the gRPC clients
+
+	TLS        TLSConfig         `json:"tls"`
+	Machines   []MachineConfig   `json:"machines"`
+	Storage    StorageConfig     `json:"storage"`
+	Log        LogConfig         `json:"log"`
+	RateLimits []RateLimitConfig `json:"rate_limits"`
+	Admin      AdminConfig       `json:"admin"`
+	Jackpots   []jackpot.Config  `json:"jackpots"` // Progressive jackpots shared by the machines
+}
+
+type TLSConfig struct {
+	Enabled         bool     `json:"enabled"`
+	CertFile        string   `json:"cert_file"`
+	KeyFile         string   `json:"key_file"`
+	ReloadInterval  Duration `json:"reload_interval"`   // Interval to check the key pair files for changes
+	ClientCAFile    string   `json:"client_ca_file"`    // CA of the client certificates, enables mutual TLS
+	ClientCertPaths []string `json:"client_cert_paths"` // Path prefixes of operator endpoints requiring a client certificate
+	RedirectListen  string   `json:"redirect_listen"`   // Address of a plain HTTP webserver redirecting to HTTPS, disabled if empty
+}
+
+type MachineConfig struct {
+	Name       string `json:"name"`       // Name of the machine in the API
+	Engine     string `json:"engine"`     // Engine running the machine
+	Enabled    bool   `json:"enabled"`    // Disabled machines are not served
+	Definition string `json:"definition"` // Machine definition file, the engine default if empty
+
+	Mystery *jackpot.MysteryConfig `json:"mystery"` // Mystery jackpots of the machine, none if nil
+}
+
+type StorageConfig struct {
+	Backend string `json:"backend"` // memory or file
+	Path    string `json:"path"`    // Directory of the file backend
+}
+
+// RateLimitConfig limits the requests to a route of every player or client address.
+// Every limit has its own token buckets, refilled at rate per second up to burst.
+type RateLimitConfig struct {
+	Route string  `json:"route"` // Route pattern of the router, eg. /api/machines/:machine/spins, or gRPC method
+	Key   string  `json:"key"`   // uid for the player in the JWT, ip for the client address
+	Rate  float64 `json:"rate"`  // Requests per second
+	Burst int     `json:"burst"` // Requests allowed at once
+}
+
+type AdminConfig struct {
+	KeyPath string `json:"key_path"` // File with the bearer key of the admin API, disabled if empty
+}
+
+type LogConfig struct {
+	Level    string `json:"level"`     // debug, info, warn or error
+	Format   string `json:"format"`    // logfmt or json
+	WinLines bool   `json:"win_lines"` // Log every paid line at debug level
+}
+
+const (
+	_STORAGE_MEMORY = "memory"
+	_STORAGE_FILE   = "file"
+
+	_ENGINE_ATKINS = "atkins"
+)
+
+// Env variables overriding the config file
+const (
+	_ENV_LISTEN           = "TRIPPY_LISTEN"
+	_ENV_DEBUG_LISTEN     = "TRIPPY_DEBUG_LISTEN"
+	_ENV_SHUTDOWN_TIMEOUT = "TRIPPY_SHUTDOWN_TIMEOUT"
+	_ENV_TLS_CERT         = "TRIPPY_TLS_CERT"
+	_ENV_TLS_KEY          = "TRIPPY_TLS_KEY"
+	_ENV_TLS_CLIENT_CA    = "TRIPPY_TLS_CLIENT_CA"
+	_ENV_TLS_REDIRECT     = "TRIPPY_TLS_REDIRECT_LISTEN"
+	_ENV_MACHINES         = "TRIPPY_MACHINES" // Comma separated names of the enabled machines
+	_ENV_STORAGE_BACKEND  = "TRIPPY_STORAGE_BACKEND"
+	_ENV_STORAGE_PATH     = "TRIPPY_STORAGE_PATH"
+	_ENV_ADMIN_KEY_PATH   = "TRIPPY_ADMIN_KEY_PATH"
+)
+
+// DefaultConfig is the configuration used when nothing else is set
+func DefaultConfig() Config {
+	return Config{
+		Listen:          ":" + _WEBSERVER_PORT,
+		DebugListen:     "localhost:6060",
+		ReadTimeout:     Duration{10 * time.Second},
+		WriteTimeout:    Duration{30 * time.Second},
+		IdleTimeout:     Duration{120 * time.Second},
+		ShutdownTimeout: Duration{_WS_SHUTDOWN_TIMEOUT},
+		DrainTimeout:    Duration{30 * time.Second},
+		IdempotencyTTL:  Duration{_IDEMPOTENCY_TTL},
+		TLS: TLSConfig{
+			ReloadInterval:  Duration{30 * time.Second},
+			ClientCertPaths: []string{"/admin/"},
+		},
+		Machines: []MachineConfig{
+			{Name: _ATKINS_DIET_MACHINE, Engine: _ENGINE_ATKINS, Enabled: true},
+		},
+		Storage: StorageConfig{Backend: _STORAGE_MEMORY},
+		Log: LogConfig{
+			Level:  logger.INFO.String(),
+			Format: string(logger.LOGFMT),
+		},
+	}
+}
+
+// LoadConfig returns the default configuration overridden by the config file, if any
+func LoadConfig(path string) (Config, error) {
+	cfg := DefaultConfig()
+	if path == "" {
+		return cfg, nil
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return cfg, fmt.Errorf("Unable to read config [File:%s] [Error:%s]", path, err)
+	}
+	// The lists of the file replace the default ones: decoding into the default elements would keep
+	// the fields the file does not set
+	defaults := cfg
+	cfg.Machines, cfg.RateLimits, cfg.Jackpots, cfg.TLS.ClientCertPaths = nil, nil, nil, nil
+	if err = json.Unmarshal(data, &cfg); err != nil {
+		return defaults, fmt.Errorf("Unable to parse config [File:%s] [Error:%s]", path, err)
+	}
+	if cfg.Machines == nil {
+		cfg.Machines = defaults.Machines
+	}
+	if cfg.RateLimits == nil {
+		cfg.RateLimits = defaults.RateLimits
+	}
+	if cfg.Jackpots == nil {
+		cfg.Jackpots = defaults.Jackpots
+	}
+	if cfg.TLS.ClientCertPaths == nil {
+		cfg.TLS.ClientCertPaths = defaults.TLS.ClientCertPaths
+	}
+	return cfg, nil
+}
+
+// ApplyEnv overrides the configuration with the env variables which are set
+func (c *Config) ApplyEnv() error {
+	var errs []string
+	setString := func(env string, field *string) {
+		if value, ok := os.LookupEnv(env); ok {
+			*field = value
+		}
+	}
+	setString(_ENV_LISTEN, &c.Listen)
+	setString(_ENV_DEBUG_LISTEN, &c.DebugListen)
+	setString(_API_KEY_PATH, &c.APIKeyPath)
+	setString(_ENV_TLS_CERT, &c.TLS.CertFile)
+	setString(_ENV_TLS_KEY, &c.TLS.KeyFile)
+	setString(_ENV_TLS_CLIENT_CA, &c.TLS.ClientCAFile)
+	setString(_ENV_TLS_REDIRECT, &c.TLS.RedirectListen)
+	setString(_ENV_STORAGE_BACKEND, &c.Storage.Backend)
+	setString(_ENV_STORAGE_PATH, &c.Storage.Path)
+	setString(_ENV_ADMIN_KEY_PATH, &c.Admin.KeyPath)
+	setString(_LOG_LEVEL, &c.Log.Level)
+	setString(_LOG_FORMAT, &c.Log.Format)
+
+	if c.TLS.CertFile != "" && c.TLS.KeyFile != "" {
+		c.TLS.Enabled = true
+	}
+	if value, ok := os.LookupEnv(_ENV_SHUTDOWN_TIMEOUT); ok {
+		if err := c.ShutdownTimeout.Set(value); err != nil {
+			errs = append(errs, fmt.Sprintf("Env:[%s] %s", _ENV_SHUTDOWN_TIMEOUT, err))
+		}
+	}
+	if value, ok := os.LookupEnv(_LOG_WIN_LINES); ok {
+		winLines, err := strconv.ParseBool(value)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("Env:[%s] %s", _LOG_WIN_LINES, err))
+		}
+		c.Log.WinLines = winLines
+	}
+	if value, ok := os.LookupEnv(_ENV_MACHINES); ok {
+		c.enableMachines(strings.Split(value, ","))
+	}
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// enableMachines enables the machines in the list and disables all the others
+func (c *Config) enableMachines(names []string) {
+	enabled := make(map[string]bool)
+	for _, name := range names {
+		if name = strings.TrimSpace(name); name != "" {
+			enabled[name] = true
+		}
+	}
+	for i := range c.Machines {
+		c.Machines[i].Enabled = enabled[c.Machines[i].Name]
+	}
+}
+
+// Flags are the command line flags overriding the configuration
+type Flags struct {
+	fs *flag.FlagSet
+
+	ConfigPath      string
+	listen          string
+	debugListen     string
+	apiKeyPath      string
+	tlsCert         string
+	tlsKey          string
+	shutdownTimeout Duration
+	machines        string
+	adminKeyPath    string
+	logLevel        string
+	logFormat       string
+}
+
+// NewFlags defines the configuration flags in the flag set
+func NewFlags(fs *flag.FlagSet) *Flags {
+	f := &Flags{fs: fs}
+	fs.StringVar(&f.ConfigPath, "config", "", "Path of the JSON config file")
+	fs.StringVar(&f.listen, "listen", "", "Address of the API webserver, eg. :7070")
+	fs.StringVar(&f.debugListen, "debug-listen", "", "Address of the pprof and metrics webserver, eg. localhost:6060")
+	fs.StringVar(&f.apiKeyPath, "api-key-path", "", "File with the key used to sign the JWTs")
+	fs.StringVar(&f.tlsCert, "tls-cert", "", "TLS certificate file, enables HTTPS with -tls-key")
+	fs.StringVar(&f.tlsKey, "tls-key", "", "TLS key file, enables HTTPS with -tls-cert")
+	fs.Var(&f.shutdownTimeout, "shutdown-timeout", "Timeout of the graceful shutdown, eg. 5s")
+	fs.StringVar(&f.machines, "machines", "", "Comma separated names of the enabled machines")
+	fs.StringVar(&f.adminKeyPath, "admin-key-path", "", "File with the key of the admin API, enables it")
+	fs.StringVar(&f.logLevel, "log-level", "", "Log level: debug, info, warn or error")
+	fs.StringVar(&f.logFormat, "log-format", "", "Log format: logfmt or json")
+	return f
+}
+
+// Apply overrides the configuration with the flags set on the command line
+func (f *Flags) Apply(c *Config) {
+	f.fs.Visit(func(fl *flag.Flag) {
+		switch fl.Name {
+		case "listen":
+			c.Listen = f.listen
+		case "debug-listen":
+			c.DebugListen = f.debugListen
+		case "api-key-path":
+			c.APIKeyPath = f.apiKeyPath
+		case "tls-cert":
+			c.TLS.CertFile = f.tlsCert
+		case "tls-key":
+			c.TLS.KeyFile = f.tlsKey
+		case "shutdown-timeout":
+			c.ShutdownTimeout = f.shutdownTimeout
+		case "machines":
+			c.enableMachines(strings.Split(f.machines, ","))
+		case "admin-key-path":
+			c.Admin.KeyPath = f.adminKeyPath
+		case "log-level":
+			c.Log.Level = f.logLevel
+		case "log-format":
+			c.Log.Format = f.logFormat
+		}
+	})
+	if c.TLS.CertFile != "" && c.TLS.KeyFile != "" {
+		c.TLS.Enabled = true
+	}
+}
+
+// Validate checks the whole configuration and reports all the problems found
+func (c *Config) Validate() error {
+	var errs []string
+	addErr := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Sprintf(format, args...))
+	}
+
+	if _, _, err := net.SplitHostPort(c.Listen); err != nil {
+		addErr("listen:[%s] is not a valid address [Error:%s]", c.Listen, err)
+	}
+	if c.DebugListen != "" {
+		if _, _, err := net.SplitHostPort(c.DebugListen); err != nil {
+			addErr("debug_listen:[%s] is not a valid address [Error:%s]", c.DebugListen, err)
+		}
+	}
+	for _, timeout := range []struct {
+		name  string
+		value Duration
+	}{
+		{"read_timeout", c.ReadTimeout},
+		{"write_timeout", c.WriteTimeout},
+		{"idle_timeout", c.IdleTimeout},
+		{"shutdown_timeout", c.ShutdownTimeout},
+		{"drain_timeout", c.DrainTimeout},
+		{"idempotency_ttl", c.IdempotencyTTL},
+	} {
+		if timeout.value.Duration <= 0 {
+			addErr("%s:[%s] must be greater than 0", timeout.name, timeout.value)
+		}
+	}
+	if c.APIKeyPath == "" {
+		addErr("api_key_path is not set [Env:%s]", _API_KEY_PATH)
+	}
+
+	if c.TLS.Enabled {
+		if c.TLS.CertFile == "" || c.TLS.KeyFile == "" {
+			addErr("tls: cert_file and key_file are required when TLS is enabled")
+		}
+		for _, file := range []string{c.TLS.CertFile, c.TLS.KeyFile, c.TLS.ClientCAFile} {
+			if _, err := os.Stat(file); file != "" && err != nil {
+				addErr("tls: [File:%s] is not readable [Error:%s]", file, err)
+			}
+		}
+		if c.TLS.ReloadInterval.Duration <= 0 {
+			addErr("tls: reload_interval:[%s] must be greater than 0", c.TLS.ReloadInterval)
+		}
+		if c.TLS.RedirectListen != "" {
+			if _, _, err := net.SplitHostPort(c.TLS.RedirectListen); err != nil {
+				addErr("tls: redirect_listen:[%s] is not a valid address [Error:%s]", c.TLS.RedirectListen, err)
+			}
+		}
+		if c.H2C {
+			addErr("h2c: HTTP/2 without TLS cannot be accepted when TLS is enabled")
+		}
+	} else if c.TLS.ClientCAFile != "" || c.TLS.RedirectListen != "" {
+		addErr("tls: client_ca_file and redirect_listen require TLS to be enabled")
+	}
+
+	var enabled int
+	names := make(map[string]MachineConfig)
+	for i, machine := range c.Machines {
+		if machine.Name == "" {
+			addErr("machines[%d]: name is empty", i)
+		}
+		if _, ok := names[machine.Name]; ok {
+			addErr("machines[%d]: name:[%s] is used twice", i, machine.Name)
+		} else {
+			names[machine.Name] = machine
+		}
+		if _, ok := engines[machine.Engine]; !ok {
+			addErr("machines[%d]: engine:[%s] is unknown", i, machine.Engine)
+		}
+		if machine.Definition != "" {
+			if _, err := os.Stat(machine.Definition); err != nil {
+				addErr("machines[%d]: definition [File:%s] is not readable [Error:%s]", i, machine.Definition, err)
+			}
+		}
+		if machine.Mystery != nil {
+			if err := machine.Mystery.Validate(); err != nil {
+				addErr("machines[%d]: mystery: %s", i, err)
+			}
+		}
+		if machine.Enabled {
+			enabled++
+		}
+	}
+	if enabled == 0 {
+		addErr("machines: no machine is enabled")
+	}
+
+	jackpots := make(map[string]bool)
+	for i, jp := range c.Jackpots {
+		if err := jp.Validate(); err != nil {
+			addErr("jackpots[%d]: %s", i, err)
+		}
+		if jackpots[jp.Name] {
+			addErr("jackpots[%d]: name:[%s] is used twice", i, jp.Name)
+		}
+		jackpots[jp.Name] = true
+		for _, machine := range jp.Machines {
+			machineCfg, ok := names[machine]
+			if !ok {
+				addErr("jackpots[%d]: machine:[%s] is not configured", i, machine)
+				continue
+			}
+			if jp.Trigger == nil || jp.Trigger.Symbol == "" {
+				continue
+			}
+			// A trigger on a symbol the machine does not pay would never win the pool.
+			// The definitions which cannot be loaded are reported by the machines.
+			if def, err := loadDefinition(machineCfg); err == nil && !paysSymbol(def, jp.Trigger.Symbol) {
+				addErr("jackpots[%d]: trigger: symbol:[%s] is not paid by machine:[%s]", i, jp.Trigger.Symbol, machine)
+			}
+		}
+	}
+
+	switch c.Storage.Backend {
+	case _STORAGE_MEMORY:
+	case _STORAGE_FILE:
+		if c.Storage.Path == "" {
+			addErr("storage: path is required by the file backend")
+		}
+	default:
+		addErr("storage: backend:[%s] is unknown, expected memory or file", c.Storage.Backend)
+	}
+
+	if c.Admin.KeyPath != "" {
+		if _, err := os.Stat(c.Admin.KeyPath); err != nil {
+			addErr("admin: key [File:%s] is not readable [Error:%s]", c.Admin.KeyPath, err)
+		}
+	}
+
+	for i, limit := range c.RateLimits {
+		if !strings.HasPrefix(limit.Route, "/") {
+			addErr("rate_limits[%d]: route:[%s] must start with /", i, limit.Route)
+		}
+		if limit.Key != _RATE_LIMIT_UID && limit.Key != _RATE_LIMIT_IP {
+			addErr("rate_limits[%d]: key:[%s] is unknown, expected uid or ip", i, limit.Key)
+		}
+		if limit.Rate <= 0 {
+			addErr("rate_limits[%d]: rate:[%g] must be greater than 0", i, limit.Rate)
+		}
+		if limit.Burst < 1 {
+			addErr("rate_limits[%d]: burst:[%d] must be at least 1", i, limit.Burst)
+		}
+	}
+
+	if _, err := logger.ParseLevel(c.Log.Level); err != nil {
+		addErr("log: %s", err)
+	}
+	if _, err := logger.ParseFormat(c.Log.Format); err != nil {
+		addErr("log: %s", err)
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("Invalid config: %s", strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// Duration is a time.Duration read from strings such as "5s" in JSON and flags
+type Duration struct {
+	time.Duration
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return fmt.Errorf("Duration must be a string such as \"5s\" [Error:%s]", err)
+	}
+	return d.Set(s)
+}
+
+// Set adheres to the flag.Value interface
+func (d *Duration) Set(s string) error {
+	duration, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	d.Duration = duration
+	return nil
+}
@@ -0,0 +1,150 @@
+package server
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"trippy/jackpot"
+)
+
+func writeTempFile(t *testing.T, dir, name, content string) string {
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("Unable to write [File:%s] [Error:%s]", path, err)
+	}
+	return path
+}
+
+func TestConfigLayers(t *testing.T) {
+	dir, err := ioutil.TempDir("", "trippy-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	keyPath := writeTempFile(t, dir, "key", "secret")
+	configPath := writeTempFile(t, dir, "trippy.json", `{
+		"listen": ":8080",
+		"shutdown_timeout": "10s",
+		"api_key_path": "`+keyPath+`",
+		"log": {"level": "warn"}
+	}`)
+
+	cfg, err := LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("Unable to load config [Error:%s]", err)
+	}
+	if cfg.Listen != ":8080" || cfg.ShutdownTimeout.Duration != 10*time.Second || cfg.Log.Level != "warn" {
+		t.Errorf("Config file not applied. Got:[%+v]", cfg)
+	}
+	if cfg.Log.Format != "logfmt" || len(cfg.Machines) != 1 {
+		t.Errorf("Defaults not kept. Got:[%+v]", cfg)
+	}
+
+	os.Setenv(_ENV_LISTEN, ":9090")
+	os.Setenv(_LOG_LEVEL, "debug")
+	defer os.Unsetenv(_ENV_LISTEN)
+	defer os.Unsetenv(_LOG_LEVEL)
+	if err = cfg.ApplyEnv(); err != nil {
+		t.Fatalf("Unable to apply env [Error:%s]", err)
+	}
+	if cfg.Listen != ":9090" || cfg.Log.Level != "debug" {
+		t.Errorf("Env not applied. Got:[%+v]", cfg)
+	}
+
+	fs := flag.NewFlagSet("trippy", flag.ContinueOnError)
+	flags := NewFlags(fs)
+	if err = fs.Parse([]string{"-listen", ":7071", "-shutdown-timeout", "1s"}); err != nil {
+		t.Fatalf("Unable to parse flags [Error:%s]", err)
+	}
+	flags.Apply(&cfg)
+	if cfg.Listen != ":7071" || cfg.ShutdownTimeout.Duration != time.Second || cfg.Log.Level != "debug" {
+		t.Errorf("Flags not applied. Got:[%+v]", cfg)
+	}
+
+	if err = cfg.Validate(); err != nil {
+		t.Errorf("Expected:[valid config] Got:[%s]", err)
+	}
+}
+
+// The lists of the config file replace the default ones, the fields their entries leave out are not defaulted
+func TestConfigPartialLists(t *testing.T) {
+	dir, err := ioutil.TempDir("", "trippy-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	configPath := writeTempFile(t, dir, "trippy.json", `{
+		"machines": [{"name": "classic", "engine": "atkins"}],
+		"rate_limits": [{"route": "/api/machines/:machine/spins", "rate": 1}],
+		"tls": {"client_cert_paths": ["/admin/"]}
+	}`)
+	cfg, err := LoadConfig(configPath)
+	if err != nil {
+		t.Fatalf("Unable to load config [Error:%s]", err)
+	}
+	if len(cfg.Machines) != 1 || cfg.Machines[0] != (MachineConfig{Name: "classic", Engine: _ENGINE_ATKINS}) {
+		t.Errorf("Expected:[classic disabled] Got:[%+v]", cfg.Machines)
+	}
+	if len(cfg.RateLimits) != 1 || cfg.RateLimits[0] != (RateLimitConfig{Route: "/api/machines/:machine/spins", Rate: 1}) {
+		t.Errorf("Expected:[rate limit without key nor burst] Got:[%+v]", cfg.RateLimits)
+	}
+	if len(cfg.TLS.ClientCertPaths) != 1 || cfg.TLS.ClientCertPaths[0] != "/admin/" {
+		t.Errorf("Expected:[[/admin/]] Got:[%v]", cfg.TLS.ClientCertPaths)
+	}
+
+	configPath = writeTempFile(t, dir, "trippy.json", `{"listen": ":8080"}`)
+	if cfg, err = LoadConfig(configPath); err != nil {
+		t.Fatalf("Unable to load config [Error:%s]", err)
+	}
+	defaults := DefaultConfig()
+	if len(cfg.Machines) != len(defaults.Machines) || len(cfg.TLS.ClientCertPaths) != len(defaults.TLS.ClientCertPaths) {
+		t.Errorf("Expected the default lists Got:[%+v]", cfg)
+	}
+}
+
+func TestConfigValidate(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Listen = "7070"
+	cfg.ShutdownTimeout = Duration{}
+	cfg.TLS.Enabled = true
+	cfg.H2C = true
+	cfg.Machines = append(cfg.Machines, MachineConfig{Name: _ATKINS_DIET_MACHINE, Engine: "unknown"})
+	cfg.Storage.Backend = _STORAGE_FILE
+	cfg.Log.Format = "xml"
+	cfg.Jackpots = []jackpot.Config{
+		{Name: "progressive", Machines: []string{"unknown"}, Seed: 100, MustHitBy: 1000},
+		{Name: "steak", Machines: []string{_ATKINS_DIET_MACHINE}, Contribution: 1, Trigger: &jackpot.Trigger{Symbol: "STEAK", Count: 5}},
+		{Name: "unpaid", Machines: []string{_ATKINS_DIET_MACHINE}, Contribution: 1, Trigger: &jackpot.Trigger{Symbol: "EMPTY", Count: 5}},
+	}
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatalf("Expected:[error] Got:[nil]")
+	}
+	// All the problems are reported at once
+	for _, expected := range []string{"listen", "shutdown_timeout", "api_key_path", "tls", "h2c", "used twice", "engine:[unknown]", "storage", "format",
+		"jackpots[0]: contribution", "machine:[unknown] is not configured", "jackpots[2]: trigger: symbol:[EMPTY] is not paid"} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Errorf("Expected:[%s] in Error:[%s]", expected, err)
+		}
+	}
+	if strings.Contains(err.Error(), "jackpots[1]") {
+		t.Errorf("Expected the trigger on a paid symbol to be valid Got:[%s]", err)
+	}
+}
+
+func TestEnableMachines(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Machines = append(cfg.Machines, MachineConfig{Name: "other", Engine: _ENGINE_ATKINS})
+	cfg.enableMachines([]string{" other "})
+	if cfg.Machines[0].Enabled || !cfg.Machines[1].Enabled {
+		t.Errorf("Expected only [other] enabled. Got:[%+v]", cfg.Machines)
+	}
+}
@@ -0,0 +1,160 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"trippy/slotmachine/engine/atkins"
+)
+
+// errCode is the stable identifier of an API error.
+// Clients must branch on the code, never on the message.
+type errCode string
+
+const (
+	_ERR_INVALID_REQUEST         errCode = "INVALID_REQUEST"
+	_ERR_INVALID_TOKEN           errCode = "INVALID_TOKEN"
+	_ERR_UNAUTHORIZED            errCode = "UNAUTHORIZED"
+	_ERR_TOKEN_EXPIRED           errCode = "TOKEN_EXPIRED"
+	_ERR_INVALID_BET             errCode = "INVALID_BET"
+	_ERR_BET_BELOW_MIN           errCode = "BET_BELOW_MIN"
+	_ERR_BET_ABOVE_MAX           errCode = "BET_ABOVE_MAX"
+	_ERR_INVALID_DENOM           errCode = "INVALID_DENOMINATION"
+	_ERR_INVALID_LINES           errCode = "INVALID_LINES"
+	_ERR_INSUFFICIENT_CHIPS      errCode = "INSUFFICIENT_CHIPS"
+	_ERR_UNKNOWN_MACHINE         errCode = "UNKNOWN_MACHINE"
+	_ERR_MACHINE_DISABLED        errCode = "MACHINE_DISABLED"
+	_ERR_INVALID_DEFINITION      errCode = "INVALID_DEFINITION"
+	_ERR_NOT_FOUND               errCode = "NOT_FOUND"
+	_ERR_METHOD_NOT_ALLOWED      errCode = "METHOD_NOT_ALLOWED"
+	_ERR_CLIENT_CERT_REQUIRED    errCode = "CLIENT_CERT_REQUIRED"
+	_ERR_RATE_LIMITED            errCode = "RATE_LIMITED"
+	_ERR_IDEMPOTENCY_KEY_REUSED  errCode = "IDEMPOTENCY_KEY_REUSED"
+	_ERR_IDEMPOTENCY_IN_PROGRESS errCode = "IDEMPOTENCY_IN_PROGRESS"
+	_ERR_SPIN_FAILED             errCode = "SPIN_FAILED"
+	_ERR_UNAVAILABLE             errCode = "UNAVAILABLE"
+	_ERR_MAINTENANCE             errCode = "MAINTENANCE"
+	_ERR_BONUS_NOT_FOUND         errCode = "BONUS_NOT_FOUND"
+	_ERR_INVALID_PICK            errCode = "INVALID_PICK"
+	_ERR_INTERNAL                errCode = "INTERNAL"
+)
+
+type errDefinition struct {
+	status  int
+	message string
+}
+
+// errCatalogue holds the HTTP status and default message of every error code
+var errCatalogue = map[errCode]errDefinition{
+	_ERR_INVALID_REQUEST:         {http.StatusBadRequest, "Request is invalid"},
+	_ERR_INVALID_TOKEN:           {http.StatusBadRequest, "Invalid token received"},
+	_ERR_UNAUTHORIZED:            {http.StatusUnauthorized, "Admin key is missing or invalid"},
+	_ERR_TOKEN_EXPIRED:           {http.StatusUnauthorized, "Token has expired"},
+	_ERR_INVALID_BET:             {http.StatusBadRequest, "Bet is not greater than 0"},
+	_ERR_BET_BELOW_MIN:           {http.StatusBadRequest, "Bet is below the minimum bet per line"},
+	_ERR_BET_ABOVE_MAX:           {http.StatusBadRequest, "Bet is above the maximum bet per line"},
+	_ERR_INVALID_DENOM:           {http.StatusBadRequest, "Denomination is not allowed"},
+	_ERR_INVALID_LINES:           {http.StatusBadRequest, "Number of lines is out of range"},
+	_ERR_INSUFFICIENT_CHIPS:      {http.StatusBadRequest, "Chips insufficient"},
+	_ERR_UNKNOWN_MACHINE:         {http.StatusBadRequest, "Unknown machine"},
+	_ERR_MACHINE_DISABLED:        {http.StatusServiceUnavailable, "Machine is disabled"},
+	_ERR_INVALID_DEFINITION:      {http.StatusUnprocessableEntity, "Machine definition is invalid"},
+	_ERR_NOT_FOUND:               {http.StatusNotFound, "Resource not found"},
+	_ERR_METHOD_NOT_ALLOWED:      {http.StatusMethodNotAllowed, "Method not allowed"},
+	_ERR_CLIENT_CERT_REQUIRED:    {http.StatusForbidden, "A verified client certificate is required"},
+	_ERR_RATE_LIMITED:            {http.StatusTooManyRequests, "Too many requests"},
+	_ERR_IDEMPOTENCY_KEY_REUSED:  {http.StatusUnprocessableEntity, "Idempotency key was used for another request"},
+	_ERR_IDEMPOTENCY_IN_PROGRESS: {http.StatusConflict, "Request with this idempotency key is in progress"},
+	_ERR_SPIN_FAILED:             {http.StatusInternalServerError, "Unable to spin"},
+	_ERR_UNAVAILABLE:             {http.StatusServiceUnavailable, "Server is shutting down, retry on another server"},
+	_ERR_MAINTENANCE:             {http.StatusServiceUnavailable, "Server is in maintenance, retry later"},
+	_ERR_BONUS_NOT_FOUND:         {http.StatusNotFound, "Bonus game not found"},
+	_ERR_INVALID_PICK:            {http.StatusBadRequest, "Pick is not a hidden prize of the game"},
+	_ERR_INTERNAL:                {http.StatusInternalServerError, "Internal server error"},
+}
+
+// engineErrors maps the errors returned by the slot machine engines to error codes
+var engineErrors = []struct {
+	err  error
+	code errCode
+}{
+	{atkins.ErrInvalidBet, _ERR_INVALID_BET},
+	{atkins.ErrBetBelowMin, _ERR_BET_BELOW_MIN},
+	{atkins.ErrBetAboveMax, _ERR_BET_ABOVE_MAX},
+	{atkins.ErrInvalidDenom, _ERR_INVALID_DENOM},
+	{atkins.ErrInvalidLines, _ERR_INVALID_LINES},
+	{atkins.ErrChipsInsufficient, _ERR_INSUFFICIENT_CHIPS},
+	{errTokenExpired, _ERR_TOKEN_EXPIRED},
+}
+
+// apiError is the error sent in the body of every failed request
+type apiError struct {
+	Code    errCode                `json:"code"`
+	Message string                 `json:"message"`
+	Details map[string]interface{} `json:"details,omitempty"`
+
+	status int
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+// newAPIError creates an error from the catalogue.
+// The message overrides the default message of the code if it is not empty.
+func newAPIError(code errCode, message string) *apiError {
+	def, ok := errCatalogue[code]
+	if !ok {
+		def = errCatalogue[_ERR_INTERNAL]
+	}
+	if message == "" {
+		message = def.message
+	}
+	return &apiError{Code: code, Message: message, status: def.status}
+}
+
+// withDetail adds a detail field to the error
+func (e *apiError) withDetail(key string, value interface{}) *apiError {
+	if e.Details == nil {
+		e.Details = make(map[string]interface{})
+	}
+	e.Details[key] = value
+	return e
+}
+
+// toAPIError converts any error to an API error.
+// Unknown errors are reported as internal errors without leaking their text.
+func toAPIError(err error) *apiError {
+	var apiErr *apiError
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+	for _, engineErr := range engineErrors {
+		if !errors.Is(err, engineErr.err) {
+			continue
+		}
+		apiErr = newAPIError(engineErr.code, "")
+		var limitErr *atkins.LimitError
+		if errors.As(err, &limitErr) {
+			apiErr.withDetail("value", limitErr.Value)
+			if len(limitErr.Allowed) > 0 {
+				apiErr.withDetail("allowed", limitErr.Allowed)
+			} else {
+				apiErr.withDetail("min", limitErr.Min).withDetail("max", limitErr.Max)
+			}
+		}
+		return apiErr
+	}
+	return newAPIError(_ERR_INTERNAL, "")
+}
+
+// wagerError returns the error of a bet rejected by the Wager of a machine.
+// Wager only validates the bet, the errors of the engines unknown to engineErrors reject the bet
+// instead of being internal errors, which the clients would retry. The known errors are kept as they are.
+func wagerError(err error) error {
+	if toAPIError(err).Code == _ERR_INTERNAL {
+		return newAPIError(_ERR_INVALID_BET, err.Error())
+	}
+	return err
+}
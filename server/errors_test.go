@@ -0,0 +1,104 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"trippy/slotmachine/engine/atkins"
+)
+
+var (
+	apiErrorSamples = []apiErrorSample{
+		{err: atkins.ErrInvalidBet, code: _ERR_INVALID_BET, status: http.StatusBadRequest},
+		{err: atkins.ErrChipsInsufficient, code: _ERR_INSUFFICIENT_CHIPS, status: http.StatusBadRequest},
+		{err: fmt.Errorf("wrapped [%w]", atkins.ErrChipsInsufficient), code: _ERR_INSUFFICIENT_CHIPS, status: http.StatusBadRequest},
+		{err: &atkins.LimitError{Err: atkins.ErrBetAboveMax, Value: 600, Min: 1, Max: 500}, code: _ERR_BET_ABOVE_MAX, status: http.StatusBadRequest,
+			details: []string{"value", "min", "max"}},
+		{err: &atkins.LimitError{Err: atkins.ErrInvalidDenom, Value: 3, Allowed: []int{1, 2}}, code: _ERR_INVALID_DENOM, status: http.StatusBadRequest,
+			details: []string{"value", "allowed"}},
+		{err: errTokenExpired, code: _ERR_TOKEN_EXPIRED, status: http.StatusUnauthorized},
+		{err: newAPIError(_ERR_UNKNOWN_MACHINE, ""), code: _ERR_UNKNOWN_MACHINE, status: http.StatusBadRequest},
+		{err: errors.New("some internal failure"), code: _ERR_INTERNAL, status: http.StatusInternalServerError},
+	}
+)
+
+type apiErrorSample struct {
+	err     error
+	code    errCode
+	status  int
+	details []string
+}
+
+func TestToAPIError(t *testing.T) {
+	for _, sample := range apiErrorSamples {
+		testToAPIError(t, sample)
+	}
+}
+
+func testToAPIError(t *testing.T, sample apiErrorSample) {
+	apiErr := toAPIError(sample.err)
+	if apiErr.Code != sample.code || apiErr.status != sample.status {
+		t.Errorf("Error:[%s] Expected:[%s %d] Got:[%s %d]", sample.err, sample.code, sample.status, apiErr.Code, apiErr.status)
+		return
+	}
+	for _, key := range sample.details {
+		if _, ok := apiErr.Details[key]; !ok {
+			t.Errorf("Error:[%s] Detail:[%s] missing in Details:[%v]", sample.err, key, apiErr.Details)
+		}
+	}
+}
+
+// The bets rejected by any engine are not internal errors
+func TestWagerError(t *testing.T) {
+	for _, sample := range []apiErrorSample{
+		{err: errors.New("Bet is not a multiple of 5"), code: _ERR_INVALID_BET, status: http.StatusBadRequest},
+		{err: &atkins.LimitError{Err: atkins.ErrInvalidLines, Value: 30, Min: 1, Max: 20}, code: _ERR_INVALID_LINES, status: http.StatusBadRequest,
+			details: []string{"value", "min", "max"}},
+		{err: atkins.ErrChipsInsufficient, code: _ERR_INSUFFICIENT_CHIPS, status: http.StatusBadRequest},
+	} {
+		apiErr := toAPIError(wagerError(sample.err))
+		if apiErr.Code != sample.code || apiErr.status != sample.status {
+			t.Errorf("Error:[%s] Expected:[%s %d] Got:[%s %d]", sample.err, sample.code, sample.status, apiErr.Code, apiErr.status)
+		}
+		for _, key := range sample.details {
+			if _, ok := apiErr.Details[key]; !ok {
+				t.Errorf("Error:[%s] Detail:[%s] missing in Details:[%v]", sample.err, key, apiErr.Details)
+			}
+		}
+	}
+}
+
+func TestErrorEnvelope(t *testing.T) {
+	w := httptest.NewRecorder()
+	respondWithError(w, newAPIError(_ERR_INSUFFICIENT_CHIPS, "").withDetail("required", 100))
+
+	var resp struct {
+		Error struct {
+			Code    string         `json:"code"`
+			Message string         `json:"message"`
+			Details map[string]int `json:"details"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("Unable to decode response [Error:%s]", err)
+	}
+	if w.Code != http.StatusBadRequest || resp.Error.Code != string(_ERR_INSUFFICIENT_CHIPS) || resp.Error.Details["required"] != 100 {
+		t.Errorf("Expected:[%d %s required:100] Got:[%d %+v]", http.StatusBadRequest, _ERR_INSUFFICIENT_CHIPS, w.Code, resp.Error)
+	}
+}
+
+func TestTokenExpired(t *testing.T) {
+	claims := userClaims{UID: "123", Chips: 1000, Bet: 10, Expiry: time.Now().Add(-time.Minute).Unix()}
+	token, err := createToken(claims, []byte("secret"))
+	if err != nil {
+		t.Fatalf("Unable to create token [Error:%s]", err)
+	}
+	if _, err = parseToken(token, []byte("secret")); !errors.Is(err, errTokenExpired) {
+		t.Errorf("Expected:[%s] Got:[%s]", errTokenExpired, err)
+	}
+}
@@ -0,0 +1,400 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"trippy/logger"
+	trippyv1 "trippy/server/proto"
+	"trippy/slotmachine/engine/atkins"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// gRPC service of proto/trippy.proto, served by the webserver over HTTP/2
+const (
+	_GRPC_ROUTE        = "/trippy.v1.Trippy/:method"
+	_GRPC_CONTENT_TYPE = "application/grpc"
+
+	// Metadata of the calls
+	_GRPC_AUTHORIZATION = "authorization"
+	_GRPC_ERROR_CODE    = "trippy-error-code" // Error code of the REST API, in the trailers
+	_GRPC_RETRY_AFTER   = "retry-after"       // Seconds to retry in of the calls over a rate limit, in the trailers
+)
+
+// grpcStatuses maps the HTTP status of the error codes to gRPC status codes
+var grpcStatuses = map[int]codes.Code{
+	http.StatusBadRequest:          codes.InvalidArgument,
+	http.StatusUnauthorized:        codes.Unauthenticated,
+	http.StatusForbidden:           codes.PermissionDenied,
+	http.StatusNotFound:            codes.NotFound,
+	http.StatusConflict:            codes.Aborted,
+	http.StatusUnprocessableEntity: codes.InvalidArgument,
+	http.StatusTooManyRequests:     codes.ResourceExhausted,
+	http.StatusServiceUnavailable:  codes.Unavailable,
+}
+
+// grpcCodes are the error codes whose gRPC status differs from the one of their HTTP status
+var grpcCodes = map[errCode]codes.Code{
+	_ERR_INVALID_TOKEN:      codes.Unauthenticated,
+	_ERR_INSUFFICIENT_CHIPS: codes.FailedPrecondition,
+}
+
+func grpcStatus(apiErr *apiError) codes.Code {
+	if code, ok := grpcCodes[apiErr.Code]; ok {
+		return code
+	}
+	if code, ok := grpcStatuses[apiErr.status]; ok {
+		return code
+	}
+	return codes.Internal
+}
+
+// grpcService implements the gRPC service with the rounds of the REST API
+type grpcService struct {
+	trippyv1.UnimplementedTrippyServer
+}
+
+// newGRPCServer returns the server of the gRPC service.
+// The player of the JWT in the authorization metadata is checked before every call, then the rate limits
+// of the method, if any, and the errors of the REST API are sent as the gRPC status of the call.
+func newGRPCServer(limits *rateLimits) *grpc.Server {
+	unary := []grpc.UnaryServerInterceptor{grpcUnaryInterceptor}
+	if limits != nil {
+		unary = append(unary, limits.grpcInterceptor)
+	}
+	srv := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(unary...),
+		grpc.ChainStreamInterceptor(grpcStreamInterceptor),
+		grpc.UnknownServiceHandler(grpcUnknownMethod),
+	)
+	trippyv1.RegisterTrippyServer(srv, &grpcService{})
+	return srv
+}
+
+// GRPC serves the gRPC calls routed by the webserver, they need HTTP/2
+func GRPC(srv *grpc.Server) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor != 2 || !strings.HasPrefix(r.Header.Get("Content-Type"), _GRPC_CONTENT_TYPE) {
+			respondWithError(w, newAPIError(_ERR_INVALID_REQUEST, "gRPC calls need HTTP/2 and the application/grpc content type"))
+			return
+		}
+		srv.ServeHTTP(w, r)
+	})
+}
+
+type grpcUserKey struct{}
+
+// grpcContextUser returns the player of the call, checked by the interceptors
+func grpcContextUser(ctx context.Context) userClaims {
+	user, _ := ctx.Value(grpcUserKey{}).(userClaims)
+	return user
+}
+
+func grpcUnaryInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	user, err := grpcUser(ctx)
+	if err != nil {
+		return nil, grpcError(ctx, info.FullMethod, req, err)
+	}
+	resp, err := handler(context.WithValue(ctx, grpcUserKey{}, user), req)
+	if err != nil {
+		return nil, grpcError(ctx, info.FullMethod, req, err)
+	}
+	return resp, nil
+}
+
+// grpcStream is a server stream with the player of the call in its context
+type grpcStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (gs *grpcStream) Context() context.Context {
+	return gs.ctx
+}
+
+func grpcStreamInterceptor(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx := stream.Context()
+	user, err := grpcUser(ctx)
+	if err != nil {
+		return grpcError(ctx, info.FullMethod, nil, err)
+	}
+	if err = handler(srv, &grpcStream{ServerStream: stream, ctx: context.WithValue(ctx, grpcUserKey{}, user)}); err != nil {
+		return grpcError(ctx, info.FullMethod, nil, err)
+	}
+	return nil
+}
+
+func grpcUnknownMethod(srv interface{}, stream grpc.ServerStream) error {
+	method, _ := grpc.MethodFromServerStream(stream)
+	logger.FromContext(stream.Context()).Warn("gRPC: Unknown method", "method", method)
+	apiErr := newAPIError(_ERR_NOT_FOUND, fmt.Sprintf("Method:[%s] not found", method))
+	observeError("", apiErr.Code)
+	stream.SetTrailer(metadata.Pairs(_GRPC_ERROR_CODE, string(apiErr.Code)))
+	return status.Error(codes.Unimplemented, apiErr.Error())
+}
+
+// grpcError returns the status of a failed call, with the error code of the REST API in the trailers.
+// The errors are counted for the machine of the request, if it has one. Errors with a status are kept.
+func grpcError(ctx context.Context, method string, req interface{}, err error) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	log := logger.FromContext(ctx)
+	if ctx.Err() != nil {
+		// The client went away, there is no one to send the status to
+		log.Info("gRPC: Call cancelled", "method", method)
+		return status.FromContextError(ctx.Err()).Err()
+	}
+	var machineName string
+	if machineReq, ok := req.(interface{ GetMachine() string }); ok {
+		machineName = machineReq.GetMachine()
+	}
+	apiErr := toAPIError(err)
+	observeError(machineName, apiErr.Code)
+	log.Info("gRPC: Call failed", "method", method, "code", apiErr.Code)
+	grpc.SetTrailer(ctx, metadata.Pairs(_GRPC_ERROR_CODE, string(apiErr.Code)))
+	return status.Error(grpcStatus(apiErr), apiErr.Error())
+}
+
+// grpcUser returns the player of the JWT in the authorization metadata
+func grpcUser(ctx context.Context) (userClaims, error) {
+	var token string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(_GRPC_AUTHORIZATION); len(values) > 0 {
+			token = strings.TrimPrefix(values[0], _BEARER_PREFIX)
+		}
+	}
+	if token == "" {
+		return userClaims{}, newAPIError(_ERR_INVALID_TOKEN, "Metadata:[authorization] must be Bearer <JWT>")
+	}
+	user, err := tokenUser(ctx, token)
+	if err != nil {
+		logger.FromContext(ctx).Warn("gRPC: Invalid authorization", "err", err)
+		if errors.Is(err, errTokenExpired) {
+			return user, err
+		}
+		return user, newAPIError(_ERR_INVALID_TOKEN, "")
+	}
+	return user, nil
+}
+
+// ----------------------------- Methods ---------------------------------- //
+
+func (*grpcService) Spin(ctx context.Context, req *trippyv1.SpinRequest) (*trippyv1.Round, error) {
+	log := logger.FromContext(ctx)
+	user, err := grpcMachineUser(ctx, req.GetMachine(), req.GetBet())
+	if err != nil {
+		return nil, err
+	}
+	machine, err := playableMachine(req.GetMachine())
+	if err != nil {
+		return nil, err
+	}
+	rnd, err := play(ctx, log, req.GetMachine(), machine, user)
+	if err != nil {
+		return nil, roundError(err, rnd)
+	}
+
+	user.Chips = rnd.balanceAfter()
+	if rnd.token, err = createToken(user, []byte(apiKey)); err != nil {
+		log.Error("gRPC: Unable to create new JWT token", "err", err)
+		return nil, newAPIError(_ERR_INTERNAL, "Unable to generate new JWT")
+	}
+	return newRoundMessage(computeSpinResponseV2(rnd), user.UID), nil
+}
+
+func (*grpcService) Wager(ctx context.Context, req *trippyv1.WagerRequest) (*trippyv1.WagerResponse, error) {
+	user, err := grpcMachineUser(ctx, req.GetMachine(), req.GetBet())
+	if err != nil {
+		return nil, err
+	}
+	machine, err := playableMachine(req.GetMachine())
+	if err != nil {
+		return nil, err
+	}
+	wager, err := machine.Wager(user.bet(), user.Chips)
+	if err != nil && !errors.Is(err, atkins.ErrChipsInsufficient) {
+		return nil, wagerError(err)
+	}
+	return &trippyv1.WagerResponse{
+		Wager:      int64(wager),
+		Balance:    int64(user.Chips),
+		Sufficient: err == nil,
+	}, nil
+}
+
+func (*grpcService) ListMachines(ctx context.Context, req *trippyv1.ListMachinesRequest) (*trippyv1.ListMachinesResponse, error) {
+	resp := new(trippyv1.ListMachinesResponse)
+	for _, cfg := range machines.allConfigs() {
+		machine := newRespMachine(cfg)
+		resp.Machines = append(resp.Machines, &trippyv1.Machine{
+			Name:       machine.Name,
+			Engine:     machine.Engine,
+			Enabled:    machine.Enabled,
+			Definition: machine.Definition,
+		})
+	}
+	return resp, nil
+}
+
+func (*grpcService) GetRound(ctx context.Context, req *trippyv1.GetRoundRequest) (*trippyv1.Round, error) {
+	rnd, err := recentRound(ctx, req.GetId())
+	if err != nil {
+		return nil, err
+	}
+	return newRoundMessage(rnd.Round, rnd.UID), nil
+}
+
+func (*grpcService) Replay(req *trippyv1.ReplayRequest, stream grpc.ServerStreamingServer[trippyv1.Spin]) error {
+	rnd, err := recentRound(stream.Context(), req.GetId())
+	if err != nil {
+		return err
+	}
+	for _, spin := range rnd.Round.Spins {
+		if err = stream.Send(newSpinMessage(spin)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// grpcMachineUser returns the player of the call to the machine of a SpinRequest or a WagerRequest.
+// The bet of the request, if set, replaces the bet of the token.
+func grpcMachineUser(ctx context.Context, machineName string, bet *trippyv1.Bet) (userClaims, error) {
+	user := grpcContextUser(ctx)
+	if machineName == "" {
+		return user, newAPIError(_ERR_INVALID_REQUEST, "Field:[machine] cannot be empty")
+	}
+	if bet != nil {
+		user.Bet, user.Denom, user.Lines = int(bet.GetCoins()), int(bet.GetDenomination()), int(bet.GetLines())
+	}
+	return user, nil
+}
+
+// recentRound returns the round of a GetRoundRequest or a ReplayRequest
+func recentRound(ctx context.Context, id string) (historyRound, error) {
+	rnd, ok := history.get(id, grpcContextUser(ctx).UID)
+	if !ok {
+		return historyRound{}, newAPIError(_ERR_NOT_FOUND, fmt.Sprintf("Round:[%s] not found", id)).withDetail("round", id)
+	}
+	return rnd, nil
+}
+
+// ----------------------------- Messages ---------------------------------- //
+
+func newRoundMessage(resp respSpinV2, uid string) *trippyv1.Round {
+	m := &trippyv1.Round{
+		Id:            resp.Round,
+		Machine:       resp.Machine,
+		Uid:           uid,
+		Wager:         int64(resp.Wager),
+		Total:         int64(resp.Total),
+		FreeSpins:     int32(resp.FreeSpins),
+		BalanceBefore: int64(resp.BalanceBefore),
+		BalanceAfter:  int64(resp.BalanceAfter),
+		Jwt:           resp.JWT,
+	}
+	for _, spin := range resp.Spins {
+		m.Spins = append(m.Spins, newSpinMessage(spin))
+	}
+	for _, win := range resp.Jackpots {
+		m.Jackpots = append(m.Jackpots, &trippyv1.JackpotWin{Jackpot: win.Jackpot, Amount: int64(win.Amount), Reason: win.Reason})
+	}
+	for _, value := range resp.JackpotValues {
+		m.JackpotValues = append(m.JackpotValues, &trippyv1.JackpotValue{
+			Jackpot:   value.Jackpot,
+			Amount:    int64(value.Amount),
+			MustHitBy: int64(value.MustHitBy),
+		})
+	}
+	if game := resp.Bonus; game != nil {
+		m.Bonus = &trippyv1.BonusGame{
+			Id:     game.ID,
+			Prizes: int32(game.Prizes),
+			Picks:  int32(game.Picks),
+			Won:    int64(game.Won),
+			Over:   game.Over,
+		}
+		for _, prize := range game.Reveal {
+			m.Bonus.Reveal = append(m.Bonus.Reveal, &trippyv1.BonusPrize{
+				Position: int32(prize.Position),
+				Prize:    int64(prize.Prize),
+				Picked:   prize.Picked,
+			})
+		}
+	}
+	return m
+}
+
+func newSpinMessage(spin spinV2) *trippyv1.Spin {
+	m := &trippyv1.Spin{
+		Type:       spin.Type,
+		Total:      int64(spin.Total),
+		Multiplier: int32(spin.Multiplier),
+		Scatters:   int32(spin.Scatters),
+		FreeSpins:  int32(spin.FreeSpins),
+		Jackpot:    spin.Jackpot,
+		Respins:    int32(spin.Respins),
+	}
+	for _, stop := range spin.Stops {
+		m.Stops = append(m.Stops, int32(stop))
+	}
+	for _, symbols := range spin.Grid {
+		row := new(trippyv1.Row)
+		for _, s := range symbols {
+			row.Symbols = append(row.Symbols, newSymbolMessage(s))
+		}
+		m.Grid = append(m.Grid, row)
+	}
+	for _, line := range spin.Lines {
+		m.Lines = append(m.Lines, newWinLineMessage(line))
+	}
+	for _, wild := range spin.Wilds {
+		change := &trippyv1.WildChange{Behaviour: wild.Behaviour}
+		for _, cell := range wild.Cells {
+			change.Cells = append(change.Cells, newPositionMessage(cell))
+		}
+		m.Wilds = append(m.Wilds, change)
+	}
+	for _, multiplier := range spin.Multipliers {
+		m.Multipliers = append(m.Multipliers, &trippyv1.WildMultiplier{
+			Reel:       int32(multiplier.Reel),
+			Row:        int32(multiplier.Row),
+			Multiplier: int32(multiplier.Multiplier),
+		})
+	}
+	for _, coin := range spin.Coins {
+		m.Coins = append(m.Coins, &trippyv1.Coin{Reel: int32(coin.Reel), Row: int32(coin.Row), Value: int64(coin.Value)})
+	}
+	return m
+}
+
+func newSymbolMessage(s symbol) *trippyv1.Symbol {
+	return &trippyv1.Symbol{Id: int32(s.ID), Name: s.Name, Code: s.Code, Kind: s.Kind}
+}
+
+func newWinLineMessage(line winLineV2) *trippyv1.WinLine {
+	m := &trippyv1.WinLine{
+		Index:          int32(line.Index),
+		Symbol:         newSymbolMessage(line.Symbol),
+		Count:          int32(line.Count),
+		Payout:         int64(line.Payout),
+		Multiplier:     int32(line.Multiplier),
+		WildMultiplier: int32(line.WildMultiplier),
+	}
+	for _, p := range line.Positions {
+		m.Positions = append(m.Positions, newPositionMessage(p))
+	}
+	return m
+}
+
+func newPositionMessage(p position) *trippyv1.Position {
+	return &trippyv1.Position{Reel: int32(p.Reel), Row: int32(p.Row)}
+}
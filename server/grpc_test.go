@@ -0,0 +1,242 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	trippyv1 "trippy/server/proto"
+	"trippy/slotmachine/engine/atkins"
+
+	"github.com/julienschmidt/httprouter"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// newGRPCConn serves the gRPC service over HTTP/2 without TLS, as the webserver does
+func newGRPCConn(t *testing.T, limits *rateLimits) *grpc.ClientConn {
+	router := httprouter.New()
+	router.Handler(http.MethodPost, _GRPC_ROUTE, GRPC(newGRPCServer(limits)))
+	ts := httptest.NewUnstartedServer(router)
+	ts.Config.Protocols = new(http.Protocols)
+	ts.Config.Protocols.SetUnencryptedHTTP2(true)
+	ts.Start()
+	t.Cleanup(ts.Close)
+
+	conn, err := grpc.NewClient(strings.TrimPrefix(ts.URL, "http://"), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Unable to connect [Error:%s]", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func newGRPCClient(t *testing.T, limits *rateLimits) trippyv1.TrippyClient {
+	return trippyv1.NewTrippyClient(newGRPCConn(t, limits))
+}
+
+// grpcContext returns the context of a call with the token of the player, none if empty
+func grpcContext(token string) context.Context {
+	if token == "" {
+		return context.Background()
+	}
+	return metadata.AppendToOutgoingContext(context.Background(), _GRPC_AUTHORIZATION, _BEARER_PREFIX+token)
+}
+
+func TestGRPCRound(t *testing.T) {
+	apiKey = "secret"
+	machines.set(_ATKINS_DIET_MACHINE, atkins.NewAtkinsDietMachine())
+	client := newGRPCClient(t, nil)
+	token, _ := createToken(userClaims{UID: "123", Chips: 1000, Bet: 1}, []byte(apiKey))
+	ctx := grpcContext(token)
+
+	// The bet of the request replaces the bet of the token
+	rnd, err := client.Spin(ctx, &trippyv1.SpinRequest{Machine: _ATKINS_DIET_MACHINE, Bet: &trippyv1.Bet{Coins: 2}})
+	if err != nil {
+		t.Fatalf("Expected:[nil] Got:[%s]", err)
+	}
+	if rnd.Wager != 2*20 || rnd.BalanceAfter != 1000-rnd.Wager+rnd.Total || len(rnd.Spins) == 0 {
+		t.Errorf("Expected a round of 2 coins per line. Got:[%+v]", rnd)
+	}
+	user, err := parseToken(rnd.Jwt, []byte(apiKey))
+	if err != nil || int64(user.Chips) != rnd.BalanceAfter {
+		t.Errorf("Expected a token with the balance after. Got:[%+v] [Error:%v]", user, err)
+	}
+
+	got, err := client.GetRound(ctx, &trippyv1.GetRoundRequest{Id: rnd.Id})
+	if err != nil || got.Id != rnd.Id || len(got.Spins) != len(rnd.Spins) || got.Jwt != "" {
+		t.Errorf("Expected round:[%s] Got:[%+v] [Error:%v]", rnd.Id, got, err)
+	}
+	stream, err := client.Replay(ctx, &trippyv1.ReplayRequest{Id: rnd.Id})
+	if err != nil {
+		t.Fatalf("Expected:[nil] Got:[%s]", err)
+	}
+	var spins int
+	for {
+		spin, err := stream.Recv()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Expected:[nil] Got:[%s]", err)
+		}
+		if spin.Type != rnd.Spins[spins].Type || spin.Total != rnd.Spins[spins].Total {
+			t.Errorf("Expected spin:[%+v] Got:[%+v]", rnd.Spins[spins], spin)
+		}
+		spins++
+	}
+	if spins != len(rnd.Spins) {
+		t.Errorf("Expected:[%d] spins Got:[%d]", len(rnd.Spins), spins)
+	}
+
+	// Rounds of other players are not found
+	other, _ := createToken(userClaims{UID: "456", Chips: 1000, Bet: 1}, []byte(apiKey))
+	if _, err = client.GetRound(grpcContext(other), &trippyv1.GetRoundRequest{Id: rnd.Id}); status.Code(err) != codes.NotFound {
+		t.Errorf("Expected:[%s] Got:[%v]", codes.NotFound, err)
+	}
+}
+
+func TestGRPCWager(t *testing.T) {
+	apiKey = "secret"
+	machines.set(_ATKINS_DIET_MACHINE, atkins.NewAtkinsDietMachine())
+	client := newGRPCClient(t, nil)
+	token, _ := createToken(userClaims{UID: "123", Chips: 30, Bet: 2}, []byte(apiKey))
+
+	resp, err := client.Wager(grpcContext(token), &trippyv1.WagerRequest{Machine: _ATKINS_DIET_MACHINE})
+	if err != nil {
+		t.Fatalf("Expected:[nil] Got:[%s]", err)
+	}
+	if resp.Wager != 40 || resp.Balance != 30 || resp.Sufficient {
+		t.Errorf("Expected:[Wager:40 Balance:30 Sufficient:false] Got:[%+v]", resp)
+	}
+}
+
+func TestGRPCListMachines(t *testing.T) {
+	apiKey = "secret"
+	machines.configure(MachineConfig{Name: _ATKINS_DIET_MACHINE, Engine: _ENGINE_ATKINS, Enabled: true})
+	client := newGRPCClient(t, nil)
+	token, _ := createToken(userClaims{UID: "123", Chips: 30, Bet: 1}, []byte(apiKey))
+
+	resp, err := client.ListMachines(grpcContext(token), &trippyv1.ListMachinesRequest{})
+	found := false
+	for _, machine := range resp.GetMachines() {
+		found = found || machine.Name == _ATKINS_DIET_MACHINE
+	}
+	if err != nil || !found {
+		t.Errorf("Expected machine:[%s] Got:[%+v] [Error:%v]", _ATKINS_DIET_MACHINE, resp, err)
+	}
+}
+
+type grpcErrorSample struct {
+	method string
+	user   *userClaims // No authorization if nil
+	call   func(ctx context.Context, client trippyv1.TrippyClient, trailer *metadata.MD) error
+	status codes.Code
+	code   errCode
+}
+
+func spinCall(machineName string, coins int32) func(context.Context, trippyv1.TrippyClient, *metadata.MD) error {
+	return func(ctx context.Context, client trippyv1.TrippyClient, trailer *metadata.MD) error {
+		req := &trippyv1.SpinRequest{Machine: machineName}
+		if coins > 0 {
+			req.Bet = &trippyv1.Bet{Coins: coins}
+		}
+		_, err := client.Spin(ctx, req, grpc.Trailer(trailer))
+		return err
+	}
+}
+
+func testGRPCError(t *testing.T, client trippyv1.TrippyClient, sample grpcErrorSample) {
+	var token string
+	if sample.user != nil {
+		token, _ = createToken(*sample.user, []byte(apiKey))
+	}
+	var trailer metadata.MD
+	err := sample.call(grpcContext(token), client, &trailer)
+	var code string
+	if values := trailer.Get(_GRPC_ERROR_CODE); len(values) > 0 {
+		code = values[0]
+	}
+	if status.Code(err) != sample.status || code != string(sample.code) {
+		t.Errorf("[Method:%s] Expected:[%s %s] Got:[%s %s]", sample.method, sample.status, sample.code, status.Code(err), code)
+	}
+}
+
+func TestGRPCErrors(t *testing.T) {
+	apiKey = "secret"
+	machines.set(_ATKINS_DIET_MACHINE, atkins.NewAtkinsDietMachine())
+	client := newGRPCClient(t, nil)
+	user := &userClaims{UID: "123", Chips: 1000, Bet: 1}
+
+	getUnknownRound := func(ctx context.Context, client trippyv1.TrippyClient, trailer *metadata.MD) error {
+		_, err := client.GetRound(ctx, &trippyv1.GetRoundRequest{Id: "unknown"}, grpc.Trailer(trailer))
+		return err
+	}
+	samples := []grpcErrorSample{
+		{"Spin", nil, spinCall(_ATKINS_DIET_MACHINE, 0), codes.Unauthenticated, _ERR_INVALID_TOKEN},
+		{"Spin", &userClaims{UID: "123", Chips: 1, Bet: 1}, spinCall(_ATKINS_DIET_MACHINE, 0), codes.FailedPrecondition, _ERR_INSUFFICIENT_CHIPS},
+		{"Spin", user, spinCall("unknown", 0), codes.InvalidArgument, _ERR_UNKNOWN_MACHINE},
+		{"Spin", user, spinCall("", 0), codes.InvalidArgument, _ERR_INVALID_REQUEST},
+		{"Spin", user, spinCall(_ATKINS_DIET_MACHINE, 1000), codes.InvalidArgument, _ERR_BET_ABOVE_MAX},
+		{"GetRound", user, getUnknownRound, codes.NotFound, _ERR_NOT_FOUND},
+	}
+	for _, sample := range samples {
+		testGRPCError(t, client, sample)
+	}
+}
+
+// The calls are limited by the player of their JWT, as the REST requests
+func TestGRPCRateLimit(t *testing.T) {
+	apiKey = "secret"
+	machines.set(_ATKINS_DIET_MACHINE, atkins.NewAtkinsDietMachine())
+	client := newGRPCClient(t, newRateLimits([]RateLimitConfig{
+		{Route: "/trippy.v1.Trippy/Spin", Key: _RATE_LIMIT_UID, Rate: 0.5, Burst: 1},
+	}))
+	user := &userClaims{UID: "123", Chips: 1000, Bet: 1}
+
+	samples := []grpcErrorSample{
+		{"Spin", user, spinCall(_ATKINS_DIET_MACHINE, 0), codes.OK, ""},
+		{"Spin", user, spinCall(_ATKINS_DIET_MACHINE, 0), codes.ResourceExhausted, _ERR_RATE_LIMITED},
+		{"Spin", &userClaims{UID: "456", Chips: 1000, Bet: 1}, spinCall(_ATKINS_DIET_MACHINE, 0), codes.OK, ""},
+	}
+	for _, sample := range samples {
+		testGRPCError(t, client, sample)
+	}
+
+	var trailer metadata.MD
+	token, _ := createToken(*user, []byte(apiKey))
+	if err := spinCall(_ATKINS_DIET_MACHINE, 0)(grpcContext(token), client, &trailer); status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("Expected:[%s] Got:[%v]", codes.ResourceExhausted, err)
+	}
+	if values := trailer.Get(_GRPC_RETRY_AFTER); len(values) != 1 || values[0] != "2" {
+		t.Errorf("Expected:[2] Got:[%v]", values)
+	}
+}
+
+func TestGRPCUnknownMethod(t *testing.T) {
+	apiKey = "secret"
+	conn := newGRPCConn(t, nil)
+	token, _ := createToken(userClaims{UID: "123", Chips: 1000, Bet: 1}, []byte(apiKey))
+
+	var trailer metadata.MD
+	err := conn.Invoke(grpcContext(token), "/trippy.v1.Trippy/Unknown", &trippyv1.GetRoundRequest{}, &trippyv1.Round{}, grpc.Trailer(&trailer))
+	if status.Code(err) != codes.Unimplemented || len(trailer.Get(_GRPC_ERROR_CODE)) == 0 || trailer.Get(_GRPC_ERROR_CODE)[0] != string(_ERR_NOT_FOUND) {
+		t.Errorf("Expected:[%s %s] Got:[%v] [Trailer:%v]", codes.Unimplemented, _ERR_NOT_FOUND, err, trailer)
+	}
+}
+
+func TestGRPCNeedsHTTP2(t *testing.T) {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/trippy.v1.Trippy/Spin", nil)
+	r.Header.Set("Content-Type", _GRPC_CONTENT_TYPE)
+	GRPC(newGRPCServer(nil)).ServeHTTP(w, r)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("Expected:[400] Got:[%d %s]", w.Code, w.Body)
+	}
+}
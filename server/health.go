@@ -0,0 +1,189 @@
+package server
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+type respHealth struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"` // Result of every check, "ok" or the error
+}
+
+const (
+	_HEALTH_OK       = "ok"
+	_HEALTH_FAILING  = "failing"
+	_HEALTH_DRAINING = "draining"
+)
+
+type healthCheck struct {
+	name  string
+	check func() error
+}
+
+// healthRegistry holds the checks a dependency of the server must pass to serve rounds
+type healthRegistry struct {
+	mu     sync.RWMutex
+	checks []healthCheck
+}
+
+func (h *healthRegistry) add(name string, check func() error) {
+	h.mu.Lock()
+	h.checks = append(h.checks, healthCheck{name, check})
+	h.mu.Unlock()
+}
+
+// run runs all the checks and reports whether all of them passed
+func (h *healthRegistry) run() (map[string]string, bool) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	var (
+		results = make(map[string]string, len(h.checks))
+		healthy = true
+	)
+	for _, c := range h.checks {
+		if err := c.check(); err != nil {
+			results[c.name] = err.Error()
+			healthy = false
+			continue
+		}
+		results[c.name] = _HEALTH_OK
+	}
+	return results, healthy
+}
+
+// readiness checks are registered in Initialize, one per dependency
+var readiness = &healthRegistry{}
+
+// checkMachines fails if no machine is served
+func checkMachines() error {
+	if machines.count() == 0 {
+		return fmt.Errorf("No machine is enabled")
+	}
+	return nil
+}
+
+// checkStorage fails if the storage backend cannot be written to
+func checkStorage(cfg StorageConfig) func() error {
+	return checkDir(cfg, "")
+}
+
+// checkDir fails if the directory of the file storage cannot be written to
+func checkDir(cfg StorageConfig, dir string) func() error {
+	return func() error {
+		if cfg.Backend != _STORAGE_FILE {
+			return nil
+		}
+		path := filepath.Join(cfg.Path, dir)
+		f, err := ioutil.TempFile(path, ".trippy-health-")
+		if err != nil {
+			return fmt.Errorf("Storage [Path:%s] is not writable [Error:%s]", path, err)
+		}
+		f.Close()
+		os.Remove(f.Name())
+		return nil
+	}
+}
+
+// checkHistory fails if the last round could not be saved in the history
+func checkHistory() error {
+	return history.check()
+}
+
+// checkJackpots fails if the progressive or the mystery jackpots could not be saved
+func checkJackpots() error {
+	if jackpots != nil {
+		if err := jackpots.Err(); err != nil {
+			return fmt.Errorf("Jackpots not saved [Error:%s]", err)
+		}
+	}
+	return mysteries.check()
+}
+
+// Healthz reports that the process is alive and serving requests
+func Healthz(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	writeResponse(w, http.StatusOK, respHealth{Status: _HEALTH_OK})
+}
+
+// Readyz reports whether the server can play rounds.
+// It fails while the server drains the rounds in play before stopping.
+func Readyz(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	checks, healthy := readiness.run()
+	switch {
+	case rounds.isDraining():
+		writeResponse(w, http.StatusServiceUnavailable, respHealth{Status: _HEALTH_DRAINING, Checks: checks})
+	case !healthy:
+		writeResponse(w, http.StatusServiceUnavailable, respHealth{Status: _HEALTH_FAILING, Checks: checks})
+	default:
+		writeResponse(w, http.StatusOK, respHealth{Status: _HEALTH_OK, Checks: checks})
+	}
+}
+
+// roundTracker tracks the rounds in play, free spins included,
+// so that they can finish before the server stops
+type roundTracker struct {
+	mu       sync.Mutex
+	draining bool
+	inPlay   int
+	done     sync.WaitGroup
+}
+
+var rounds = &roundTracker{}
+
+// begin registers a new round, it returns false if the server is draining
+func (rt *roundTracker) begin() bool {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	if rt.draining {
+		return false
+	}
+	rt.inPlay++
+	rt.done.Add(1)
+	return true
+}
+
+func (rt *roundTracker) end() {
+	rt.mu.Lock()
+	rt.inPlay--
+	rt.mu.Unlock()
+	rt.done.Done()
+}
+
+func (rt *roundTracker) count() int {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	return rt.inPlay
+}
+
+func (rt *roundTracker) isDraining() bool {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	return rt.draining
+}
+
+// drain stops new rounds and waits for the rounds in play to finish.
+// It returns false if they did not finish within the timeout.
+func (rt *roundTracker) drain(timeout time.Duration) bool {
+	rt.mu.Lock()
+	rt.draining = true
+	rt.mu.Unlock()
+
+	finished := make(chan struct{})
+	go func() {
+		rt.done.Wait()
+		close(finished)
+	}()
+	select {
+	case <-finished:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
@@ -0,0 +1,145 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func readyzStatus(t *testing.T) (int, respHealth) {
+	w := httptest.NewRecorder()
+	Readyz(w, httptest.NewRequest(http.MethodGet, "/readyz", nil), nil)
+	var resp respHealth
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("Unable to decode response [Error:%s]", err)
+	}
+	return w.Code, resp
+}
+
+func TestReadyz(t *testing.T) {
+	defer func(r *healthRegistry, rt *roundTracker) { readiness, rounds = r, rt }(readiness, rounds)
+	readiness, rounds = &healthRegistry{}, &roundTracker{}
+
+	readiness.add("machines", func() error { return nil })
+	if code, resp := readyzStatus(t); code != http.StatusOK || resp.Checks["machines"] != _HEALTH_OK {
+		t.Errorf("Expected:[200 ok] Got:[%d %+v]", code, resp)
+	}
+
+	readiness.add("wallet", func() error { return errors.New("wallet unreachable") })
+	if code, resp := readyzStatus(t); code != http.StatusServiceUnavailable || resp.Status != _HEALTH_FAILING ||
+		resp.Checks["wallet"] != "wallet unreachable" {
+		t.Errorf("Expected:[503 failing] Got:[%d %+v]", code, resp)
+	}
+
+	rounds.drain(time.Millisecond)
+	if code, resp := readyzStatus(t); code != http.StatusServiceUnavailable || resp.Status != _HEALTH_DRAINING {
+		t.Errorf("Expected:[503 draining] Got:[%d %+v]", code, resp)
+	}
+}
+
+func TestHealthz(t *testing.T) {
+	w := httptest.NewRecorder()
+	Healthz(w, httptest.NewRequest(http.MethodGet, "/healthz", nil), nil)
+	if w.Code != http.StatusOK {
+		t.Errorf("Expected:[200] Got:[%d]", w.Code)
+	}
+}
+
+func TestRoundDrain(t *testing.T) {
+	rt := &roundTracker{}
+	if !rt.begin() {
+		t.Fatalf("Expected round to begin")
+	}
+
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		rt.end()
+	}()
+	if !rt.drain(time.Second) {
+		t.Errorf("Expected the round in play to finish within the timeout")
+	}
+	if rt.begin() {
+		t.Errorf("Expected no new round while draining")
+	}
+	if rt.count() != 0 {
+		t.Errorf("Expected:[0] Got:[%d]", rt.count())
+	}
+
+	rt = &roundTracker{}
+	rt.begin()
+	if rt.drain(10 * time.Millisecond) {
+		t.Errorf("Expected the drain to time out")
+	}
+}
+
+func TestStoreChecks(t *testing.T) {
+	defer func(rh *roundHistory) { history = rh }(history)
+	storage := StorageConfig{Backend: _STORAGE_FILE, Path: t.TempDir()}
+	history, _ = newStoredHistory(10, storage)
+
+	history.add(historyTestRound("a", "123", 0))
+	if err := checkHistory(); err != nil {
+		t.Errorf("Expected:[nil] Got:[%s]", err)
+	}
+	os.RemoveAll(history.dir)
+	history.add(historyTestRound("b", "123", 0))
+	if err := checkHistory(); err == nil {
+		t.Errorf("Expected the history to fail once a round is not saved")
+	}
+
+	if err := checkDir(storage, _BONUS_DIR)(); err == nil {
+		t.Errorf("Expected the bonus games to fail without their directory")
+	}
+	os.Mkdir(filepath.Join(storage.Path, _BONUS_DIR), 0700)
+	if err := checkDir(storage, _BONUS_DIR)(); err != nil {
+		t.Errorf("Expected:[nil] Got:[%s]", err)
+	}
+}
+
+func TestStopBeforeDrain(t *testing.T) {
+	defer func(rt *roundTracker) { rounds = rt }(rounds)
+	rounds = &roundTracker{}
+	s := &Server{
+		Config: Config{
+			Listen:          "127.0.0.1:0",
+			ShutdownTimeout: Duration{time.Second},
+			DrainTimeout:    Duration{time.Second},
+		},
+		idempotency: newIdempotencyStore(time.Hour),
+	}
+	if err := s.StartWebServer(make(chan error, 1)); err != nil {
+		t.Fatalf("Unable to start webserver [Error:%s]", err)
+	}
+	addr := s.apiListener.Addr().String()
+
+	// A round in play outlives the shutdown of the webserver
+	rounds.begin()
+	stopped := make(chan struct{})
+	go func() {
+		s.Stop()
+		close(stopped)
+	}()
+	for deadline := time.Now().Add(time.Second); ; time.Sleep(10 * time.Millisecond) {
+		conn, err := net.Dial("tcp", addr)
+		if err != nil {
+			break
+		}
+		conn.Close()
+		if time.Now().After(deadline) {
+			t.Fatalf("Expected the webserver to stop accepting before the rounds are drained")
+		}
+	}
+	select {
+	case <-stopped:
+		t.Errorf("Expected Stop to drain the round in play")
+	default:
+	}
+	rounds.end()
+	<-stopped
+}
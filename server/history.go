@@ -0,0 +1,158 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	_ROUND_HISTORY_SIZE = 10000    // Rounds kept to be looked up by ID, the oldest is dropped for every new one
+	_ROUNDS_DIR         = "rounds" // Directory of the rounds in the storage path
+)
+
+// historyRound is a round as it is looked up, the response of the round without its token
+type historyRound struct {
+	UID   string     `json:"uid"`
+	Round respSpinV2 `json:"round"`
+}
+
+// roundHistory keeps the last rounds played, the oldest is dropped for every new one once it is full.
+// With the file storage every round is kept in a JSON file of its directory, across restarts.
+type roundHistory struct {
+	dir string // Directory of the rounds, empty if they are kept in memory only
+
+	mu     sync.RWMutex
+	size   int
+	ids    []string // Ring of the round IDs, in the order played
+	next   int
+	rounds map[string]*historyRound // nil for the rounds of the previous runs, read from their file
+	err    error                    // Error of the last round saved
+}
+
+var history = newRoundHistory(_ROUND_HISTORY_SIZE)
+
+func newRoundHistory(size int) *roundHistory {
+	return &roundHistory{
+		size:   size,
+		ids:    make([]string, 0, size),
+		rounds: make(map[string]*historyRound, size),
+	}
+}
+
+// newStoredHistory returns the history kept in the storage backend, in memory unless it is a file.
+// The rounds saved by the previous runs are found again, the oldest over size being dropped.
+func newStoredHistory(size int, storage StorageConfig) (*roundHistory, error) {
+	rh := newRoundHistory(size)
+	if storage.Backend != _STORAGE_FILE {
+		return rh, nil
+	}
+	rh.dir = filepath.Join(storage.Path, _ROUNDS_DIR)
+	if err := os.MkdirAll(rh.dir, 0700); err != nil {
+		return nil, fmt.Errorf("Unable to create round history [Dir:%s] [Error:%s]", rh.dir, err)
+	}
+	files, err := ioutil.ReadDir(rh.dir)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read round history [Dir:%s] [Error:%s]", rh.dir, err)
+	}
+	sort.SliceStable(files, func(i, j int) bool { return files[i].ModTime().Before(files[j].ModTime()) })
+	for i, file := range files {
+		id := strings.TrimSuffix(file.Name(), ".json")
+		if file.IsDir() || id == file.Name() {
+			continue
+		}
+		if i < len(files)-size {
+			os.Remove(rh.path(id))
+			continue
+		}
+		rh.ids = append(rh.ids, id)
+		rh.rounds[id] = nil
+	}
+	return rh, nil
+}
+
+// path returns the file of the round, the IDs being generated by the server
+func (rh *roundHistory) path(id string) string {
+	return filepath.Join(rh.dir, filepath.Base(id)+".json")
+}
+
+// add keeps the round, its file being written before it can be looked up.
+// A round which cannot be saved is kept in memory, the error is reported by check.
+func (rh *roundHistory) add(rnd round) {
+	// The token is given to the player once, it is not kept
+	rnd.token = ""
+	hr := &historyRound{UID: rnd.user.UID, Round: computeSpinResponseV2(rnd)}
+	var err error
+	if rh.dir != "" {
+		if err = rh.save(rnd.id, hr); err != nil {
+			slog.Error("Unable to save round", "round", rnd.id, "err", err)
+		}
+	}
+
+	rh.mu.Lock()
+	rh.err = err
+	var dropped string
+	if len(rh.ids) < rh.size {
+		rh.ids = append(rh.ids, rnd.id)
+	} else {
+		dropped = rh.ids[rh.next]
+		delete(rh.rounds, dropped)
+		rh.ids[rh.next] = rnd.id
+		rh.next = (rh.next + 1) % rh.size
+	}
+	rh.rounds[rnd.id] = hr
+	rh.mu.Unlock()
+
+	if dropped != "" && rh.dir != "" {
+		os.Remove(rh.path(dropped))
+	}
+}
+
+func (rh *roundHistory) save(id string, hr *historyRound) error {
+	data, err := json.Marshal(hr)
+	if err != nil {
+		return fmt.Errorf("Unable to encode round [Round:%s] [Error:%s]", id, err)
+	}
+	if err = ioutil.WriteFile(rh.path(id), data, 0600); err != nil {
+		return fmt.Errorf("Unable to write round [Round:%s] [Error:%s]", id, err)
+	}
+	return nil
+}
+
+// get returns the round of the player, rounds of other players are not found
+func (rh *roundHistory) get(id, uid string) (historyRound, bool) {
+	rh.mu.RLock()
+	hr, ok := rh.rounds[id]
+	rh.mu.RUnlock()
+	if !ok {
+		return historyRound{}, false
+	}
+	if hr == nil {
+		// Saved by a previous run
+		data, err := ioutil.ReadFile(rh.path(id))
+		if err != nil {
+			return historyRound{}, false
+		}
+		hr = new(historyRound)
+		if err = json.Unmarshal(data, hr); err != nil {
+			slog.Error("Unable to parse round", "round", id, "err", err)
+			return historyRound{}, false
+		}
+	}
+	if hr.UID != uid {
+		return historyRound{}, false
+	}
+	return *hr, true
+}
+
+// check fails if the last round could not be saved
+func (rh *roundHistory) check() error {
+	rh.mu.RLock()
+	defer rh.mu.RUnlock()
+	return rh.err
+}
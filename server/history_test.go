@@ -0,0 +1,67 @@
+package server
+
+import (
+	"testing"
+
+	"trippy/slotmachine"
+)
+
+func historyTestRound(id, uid string, payout int) round {
+	return round{
+		id:          id,
+		machineName: _FIXED_MACHINE,
+		machine:     &fixedMachine{wager: 10, payouts: []int{payout}},
+		user:        userClaims{UID: uid, Chips: 100, Bet: 1},
+		wager:       10,
+		payout:      payout,
+		results:     []slotmachine.SpinResult{{Type: slotmachine.MAIN_SPIN, Pay: payout, Multiplier: 1}},
+		token:       "jwt",
+	}
+}
+
+func TestRoundHistory(t *testing.T) {
+	rh := newRoundHistory(2)
+	for i, id := range []string{"a", "b", "c"} {
+		rh.add(historyTestRound(id, "123", i))
+	}
+	if _, found := rh.get("a", "123"); found {
+		t.Errorf("Expected the oldest round to be dropped")
+	}
+	if _, found := rh.get("c", "456"); found {
+		t.Errorf("Expected the round of another player not to be found")
+	}
+	hr, found := rh.get("c", "123")
+	if !found || hr.Round.Total != 2 || hr.Round.JWT != "" || len(hr.Round.Spins) != 1 {
+		t.Errorf("Expected the round without its token. Got:[%v %+v]", found, hr)
+	}
+}
+
+func TestStoredRoundHistory(t *testing.T) {
+	storage := StorageConfig{Backend: _STORAGE_FILE, Path: t.TempDir()}
+	rh, err := newStoredHistory(2, storage)
+	if err != nil {
+		t.Fatalf("Unable to create history [Error:%s]", err)
+	}
+	for i, id := range []string{"a", "b", "c"} {
+		rh.add(historyTestRound(id, "123", i))
+	}
+
+	// Rounds of the previous run
+	if rh, err = newStoredHistory(2, storage); err != nil {
+		t.Fatalf("Unable to restore history [Error:%s]", err)
+	}
+	if _, found := rh.get("a", "123"); found {
+		t.Errorf("Expected the oldest round to be dropped")
+	}
+	hr, found := rh.get("b", "123")
+	if !found || hr.Round.Round != "b" || hr.Round.Total != 1 {
+		t.Errorf("Expected the round saved. Got:[%v %+v]", found, hr)
+	}
+	rh.add(historyTestRound("d", "123", 3))
+	if _, found = rh.get("b", "123"); found {
+		t.Errorf("Expected the oldest round restored to be dropped")
+	}
+	if _, found = rh.get("c", "123"); !found {
+		t.Errorf("Expected the round:[c] to be kept")
+	}
+}
@@ -0,0 +1,230 @@
+package server
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"trippy/logger"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+const (
+	_HEADER_IDEMPOTENCY_KEY      = "Idempotency-Key"
+	_HEADER_IDEMPOTENCY_REPLAYED = "Idempotent-Replayed"
+
+	_IDEMPOTENCY_KEY_MAX_LENGTH = 255
+	_IDEMPOTENCY_TTL            = 24 * time.Hour // Default time a response is kept for the retries
+	_IDEMPOTENCY_SWEEP_INTERVAL = time.Minute    // Interval to drop the expired responses
+)
+
+// idempotentResponse is the response of the request first made with a key
+type idempotentResponse struct {
+	requestHash [sha256.Size]byte // Path and body of the request
+	done        bool              // false while the request is in progress
+	status      int
+	header      http.Header
+	body        []byte
+	created     time.Time
+}
+
+// idempotencyStore keeps the responses of the rounds played with an Idempotency-Key, per player
+type idempotencyStore struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu        sync.Mutex
+	responses map[string]*idempotentResponse // By UID and key
+	lastSweep time.Time
+}
+
+func newIdempotencyStore(ttl time.Duration) *idempotencyStore {
+	return &idempotencyStore{
+		ttl:       ttl,
+		now:       time.Now,
+		responses: make(map[string]*idempotentResponse),
+	}
+}
+
+// reserve returns the response stored for the key, or reserves the key for a new request if there is none
+func (is *idempotencyStore) reserve(storeKey string, requestHash [sha256.Size]byte) (idempotentResponse, bool) {
+	is.mu.Lock()
+	defer is.mu.Unlock()
+	now := is.now()
+	is.sweep(now)
+	if resp, ok := is.responses[storeKey]; ok {
+		return *resp, true
+	}
+	is.responses[storeKey] = &idempotentResponse{requestHash: requestHash, created: now}
+	return idempotentResponse{}, false
+}
+
+// complete stores the response of the request which reserved the key
+func (is *idempotencyStore) complete(storeKey string, status int, header http.Header, body []byte) {
+	is.mu.Lock()
+	defer is.mu.Unlock()
+	if resp, ok := is.responses[storeKey]; ok {
+		resp.done, resp.status, resp.header, resp.body = true, status, header, body
+	}
+}
+
+// release frees the key of a request which failed, so that it can be retried
+func (is *idempotencyStore) release(storeKey string) {
+	is.mu.Lock()
+	delete(is.responses, storeKey)
+	is.mu.Unlock()
+}
+
+func (is *idempotencyStore) sweep(now time.Time) {
+	if now.Sub(is.lastSweep) < _IDEMPOTENCY_SWEEP_INTERVAL {
+		return
+	}
+	is.lastSweep = now
+	for key, resp := range is.responses {
+		if resp.done && now.Sub(resp.created) >= is.ttl {
+			delete(is.responses, key)
+		}
+	}
+}
+
+// recordingWriter writes the response through and keeps a copy of it, until it is complete
+type recordingWriter struct {
+	http.ResponseWriter
+	status     int
+	failed     bool // Failed after a success status was written
+	body       bytes.Buffer
+	completed  bool
+	onComplete func()
+}
+
+// responseFailer is implemented by the writers which need to know that a response failed after its status
+type responseFailer interface {
+	fail()
+}
+
+// failResponse marks the response as failed, for the errors sent once a success status was written
+func failResponse(w http.ResponseWriter) {
+	if failer, ok := w.(responseFailer); ok {
+		failer.fail()
+	}
+}
+
+func (rw *recordingWriter) fail() {
+	rw.failed = true
+}
+
+// responseCompleter is implemented by the writers which keep the response of a round,
+// for the streams which go on once their round is played
+type responseCompleter interface {
+	complete()
+}
+
+// completeResponse ends the response kept for the round, what is written next is not part of it
+func completeResponse(w http.ResponseWriter) {
+	if completer, ok := w.(responseCompleter); ok {
+		completer.complete()
+	}
+}
+
+func (rw *recordingWriter) complete() {
+	if rw.completed {
+		return
+	}
+	rw.completed = true
+	if rw.onComplete != nil {
+		rw.onComplete()
+	}
+}
+
+func (rw *recordingWriter) WriteHeader(status int) {
+	rw.status = status
+	rw.ResponseWriter.WriteHeader(status)
+}
+
+// Flush adheres to http.Flusher, for the streamed responses
+func (rw *recordingWriter) Flush() {
+	if flusher, ok := rw.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (rw *recordingWriter) Write(b []byte) (int, error) {
+	if rw.status == 0 {
+		rw.status = http.StatusOK
+	}
+	if !rw.completed {
+		rw.body.Write(b)
+	}
+	return rw.ResponseWriter.Write(b)
+}
+
+// idempotent makes a round played with an Idempotency-Key happen once.
+// Retries with the key get the response of the round played, requests reusing the key
+// for another path or body are rejected. Only the rounds played are stored, failed
+// requests, streams ended by an error event included, can be retried with the same key.
+func (is *idempotencyStore) idempotent(next httprouter.Handle) httprouter.Handle {
+	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		key := r.Header.Get(_HEADER_IDEMPOTENCY_KEY)
+		if key == "" {
+			next(w, r, ps)
+			return
+		}
+		if len(key) > _IDEMPOTENCY_KEY_MAX_LENGTH {
+			respondWithError(w, newAPIError(_ERR_INVALID_REQUEST,
+				fmt.Sprintf("Header:[%s] is longer than [%d] characters", _HEADER_IDEMPOTENCY_KEY, _IDEMPOTENCY_KEY_MAX_LENGTH)))
+			return
+		}
+
+		body, err := readBody(w, r)
+		if err != nil {
+			respondWithError(w, newAPIError(_ERR_INVALID_REQUEST, fmt.Sprintf("Reading body failed. [Error:%s]", err)))
+			return
+		}
+		// The token parsed by the rate limits is reused, and kept for the handler
+		r, user, err := withToken(r, bodyToken(body))
+		if err != nil {
+			// Rejected by the handler
+			next(w, r, ps)
+			return
+		}
+
+		var (
+			log         = logger.FromContext(r.Context()).With("uid", user.UID, "idempotency_key", key)
+			storeKey    = user.UID + "\x00" + key
+			requestHash = sha256.Sum256(append([]byte(r.URL.Path+"\x00"), body...))
+		)
+		stored, found := is.reserve(storeKey, requestHash)
+		switch {
+		case found && stored.requestHash != requestHash:
+			log.Warn("Idempotency key reused for another request")
+			respondWithError(w, newAPIError(_ERR_IDEMPOTENCY_KEY_REUSED, ""))
+		case found && !stored.done:
+			respondWithError(w, newAPIError(_ERR_IDEMPOTENCY_IN_PROGRESS, ""))
+		case found:
+			log.Info("Round replayed for idempotency key")
+			for name, values := range stored.header {
+				w.Header()[name] = values
+			}
+			w.Header().Set(_HEADER_IDEMPOTENCY_REPLAYED, "true")
+			w.WriteHeader(stored.status)
+			w.Write(stored.body)
+		default:
+			rw := &recordingWriter{ResponseWriter: w}
+			rw.onComplete = func() {
+				// The key is released if the round failed, even with a panic or in a stream
+				if rw.failed || rw.status < 200 || rw.status >= 300 {
+					is.release(storeKey)
+					return
+				}
+				header := http.Header{"Content-Type": w.Header()["Content-Type"]}
+				is.complete(storeKey, rw.status, header, rw.body.Bytes())
+			}
+			defer rw.complete()
+			next(rw, r, ps)
+		}
+	}
+}
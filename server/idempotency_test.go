@@ -0,0 +1,122 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"trippy/slotmachine/engine/atkins"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+func idempotentRequest(t *testing.T, is *idempotencyStore, path, key string, user userClaims) *httptest.ResponseRecorder {
+	token, err := createToken(user, []byte(apiKey))
+	if err != nil {
+		t.Fatalf("Unable to create token [Error:%s]", err)
+	}
+	r := httptest.NewRequest(http.MethodPost, path, strings.NewReader(token))
+	r.Header.Set(_HEADER_IDEMPOTENCY_KEY, key)
+	w := httptest.NewRecorder()
+	is.idempotent(SpinV2)(w, r, httprouter.Params{{Key: _PARA_SPIN_MACHINE, Value: _ATKINS_DIET_MACHINE}})
+	return w
+}
+
+func TestIdempotentSpin(t *testing.T) {
+	is := newIdempotencyStore(time.Hour)
+	apiKey = "secret"
+	machines.set(_ATKINS_DIET_MACHINE, atkins.NewAtkinsDietMachine())
+
+	const path = "/api/v2/machines/atkins-diet/spins"
+	user := userClaims{UID: "123", Chips: 1000, Bet: 1}
+	first := idempotentRequest(t, is, path, "round-1", user)
+	if first.Code != http.StatusOK {
+		t.Fatalf("Expected:[200] Got:[%d %s]", first.Code, first.Body)
+	}
+	retry := idempotentRequest(t, is, path, "round-1", user)
+	if retry.Code != http.StatusOK || retry.Body.String() != first.Body.String() {
+		t.Errorf("Expected the first round again. Expected:[%s] Got:[%d %s]", first.Body, retry.Code, retry.Body)
+	}
+	if retry.Header().Get(_HEADER_IDEMPOTENCY_REPLAYED) != "true" {
+		t.Errorf("Expected header:[%s]", _HEADER_IDEMPOTENCY_REPLAYED)
+	}
+
+	// Keys are per player
+	other := idempotentRequest(t, is, path, "round-1", userClaims{UID: "456", Chips: 1000, Bet: 1})
+	if other.Code != http.StatusOK || other.Header().Get(_HEADER_IDEMPOTENCY_REPLAYED) != "" {
+		t.Errorf("Expected a new round for another player. Got:[%d %s]", other.Code, other.Body)
+	}
+
+	reused := idempotentRequest(t, is, path, "round-1", userClaims{UID: "123", Chips: 1000, Bet: 2})
+	if reused.Code != http.StatusUnprocessableEntity || !strings.Contains(reused.Body.String(), string(_ERR_IDEMPOTENCY_KEY_REUSED)) {
+		t.Errorf("Expected:[%s] Got:[%d %s]", _ERR_IDEMPOTENCY_KEY_REUSED, reused.Code, reused.Body)
+	}
+}
+
+func TestIdempotentFailedSpin(t *testing.T) {
+	is := newIdempotencyStore(time.Hour)
+	apiKey = "secret"
+	machines.set(_ATKINS_DIET_MACHINE, atkins.NewAtkinsDietMachine())
+
+	const path = "/api/v2/machines/atkins-diet/spins"
+	user := userClaims{UID: "123", Chips: 1, Bet: 1}
+	if w := idempotentRequest(t, is, path, "round-1", user); w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected:[400] Got:[%d %s]", w.Code, w.Body)
+	}
+	if len(is.responses) != 0 {
+		t.Errorf("Expected the key of a failed round to be released")
+	}
+}
+
+func TestIdempotentFailedStream(t *testing.T) {
+	is := newIdempotencyStore(time.Hour)
+	apiKey = "secret"
+
+	token, _ := createToken(userClaims{UID: "123", Chips: 1000, Bet: 1}, []byte(apiKey))
+	r := httptest.NewRequest(http.MethodPost, "/api/v2/machines/atkins-diet/spins/stream", strings.NewReader(token))
+	r.Header.Set(_HEADER_IDEMPOTENCY_KEY, "round-1")
+	w := httptest.NewRecorder()
+	is.idempotent(func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		stream, _ := newEventStream(w)
+		stream.send(_EVENT_SPIN, streamSpin{})
+		stream.sendError(newAPIError(_ERR_INTERNAL, ""))
+	})(w, r, nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected:[200] Got:[%d %s]", w.Code, w.Body)
+	}
+	if len(is.responses) != 0 {
+		t.Errorf("Expected the key of a stream ended by an error to be released")
+	}
+}
+
+func TestIdempotentBodyTooLarge(t *testing.T) {
+	is := newIdempotencyStore(time.Hour)
+	r := httptest.NewRequest(http.MethodPost, "/api/v2/machines/atkins-diet/spins", strings.NewReader(strings.Repeat("a", _MAX_BODY_BYTES+1)))
+	r.Header.Set(_HEADER_IDEMPOTENCY_KEY, "round-1")
+	w := httptest.NewRecorder()
+	is.idempotent(func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+		t.Errorf("Expected the request to be rejected before the handler")
+	})(w, r, nil)
+	if w.Code != http.StatusBadRequest || len(is.responses) != 0 {
+		t.Errorf("Expected:[400] Got:[%d %s]", w.Code, w.Body)
+	}
+}
+
+func TestIdempotencyStoreExpiry(t *testing.T) {
+	now := time.Unix(1000, 0)
+	is := newIdempotencyStore(time.Hour)
+	is.now = func() time.Time { return now }
+
+	is.reserve("123\x00a", [32]byte{1})
+	is.complete("123\x00a", http.StatusOK, nil, []byte("{}"))
+	if resp, found := is.reserve("123\x00a", [32]byte{1}); !found || !resp.done {
+		t.Errorf("Expected the response to be stored")
+	}
+
+	now = now.Add(time.Hour)
+	if _, found := is.reserve("123\x00a", [32]byte{1}); found {
+		t.Errorf("Expected the response to expire")
+	}
+}
@@ -0,0 +1,210 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"trippy/jackpot"
+	"trippy/logger"
+	"trippy/slotmachine"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+const (
+	_JACKPOT_FILE           = "jackpots.json" // File of the pools in the storage path
+	_MYSTERY_DIR            = "mystery"       // Directory of the mystery jackpots of each machine in the storage path
+	_JACKPOT_FLUSH_INTERVAL = time.Second     // Interval to save the contributions of the rounds
+
+	_PARA_JACKPOT_MACHINE = "machine" // Query parameter of the machine the jackpots are listed for
+)
+
+var (
+	jackpots  *jackpot.Pools                                                // Progressive jackpots, nil if none is configured
+	mysteries = newMysteryRegistry(StorageConfig{Backend: _STORAGE_MEMORY}) // Mystery jackpots of the machines
+)
+
+// newJackpots returns the pools of the jackpots, kept in the storage backend
+func newJackpots(cfgs []jackpot.Config, storage StorageConfig) (*jackpot.Pools, error) {
+	var store jackpot.Store
+	if storage.Backend == _STORAGE_FILE {
+		store = jackpot.NewFileStore(filepath.Join(storage.Path, _JACKPOT_FILE))
+	}
+	return jackpot.New(cfgs, store)
+}
+
+// mysteryRegistry holds the mystery jackpots of every machine, kept in the storage backend.
+// The levels of a machine are created once, the machine keeps them when it is reloaded or enabled again.
+type mysteryRegistry struct {
+	storage StorageConfig
+
+	mu       sync.Mutex
+	jackpots map[string]*jackpot.MysteryJackpots
+}
+
+func newMysteryRegistry(storage StorageConfig) *mysteryRegistry {
+	return &mysteryRegistry{storage: storage, jackpots: make(map[string]*jackpot.MysteryJackpots)}
+}
+
+// get returns the mystery jackpots of the machine, restored from the storage the first time,
+// their increments being saved in the background
+func (mr *mysteryRegistry) get(machineName string, cfg jackpot.MysteryConfig) (*jackpot.MysteryJackpots, error) {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	if levels, ok := mr.jackpots[machineName]; ok {
+		return levels, nil
+	}
+	var store jackpot.Store
+	if mr.storage.Backend == _STORAGE_FILE {
+		dir := filepath.Join(mr.storage.Path, _MYSTERY_DIR)
+		if err := os.MkdirAll(dir, 0700); err != nil {
+			return nil, fmt.Errorf("Unable to create mystery jackpots [Dir:%s] [Error:%s]", dir, err)
+		}
+		store = jackpot.NewFileStore(filepath.Join(dir, machineName+".json"))
+	}
+	levels, err := jackpot.NewMysteryJackpots(cfg, store)
+	if err != nil {
+		return nil, err
+	}
+	go levels.Persist(_JACKPOT_FLUSH_INTERVAL, func(err error) {
+		slog.Error("Unable to save mystery jackpots", "machine", machineName, "err", err)
+	})
+	mr.jackpots[machineName] = levels
+	return levels, nil
+}
+
+// check fails if the increments of a machine could not be saved
+func (mr *mysteryRegistry) check() error {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	for machineName, levels := range mr.jackpots {
+		if err := levels.Err(); err != nil {
+			return fmt.Errorf("Mystery jackpots of [Machine:%s] not saved [Error:%s]", machineName, err)
+		}
+	}
+	return nil
+}
+
+// close flushes the increments of every machine, no round contributing anymore
+func (mr *mysteryRegistry) close() {
+	mr.mu.Lock()
+	defer mr.mu.Unlock()
+	for machineName, levels := range mr.jackpots {
+		if err := levels.Close(); err != nil {
+			slog.Error("Unable to save mystery jackpots", "machine", machineName, "err", err)
+		}
+	}
+}
+
+// betLimited is implemented by the machines which describe the bets they accept
+type betLimited interface {
+	Limits() slotmachine.BetLimits
+}
+
+// isMaxBet returns true if the bet has the maximum coins per line of the machine
+func isMaxBet(machine slotmachine.SlotMachine, bet slotmachine.Bet) bool {
+	limited, ok := slotmachine.Capability[betLimited](machine)
+	if !ok {
+		return false
+	}
+	max := limited.Limits().MaxBet
+	return max > 0 && bet.Coins >= max
+}
+
+// mysteryJackpots is implemented by the machines with mystery jackpots
+type mysteryJackpots interface {
+	JackpotValues() []jackpot.Value
+}
+
+// playJackpots contributes the wager of the round to the progressive jackpots of its machine,
+// the jackpots won are added to the payout of the round. Mystery jackpots are spins of the round.
+func playJackpots(log *logger.Logger, rnd *round) {
+	if jackpots != nil {
+		wins, err := jackpots.Play(jackpot.Play{
+			Round:   rnd.id,
+			Machine: rnd.machineName,
+			Wager:   rnd.wager,
+			MaxBet:  isMaxBet(rnd.machine, rnd.user.bet()),
+			Results: rnd.results,
+		})
+		if err != nil {
+			// The jackpot is paid, the pools are saved again by the next flush
+			log.Error("Unable to save jackpots", "err", err)
+		}
+		for _, win := range wins {
+			log.Info("Jackpot won", "jackpot", win.Jackpot, "amount", win.Amount, "reason", win.Reason)
+			rnd.payout = rnd.payout + win.Amount
+		}
+		rnd.jackpots = wins
+	}
+	rnd.jackpotValues = machineJackpots(rnd.machineName, rnd.machine)
+}
+
+// machineJackpots returns the progressive jackpots of the machine, then its mystery jackpots
+func machineJackpots(machineName string, machine slotmachine.SlotMachine) []jackpot.Value {
+	var values []jackpot.Value
+	if jackpots != nil {
+		values = jackpots.Values(machineName)
+	}
+	return append(values, mysteryValues(machineName, machine)...)
+}
+
+// mysteryValues returns the mystery jackpots of the machine, if it has some
+func mysteryValues(machineName string, machine slotmachine.SlotMachine) []jackpot.Value {
+	mystery, ok := slotmachine.Capability[mysteryJackpots](machine)
+	if !ok {
+		return nil
+	}
+	values := mystery.JackpotValues()
+	for i := range values {
+		values[i].Machines = []string{machineName}
+	}
+	return values
+}
+
+// Jackpots lists the current amount of the jackpots, only those of the machine in the query if set.
+// The progressive jackpots come first, then the mystery jackpots of each machine served.
+func Jackpots(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
+	machineName := r.URL.Query().Get(_PARA_JACKPOT_MACHINE)
+	if _, configured := machines.config(machineName); machineName != "" && !configured {
+		respondWithError(w, newAPIError(_ERR_UNKNOWN_MACHINE, fmt.Sprintf("Unknown machine:[%s]", machineName)).
+			withDetail("machine", machineName))
+		return
+	}
+	resp := respJackpots{Jackpots: []jackpotValue{}}
+	if machineName != "" {
+		machine, _ := getMachine(machineName)
+		resp.Jackpots = append(resp.Jackpots, newJackpotValues(machineJackpots(machineName, machine))...)
+		writeResponse(w, http.StatusOK, resp)
+		return
+	}
+	if jackpots != nil {
+		resp.Jackpots = append(resp.Jackpots, newJackpotValues(jackpots.Values(""))...)
+	}
+	for _, cfg := range machines.allConfigs() {
+		if machine, found := getMachine(cfg.Name); found {
+			resp.Jackpots = append(resp.Jackpots, newJackpotValues(mysteryValues(cfg.Name, machine))...)
+		}
+	}
+	writeResponse(w, http.StatusOK, resp)
+}
+
+func newJackpotWins(wins []jackpot.Win) []jackpotWin {
+	var resp []jackpotWin
+	for _, win := range wins {
+		resp = append(resp, jackpotWin{Jackpot: win.Jackpot, Amount: win.Amount, Reason: win.Reason})
+	}
+	return resp
+}
+
+func newJackpotValues(values []jackpot.Value) []jackpotValue {
+	var resp []jackpotValue
+	for _, value := range values {
+		resp = append(resp, jackpotValue{Jackpot: value.Jackpot, Amount: value.Amount, MustHitBy: value.MustHitBy, Machines: value.Machines})
+	}
+	return resp
+}
@@ -0,0 +1,171 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"trippy/jackpot"
+	"trippy/slotmachine"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// jackpotMachine pays 5 ATKINS on the first line of every round
+type jackpotMachine struct {
+	fixedMachine
+}
+
+func (m *jackpotMachine) Spin(ctx context.Context, bet slotmachine.Bet) (int, []slotmachine.SpinResult, error) {
+	result := slotmachine.SpinResult{Type: slotmachine.MAIN_SPIN, Pay: 50, Multiplier: 1,
+		WinLines: []slotmachine.WinLine{{Index: 1, Name: "ATKINS", Count: 5, Payout: 50}}}
+	return result.Pay, []slotmachine.SpinResult{result}, nil
+}
+
+func (m *jackpotMachine) Limits() slotmachine.BetLimits {
+	return slotmachine.BetLimits{MinBet: 1, MaxBet: 5}
+}
+
+type jackpotSample struct {
+	bet   int
+	total int // Payout of the round
+	won   int // Jackpots won
+}
+
+var jackpotSamples = []jackpotSample{
+	{bet: 1, total: 50},
+	{bet: 5, total: 50 + 1010, won: 1},
+}
+
+func TestSpinJackpots(t *testing.T) {
+	defer func(jp *jackpot.Pools) { jackpots = jp }(jackpots)
+	for _, sample := range jackpotSamples {
+		testSpinJackpots(t, sample)
+	}
+}
+
+func testSpinJackpots(t *testing.T, sample jackpotSample) {
+	var err error
+	jackpots, err = jackpot.New([]jackpot.Config{{Name: "progressive", Machines: []string{_FIXED_MACHINE}, Contribution: 10, Seed: 1000,
+		Trigger: &jackpot.Trigger{Symbol: "ATKINS", Count: 5, Line: 1, MaxBet: true}}}, nil)
+	if err != nil {
+		t.Fatalf("Expected:[nil] Got:[%s]", err)
+	}
+	apiKey = "secret"
+	machines.set(_FIXED_MACHINE, &jackpotMachine{fixedMachine{wager: 100}})
+	token, _ := createToken(userClaims{UID: "123", Chips: 1000, Bet: sample.bet}, []byte(apiKey))
+	paid := counterValue(paidTotal, _FIXED_MACHINE)
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/api/v2/machines/fixed/spins", strings.NewReader(token))
+	SpinV2(w, r, httprouter.Params{{Key: _PARA_SPIN_MACHINE, Value: _FIXED_MACHINE}})
+	var resp respSpinV2
+	if err = json.NewDecoder(w.Body).Decode(&resp); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("Expected:[200] Got:[%d] [Error:%v]", w.Code, err)
+	}
+	if resp.Total != sample.total || len(resp.Jackpots) != sample.won || resp.BalanceAfter != 1000-100+sample.total {
+		t.Errorf("[Bet:%d] Expected:[%d %d] Got:[%d %+v]", sample.bet, sample.total, sample.won, resp.Total, resp.Jackpots)
+	}
+	if paid = counterValue(paidTotal, _FIXED_MACHINE) - paid; paid != float64(sample.total) {
+		t.Errorf("[Bet:%d] Expected the jackpots in the chips paid:[%d] Got:[%f]", sample.bet, sample.total, paid)
+	}
+	expected := 1010
+	if sample.won > 0 {
+		expected = 1000
+	}
+	if len(resp.JackpotValues) != 1 || resp.JackpotValues[0].Amount != expected {
+		t.Errorf("[Bet:%d] Expected:[%d] Got:[%+v]", sample.bet, expected, resp.JackpotValues)
+	}
+
+	w = httptest.NewRecorder()
+	Jackpots(w, httptest.NewRequest(http.MethodGet, "/api/v2/jackpots?machine=unknown", nil), nil)
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), string(_ERR_UNKNOWN_MACHINE)) {
+		t.Errorf("Expected:[%s] Got:[%d %s]", _ERR_UNKNOWN_MACHINE, w.Code, w.Body)
+	}
+}
+
+// Mystery jackpots are spins of the round, the machine wrapped keeping its catalogue
+func TestSpinMysteryJackpots(t *testing.T) {
+	const name = "mystery"
+	cfg := MachineConfig{Name: name, Engine: _ENGINE_ATKINS, Enabled: true, Mystery: &jackpot.MysteryConfig{Probability: 1}}
+	machine, err := newMachine(cfg, LogConfig{})
+	if err != nil {
+		t.Fatalf("Expected:[nil] Got:[%s]", err)
+	}
+	machines.configure(cfg)
+	machines.set(name, machine)
+	defer machines.remove(name)
+	apiKey = "secret"
+	token, _ := createToken(userClaims{UID: "123", Chips: 1000, Bet: 1}, []byte(apiKey))
+
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/api/v2/machines/mystery/spins", strings.NewReader(token))
+	SpinV2(w, r, httprouter.Params{{Key: _PARA_SPIN_MACHINE, Value: name}})
+	var resp respSpinV2
+	if err = json.NewDecoder(w.Body).Decode(&resp); err != nil || w.Code != http.StatusOK {
+		t.Fatalf("Expected:[200] Got:[%d] [Error:%v]", w.Code, err)
+	}
+	last := resp.Spins[len(resp.Spins)-1]
+	if last.Type != slotmachine.JACKPOT_SPIN || last.Jackpot == "" || last.Total == 0 {
+		t.Errorf("Expected a jackpot spin Got:[%+v]", last)
+	}
+	if resp.Spins[0].Grid[0][0].Name == "" || resp.Spins[0].Grid[0][0].Kind == "" {
+		t.Errorf("Expected the symbols of the catalogue Got:[%+v]", resp.Spins[0].Grid[0][0])
+	}
+	if len(resp.JackpotValues) != 4 || resp.JackpotValues[0].Machines[0] != name {
+		t.Errorf("Expected the levels of the machine Got:[%+v]", resp.JackpotValues)
+	}
+
+	w = httptest.NewRecorder()
+	Jackpots(w, httptest.NewRequest(http.MethodGet, "/api/v2/jackpots", nil), nil)
+	var values respJackpots
+	if err = json.NewDecoder(w.Body).Decode(&values); err != nil || len(values.Jackpots) < 4 {
+		t.Errorf("Expected the levels listed Got:[%+v] [Error:%v]", values, err)
+	}
+}
+
+// The mystery jackpots of a machine keep their increments when it is reloaded, enabled again
+// or the server restarts
+func TestMysteryJackpotsKept(t *testing.T) {
+	defer func(mr *machineRegistry, my *mysteryRegistry) { machines, mysteries = mr, my }(machines, mysteries)
+	storage := StorageConfig{Backend: _STORAGE_FILE, Path: t.TempDir()}
+	mysteries = newMysteryRegistry(storage)
+	machines = newMachineRegistry()
+	// Hardly ever drawn, never hit by
+	cfg := MachineConfig{Name: _ATKINS_DIET_MACHINE, Engine: _ENGINE_ATKINS, Enabled: true, Mystery: &jackpot.MysteryConfig{
+		Probability: 1e-12, Levels: []jackpot.MysteryLevel{{Name: jackpot.MINI, Seed: 10, Increment: 10, Weight: 1}}}}
+	machine, err := newMachine(cfg, LogConfig{})
+	if err != nil {
+		t.Fatalf("Expected:[nil] Got:[%s]", err)
+	}
+	machines.configure(cfg)
+	machines.set(cfg.Name, machine)
+	router := httprouter.New()
+	(&adminAPI{key: _TEST_ADMIN_KEY}).register(router)
+
+	// 10% of 20 chips wagered on each round
+	for i := 0; i < 3; i++ {
+		if w := playRequest(t, SpinV2, userClaims{UID: "123", Chips: 1000, Bet: 1}); w.Code != http.StatusOK {
+			t.Fatalf("Expected:[200] Got:[%d %s]", w.Code, w.Body)
+		}
+	}
+	for _, action := range []string{"reload", "disable", "enable"} {
+		testAdmin(t, router, adminSample{http.MethodPost, "/admin/machines/atkins-diet/" + action, "", _TEST_ADMIN_KEY, http.StatusOK})
+	}
+	machine, _ = machines.get(cfg.Name)
+	if values := mysteryValues(cfg.Name, machine); len(values) != 1 || values[0].Amount != 16 {
+		t.Errorf("Expected the increments kept by the machine reloaded Got:[%+v]", values)
+	}
+
+	mysteries.close()
+	mysteries = newMysteryRegistry(storage)
+	if machine, err = newMachine(cfg, LogConfig{}); err != nil {
+		t.Fatalf("Expected:[nil] Got:[%s]", err)
+	}
+	if values := mysteryValues(cfg.Name, machine); len(values) != 1 || values[0].Amount != 16 {
+		t.Errorf("Expected the increments restored from the storage Got:[%+v]", values)
+	}
+}
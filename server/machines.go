@@ -0,0 +1,155 @@
+package server
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"trippy/jackpot"
+	"trippy/slotmachine"
+	"trippy/slotmachine/engine/atkins"
+	"trippy/slotmachine/validator"
+)
+
+// engines create the machines run by each engine
+var engines = map[string]func(cfg MachineConfig, logCfg LogConfig) (slotmachine.SlotMachine, error){
+	_ENGINE_ATKINS: newAtkinsMachine,
+}
+
+// definitions are the definitions played by the machines of each engine without their own
+var definitions = map[string]func() slotmachine.Definition{
+	_ENGINE_ATKINS: atkins.DefaultDefinition,
+}
+
+// loadDefinition returns the definition of the machine, from its file or the one of its engine
+func loadDefinition(cfg MachineConfig) (slotmachine.Definition, error) {
+	if cfg.Definition != "" {
+		return slotmachine.LoadDefinition(cfg.Definition)
+	}
+	defaultDefinition, ok := definitions[cfg.Engine]
+	if !ok {
+		return slotmachine.Definition{}, fmt.Errorf("Machine:[%s] Engine:[%s] is unknown", cfg.Name, cfg.Engine)
+	}
+	return defaultDefinition(), nil
+}
+
+// paysSymbol returns true if the symbol of the name is in the catalogue of the definition and paid on the lines
+func paysSymbol(def slotmachine.Definition, name string) bool {
+	id, err := def.Symbols.Lookup(name)
+	if err != nil || def.Symbols.Name(id) != name {
+		return false
+	}
+	_, paid := def.PayTable[id]
+	return paid
+}
+
+func newAtkinsMachine(cfg MachineConfig, logCfg LogConfig) (slotmachine.SlotMachine, error) {
+	def, err := loadDefinition(cfg)
+	if err != nil {
+		return nil, err
+	}
+	// A definition the spinner cannot play, eg. with a ragged stop, is refused with all its problems
+	if problems := validator.Validate(def).Errors(); len(problems) > 0 {
+		errs := make([]string, len(problems))
+		for i, problem := range problems {
+			errs[i] = problem.String()
+		}
+		return nil, fmt.Errorf("Definition:[%s] is invalid [Problems:%s]", def.Name, strings.Join(errs, "; "))
+	}
+	adm := atkins.NewAtkinsDietMachineFromDefinition(def)
+	adm.LogWinLines = logCfg.WinLines
+	return adm, nil
+}
+
+// newMachine creates the machine with its engine
+func newMachine(cfg MachineConfig, logCfg LogConfig) (slotmachine.SlotMachine, error) {
+	newEngineMachine, ok := engines[cfg.Engine]
+	if !ok {
+		return nil, fmt.Errorf("Machine:[%s] Engine:[%s] is unknown", cfg.Name, cfg.Engine)
+	}
+	machine, err := newEngineMachine(cfg, logCfg)
+	if err != nil {
+		return nil, fmt.Errorf("Machine:[%s] cannot be created [Error:%s]", cfg.Name, err)
+	}
+	// Mystery jackpots are played after the rounds of any engine, on the levels of the machine
+	if cfg.Mystery != nil {
+		levels, err := mysteries.get(cfg.Name, *cfg.Mystery)
+		if err != nil {
+			return nil, fmt.Errorf("Machine:[%s] %s", cfg.Name, err)
+		}
+		machine = jackpot.NewMysteryMachine(machine, levels)
+	}
+	return machine, nil
+}
+
+// machineRegistry holds the machines served, by their name in the API,
+// and the config of every machine, served or not
+type machineRegistry struct {
+	mu       sync.RWMutex
+	machines map[string]slotmachine.SlotMachine
+	configs  map[string]MachineConfig
+}
+
+func newMachineRegistry() *machineRegistry {
+	return &machineRegistry{
+		machines: make(map[string]slotmachine.SlotMachine),
+		configs:  make(map[string]MachineConfig),
+	}
+}
+
+func (mr *machineRegistry) get(name string) (slotmachine.SlotMachine, bool) {
+	mr.mu.RLock()
+	defer mr.mu.RUnlock()
+	machine, ok := mr.machines[name]
+	return machine, ok
+}
+
+func (mr *machineRegistry) set(name string, machine slotmachine.SlotMachine) {
+	mr.mu.Lock()
+	mr.machines[name] = machine
+	mr.mu.Unlock()
+}
+
+// remove stops serving the machine, the rounds in play finish on it
+func (mr *machineRegistry) remove(name string) {
+	mr.mu.Lock()
+	delete(mr.machines, name)
+	mr.mu.Unlock()
+}
+
+func (mr *machineRegistry) count() int {
+	mr.mu.RLock()
+	defer mr.mu.RUnlock()
+	return len(mr.machines)
+}
+
+func (mr *machineRegistry) configure(cfg MachineConfig) {
+	mr.mu.Lock()
+	mr.configs[cfg.Name] = cfg
+	mr.mu.Unlock()
+}
+
+func (mr *machineRegistry) config(name string) (MachineConfig, bool) {
+	mr.mu.RLock()
+	defer mr.mu.RUnlock()
+	cfg, ok := mr.configs[name]
+	return cfg, ok
+}
+
+// allConfigs returns the config of every machine, ordered by name
+func (mr *machineRegistry) allConfigs() []MachineConfig {
+	mr.mu.RLock()
+	defer mr.mu.RUnlock()
+	cfgs := make([]MachineConfig, 0, len(mr.configs))
+	for _, cfg := range mr.configs {
+		cfgs = append(cfgs, cfg)
+	}
+	sort.Slice(cfgs, func(i, j int) bool { return cfgs[i].Name < cfgs[j].Name })
+	return cfgs
+}
+
+// getMachine returns the slot machine engine for the machine name in the API
+func getMachine(name string) (slotmachine.SlotMachine, bool) {
+	return machines.get(name)
+}
@@ -0,0 +1,114 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"trippy/slotmachine"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	dto "github.com/prometheus/client_model/go"
+)
+
+var (
+	// Metrics exposed on /metrics of the debug webserver
+	metricsRegistry = prometheus.NewRegistry()
+	newMetric       = promauto.With(metricsRegistry)
+
+	roundsTotal = newMetric.NewCounterVec(prometheus.CounterOpts{
+		Name: "trippy_rounds_total",
+		Help: "Rounds played, a round is a main spin with its free spins",
+	}, []string{"machine"})
+	spinsTotal = newMetric.NewCounterVec(prometheus.CounterOpts{
+		Name: "trippy_spins_total",
+		Help: "Spins played by type of spin",
+	}, []string{"machine", "type"})
+	wageredTotal = newMetric.NewCounterVec(prometheus.CounterOpts{
+		Name: "trippy_wagered_chips_total",
+		Help: "Chips wagered",
+	}, []string{"machine"})
+	paidTotal = newMetric.NewCounterVec(prometheus.CounterOpts{
+		Name: "trippy_paid_chips_total",
+		Help: "Chips paid",
+	}, []string{"machine"})
+	observedRTP = newMetric.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "trippy_observed_rtp_ratio",
+		Help: "Chips paid over chips wagered since the server started",
+	}, []string{"machine"})
+	freeSpinTriggers = newMetric.NewCounterVec(prometheus.CounterOpts{
+		Name: "trippy_free_spin_triggers_total",
+		Help: "Spins which awarded free spins, including retriggers",
+	}, []string{"machine"})
+	errorsTotal = newMetric.NewCounterVec(prometheus.CounterOpts{
+		Name: "trippy_errors_total",
+		Help: "Error responses by machine and error code, without machine for the requests to none",
+	}, []string{"machine", "code"})
+	spinDuration = newMetric.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "trippy_spin_duration_seconds",
+		Help:    "Time taken by the engine to play a round",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"machine"})
+	freeSpinChainLength = newMetric.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "trippy_free_spin_chain_length",
+		Help:    "Free spins played in a round which triggered free spins",
+		Buckets: []float64{10, 20, 30, 50, 100, 200, 500},
+	}, []string{"machine"})
+)
+
+// observeRound updates the metrics of the machine with a round played
+func observeRound(machine string, wager, payout int, results []slotmachine.SpinResult, duration time.Duration) {
+	var freeSpins int
+	for _, result := range results {
+		spinsTotal.WithLabelValues(machine, result.Type).Inc()
+		if result.Type == slotmachine.FREE_SPIN {
+			freeSpins++
+		}
+		if result.FreeSpins > 0 {
+			freeSpinTriggers.WithLabelValues(machine).Inc()
+		}
+	}
+	if freeSpins > 0 {
+		freeSpinChainLength.WithLabelValues(machine).Observe(float64(freeSpins))
+	}
+
+	roundsTotal.WithLabelValues(machine).Inc()
+	wageredTotal.WithLabelValues(machine).Add(float64(wager))
+	paidTotal.WithLabelValues(machine).Add(float64(payout))
+	if wagered := counterValue(wageredTotal, machine); wagered > 0 {
+		observedRTP.WithLabelValues(machine).Set(counterValue(paidTotal, machine) / wagered)
+	}
+	spinDuration.WithLabelValues(machine).Observe(duration.Seconds())
+}
+
+// observeError counts an error response to a request for the machine.
+// The machines not configured are counted without machine, their names come from the clients.
+func observeError(machine string, code errCode) {
+	if _, configured := machines.config(machine); !configured {
+		machine = ""
+	}
+	errorsTotal.WithLabelValues(machine, string(code)).Inc()
+}
+
+// counterValue returns the value of the counter with the label values
+func counterValue(counter *prometheus.CounterVec, labelValues ...string) float64 {
+	var m dto.Metric
+	counter.WithLabelValues(labelValues...).Write(&m)
+	return m.GetCounter().GetValue()
+}
+
+// observeBonusPick counts the prize of a bonus pick as paid by the machine, the pick is not a round
+func observeBonusPick(machine string, prize int) {
+	spinsTotal.WithLabelValues(machine, slotmachine.BONUS_SPIN).Inc()
+	paidTotal.WithLabelValues(machine).Add(float64(prize))
+	if wagered := counterValue(wageredTotal, machine); wagered > 0 {
+		observedRTP.WithLabelValues(machine).Set(counterValue(paidTotal, machine) / wagered)
+	}
+}
+
+// MetricsHandler serves the metrics in the Prometheus format.
+// It is not routed by the API, the metrics are served with pprof on the debug webserver.
+func MetricsHandler() http.Handler {
+	return promhttp.HandlerFor(metricsRegistry, promhttp.HandlerOpts{})
+}
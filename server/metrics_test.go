@@ -0,0 +1,77 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"trippy/slotmachine"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+)
+
+func TestObserveRound(t *testing.T) {
+	machine := "metrics-test"
+	results := []slotmachine.SpinResult{
+		{Type: slotmachine.MAIN_SPIN, FreeSpins: 10},
+		{Type: slotmachine.FREE_SPIN},
+		{Type: slotmachine.FREE_SPIN, FreeSpins: 10},
+	}
+	observeRound(machine, 100, 50, results, time.Millisecond)
+	observeRound(machine, 100, 250, results[:1], time.Millisecond)
+
+	if v := testutil.ToFloat64(roundsTotal.WithLabelValues(machine)); v != 2 {
+		t.Errorf("Rounds Expected:[2] Got:[%v]", v)
+	}
+	if v := testutil.ToFloat64(spinsTotal.WithLabelValues(machine, slotmachine.FREE_SPIN)); v != 2 {
+		t.Errorf("Free spins Expected:[2] Got:[%v]", v)
+	}
+	if v := testutil.ToFloat64(freeSpinTriggers.WithLabelValues(machine)); v != 3 {
+		t.Errorf("Free spin triggers Expected:[3] Got:[%v]", v)
+	}
+	if v := testutil.ToFloat64(observedRTP.WithLabelValues(machine)); v != 1.5 {
+		t.Errorf("RTP Expected:[1.5] Got:[%v]", v)
+	}
+	var chains dto.Metric
+	freeSpinChainLength.WithLabelValues(machine).(prometheus.Histogram).Write(&chains)
+	if c := chains.GetHistogram().GetSampleCount(); c != 1 {
+		t.Errorf("Free spin chains Expected:[1] Got:[%d]", c)
+	}
+
+	w := httptest.NewRecorder()
+	MetricsHandler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	if !strings.Contains(w.Body.String(), `trippy_paid_chips_total{machine="metrics-test"} 300`) {
+		t.Errorf("Paid chips missing in Output:[%s]", w.Body.String())
+	}
+}
+
+func TestObserveError(t *testing.T) {
+	machines.configure(MachineConfig{Name: "metrics-errors", Engine: _ENGINE_ATKINS})
+	for _, machine := range []string{"metrics-errors", "unknown", ""} {
+		observeError(machine, _ERR_INSUFFICIENT_CHIPS)
+	}
+
+	if v := testutil.ToFloat64(errorsTotal.WithLabelValues("metrics-errors", string(_ERR_INSUFFICIENT_CHIPS))); v != 1 {
+		t.Errorf("Machine errors Expected:[1] Got:[%v]", v)
+	}
+	if v := testutil.ToFloat64(errorsTotal.WithLabelValues("unknown", string(_ERR_INSUFFICIENT_CHIPS))); v != 0 {
+		t.Errorf("Unknown machine errors Expected:[0] Got:[%v]", v)
+	}
+	if v := testutil.ToFloat64(errorsTotal.WithLabelValues("", string(_ERR_INSUFFICIENT_CHIPS))); v < 2 {
+		t.Errorf("Errors without machine Expected:[>=2] Got:[%v]", v)
+	}
+}
+
+// The metrics are served on the debug webserver only
+func TestMetricsNotRouted(t *testing.T) {
+	s := &Server{idempotency: newIdempotencyStore(time.Hour)}
+	w := httptest.NewRecorder()
+	s.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected:[%d] Got:[%d %s]", http.StatusNotFound, w.Code, w.Body.String())
+	}
+}
@@ -0,0 +1,4 @@
+// Package trippyv1 has the messages and the service of trippy.proto, generated by protoc.
+package trippyv1
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative trippy.proto
@@ -0,0 +1,1695 @@
+// gRPC API of trippy, served on the same address as the REST API.
+//
+// Every call carries the JWT of the player in the authorization metadata,
+// "Bearer <jwt>", the same token as the REST API with the chips of the player.
+// Failed calls have the error code of the REST API in the trippy-error-code
+// trailer, and in the status message as "CODE: message".
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.12
+// 	protoc        (unknown)
+// source: trippy.proto
+
+package trippyv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// Bet is the stake of a round, the bet of the token if unset.
+type Bet struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Coins         int32                  `protobuf:"varint,1,opt,name=coins,proto3" json:"coins,omitempty"`               // Coins per line
+	Denomination  int32                  `protobuf:"varint,2,opt,name=denomination,proto3" json:"denomination,omitempty"` // Coin value, machine default if 0
+	Lines         int32                  `protobuf:"varint,3,opt,name=lines,proto3" json:"lines,omitempty"`               // Active pay lines, all lines if 0
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Bet) Reset() {
+	*x = Bet{}
+	mi := &file_trippy_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Bet) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Bet) ProtoMessage() {}
+
+func (x *Bet) ProtoReflect() protoreflect.Message {
+	mi := &file_trippy_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Bet.ProtoReflect.Descriptor instead.
+func (*Bet) Descriptor() ([]byte, []int) {
+	return file_trippy_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Bet) GetCoins() int32 {
+	if x != nil {
+		return x.Coins
+	}
+	return 0
+}
+
+func (x *Bet) GetDenomination() int32 {
+	if x != nil {
+		return x.Denomination
+	}
+	return 0
+}
+
+func (x *Bet) GetLines() int32 {
+	if x != nil {
+		return x.Lines
+	}
+	return 0
+}
+
+type SpinRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Machine       string                 `protobuf:"bytes,1,opt,name=machine,proto3" json:"machine,omitempty"`
+	Bet           *Bet                   `protobuf:"bytes,2,opt,name=bet,proto3" json:"bet,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SpinRequest) Reset() {
+	*x = SpinRequest{}
+	mi := &file_trippy_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SpinRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SpinRequest) ProtoMessage() {}
+
+func (x *SpinRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_trippy_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SpinRequest.ProtoReflect.Descriptor instead.
+func (*SpinRequest) Descriptor() ([]byte, []int) {
+	return file_trippy_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *SpinRequest) GetMachine() string {
+	if x != nil {
+		return x.Machine
+	}
+	return ""
+}
+
+func (x *SpinRequest) GetBet() *Bet {
+	if x != nil {
+		return x.Bet
+	}
+	return nil
+}
+
+type WagerRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Machine       string                 `protobuf:"bytes,1,opt,name=machine,proto3" json:"machine,omitempty"`
+	Bet           *Bet                   `protobuf:"bytes,2,opt,name=bet,proto3" json:"bet,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WagerRequest) Reset() {
+	*x = WagerRequest{}
+	mi := &file_trippy_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WagerRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WagerRequest) ProtoMessage() {}
+
+func (x *WagerRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_trippy_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WagerRequest.ProtoReflect.Descriptor instead.
+func (*WagerRequest) Descriptor() ([]byte, []int) {
+	return file_trippy_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *WagerRequest) GetMachine() string {
+	if x != nil {
+		return x.Machine
+	}
+	return ""
+}
+
+func (x *WagerRequest) GetBet() *Bet {
+	if x != nil {
+		return x.Bet
+	}
+	return nil
+}
+
+type WagerResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Wager         int64                  `protobuf:"varint,1,opt,name=wager,proto3" json:"wager,omitempty"`           // Chips the bet costs
+	Balance       int64                  `protobuf:"varint,2,opt,name=balance,proto3" json:"balance,omitempty"`       // Chips of the player
+	Sufficient    bool                   `protobuf:"varint,3,opt,name=sufficient,proto3" json:"sufficient,omitempty"` // Whether the chips cover the wager
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WagerResponse) Reset() {
+	*x = WagerResponse{}
+	mi := &file_trippy_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WagerResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WagerResponse) ProtoMessage() {}
+
+func (x *WagerResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_trippy_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WagerResponse.ProtoReflect.Descriptor instead.
+func (*WagerResponse) Descriptor() ([]byte, []int) {
+	return file_trippy_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *WagerResponse) GetWager() int64 {
+	if x != nil {
+		return x.Wager
+	}
+	return 0
+}
+
+func (x *WagerResponse) GetBalance() int64 {
+	if x != nil {
+		return x.Balance
+	}
+	return 0
+}
+
+func (x *WagerResponse) GetSufficient() bool {
+	if x != nil {
+		return x.Sufficient
+	}
+	return false
+}
+
+type ListMachinesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListMachinesRequest) Reset() {
+	*x = ListMachinesRequest{}
+	mi := &file_trippy_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListMachinesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListMachinesRequest) ProtoMessage() {}
+
+func (x *ListMachinesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_trippy_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListMachinesRequest.ProtoReflect.Descriptor instead.
+func (*ListMachinesRequest) Descriptor() ([]byte, []int) {
+	return file_trippy_proto_rawDescGZIP(), []int{4}
+}
+
+type ListMachinesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Machines      []*Machine             `protobuf:"bytes,1,rep,name=machines,proto3" json:"machines,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListMachinesResponse) Reset() {
+	*x = ListMachinesResponse{}
+	mi := &file_trippy_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListMachinesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListMachinesResponse) ProtoMessage() {}
+
+func (x *ListMachinesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_trippy_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListMachinesResponse.ProtoReflect.Descriptor instead.
+func (*ListMachinesResponse) Descriptor() ([]byte, []int) {
+	return file_trippy_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *ListMachinesResponse) GetMachines() []*Machine {
+	if x != nil {
+		return x.Machines
+	}
+	return nil
+}
+
+type Machine struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Engine        string                 `protobuf:"bytes,2,opt,name=engine,proto3" json:"engine,omitempty"`
+	Enabled       bool                   `protobuf:"varint,3,opt,name=enabled,proto3" json:"enabled,omitempty"`
+	Definition    string                 `protobuf:"bytes,4,opt,name=definition,proto3" json:"definition,omitempty"` // Empty for the engine default
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Machine) Reset() {
+	*x = Machine{}
+	mi := &file_trippy_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Machine) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Machine) ProtoMessage() {}
+
+func (x *Machine) ProtoReflect() protoreflect.Message {
+	mi := &file_trippy_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Machine.ProtoReflect.Descriptor instead.
+func (*Machine) Descriptor() ([]byte, []int) {
+	return file_trippy_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *Machine) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Machine) GetEngine() string {
+	if x != nil {
+		return x.Engine
+	}
+	return ""
+}
+
+func (x *Machine) GetEnabled() bool {
+	if x != nil {
+		return x.Enabled
+	}
+	return false
+}
+
+func (x *Machine) GetDefinition() string {
+	if x != nil {
+		return x.Definition
+	}
+	return ""
+}
+
+type GetRoundRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetRoundRequest) Reset() {
+	*x = GetRoundRequest{}
+	mi := &file_trippy_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetRoundRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetRoundRequest) ProtoMessage() {}
+
+func (x *GetRoundRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_trippy_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetRoundRequest.ProtoReflect.Descriptor instead.
+func (*GetRoundRequest) Descriptor() ([]byte, []int) {
+	return file_trippy_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *GetRoundRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type ReplayRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ReplayRequest) Reset() {
+	*x = ReplayRequest{}
+	mi := &file_trippy_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ReplayRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReplayRequest) ProtoMessage() {}
+
+func (x *ReplayRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_trippy_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReplayRequest.ProtoReflect.Descriptor instead.
+func (*ReplayRequest) Descriptor() ([]byte, []int) {
+	return file_trippy_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *ReplayRequest) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+type Round struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Machine       string                 `protobuf:"bytes,2,opt,name=machine,proto3" json:"machine,omitempty"`
+	Uid           string                 `protobuf:"bytes,3,opt,name=uid,proto3" json:"uid,omitempty"`
+	Wager         int64                  `protobuf:"varint,4,opt,name=wager,proto3" json:"wager,omitempty"`
+	Total         int64                  `protobuf:"varint,5,opt,name=total,proto3" json:"total,omitempty"`
+	FreeSpins     int32                  `protobuf:"varint,6,opt,name=free_spins,json=freeSpins,proto3" json:"free_spins,omitempty"` // Free spins awarded in the round
+	BalanceBefore int64                  `protobuf:"varint,7,opt,name=balance_before,json=balanceBefore,proto3" json:"balance_before,omitempty"`
+	BalanceAfter  int64                  `protobuf:"varint,8,opt,name=balance_after,json=balanceAfter,proto3" json:"balance_after,omitempty"`
+	Spins         []*Spin                `protobuf:"bytes,9,rep,name=spins,proto3" json:"spins,omitempty"`
+	Jwt           string                 `protobuf:"bytes,10,opt,name=jwt,proto3" json:"jwt,omitempty"`                                          // New token with the balance after, only from Spin
+	Jackpots      []*JackpotWin          `protobuf:"bytes,11,rep,name=jackpots,proto3" json:"jackpots,omitempty"`                                // Jackpots won, included in the total
+	JackpotValues []*JackpotValue        `protobuf:"bytes,12,rep,name=jackpot_values,json=jackpotValues,proto3" json:"jackpot_values,omitempty"` // Jackpots of the machine after the round
+	Bonus         *BonusGame             `protobuf:"bytes,13,opt,name=bonus,proto3" json:"bonus,omitempty"`                                      // Bonus game launched by the round, picked over REST
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Round) Reset() {
+	*x = Round{}
+	mi := &file_trippy_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Round) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Round) ProtoMessage() {}
+
+func (x *Round) ProtoReflect() protoreflect.Message {
+	mi := &file_trippy_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Round.ProtoReflect.Descriptor instead.
+func (*Round) Descriptor() ([]byte, []int) {
+	return file_trippy_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *Round) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Round) GetMachine() string {
+	if x != nil {
+		return x.Machine
+	}
+	return ""
+}
+
+func (x *Round) GetUid() string {
+	if x != nil {
+		return x.Uid
+	}
+	return ""
+}
+
+func (x *Round) GetWager() int64 {
+	if x != nil {
+		return x.Wager
+	}
+	return 0
+}
+
+func (x *Round) GetTotal() int64 {
+	if x != nil {
+		return x.Total
+	}
+	return 0
+}
+
+func (x *Round) GetFreeSpins() int32 {
+	if x != nil {
+		return x.FreeSpins
+	}
+	return 0
+}
+
+func (x *Round) GetBalanceBefore() int64 {
+	if x != nil {
+		return x.BalanceBefore
+	}
+	return 0
+}
+
+func (x *Round) GetBalanceAfter() int64 {
+	if x != nil {
+		return x.BalanceAfter
+	}
+	return 0
+}
+
+func (x *Round) GetSpins() []*Spin {
+	if x != nil {
+		return x.Spins
+	}
+	return nil
+}
+
+func (x *Round) GetJwt() string {
+	if x != nil {
+		return x.Jwt
+	}
+	return ""
+}
+
+func (x *Round) GetJackpots() []*JackpotWin {
+	if x != nil {
+		return x.Jackpots
+	}
+	return nil
+}
+
+func (x *Round) GetJackpotValues() []*JackpotValue {
+	if x != nil {
+		return x.JackpotValues
+	}
+	return nil
+}
+
+func (x *Round) GetBonus() *BonusGame {
+	if x != nil {
+		return x.Bonus
+	}
+	return nil
+}
+
+// JackpotWin is a progressive jackpot won in a round
+type JackpotWin struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Jackpot       string                 `protobuf:"bytes,1,opt,name=jackpot,proto3" json:"jackpot,omitempty"`
+	Amount        int64                  `protobuf:"varint,2,opt,name=amount,proto3" json:"amount,omitempty"`
+	Reason        string                 `protobuf:"bytes,3,opt,name=reason,proto3" json:"reason,omitempty"` // combination or must_hit_by
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *JackpotWin) Reset() {
+	*x = JackpotWin{}
+	mi := &file_trippy_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *JackpotWin) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*JackpotWin) ProtoMessage() {}
+
+func (x *JackpotWin) ProtoReflect() protoreflect.Message {
+	mi := &file_trippy_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use JackpotWin.ProtoReflect.Descriptor instead.
+func (*JackpotWin) Descriptor() ([]byte, []int) {
+	return file_trippy_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *JackpotWin) GetJackpot() string {
+	if x != nil {
+		return x.Jackpot
+	}
+	return ""
+}
+
+func (x *JackpotWin) GetAmount() int64 {
+	if x != nil {
+		return x.Amount
+	}
+	return 0
+}
+
+func (x *JackpotWin) GetReason() string {
+	if x != nil {
+		return x.Reason
+	}
+	return ""
+}
+
+type JackpotValue struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Jackpot       string                 `protobuf:"bytes,1,opt,name=jackpot,proto3" json:"jackpot,omitempty"`
+	Amount        int64                  `protobuf:"varint,2,opt,name=amount,proto3" json:"amount,omitempty"`
+	MustHitBy     int64                  `protobuf:"varint,3,opt,name=must_hit_by,json=mustHitBy,proto3" json:"must_hit_by,omitempty"` // Amount the jackpot is won before exceeding, 0 if none
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *JackpotValue) Reset() {
+	*x = JackpotValue{}
+	mi := &file_trippy_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *JackpotValue) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*JackpotValue) ProtoMessage() {}
+
+func (x *JackpotValue) ProtoReflect() protoreflect.Message {
+	mi := &file_trippy_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use JackpotValue.ProtoReflect.Descriptor instead.
+func (*JackpotValue) Descriptor() ([]byte, []int) {
+	return file_trippy_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *JackpotValue) GetJackpot() string {
+	if x != nil {
+		return x.Jackpot
+	}
+	return ""
+}
+
+func (x *JackpotValue) GetAmount() int64 {
+	if x != nil {
+		return x.Amount
+	}
+	return 0
+}
+
+func (x *JackpotValue) GetMustHitBy() int64 {
+	if x != nil {
+		return x.MustHitBy
+	}
+	return 0
+}
+
+// BonusGame is a pick game, its prizes revealed as they are picked
+type BonusGame struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Prizes        int32                  `protobuf:"varint,2,opt,name=prizes,proto3" json:"prizes,omitempty"` // Hidden prizes to pick from
+	Picks         int32                  `protobuf:"varint,3,opt,name=picks,proto3" json:"picks,omitempty"`   // Picks left
+	Won           int64                  `protobuf:"varint,4,opt,name=won,proto3" json:"won,omitempty"`
+	Over          bool                   `protobuf:"varint,5,opt,name=over,proto3" json:"over,omitempty"`
+	Reveal        []*BonusPrize          `protobuf:"bytes,6,rep,name=reveal,proto3" json:"reveal,omitempty"` // Prizes picked, all of them once the game is over
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BonusGame) Reset() {
+	*x = BonusGame{}
+	mi := &file_trippy_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BonusGame) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BonusGame) ProtoMessage() {}
+
+func (x *BonusGame) ProtoReflect() protoreflect.Message {
+	mi := &file_trippy_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BonusGame.ProtoReflect.Descriptor instead.
+func (*BonusGame) Descriptor() ([]byte, []int) {
+	return file_trippy_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *BonusGame) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *BonusGame) GetPrizes() int32 {
+	if x != nil {
+		return x.Prizes
+	}
+	return 0
+}
+
+func (x *BonusGame) GetPicks() int32 {
+	if x != nil {
+		return x.Picks
+	}
+	return 0
+}
+
+func (x *BonusGame) GetWon() int64 {
+	if x != nil {
+		return x.Won
+	}
+	return 0
+}
+
+func (x *BonusGame) GetOver() bool {
+	if x != nil {
+		return x.Over
+	}
+	return false
+}
+
+func (x *BonusGame) GetReveal() []*BonusPrize {
+	if x != nil {
+		return x.Reveal
+	}
+	return nil
+}
+
+type BonusPrize struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Position      int32                  `protobuf:"varint,1,opt,name=position,proto3" json:"position,omitempty"` // From 1
+	Prize         int64                  `protobuf:"varint,2,opt,name=prize,proto3" json:"prize,omitempty"`
+	Picked        bool                   `protobuf:"varint,3,opt,name=picked,proto3" json:"picked,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BonusPrize) Reset() {
+	*x = BonusPrize{}
+	mi := &file_trippy_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BonusPrize) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BonusPrize) ProtoMessage() {}
+
+func (x *BonusPrize) ProtoReflect() protoreflect.Message {
+	mi := &file_trippy_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BonusPrize.ProtoReflect.Descriptor instead.
+func (*BonusPrize) Descriptor() ([]byte, []int) {
+	return file_trippy_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *BonusPrize) GetPosition() int32 {
+	if x != nil {
+		return x.Position
+	}
+	return 0
+}
+
+func (x *BonusPrize) GetPrize() int64 {
+	if x != nil {
+		return x.Prize
+	}
+	return 0
+}
+
+func (x *BonusPrize) GetPicked() bool {
+	if x != nil {
+		return x.Picked
+	}
+	return false
+}
+
+type Spin struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Type          string                 `protobuf:"bytes,1,opt,name=type,proto3" json:"type,omitempty"` // main, free, respin, hold, jackpot or bonus
+	Total         int64                  `protobuf:"varint,2,opt,name=total,proto3" json:"total,omitempty"`
+	Multiplier    int32                  `protobuf:"varint,3,opt,name=multiplier,proto3" json:"multiplier,omitempty"`
+	Stops         []int32                `protobuf:"varint,4,rep,packed,name=stops,proto3" json:"stops,omitempty"`
+	Grid          []*Row                 `protobuf:"bytes,5,rep,name=grid,proto3" json:"grid,omitempty"` // Visible window, grid[row].symbols[reel]
+	Lines         []*WinLine             `protobuf:"bytes,6,rep,name=lines,proto3" json:"lines,omitempty"`
+	Scatters      int32                  `protobuf:"varint,7,opt,name=scatters,proto3" json:"scatters,omitempty"`
+	FreeSpins     int32                  `protobuf:"varint,8,opt,name=free_spins,json=freeSpins,proto3" json:"free_spins,omitempty"` // Free spins awarded by this spin
+	Jackpot       string                 `protobuf:"bytes,9,opt,name=jackpot,proto3" json:"jackpot,omitempty"`                       // Mystery jackpot awarded by a jackpot spin
+	Wilds         []*WildChange          `protobuf:"bytes,10,rep,name=wilds,proto3" json:"wilds,omitempty"`                          // Cells turned wild by the wild behaviours
+	Multipliers   []*WildMultiplier      `protobuf:"bytes,11,rep,name=multipliers,proto3" json:"multipliers,omitempty"`              // Multipliers carried by the wilds of the grid
+	Coins         []*Coin                `protobuf:"bytes,12,rep,name=coins,proto3" json:"coins,omitempty"`                          // Coins held after a hold spin
+	Respins       int32                  `protobuf:"varint,13,opt,name=respins,proto3" json:"respins,omitempty"`                     // Respins left after a hold spin
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Spin) Reset() {
+	*x = Spin{}
+	mi := &file_trippy_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Spin) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Spin) ProtoMessage() {}
+
+func (x *Spin) ProtoReflect() protoreflect.Message {
+	mi := &file_trippy_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Spin.ProtoReflect.Descriptor instead.
+func (*Spin) Descriptor() ([]byte, []int) {
+	return file_trippy_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *Spin) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *Spin) GetTotal() int64 {
+	if x != nil {
+		return x.Total
+	}
+	return 0
+}
+
+func (x *Spin) GetMultiplier() int32 {
+	if x != nil {
+		return x.Multiplier
+	}
+	return 0
+}
+
+func (x *Spin) GetStops() []int32 {
+	if x != nil {
+		return x.Stops
+	}
+	return nil
+}
+
+func (x *Spin) GetGrid() []*Row {
+	if x != nil {
+		return x.Grid
+	}
+	return nil
+}
+
+func (x *Spin) GetLines() []*WinLine {
+	if x != nil {
+		return x.Lines
+	}
+	return nil
+}
+
+func (x *Spin) GetScatters() int32 {
+	if x != nil {
+		return x.Scatters
+	}
+	return 0
+}
+
+func (x *Spin) GetFreeSpins() int32 {
+	if x != nil {
+		return x.FreeSpins
+	}
+	return 0
+}
+
+func (x *Spin) GetJackpot() string {
+	if x != nil {
+		return x.Jackpot
+	}
+	return ""
+}
+
+func (x *Spin) GetWilds() []*WildChange {
+	if x != nil {
+		return x.Wilds
+	}
+	return nil
+}
+
+func (x *Spin) GetMultipliers() []*WildMultiplier {
+	if x != nil {
+		return x.Multipliers
+	}
+	return nil
+}
+
+func (x *Spin) GetCoins() []*Coin {
+	if x != nil {
+		return x.Coins
+	}
+	return nil
+}
+
+func (x *Spin) GetRespins() int32 {
+	if x != nil {
+		return x.Respins
+	}
+	return 0
+}
+
+// Coin is a coin held in a cell of the grid, with its credits in chips
+type Coin struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Reel          int32                  `protobuf:"varint,1,opt,name=reel,proto3" json:"reel,omitempty"`
+	Row           int32                  `protobuf:"varint,2,opt,name=row,proto3" json:"row,omitempty"`
+	Value         int64                  `protobuf:"varint,3,opt,name=value,proto3" json:"value,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Coin) Reset() {
+	*x = Coin{}
+	mi := &file_trippy_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Coin) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Coin) ProtoMessage() {}
+
+func (x *Coin) ProtoReflect() protoreflect.Message {
+	mi := &file_trippy_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Coin.ProtoReflect.Descriptor instead.
+func (*Coin) Descriptor() ([]byte, []int) {
+	return file_trippy_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *Coin) GetReel() int32 {
+	if x != nil {
+		return x.Reel
+	}
+	return 0
+}
+
+func (x *Coin) GetRow() int32 {
+	if x != nil {
+		return x.Row
+	}
+	return 0
+}
+
+func (x *Coin) GetValue() int64 {
+	if x != nil {
+		return x.Value
+	}
+	return 0
+}
+
+// WildMultiplier is the multiplier carried by the wild of a cell of the grid
+type WildMultiplier struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Reel          int32                  `protobuf:"varint,1,opt,name=reel,proto3" json:"reel,omitempty"`
+	Row           int32                  `protobuf:"varint,2,opt,name=row,proto3" json:"row,omitempty"`
+	Multiplier    int32                  `protobuf:"varint,3,opt,name=multiplier,proto3" json:"multiplier,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WildMultiplier) Reset() {
+	*x = WildMultiplier{}
+	mi := &file_trippy_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WildMultiplier) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WildMultiplier) ProtoMessage() {}
+
+func (x *WildMultiplier) ProtoReflect() protoreflect.Message {
+	mi := &file_trippy_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WildMultiplier.ProtoReflect.Descriptor instead.
+func (*WildMultiplier) Descriptor() ([]byte, []int) {
+	return file_trippy_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *WildMultiplier) GetReel() int32 {
+	if x != nil {
+		return x.Reel
+	}
+	return 0
+}
+
+func (x *WildMultiplier) GetRow() int32 {
+	if x != nil {
+		return x.Row
+	}
+	return 0
+}
+
+func (x *WildMultiplier) GetMultiplier() int32 {
+	if x != nil {
+		return x.Multiplier
+	}
+	return 0
+}
+
+// WildChange are the cells turned wild by a behaviour: expanding, sticky or walking
+type WildChange struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Behaviour     string                 `protobuf:"bytes,1,opt,name=behaviour,proto3" json:"behaviour,omitempty"`
+	Cells         []*Position            `protobuf:"bytes,2,rep,name=cells,proto3" json:"cells,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WildChange) Reset() {
+	*x = WildChange{}
+	mi := &file_trippy_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WildChange) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WildChange) ProtoMessage() {}
+
+func (x *WildChange) ProtoReflect() protoreflect.Message {
+	mi := &file_trippy_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WildChange.ProtoReflect.Descriptor instead.
+func (*WildChange) Descriptor() ([]byte, []int) {
+	return file_trippy_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *WildChange) GetBehaviour() string {
+	if x != nil {
+		return x.Behaviour
+	}
+	return ""
+}
+
+func (x *WildChange) GetCells() []*Position {
+	if x != nil {
+		return x.Cells
+	}
+	return nil
+}
+
+type Row struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Symbols       []*Symbol              `protobuf:"bytes,1,rep,name=symbols,proto3" json:"symbols,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Row) Reset() {
+	*x = Row{}
+	mi := &file_trippy_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Row) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Row) ProtoMessage() {}
+
+func (x *Row) ProtoReflect() protoreflect.Message {
+	mi := &file_trippy_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Row.ProtoReflect.Descriptor instead.
+func (*Row) Descriptor() ([]byte, []int) {
+	return file_trippy_proto_rawDescGZIP(), []int{18}
+}
+
+func (x *Row) GetSymbols() []*Symbol {
+	if x != nil {
+		return x.Symbols
+	}
+	return nil
+}
+
+type Symbol struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            int32                  `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Code          string                 `protobuf:"bytes,3,opt,name=code,proto3" json:"code,omitempty"` // Short code displaying the symbol
+	Kind          string                 `protobuf:"bytes,4,opt,name=kind,proto3" json:"kind,omitempty"` // regular, wild, scatter, bonus, coin or blank
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Symbol) Reset() {
+	*x = Symbol{}
+	mi := &file_trippy_proto_msgTypes[19]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Symbol) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Symbol) ProtoMessage() {}
+
+func (x *Symbol) ProtoReflect() protoreflect.Message {
+	mi := &file_trippy_proto_msgTypes[19]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Symbol.ProtoReflect.Descriptor instead.
+func (*Symbol) Descriptor() ([]byte, []int) {
+	return file_trippy_proto_rawDescGZIP(), []int{19}
+}
+
+func (x *Symbol) GetId() int32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *Symbol) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Symbol) GetCode() string {
+	if x != nil {
+		return x.Code
+	}
+	return ""
+}
+
+func (x *Symbol) GetKind() string {
+	if x != nil {
+		return x.Kind
+	}
+	return ""
+}
+
+type WinLine struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Index          int32                  `protobuf:"varint,1,opt,name=index,proto3" json:"index,omitempty"`
+	Symbol         *Symbol                `protobuf:"bytes,2,opt,name=symbol,proto3" json:"symbol,omitempty"`
+	Count          int32                  `protobuf:"varint,3,opt,name=count,proto3" json:"count,omitempty"`
+	Payout         int64                  `protobuf:"varint,4,opt,name=payout,proto3" json:"payout,omitempty"`
+	Multiplier     int32                  `protobuf:"varint,5,opt,name=multiplier,proto3" json:"multiplier,omitempty"`                               // Free spins and wilds multipliers of the line
+	Positions      []*Position            `protobuf:"bytes,6,rep,name=positions,proto3" json:"positions,omitempty"`                                  // Cells of the pay line, the first count are paid
+	WildMultiplier int32                  `protobuf:"varint,7,opt,name=wild_multiplier,json=wildMultiplier,proto3" json:"wild_multiplier,omitempty"` // Multiplier of the wilds paid, part of the multiplier
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *WinLine) Reset() {
+	*x = WinLine{}
+	mi := &file_trippy_proto_msgTypes[20]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WinLine) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WinLine) ProtoMessage() {}
+
+func (x *WinLine) ProtoReflect() protoreflect.Message {
+	mi := &file_trippy_proto_msgTypes[20]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WinLine.ProtoReflect.Descriptor instead.
+func (*WinLine) Descriptor() ([]byte, []int) {
+	return file_trippy_proto_rawDescGZIP(), []int{20}
+}
+
+func (x *WinLine) GetIndex() int32 {
+	if x != nil {
+		return x.Index
+	}
+	return 0
+}
+
+func (x *WinLine) GetSymbol() *Symbol {
+	if x != nil {
+		return x.Symbol
+	}
+	return nil
+}
+
+func (x *WinLine) GetCount() int32 {
+	if x != nil {
+		return x.Count
+	}
+	return 0
+}
+
+func (x *WinLine) GetPayout() int64 {
+	if x != nil {
+		return x.Payout
+	}
+	return 0
+}
+
+func (x *WinLine) GetMultiplier() int32 {
+	if x != nil {
+		return x.Multiplier
+	}
+	return 0
+}
+
+func (x *WinLine) GetPositions() []*Position {
+	if x != nil {
+		return x.Positions
+	}
+	return nil
+}
+
+func (x *WinLine) GetWildMultiplier() int32 {
+	if x != nil {
+		return x.WildMultiplier
+	}
+	return 0
+}
+
+// Position is a cell of the visible window, numbered from 1
+type Position struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Reel          int32                  `protobuf:"varint,1,opt,name=reel,proto3" json:"reel,omitempty"`
+	Row           int32                  `protobuf:"varint,2,opt,name=row,proto3" json:"row,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Position) Reset() {
+	*x = Position{}
+	mi := &file_trippy_proto_msgTypes[21]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Position) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Position) ProtoMessage() {}
+
+func (x *Position) ProtoReflect() protoreflect.Message {
+	mi := &file_trippy_proto_msgTypes[21]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Position.ProtoReflect.Descriptor instead.
+func (*Position) Descriptor() ([]byte, []int) {
+	return file_trippy_proto_rawDescGZIP(), []int{21}
+}
+
+func (x *Position) GetReel() int32 {
+	if x != nil {
+		return x.Reel
+	}
+	return 0
+}
+
+func (x *Position) GetRow() int32 {
+	if x != nil {
+		return x.Row
+	}
+	return 0
+}
+
+var File_trippy_proto protoreflect.FileDescriptor
+
+const file_trippy_proto_rawDesc = "" +
+	"\n" +
+	"\ftrippy.proto\x12\ttrippy.v1\"U\n" +
+	"\x03Bet\x12\x14\n" +
+	"\x05coins\x18\x01 \x01(\x05R\x05coins\x12\"\n" +
+	"\fdenomination\x18\x02 \x01(\x05R\fdenomination\x12\x14\n" +
+	"\x05lines\x18\x03 \x01(\x05R\x05lines\"I\n" +
+	"\vSpinRequest\x12\x18\n" +
+	"\amachine\x18\x01 \x01(\tR\amachine\x12 \n" +
+	"\x03bet\x18\x02 \x01(\v2\x0e.trippy.v1.BetR\x03bet\"J\n" +
+	"\fWagerRequest\x12\x18\n" +
+	"\amachine\x18\x01 \x01(\tR\amachine\x12 \n" +
+	"\x03bet\x18\x02 \x01(\v2\x0e.trippy.v1.BetR\x03bet\"_\n" +
+	"\rWagerResponse\x12\x14\n" +
+	"\x05wager\x18\x01 \x01(\x03R\x05wager\x12\x18\n" +
+	"\abalance\x18\x02 \x01(\x03R\abalance\x12\x1e\n" +
+	"\n" +
+	"sufficient\x18\x03 \x01(\bR\n" +
+	"sufficient\"\x15\n" +
+	"\x13ListMachinesRequest\"F\n" +
+	"\x14ListMachinesResponse\x12.\n" +
+	"\bmachines\x18\x01 \x03(\v2\x12.trippy.v1.MachineR\bmachines\"o\n" +
+	"\aMachine\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x16\n" +
+	"\x06engine\x18\x02 \x01(\tR\x06engine\x12\x18\n" +
+	"\aenabled\x18\x03 \x01(\bR\aenabled\x12\x1e\n" +
+	"\n" +
+	"definition\x18\x04 \x01(\tR\n" +
+	"definition\"!\n" +
+	"\x0fGetRoundRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"\x1f\n" +
+	"\rReplayRequest\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\"\xb2\x03\n" +
+	"\x05Round\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x18\n" +
+	"\amachine\x18\x02 \x01(\tR\amachine\x12\x10\n" +
+	"\x03uid\x18\x03 \x01(\tR\x03uid\x12\x14\n" +
+	"\x05wager\x18\x04 \x01(\x03R\x05wager\x12\x14\n" +
+	"\x05total\x18\x05 \x01(\x03R\x05total\x12\x1d\n" +
+	"\n" +
+	"free_spins\x18\x06 \x01(\x05R\tfreeSpins\x12%\n" +
+	"\x0ebalance_before\x18\a \x01(\x03R\rbalanceBefore\x12#\n" +
+	"\rbalance_after\x18\b \x01(\x03R\fbalanceAfter\x12%\n" +
+	"\x05spins\x18\t \x03(\v2\x0f.trippy.v1.SpinR\x05spins\x12\x10\n" +
+	"\x03jwt\x18\n" +
+	" \x01(\tR\x03jwt\x121\n" +
+	"\bjackpots\x18\v \x03(\v2\x15.trippy.v1.JackpotWinR\bjackpots\x12>\n" +
+	"\x0ejackpot_values\x18\f \x03(\v2\x17.trippy.v1.JackpotValueR\rjackpotValues\x12*\n" +
+	"\x05bonus\x18\r \x01(\v2\x14.trippy.v1.BonusGameR\x05bonus\"V\n" +
+	"\n" +
+	"JackpotWin\x12\x18\n" +
+	"\ajackpot\x18\x01 \x01(\tR\ajackpot\x12\x16\n" +
+	"\x06amount\x18\x02 \x01(\x03R\x06amount\x12\x16\n" +
+	"\x06reason\x18\x03 \x01(\tR\x06reason\"`\n" +
+	"\fJackpotValue\x12\x18\n" +
+	"\ajackpot\x18\x01 \x01(\tR\ajackpot\x12\x16\n" +
+	"\x06amount\x18\x02 \x01(\x03R\x06amount\x12\x1e\n" +
+	"\vmust_hit_by\x18\x03 \x01(\x03R\tmustHitBy\"\x9e\x01\n" +
+	"\tBonusGame\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x16\n" +
+	"\x06prizes\x18\x02 \x01(\x05R\x06prizes\x12\x14\n" +
+	"\x05picks\x18\x03 \x01(\x05R\x05picks\x12\x10\n" +
+	"\x03won\x18\x04 \x01(\x03R\x03won\x12\x12\n" +
+	"\x04over\x18\x05 \x01(\bR\x04over\x12-\n" +
+	"\x06reveal\x18\x06 \x03(\v2\x15.trippy.v1.BonusPrizeR\x06reveal\"V\n" +
+	"\n" +
+	"BonusPrize\x12\x1a\n" +
+	"\bposition\x18\x01 \x01(\x05R\bposition\x12\x14\n" +
+	"\x05prize\x18\x02 \x01(\x03R\x05prize\x12\x16\n" +
+	"\x06picked\x18\x03 \x01(\bR\x06picked\"\xb4\x03\n" +
+	"\x04Spin\x12\x12\n" +
+	"\x04type\x18\x01 \x01(\tR\x04type\x12\x14\n" +
+	"\x05total\x18\x02 \x01(\x03R\x05total\x12\x1e\n" +
+	"\n" +
+	"multiplier\x18\x03 \x01(\x05R\n" +
+	"multiplier\x12\x14\n" +
+	"\x05stops\x18\x04 \x03(\x05R\x05stops\x12\"\n" +
+	"\x04grid\x18\x05 \x03(\v2\x0e.trippy.v1.RowR\x04grid\x12(\n" +
+	"\x05lines\x18\x06 \x03(\v2\x12.trippy.v1.WinLineR\x05lines\x12\x1a\n" +
+	"\bscatters\x18\a \x01(\x05R\bscatters\x12\x1d\n" +
+	"\n" +
+	"free_spins\x18\b \x01(\x05R\tfreeSpins\x12\x18\n" +
+	"\ajackpot\x18\t \x01(\tR\ajackpot\x12+\n" +
+	"\x05wilds\x18\n" +
+	" \x03(\v2\x15.trippy.v1.WildChangeR\x05wilds\x12;\n" +
+	"\vmultipliers\x18\v \x03(\v2\x19.trippy.v1.WildMultiplierR\vmultipliers\x12%\n" +
+	"\x05coins\x18\f \x03(\v2\x0f.trippy.v1.CoinR\x05coins\x12\x18\n" +
+	"\arespins\x18\r \x01(\x05R\arespins\"B\n" +
+	"\x04Coin\x12\x12\n" +
+	"\x04reel\x18\x01 \x01(\x05R\x04reel\x12\x10\n" +
+	"\x03row\x18\x02 \x01(\x05R\x03row\x12\x14\n" +
+	"\x05value\x18\x03 \x01(\x03R\x05value\"V\n" +
+	"\x0eWildMultiplier\x12\x12\n" +
+	"\x04reel\x18\x01 \x01(\x05R\x04reel\x12\x10\n" +
+	"\x03row\x18\x02 \x01(\x05R\x03row\x12\x1e\n" +
+	"\n" +
+	"multiplier\x18\x03 \x01(\x05R\n" +
+	"multiplier\"U\n" +
+	"\n" +
+	"WildChange\x12\x1c\n" +
+	"\tbehaviour\x18\x01 \x01(\tR\tbehaviour\x12)\n" +
+	"\x05cells\x18\x02 \x03(\v2\x13.trippy.v1.PositionR\x05cells\"2\n" +
+	"\x03Row\x12+\n" +
+	"\asymbols\x18\x01 \x03(\v2\x11.trippy.v1.SymbolR\asymbols\"T\n" +
+	"\x06Symbol\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\x05R\x02id\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x12\n" +
+	"\x04code\x18\x03 \x01(\tR\x04code\x12\x12\n" +
+	"\x04kind\x18\x04 \x01(\tR\x04kind\"\xf4\x01\n" +
+	"\aWinLine\x12\x14\n" +
+	"\x05index\x18\x01 \x01(\x05R\x05index\x12)\n" +
+	"\x06symbol\x18\x02 \x01(\v2\x11.trippy.v1.SymbolR\x06symbol\x12\x14\n" +
+	"\x05count\x18\x03 \x01(\x05R\x05count\x12\x16\n" +
+	"\x06payout\x18\x04 \x01(\x03R\x06payout\x12\x1e\n" +
+	"\n" +
+	"multiplier\x18\x05 \x01(\x05R\n" +
+	"multiplier\x121\n" +
+	"\tpositions\x18\x06 \x03(\v2\x13.trippy.v1.PositionR\tpositions\x12'\n" +
+	"\x0fwild_multiplier\x18\a \x01(\x05R\x0ewildMultiplier\"0\n" +
+	"\bPosition\x12\x12\n" +
+	"\x04reel\x18\x01 \x01(\x05R\x04reel\x12\x10\n" +
+	"\x03row\x18\x02 \x01(\x05R\x03row2\xb8\x02\n" +
+	"\x06Trippy\x120\n" +
+	"\x04Spin\x12\x16.trippy.v1.SpinRequest\x1a\x10.trippy.v1.Round\x12:\n" +
+	"\x05Wager\x12\x17.trippy.v1.WagerRequest\x1a\x18.trippy.v1.WagerResponse\x12O\n" +
+	"\fListMachines\x12\x1e.trippy.v1.ListMachinesRequest\x1a\x1f.trippy.v1.ListMachinesResponse\x128\n" +
+	"\bGetRound\x12\x1a.trippy.v1.GetRoundRequest\x1a\x10.trippy.v1.Round\x125\n" +
+	"\x06Replay\x12\x18.trippy.v1.ReplayRequest\x1a\x0f.trippy.v1.Spin0\x01B\x1eZ\x1ctrippy/server/proto;trippyv1b\x06proto3"
+
+var (
+	file_trippy_proto_rawDescOnce sync.Once
+	file_trippy_proto_rawDescData []byte
+)
+
+func file_trippy_proto_rawDescGZIP() []byte {
+	file_trippy_proto_rawDescOnce.Do(func() {
+		file_trippy_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_trippy_proto_rawDesc), len(file_trippy_proto_rawDesc)))
+	})
+	return file_trippy_proto_rawDescData
+}
+
+var file_trippy_proto_msgTypes = make([]protoimpl.MessageInfo, 22)
+var file_trippy_proto_goTypes = []any{
+	(*Bet)(nil),                  // 0: trippy.v1.Bet
+	(*SpinRequest)(nil),          // 1: trippy.v1.SpinRequest
+	(*WagerRequest)(nil),         // 2: trippy.v1.WagerRequest
+	(*WagerResponse)(nil),        // 3: trippy.v1.WagerResponse
+	(*ListMachinesRequest)(nil),  // 4: trippy.v1.ListMachinesRequest
+	(*ListMachinesResponse)(nil), // 5: trippy.v1.ListMachinesResponse
+	(*Machine)(nil),              // 6: trippy.v1.Machine
+	(*GetRoundRequest)(nil),      // 7: trippy.v1.GetRoundRequest
+	(*ReplayRequest)(nil),        // 8: trippy.v1.ReplayRequest
+	(*Round)(nil),                // 9: trippy.v1.Round
+	(*JackpotWin)(nil),           // 10: trippy.v1.JackpotWin
+	(*JackpotValue)(nil),         // 11: trippy.v1.JackpotValue
+	(*BonusGame)(nil),            // 12: trippy.v1.BonusGame
+	(*BonusPrize)(nil),           // 13: trippy.v1.BonusPrize
+	(*Spin)(nil),                 // 14: trippy.v1.Spin
+	(*Coin)(nil),                 // 15: trippy.v1.Coin
+	(*WildMultiplier)(nil),       // 16: trippy.v1.WildMultiplier
+	(*WildChange)(nil),           // 17: trippy.v1.WildChange
+	(*Row)(nil),                  // 18: trippy.v1.Row
+	(*Symbol)(nil),               // 19: trippy.v1.Symbol
+	(*WinLine)(nil),              // 20: trippy.v1.WinLine
+	(*Position)(nil),             // 21: trippy.v1.Position
+}
+var file_trippy_proto_depIdxs = []int32{
+	0,  // 0: trippy.v1.SpinRequest.bet:type_name -> trippy.v1.Bet
+	0,  // 1: trippy.v1.WagerRequest.bet:type_name -> trippy.v1.Bet
+	6,  // 2: trippy.v1.ListMachinesResponse.machines:type_name -> trippy.v1.Machine
+	14, // 3: trippy.v1.Round.spins:type_name -> trippy.v1.Spin
+	10, // 4: trippy.v1.Round.jackpots:type_name -> trippy.v1.JackpotWin
+	11, // 5: trippy.v1.Round.jackpot_values:type_name -> trippy.v1.JackpotValue
+	12, // 6: trippy.v1.Round.bonus:type_name -> trippy.v1.BonusGame
+	13, // 7: trippy.v1.BonusGame.reveal:type_name -> trippy.v1.BonusPrize
+	18, // 8: trippy.v1.Spin.grid:type_name -> trippy.v1.Row
+	20, // 9: trippy.v1.Spin.lines:type_name -> trippy.v1.WinLine
+	17, // 10: trippy.v1.Spin.wilds:type_name -> trippy.v1.WildChange
+	16, // 11: trippy.v1.Spin.multipliers:type_name -> trippy.v1.WildMultiplier
+	15, // 12: trippy.v1.Spin.coins:type_name -> trippy.v1.Coin
+	21, // 13: trippy.v1.WildChange.cells:type_name -> trippy.v1.Position
+	19, // 14: trippy.v1.Row.symbols:type_name -> trippy.v1.Symbol
+	19, // 15: trippy.v1.WinLine.symbol:type_name -> trippy.v1.Symbol
+	21, // 16: trippy.v1.WinLine.positions:type_name -> trippy.v1.Position
+	1,  // 17: trippy.v1.Trippy.Spin:input_type -> trippy.v1.SpinRequest
+	2,  // 18: trippy.v1.Trippy.Wager:input_type -> trippy.v1.WagerRequest
+	4,  // 19: trippy.v1.Trippy.ListMachines:input_type -> trippy.v1.ListMachinesRequest
+	7,  // 20: trippy.v1.Trippy.GetRound:input_type -> trippy.v1.GetRoundRequest
+	8,  // 21: trippy.v1.Trippy.Replay:input_type -> trippy.v1.ReplayRequest
+	9,  // 22: trippy.v1.Trippy.Spin:output_type -> trippy.v1.Round
+	3,  // 23: trippy.v1.Trippy.Wager:output_type -> trippy.v1.WagerResponse
+	5,  // 24: trippy.v1.Trippy.ListMachines:output_type -> trippy.v1.ListMachinesResponse
+	9,  // 25: trippy.v1.Trippy.GetRound:output_type -> trippy.v1.Round
+	14, // 26: trippy.v1.Trippy.Replay:output_type -> trippy.v1.Spin
+	22, // [22:27] is the sub-list for method output_type
+	17, // [17:22] is the sub-list for method input_type
+	17, // [17:17] is the sub-list for extension type_name
+	17, // [17:17] is the sub-list for extension extendee
+	0,  // [0:17] is the sub-list for field type_name
+}
+
+func init() { file_trippy_proto_init() }
+func file_trippy_proto_init() {
+	if File_trippy_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_trippy_proto_rawDesc), len(file_trippy_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   22,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_trippy_proto_goTypes,
+		DependencyIndexes: file_trippy_proto_depIdxs,
+		MessageInfos:      file_trippy_proto_msgTypes,
+	}.Build()
+	File_trippy_proto = out.File
+	file_trippy_proto_goTypes = nil
+	file_trippy_proto_depIdxs = nil
+}
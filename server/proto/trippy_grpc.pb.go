@@ -0,0 +1,298 @@
+// gRPC API of trippy, served on the same address as the REST API.
+//
+// Every call carries the JWT of the player in the authorization metadata,
+// "Bearer <jwt>", the same token as the REST API with the chips of the player.
+// Failed calls have the error code of the REST API in the trippy-error-code
+// trailer, and in the status message as "CODE: message".
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.6.2
+// - protoc             (unknown)
+// source: trippy.proto
+
+package trippyv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Trippy_Spin_FullMethodName         = "/trippy.v1.Trippy/Spin"
+	Trippy_Wager_FullMethodName        = "/trippy.v1.Trippy/Wager"
+	Trippy_ListMachines_FullMethodName = "/trippy.v1.Trippy/ListMachines"
+	Trippy_GetRound_FullMethodName     = "/trippy.v1.Trippy/GetRound"
+	Trippy_Replay_FullMethodName       = "/trippy.v1.Trippy/Replay"
+)
+
+// TrippyClient is the client API for Trippy service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type TrippyClient interface {
+	// Spin plays a round for the player of the token and returns the new token.
+	// It has no idempotency key: a call retried plays another round, as a REST spin without Idempotency-Key.
+	Spin(ctx context.Context, in *SpinRequest, opts ...grpc.CallOption) (*Round, error)
+	// Wager returns the chips a bet costs, without playing it.
+	Wager(ctx context.Context, in *WagerRequest, opts ...grpc.CallOption) (*WagerResponse, error)
+	// ListMachines returns every machine configured, enabled or not.
+	ListMachines(ctx context.Context, in *ListMachinesRequest, opts ...grpc.CallOption) (*ListMachinesResponse, error)
+	// GetRound returns a recent round of the player of the token, one of the last 10000 played on the server.
+	GetRound(ctx context.Context, in *GetRoundRequest, opts ...grpc.CallOption) (*Round, error)
+	// Replay streams the spins of a recent round of the player of the token, in the order played.
+	// The recent rounds are kept in the storage backend, across restarts with the file storage.
+	Replay(ctx context.Context, in *ReplayRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Spin], error)
+}
+
+type trippyClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewTrippyClient(cc grpc.ClientConnInterface) TrippyClient {
+	return &trippyClient{cc}
+}
+
+func (c *trippyClient) Spin(ctx context.Context, in *SpinRequest, opts ...grpc.CallOption) (*Round, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Round)
+	err := c.cc.Invoke(ctx, Trippy_Spin_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *trippyClient) Wager(ctx context.Context, in *WagerRequest, opts ...grpc.CallOption) (*WagerResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(WagerResponse)
+	err := c.cc.Invoke(ctx, Trippy_Wager_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *trippyClient) ListMachines(ctx context.Context, in *ListMachinesRequest, opts ...grpc.CallOption) (*ListMachinesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListMachinesResponse)
+	err := c.cc.Invoke(ctx, Trippy_ListMachines_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *trippyClient) GetRound(ctx context.Context, in *GetRoundRequest, opts ...grpc.CallOption) (*Round, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Round)
+	err := c.cc.Invoke(ctx, Trippy_GetRound_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *trippyClient) Replay(ctx context.Context, in *ReplayRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[Spin], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &Trippy_ServiceDesc.Streams[0], Trippy_Replay_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[ReplayRequest, Spin]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Trippy_ReplayClient = grpc.ServerStreamingClient[Spin]
+
+// TrippyServer is the server API for Trippy service.
+// All implementations must embed UnimplementedTrippyServer
+// for forward compatibility.
+type TrippyServer interface {
+	// Spin plays a round for the player of the token and returns the new token.
+	// It has no idempotency key: a call retried plays another round, as a REST spin without Idempotency-Key.
+	Spin(context.Context, *SpinRequest) (*Round, error)
+	// Wager returns the chips a bet costs, without playing it.
+	Wager(context.Context, *WagerRequest) (*WagerResponse, error)
+	// ListMachines returns every machine configured, enabled or not.
+	ListMachines(context.Context, *ListMachinesRequest) (*ListMachinesResponse, error)
+	// GetRound returns a recent round of the player of the token, one of the last 10000 played on the server.
+	GetRound(context.Context, *GetRoundRequest) (*Round, error)
+	// Replay streams the spins of a recent round of the player of the token, in the order played.
+	// The recent rounds are kept in the storage backend, across restarts with the file storage.
+	Replay(*ReplayRequest, grpc.ServerStreamingServer[Spin]) error
+	mustEmbedUnimplementedTrippyServer()
+}
+
+// UnimplementedTrippyServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedTrippyServer struct{}
+
+func (UnimplementedTrippyServer) Spin(context.Context, *SpinRequest) (*Round, error) {
+	return nil, status.Error(codes.Unimplemented, "method Spin not implemented")
+}
+func (UnimplementedTrippyServer) Wager(context.Context, *WagerRequest) (*WagerResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method Wager not implemented")
+}
+func (UnimplementedTrippyServer) ListMachines(context.Context, *ListMachinesRequest) (*ListMachinesResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method ListMachines not implemented")
+}
+func (UnimplementedTrippyServer) GetRound(context.Context, *GetRoundRequest) (*Round, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetRound not implemented")
+}
+func (UnimplementedTrippyServer) Replay(*ReplayRequest, grpc.ServerStreamingServer[Spin]) error {
+	return status.Error(codes.Unimplemented, "method Replay not implemented")
+}
+func (UnimplementedTrippyServer) mustEmbedUnimplementedTrippyServer() {}
+func (UnimplementedTrippyServer) testEmbeddedByValue()                {}
+
+// UnsafeTrippyServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to TrippyServer will
+// result in compilation errors.
+type UnsafeTrippyServer interface {
+	mustEmbedUnimplementedTrippyServer()
+}
+
+func RegisterTrippyServer(s grpc.ServiceRegistrar, srv TrippyServer) {
+	// If the following call panics, it indicates UnimplementedTrippyServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Trippy_ServiceDesc, srv)
+}
+
+func _Trippy_Spin_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SpinRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TrippyServer).Spin(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Trippy_Spin_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TrippyServer).Spin(ctx, req.(*SpinRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Trippy_Wager_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(WagerRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TrippyServer).Wager(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Trippy_Wager_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TrippyServer).Wager(ctx, req.(*WagerRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Trippy_ListMachines_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListMachinesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TrippyServer).ListMachines(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Trippy_ListMachines_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TrippyServer).ListMachines(ctx, req.(*ListMachinesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Trippy_GetRound_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetRoundRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TrippyServer).GetRound(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Trippy_GetRound_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TrippyServer).GetRound(ctx, req.(*GetRoundRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Trippy_Replay_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(ReplayRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(TrippyServer).Replay(m, &grpc.GenericServerStream[ReplayRequest, Spin]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type Trippy_ReplayServer = grpc.ServerStreamingServer[Spin]
+
+// Trippy_ServiceDesc is the grpc.ServiceDesc for Trippy service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Trippy_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "trippy.v1.Trippy",
+	HandlerType: (*TrippyServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Spin",
+			Handler:    _Trippy_Spin_Handler,
+		},
+		{
+			MethodName: "Wager",
+			Handler:    _Trippy_Wager_Handler,
+		},
+		{
+			MethodName: "ListMachines",
+			Handler:    _Trippy_ListMachines_Handler,
+		},
+		{
+			MethodName: "GetRound",
+			Handler:    _Trippy_GetRound_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Replay",
+			Handler:       _Trippy_Replay_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "trippy.proto",
+}
@@ -0,0 +1,246 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+const (
+	// Keys of the rate limits
+	_RATE_LIMIT_UID = "uid" // Player in the JWT of the body
+	_RATE_LIMIT_IP  = "ip"  // Client address
+
+	_RATE_LIMIT_SWEEP_INTERVAL = time.Minute // Interval to drop the buckets of the clients gone
+
+	_HEADER_RETRY_AFTER = "Retry-After"
+)
+
+// tokenBucket holds the requests a client can make at once
+type tokenBucket struct {
+	tokens float64
+	last   time.Time // Time tokens was last refilled
+}
+
+// rateLimiter keeps a token bucket per key, refilled at rate tokens per second up to burst
+type rateLimiter struct {
+	rate  float64
+	burst float64
+	now   func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+func newRateLimiter(rate float64, burst int) *rateLimiter {
+	return &rateLimiter{
+		rate:    rate,
+		burst:   float64(burst),
+		now:     time.Now,
+		buckets: make(map[string]*tokenBucket),
+	}
+}
+
+// allow takes a token from the bucket of the key.
+// If the bucket is empty it returns false and the time until the next token.
+func (rl *rateLimiter) allow(key string) (bool, time.Duration) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	now := rl.now()
+	rl.sweep(now)
+	b, ok := rl.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: rl.burst, last: now}
+		rl.buckets[key] = b
+	} else {
+		b.tokens = math.Min(rl.burst, b.tokens+now.Sub(b.last).Seconds()*rl.rate)
+		b.last = now
+	}
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - b.tokens) / rl.rate * float64(time.Second))
+}
+
+// sweep drops the buckets which are full again, a new bucket is the same
+func (rl *rateLimiter) sweep(now time.Time) {
+	if now.Sub(rl.lastSweep) < _RATE_LIMIT_SWEEP_INTERVAL {
+		return
+	}
+	rl.lastSweep = now
+	refill := time.Duration(rl.burst / rl.rate * float64(time.Second))
+	for key, b := range rl.buckets {
+		if now.Sub(b.last) >= refill {
+			delete(rl.buckets, key)
+		}
+	}
+}
+
+type rateLimitRule struct {
+	RateLimitConfig
+	route   []string // Segments of the route pattern
+	limiter *rateLimiter
+}
+
+// rateLimits throttles the requests of every route by player and by client address
+type rateLimits struct {
+	rules []rateLimitRule
+}
+
+func newRateLimits(cfgs []RateLimitConfig) *rateLimits {
+	rls := &rateLimits{rules: make([]rateLimitRule, len(cfgs))}
+	for i, cfg := range cfgs {
+		rls.rules[i] = rateLimitRule{
+			RateLimitConfig: cfg,
+			route:           splitPath(cfg.Route),
+			limiter:         newRateLimiter(cfg.Rate, cfg.Burst),
+		}
+	}
+	return rls
+}
+
+// take takes a token of the rule for the key.
+// Over the limit it returns the seconds to retry in, and the error of the request.
+func (rule rateLimitRule) take(path, key string) (int, *apiError) {
+	ok, wait := rule.limiter.allow(key)
+	if ok {
+		return 0, nil
+	}
+	retryAfter := int(math.Ceil(wait.Seconds()))
+	slog.Warn("Request blocked, rate limit exceeded", "path", path, "route", rule.Route,
+		"limit", rule.Key, "key", key, "retry_after", retryAfter)
+	return retryAfter, newAPIError(_ERR_RATE_LIMITED, fmt.Sprintf("Too many requests. Retry in [%d] seconds.", retryAfter)).
+		withDetail("limit", rule.Key).
+		withDetail("retry_after", retryAfter)
+}
+
+// middleware rejects the requests over the limits of their route with 429 Too Many Requests.
+// The gRPC calls are limited by grpcInterceptor, with the player checked by the service.
+func (rls *rateLimits) middleware(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	var (
+		path    = splitPath(r.URL.Path)
+		uid     string
+		uidRead bool
+	)
+	if matchRoute(splitPath(_GRPC_ROUTE), path) {
+		next(w, r)
+		return
+	}
+	for _, rule := range rls.rules {
+		if !matchRoute(rule.route, path) {
+			continue
+		}
+		key := clientIP(r)
+		if rule.Key == _RATE_LIMIT_UID {
+			if !uidRead {
+				var err error
+				if r, uid, err = requestUID(w, r); err != nil {
+					respondWithError(w, newAPIError(_ERR_INVALID_REQUEST, fmt.Sprintf("Reading body failed. [Error:%s]", err)))
+					return
+				}
+				uidRead = true
+			}
+			if uid == "" {
+				// Requests without a valid token are rejected by the handler
+				continue
+			}
+			key = uid
+		}
+		if retryAfter, apiErr := rule.take(r.URL.Path, key); apiErr != nil {
+			w.Header().Set(_HEADER_RETRY_AFTER, strconv.Itoa(retryAfter))
+			respondWithError(w, apiErr)
+			return
+		}
+	}
+	next(w, r)
+}
+
+// grpcInterceptor rejects the gRPC calls over the limits of their method, eg. /trippy.v1.Trippy/Spin,
+// with ResourceExhausted and the seconds to retry in in the retry-after trailer.
+// It runs after the player of the call is checked, calls are limited by the uid of their JWT.
+func (rls *rateLimits) grpcInterceptor(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	path := splitPath(info.FullMethod)
+	for _, rule := range rls.rules {
+		if !matchRoute(rule.route, path) {
+			continue
+		}
+		key := grpcClientIP(ctx)
+		if rule.Key == _RATE_LIMIT_UID {
+			key = grpcContextUser(ctx).UID
+		}
+		if retryAfter, apiErr := rule.take(info.FullMethod, key); apiErr != nil {
+			grpc.SetTrailer(ctx, metadata.Pairs(_GRPC_RETRY_AFTER, strconv.Itoa(retryAfter)))
+			return nil, apiErr
+		}
+	}
+	return handler(ctx, req)
+}
+
+func splitPath(path string) []string {
+	return strings.Split(strings.Trim(path, "/"), "/")
+}
+
+// matchRoute reports whether the path matches the route pattern of the router.
+// :param matches any segment and *param all the remaining ones.
+func matchRoute(route, path []string) bool {
+	for i, segment := range route {
+		if strings.HasPrefix(segment, "*") {
+			return true
+		}
+		if i >= len(path) {
+			return false
+		}
+		if !strings.HasPrefix(segment, ":") && segment != path[i] {
+			return false
+		}
+	}
+	return len(route) == len(path)
+}
+
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func grpcClientIP(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
+
+// requestUID returns the player of the JWT in the body, empty if the token is invalid.
+// The token parsed is kept in the context of the request returned, for the handler,
+// and the body is left to be read again. The error is the one of reading the body.
+func requestUID(w http.ResponseWriter, r *http.Request) (*http.Request, string, error) {
+	body, err := readBody(w, r)
+	if err != nil || len(body) == 0 {
+		return r, "", err
+	}
+	r, user, err := withToken(r, bodyToken(body))
+	if err != nil {
+		return r, "", nil
+	}
+	return r, user.UID, nil
+}
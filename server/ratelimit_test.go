@@ -0,0 +1,123 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type routeSample struct {
+	route string
+	path  string
+	match bool
+}
+
+func testMatchRoute(t *testing.T, sample routeSample) {
+	if got := matchRoute(splitPath(sample.route), splitPath(sample.path)); got != sample.match {
+		t.Errorf("[Route:%s] [Path:%s] Expected:[%t] Got:[%t]", sample.route, sample.path, sample.match, got)
+	}
+}
+
+func TestMatchRoute(t *testing.T) {
+	samples := []routeSample{
+		{"/api/machines/:machine/spins", "/api/machines/atkins-diet/spins", true},
+		{"/api/machines/:machine/spins", "/api/machines/atkins-diet/spins/", true},
+		{"/api/machines/:machine/spins", "/api/v2/machines/atkins-diet/spins", false},
+		{"/api/machines/:machine/spins", "/api/machines/atkins-diet", false},
+		{"/api/machines/:machine/spins", "/api/machines/atkins-diet/spins/1", false},
+		{"/admin/*path", "/admin/machines/atkins-diet", true},
+		{"/", "/", true},
+		{"/", "/hello/trippy", false},
+	}
+	for _, sample := range samples {
+		testMatchRoute(t, sample)
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	now := time.Unix(1000, 0)
+	rl := newRateLimiter(2, 3)
+	rl.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		if ok, _ := rl.allow("a"); !ok {
+			t.Fatalf("Expected request [%d] within the burst to be allowed", i)
+		}
+	}
+	ok, wait := rl.allow("a")
+	if ok || wait != 500*time.Millisecond {
+		t.Errorf("Expected:[false 500ms] Got:[%t %s]", ok, wait)
+	}
+	if ok, _ = rl.allow("b"); !ok {
+		t.Errorf("Expected every key to have its own bucket")
+	}
+
+	now = now.Add(500 * time.Millisecond)
+	if ok, _ = rl.allow("a"); !ok {
+		t.Errorf("Expected a token to be refilled")
+	}
+
+	now = now.Add(_RATE_LIMIT_SWEEP_INTERVAL)
+	rl.allow("c")
+	if len(rl.buckets) != 1 {
+		t.Errorf("Expected the full buckets to be dropped. Got:[%d] buckets", len(rl.buckets))
+	}
+}
+
+func TestRateLimitBodyTooLarge(t *testing.T) {
+	rls := newRateLimits([]RateLimitConfig{{Route: "/api/machines/:machine/spins", Key: _RATE_LIMIT_UID, Rate: 1, Burst: 1}})
+	r := httptest.NewRequest(http.MethodPost, "/api/machines/atkins-diet/spins", strings.NewReader(strings.Repeat("a", _MAX_BODY_BYTES+1)))
+	w := httptest.NewRecorder()
+	rls.middleware(w, r, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("Expected the request to be rejected before the handler")
+	})
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), string(_ERR_INVALID_REQUEST)) {
+		t.Errorf("Expected:[400 %s] Got:[%d %s]", _ERR_INVALID_REQUEST, w.Code, w.Body)
+	}
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	apiKey = "secret"
+	rls := newRateLimits([]RateLimitConfig{
+		{Route: "/api/machines/:machine/spins", Key: _RATE_LIMIT_UID, Rate: 1, Burst: 1},
+		{Route: "/api/machines/:machine/spins", Key: _RATE_LIMIT_IP, Rate: 1, Burst: 2},
+	})
+	request := func(uid, ip string) *httptest.ResponseRecorder {
+		token, _ := createToken(userClaims{UID: uid, Chips: 100, Bet: 1}, []byte(apiKey))
+		r := httptest.NewRequest(http.MethodPost, "/api/machines/atkins-diet/spins", strings.NewReader(token))
+		r.RemoteAddr = ip + ":1234"
+		w := httptest.NewRecorder()
+		rls.middleware(w, r, func(w http.ResponseWriter, r *http.Request) {
+			if body, _ := readBody(w, r); string(body) != token {
+				t.Errorf("Expected the body to be readable by the handler. Got:[%s]", body)
+			}
+			if parsed, _ := r.Context().Value(tokenKey{}).(requestToken); parsed.user.UID != uid {
+				t.Errorf("Expected the token parsed in the context. Expected:[%s] Got:[%+v]", uid, parsed)
+			}
+			w.WriteHeader(http.StatusOK)
+		})
+		return w
+	}
+
+	if w := request("1", "10.0.0.1"); w.Code != http.StatusOK {
+		t.Fatalf("Expected:[200] Got:[%d %s]", w.Code, w.Body)
+	}
+	w := request("1", "10.0.0.1")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get(_HEADER_RETRY_AFTER) != "1" {
+		t.Errorf("Expected the player to be limited. Got:[%d %s] [Retry-After:%s]", w.Code, w.Body, w.Header().Get(_HEADER_RETRY_AFTER))
+	}
+	if !strings.Contains(w.Body.String(), string(_ERR_RATE_LIMITED)) {
+		t.Errorf("Expected:[%s] Got:[%s]", _ERR_RATE_LIMITED, w.Body)
+	}
+	if w = request("2", "10.0.0.1"); w.Code != http.StatusOK {
+		t.Errorf("Expected:[200] Got:[%d %s]", w.Code, w.Body)
+	}
+	if w = request("3", "10.0.0.1"); w.Code != http.StatusTooManyRequests {
+		t.Errorf("Expected the address to be limited. Got:[%d %s]", w.Code, w.Body)
+	}
+	if w = request("4", "10.0.0.2"); w.Code != http.StatusOK {
+		t.Errorf("Expected:[200] Got:[%d %s]", w.Code, w.Body)
+	}
+}
@@ -2,21 +2,23 @@ package server
 
 import (
 	"encoding/json"
-	"fmt"
+	"errors"
 	"net/http"
+	"time"
 
 	"trippy/slotmachine"
 )
 
-type respGeneric struct {
-	Resp  string `json:"resp,omitempty"`
-	Error string `json:"err,omitempty"`
+// respError is the envelope of every error response
+type respError struct {
+	Error *apiError `json:"error"`
 }
 
 type respSpin struct {
-	Total int    `json:"total"`
-	Spins []spin `json:"spins"`
-	JWT   string `json:"jwt"`
+	Total    int          `json:"total"` // Jackpots won included
+	Spins    []spin       `json:"spins"`
+	Jackpots []jackpotWin `json:"jackpots,omitempty"`
+	JWT      string       `json:"jwt"`
 }
 
 type spin struct {
@@ -26,32 +28,168 @@ type spin struct {
 	Lines []slotmachine.WinLine `json:"lines"`
 }
 
+// v2 response schema
+
+type respSpinV2 struct {
+	Version       int      `json:"version"`
+	Round         string   `json:"round"` // ID of the round, found in the server logs
+	Machine       string   `json:"machine"`
+	Wager         int      `json:"wager"`
+	Total         int      `json:"total"`
+	FreeSpins     int      `json:"free_spins"` // Free spins awarded in the round
+	BalanceBefore int      `json:"balance_before"`
+	BalanceAfter  int      `json:"balance_after"`
+	Spins         []spinV2 `json:"spins"`
+	JWT           string   `json:"jwt,omitempty"` // Only in the rounds played alone
+
+	Jackpots      []jackpotWin   `json:"jackpots,omitempty"`       // Jackpots won, included in the total
+	JackpotValues []jackpotValue `json:"jackpot_values,omitempty"` // Jackpots of the machine after the round
+	Bonus         *bonusGame     `json:"bonus,omitempty"`          // Bonus game launched or picked by the round
+}
+
+type spinV2 struct {
+	Type        string             `json:"type"`
+	Total       int                `json:"total"`
+	Multiplier  int                `json:"multiplier"`
+	Stops       []int              `json:"stops"`
+	Grid        [][]symbol         `json:"grid"` // Visible window, grid[row][reel]
+	Lines       []winLineV2        `json:"lines"`
+	Scatters    int                `json:"scatters"`
+	FreeSpins   int                `json:"free_spins"`            // Free spins awarded by this spin
+	Jackpot     string             `json:"jackpot,omitempty"`     // Mystery jackpot awarded by a jackpot spin
+	Wilds       []wildV2           `json:"wilds,omitempty"`       // Cells turned wild by the wild behaviours
+	Multipliers []wildMultiplierV2 `json:"multipliers,omitempty"` // Multipliers carried by the wilds of the grid
+	Coins       []coinV2           `json:"coins,omitempty"`       // Coins held after a hold spin
+	Respins     int                `json:"respins,omitempty"`     // Respins left after a hold spin
+}
+
+// coinV2 is a coin held in a cell of the grid, with its credits in chips
+type coinV2 struct {
+	position
+	Value int `json:"value"`
+}
+
+// wildV2 are the cells of the grid turned wild by a behaviour: expanding, sticky or walking
+type wildV2 struct {
+	Behaviour string     `json:"behaviour"`
+	Cells     []position `json:"cells"`
+}
+
+// wildMultiplierV2 is the multiplier carried by the wild of a cell of the grid
+type wildMultiplierV2 struct {
+	position
+	Multiplier int `json:"multiplier"`
+}
+
+type winLineV2 struct {
+	Index          int        `json:"index"`
+	Symbol         symbol     `json:"symbol"`
+	Count          int        `json:"count"`
+	Payout         int        `json:"payout"`
+	Multiplier     int        `json:"multiplier"`                // Free spins and wilds multipliers of the line
+	WildMultiplier int        `json:"wild_multiplier,omitempty"` // Multiplier of the wilds paid, part of the multiplier
+	Positions      []position `json:"positions"`                 // Cells of the pay line, the first Count are paid
+	Symbols        []symbol   `json:"symbols"`                   // Symbols paid
+}
+
+type symbol struct {
+	ID   int    `json:"id"`
+	Name string `json:"name"`
+	Code string `json:"code"` // Short code displaying the symbol
+	Kind string `json:"kind"` // regular, wild, scatter, bonus, coin or blank
+}
+
+// position is a cell of the visible window, numbered from 1
+type position struct {
+	Reel int `json:"reel"`
+	Row  int `json:"row"`
+}
+
+// jackpotWin is a progressive jackpot won in a round
+type jackpotWin struct {
+	Jackpot string `json:"jackpot"`
+	Amount  int    `json:"amount"`
+	Reason  string `json:"reason"` // combination or must_hit_by
+}
+
+type jackpotValue struct {
+	Jackpot   string   `json:"jackpot"`
+	Amount    int      `json:"amount"`
+	MustHitBy int      `json:"must_hit_by,omitempty"` // Amount the jackpot is won before exceeding
+	Machines  []string `json:"machines"`              // Machines contributing to the jackpot
+}
+
+type bonusGame struct {
+	ID     string       `json:"id"`
+	Prizes int          `json:"prizes"` // Hidden prizes to pick from
+	Picks  int          `json:"picks"`  // Picks left
+	Won    int          `json:"won"`    // Chips won by the picks so far
+	Over   bool         `json:"over"`
+	Reveal []bonusPrize `json:"reveal"` // Prizes picked, all of them once the game is over
+}
+
+type bonusPrize struct {
+	Position int  `json:"position"` // From 1
+	Prize    int  `json:"prize"`
+	Picked   bool `json:"picked"`
+}
+
+type respJackpots struct {
+	Jackpots []jackpotValue `json:"jackpots"`
+}
+
 type userClaims struct {
 	UID   string `json:"uid"`
 	Chips int    `json:"chips"`
-	Bet   int    `json:"bet"`
+	Bet   int    `json:"bet"`             // Coins per line
+	Denom int    `json:"denom,omitempty"` // Coin value, machine default if 0
+	Lines int    `json:"lines,omitempty"` // Active pay lines, all lines if 0
+
+	Expiry int64 `json:"exp,omitempty"` // Unix time after which the token is rejected, never if 0
 }
 
-// UserClaims is always valid since we do not expire a JWT token
-// 'Valid' method is present to adhere to the jwt.Claims interface
-func (u userClaims) Valid() error { return nil }
+func (u userClaims) bet() slotmachine.Bet {
+	return slotmachine.Bet{Coins: u.Bet, Denom: u.Denom, Lines: u.Lines}
+}
+
+var errTokenExpired = errors.New("Token has expired")
+
+// Valid adheres to the jwt.Claims interface.
+// Tokens without an expiry are always valid.
+func (u userClaims) Valid() error {
+	if u.Expiry != 0 && time.Now().Unix() > u.Expiry {
+		return errTokenExpired
+	}
+	return nil
+}
 
 // ----------------------------- Response Methods ---------------------------------- //
 
 func writeSpinResponse(w http.ResponseWriter, statusCode int, resp respSpin) {
+	writeResponse(w, statusCode, resp)
+}
+
+func writeResponse(w http.ResponseWriter, statusCode int, resp interface{}) {
 	var respEncoder *json.Encoder = json.NewEncoder(w)
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
 	respEncoder.Encode(resp)
 }
 
-func respondWithError(w http.ResponseWriter, statusCode int, err error) {
+// respondWithError writes the error in the error envelope.
+// Errors which are not API errors are mapped to one through toAPIError.
+func respondWithError(w http.ResponseWriter, err error) {
+	respondWithMachineError(w, "", err)
+}
+
+// respondWithMachineError writes the error of a request to the machine, counted for the machine
+func respondWithMachineError(w http.ResponseWriter, machineName string, err error) {
 	var (
-		resp        respGeneric
-		respEncoder *json.Encoder = json.NewEncoder(w)
+		apiErr      = toAPIError(err)
+		respEncoder = json.NewEncoder(w)
 	)
+	observeError(machineName, apiErr.Code)
 	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(statusCode)
-	resp.Error = fmt.Sprintf("%s", err)
-	respEncoder.Encode(resp)
+	w.WriteHeader(apiErr.status)
+	respEncoder.Encode(respError{Error: apiErr})
 }
@@ -0,0 +1,123 @@
+//go:build !windows
+// +build !windows
+
+package server
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+const (
+	// Env variables set for a process started by a handoff
+	_ENV_LISTEN_FDS = "TRIPPY_LISTEN_FDS" // Number of listening sockets inherited, from fd 3
+	_ENV_PARENT_PID = "TRIPPY_PARENT_PID" // Process to stop once the sockets are served
+)
+
+// restartSignals start a zero-downtime restart
+var restartSignals = []os.Signal{syscall.SIGUSR2}
+
+var (
+	inheritedMu    sync.Mutex
+	inheritedFiles []*os.File // Listening sockets inherited, nil once used
+)
+
+func init() {
+	n, _ := strconv.Atoi(os.Getenv(_ENV_LISTEN_FDS))
+	for i := 0; i < n; i++ {
+		inheritedFiles = append(inheritedFiles, os.NewFile(uintptr(3+i), fmt.Sprintf("listener-%d", i)))
+	}
+	os.Unsetenv(_ENV_LISTEN_FDS)
+}
+
+// inheritedListener returns the listening socket at index inherited from the parent process.
+// A socket is returned only once, nil is returned if there is none.
+func inheritedListener(index int) (net.Listener, error) {
+	inheritedMu.Lock()
+	defer inheritedMu.Unlock()
+	if index >= len(inheritedFiles) || inheritedFiles[index] == nil {
+		return nil, nil
+	}
+	f := inheritedFiles[index]
+	inheritedFiles[index] = nil
+	defer f.Close()
+	l, err := net.FileListener(f)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to use inherited listener [Index:%d] [Error:%s]", index, err)
+	}
+	return l, nil
+}
+
+// handoff starts a new trippy process which inherits the listening sockets.
+// Once the new process serves them, it stops this one with SIGTERM, which drains its rounds.
+func (s *Server) handoff() error {
+	var files []*os.File
+	defer func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}()
+	for _, l := range []net.Listener{s.apiListener, s.redirectListener} {
+		if l == nil {
+			break
+		}
+		fl, ok := l.(interface{ File() (*os.File, error) })
+		if !ok {
+			return fmt.Errorf("Listener on [Addr:%s] cannot be handed off", l.Addr())
+		}
+		f, err := fl.File()
+		if err != nil {
+			return fmt.Errorf("Unable to get the file of listener [Addr:%s] [Error:%s]", l.Addr(), err)
+		}
+		files = append(files, f)
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("Unable to find the trippy executable [Error:%s]", err)
+	}
+	cmd := exec.Command(exe, os.Args[1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	cmd.ExtraFiles = files
+	cmd.Env = append(envWithout(os.Environ(), _ENV_LISTEN_FDS, _ENV_PARENT_PID),
+		fmt.Sprintf("%s=%d", _ENV_LISTEN_FDS, len(files)),
+		fmt.Sprintf("%s=%d", _ENV_PARENT_PID, os.Getpid()))
+	if err = cmd.Start(); err != nil {
+		return fmt.Errorf("Unable to start the new trippy process [Error:%s]", err)
+	}
+	slog.Info("Handed off the listeners to a new process", "pid", cmd.Process.Pid, "listeners", len(files))
+	return nil
+}
+
+// notifyParent stops the process which handed off its listeners, now that they are served
+func notifyParent() {
+	pid, err := strconv.Atoi(os.Getenv(_ENV_PARENT_PID))
+	os.Unsetenv(_ENV_PARENT_PID)
+	if err != nil || pid <= 1 {
+		return
+	}
+	slog.Info("Serving the handed off listeners, stopping the parent process", "parent_pid", pid)
+	if err = syscall.Kill(pid, syscall.SIGTERM); err != nil {
+		slog.Error("Unable to stop the parent process", "parent_pid", pid, "err", err)
+	}
+}
+
+func envWithout(env []string, names ...string) []string {
+	filtered := make([]string, 0, len(env))
+LOOP:
+	for _, kv := range env {
+		for _, name := range names {
+			if strings.HasPrefix(kv, name+"=") {
+				continue LOOP
+			}
+		}
+		filtered = append(filtered, kv)
+	}
+	return filtered
+}
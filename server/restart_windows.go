@@ -0,0 +1,20 @@
+package server
+
+import (
+	"errors"
+	"net"
+	"os"
+)
+
+// Zero-downtime restarts are not supported on Windows
+var restartSignals []os.Signal
+
+func inheritedListener(index int) (net.Listener, error) {
+	return nil, nil
+}
+
+func (s *Server) handoff() error {
+	return errors.New("Handoff of listeners is not supported on Windows")
+}
+
+func notifyParent() {}
@@ -3,16 +3,16 @@ package server
 import (
 	"fmt"
 	"io/ioutil"
-	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"runtime/debug"
 	"strings"
 	"syscall"
+	"time"
 
-	"trippy/slotmachine"
-	"trippy/slotmachine/engine/atkins"
+	"trippy/logger"
 )
 
 type Service interface {
@@ -23,100 +23,255 @@ type Service interface {
 
 type Server struct {
 	Id        string
+	Config    Config
 	webserver *http.Server
+
+	certs          *certReloader     // TLS key pair, nil if TLS is disabled
+	redirectServer *http.Server      // Redirects HTTP to HTTPS, nil if disabled
+	limits         *rateLimits       // Rate limits of the routes, nil if there are none
+	idempotency    *idempotencyStore // Responses of the rounds played with an Idempotency-Key
+	admin          *adminAPI         // Operator endpoints, nil if disabled
+
+	// Listening sockets, handed off to the new process on a zero-downtime restart
+	apiListener      net.Listener
+	redirectListener net.Listener
 }
 
 var (
-	slog              *log.Logger             // Stdout Logger
-	apiKey            string                  // API key used for encrypting teh JWT
-	atkinsDietMachine slotmachine.SlotMachine // Slot machine engine
+	slog     = logger.Default()     // Structured logger, injected in the requests and rounds
+	apiKey   string                 // API key used for encrypting teh JWT
+	machines = newMachineRegistry() // Slot machines served
 )
 
 const (
+	// The webserver is restarted if it stops unexpectedly, unless it keeps failing
+	_WS_MAX_RESTARTS  = 5
+	_WS_STABLE_UPTIME = time.Minute // Uptime after which the restarts are counted again
+
 	// Env variable for API Key used to encrypt the JWT token
 	_API_KEY_PATH = "TRIPPY_API_KEY_PATH"
+
+	// Env variables for logging
+	_LOG_LEVEL     = "TRIPPY_LOG_LEVEL"     // debug, info, warn or error. Default: info
+	_LOG_FORMAT    = "TRIPPY_LOG_FORMAT"    // logfmt or json. Default: logfmt
+	_LOG_WIN_LINES = "TRIPPY_LOG_WIN_LINES" // true to log every paid line at debug level
 )
 
+// Initialize validates the config and sets up the logger, the API key and the machines
 func (s *Server) Initialize() error {
 
 	// Initialize server
 	s.Id, _ = os.Hostname()
 
+	if err := s.Config.Validate(); err != nil {
+		return err
+	}
+
 	// Log to stdout
-	slog = log.New(os.Stdout, "", 0)
-	slog.SetFlags(log.Lshortfile | log.Ldate | log.Ltime | log.Lmicroseconds)
-
-	// Checking the required environment variables
-	apiKeyFile := os.Getenv(_API_KEY_PATH)
-	if apiKeyFile == "" {
-		slog.Printf("Webserver API key file [Env:%s] not initialized", _API_KEY_PATH)
-		return fmt.Errorf("Webserver API key file not initialized")
+	level, _ := logger.ParseLevel(s.Config.Log.Level)
+	format, _ := logger.ParseFormat(s.Config.Log.Format)
+	slog = logger.New(os.Stdout, level, format).With("server", s.Id)
+	logger.SetDefault(slog)
+
+	apiKeyFile := s.Config.APIKeyPath
+	if key, err := ioutil.ReadFile(apiKeyFile); err != nil {
+		slog.Error("Unable to read Authentication Key", "file", apiKeyFile, "err", err)
+		return fmt.Errorf("Unable to read Webserver API key from [File:%s] [E:%s]", apiKeyFile, err)
 	} else {
-		if key, err := ioutil.ReadFile(apiKeyFile); err != nil {
-			slog.Printf("WARN: Unable to read Authentication Key from [File:%s] [E:%s]", apiKeyFile, err)
-			return fmt.Errorf("Unable to read Webserver API key from [File:%s] [E:%s]", apiKeyFile, err)
-		} else {
-			apiKey = strings.TrimSpace(string(key))
+		apiKey = strings.TrimSpace(string(key))
+	}
+
+	// Loading the TLS key pair, reloaded whenever the files change
+	if s.Config.TLS.Enabled {
+		certs, err := newCertReloader(s.Config.TLS.CertFile, s.Config.TLS.KeyFile)
+		if err != nil {
+			return err
+		}
+		if _, err = newTLSConfig(s.Config.TLS, certs); err != nil {
+			return err
+		}
+		s.certs = certs
+		go certs.watch(s.Config.TLS.ReloadInterval.Duration)
+	}
+
+	// Operator endpoints, authenticated with their own key
+	if s.Config.Admin.KeyPath != "" {
+		key, err := ioutil.ReadFile(s.Config.Admin.KeyPath)
+		if err != nil {
+			return fmt.Errorf("Unable to read admin key from [File:%s] [E:%s]", s.Config.Admin.KeyPath, err)
+		}
+		if strings.TrimSpace(string(key)) == "" {
+			return fmt.Errorf("Admin key in [File:%s] is empty", s.Config.Admin.KeyPath)
 		}
+		s.admin = &adminAPI{key: strings.TrimSpace(string(key)), logCfg: s.Config.Log}
+	}
+
+	s.idempotency = newIdempotencyStore(s.Config.IdempotencyTTL.Duration)
+
+	if len(s.Config.RateLimits) > 0 {
+		s.limits = newRateLimits(s.Config.RateLimits)
 	}
 
+	// Mystery jackpots of the machines, kept across their reloads
+	mysteries = newMysteryRegistry(s.Config.Storage)
+
 	// Initializing slot machines
-	atkinsDietMachine = atkins.NewAtkinsDietMachine()
+	for _, machineCfg := range s.Config.Machines {
+		machines.configure(machineCfg)
+		if !machineCfg.Enabled {
+			continue
+		}
+		machine, err := newMachine(machineCfg, s.Config.Log)
+		if err != nil {
+			return err
+		}
+		machines.set(machineCfg.Name, machine)
+		slog.Info("Machine enabled", "machine", machineCfg.Name, "engine", machineCfg.Engine, "definition", machineCfg.Definition)
+	}
+
+	// Progressive jackpots, the contributions being saved in the background
+	if len(s.Config.Jackpots) > 0 {
+		pools, err := newJackpots(s.Config.Jackpots, s.Config.Storage)
+		if err != nil {
+			return err
+		}
+		jackpots = pools
+		go jackpots.Persist(_JACKPOT_FLUSH_INTERVAL, func(err error) {
+			slog.Error("Unable to save jackpots", "err", err)
+		})
+		slog.Info("Jackpots enabled", "jackpots", len(s.Config.Jackpots))
+	}
+
+	// Bonus games wait for the picks of the players, across restarts with the file storage
+	games, err := newBonusGames(s.Config.Storage)
+	if err != nil {
+		return err
+	}
+	bonusGames = games
+
+	// Recent rounds looked up by GetRound and Replay, across restarts with the file storage
+	if history, err = newStoredHistory(_ROUND_HISTORY_SIZE, s.Config.Storage); err != nil {
+		return err
+	}
+
+	// Dependencies which must be healthy to play rounds
+	readiness.add("machines", checkMachines)
+	readiness.add("storage", checkStorage(s.Config.Storage))
+	readiness.add("history", checkHistory)
+	readiness.add("jackpots", checkJackpots)
+	readiness.add("bonus", checkDir(s.Config.Storage, _BONUS_DIR))
 
 	return nil
 }
 
+// Start serves the API until a stop signal is received.
+// The webserver is restarted if it stops unexpectedly, and the listeners are handed off
+// to a new process on a restart signal.
 func (s *Server) Start() (err error) {
-	slog.Printf("Trippy: [%s] starting up...", s.Id)
+	slog.Info("Trippy starting up...")
 
 	var (
-		wsServerStopped = make(chan struct{}, 1)
+		wsServerStopped = make(chan error, 1)
+		restarts        int
+		started         = time.Now()
 	)
 	// handler for signals. Capture ctrl-C and KILL signals
 	sigchan := make(chan os.Signal, 1)
 	// Reset undos the effect of any prior calls to Notify for the provided signals. If no signals are provided, all signal handlers will be reset.
 	signal.Reset()
-	signal.Notify(sigchan,
+	signal.Notify(sigchan, append([]os.Signal{
 		os.Interrupt,
 		os.Kill,
 		syscall.SIGINT,
 		syscall.SIGTERM,
-		syscall.SIGQUIT)
+		syscall.SIGQUIT}, restartSignals...)...)
 	defer func() {
 		if r := recover(); r != nil {
-			slog.Println("Trippy >> Recovered from panic: ", r)
-			slog.Printf("%s \n", debug.Stack())
-			debug.PrintStack()
+			slog.Error("Trippy recovered from panic", "panic", fmt.Sprint(r), "stack", string(debug.Stack()))
 			//Ungraceful shutdown of webserver
 			s.CloseWebServer()
 		}
-		slog.Printf("Trippy: [%s] shutting down...", s.Id)
+		slog.Info("Trippy shutting down...")
 	}()
 
-	s.StartWebServer(wsServerStopped)
-
-LOOP:
+	if err = s.StartWebServer(wsServerStopped); err != nil {
+		return err
+	}
+	if err = s.startRedirectServer(); err != nil {
+		s.CloseWebServer()
+		return err
+	}
+	// If the listeners were handed off, the previous process can stop now
+	notifyParent()
 
 	for {
 		select {
-		case <-wsServerStopped:
-			slog.Printf("Trippy:[%s] WebServer stopped. Restarting...", s.Id)
-			s.StartWebServer(wsServerStopped)
+		case wsErr := <-wsServerStopped:
+			if wsErr == nil {
+				// Closed by Stop or CloseWebServer
+				return nil
+			}
+			if time.Since(started) > _WS_STABLE_UPTIME {
+				restarts = 0
+			}
+			restarts++
+			if restarts > _WS_MAX_RESTARTS {
+				s.Stop()
+				return fmt.Errorf("WebServer stopped %d times, giving up [Error:%s]", _WS_MAX_RESTARTS, wsErr)
+			}
+			slog.Warn("WebServer stopped unexpectedly. Restarting...", "err", wsErr, "attempt", restarts)
+			time.Sleep(time.Duration(restarts) * time.Second)
+			started = time.Now()
+			if err = s.StartWebServer(wsServerStopped); err != nil {
+				wsServerStopped <- err
+			}
 
 		case sig := <-sigchan:
-			slog.Printf("Trippy received [Signal:%s]. Stopping [Server:%s]...", sig, s.Id)
-			s.Stop()
-			break LOOP
+			if isRestartSignal(sig) {
+				slog.Info("Trippy received restart signal. Handing off listeners...", "signal", sig)
+				if err = s.handoff(); err != nil {
+					slog.Error("Handoff failed, still serving", "err", err)
+				}
+				continue
+			}
+			slog.Info("Trippy received signal. Stopping...", "signal", sig)
+			return s.Stop()
 		}
 	}
-	return nil
 }
 
-func (s *Server) Stop() (err error) {
+func isRestartSignal(sig os.Signal) bool {
+	for _, restartSig := range restartSignals {
+		if sig == restartSig {
+			return true
+		}
+	}
+	return false
+}
 
-	// Graceful Shutdown of webserver
-	slog.Printf("Stopping Webserver...")
+// Stop stops accepting requests and shuts the webserver down gracefully, the requests in flight being served.
+// The rounds still in play after the shutdown timeout, free spins included, are then drained.
+func (s *Server) Stop() (err error) {
+	// Graceful Shutdown of webserver, the listeners are closed first
+	slog.Info("Stopping Webserver...")
 	s.StopWebServer()
+	s.stopRedirectServer()
+
+	slog.Info("Draining rounds in play...", "rounds", rounds.count(), "timeout", s.Config.DrainTimeout)
+	if !rounds.drain(s.Config.DrainTimeout.Duration) {
+		slog.Warn("Rounds still in play after the drain timeout", "rounds", rounds.count())
+	}
+
+	if s.certs != nil {
+		s.certs.close()
+	}
+	// No round contributes anymore
+	if jackpots != nil {
+		if err := jackpots.Close(); err != nil {
+			slog.Error("Unable to save jackpots", "err", err)
+		}
+	}
+	mysteries.close()
 
 	return nil
 }
@@ -0,0 +1,300 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"trippy/logger"
+	"trippy/slotmachine"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// Server-Sent Events of a streamed round
+const (
+	_EVENT_SPIN  = "spin"  // A spin, main or free, as soon as it is played
+	_EVENT_ROUND = "round" // Totals of the round, the new JWT and the stream of the next rounds
+	_EVENT_ERROR = "error" // Error envelope of a round which failed after its first spin
+
+	_PARA_STREAM = "stream"
+
+	_STREAM_IDLE_TIMEOUT = time.Minute // Time a stream waits for its next round before it ends
+)
+
+// streamSpin is the data of a spin event
+type streamSpin struct {
+	Index int `json:"index"` // Spin of the round, the main spin is 0
+	spinV2
+}
+
+// streamRound is the data of the round event, the spins were sent in their own events
+type streamRound struct {
+	respSpinV2
+	Spins  []spinV2 `json:"spins,omitempty"`
+	Stream string   `json:"stream,omitempty"` // ID of the stream playing the next rounds, none if it ends
+}
+
+// eventStream writes Server-Sent Events, flushing each one to the client.
+// The response headers are written with the first event.
+type eventStream struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+	started bool
+	id      int
+}
+
+func newEventStream(w http.ResponseWriter) (*eventStream, bool) {
+	flusher, ok := w.(http.Flusher)
+	return &eventStream{w: w, flusher: flusher}, ok
+}
+
+func (es *eventStream) send(event string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	if !es.started {
+		es.started = true
+		es.w.Header().Set("Content-Type", "text/event-stream")
+		es.w.Header().Set("Cache-Control", "no-cache")
+		es.w.Header().Set("X-Accel-Buffering", "no") // Disables the buffering of nginx proxies
+		es.w.WriteHeader(http.StatusOK)
+	}
+	es.id++
+	if _, err = fmt.Fprintf(es.w, "id: %d\nevent: %s\ndata: %s\n\n", es.id, event, payload); err != nil {
+		return err
+	}
+	es.flusher.Flush()
+	return nil
+}
+
+// sendError sends the error event of a round which failed once the stream started.
+// The response is marked as failed, its status was written and says otherwise.
+func (es *eventStream) sendError(apiErr *apiError) error {
+	failResponse(es.w)
+	return es.send(_EVENT_ERROR, respError{Error: apiErr})
+}
+
+// streamSession is a stream kept open for the next rounds of its player on its machine.
+// The rounds are the requests sent to the stream, played one at a time.
+type streamSession struct {
+	id          string
+	uid         string
+	machineName string
+	stream      *eventStream
+
+	mu     sync.Mutex    // Held while a round is played on the stream
+	ended  bool          // The stream is not written once ended
+	played chan struct{} // A round was played, the stream waits for the next one again
+	closed chan struct{} // Closed to end the stream, on shutdown
+}
+
+// streamSessions are the streams open, by ID
+type streamSessions struct {
+	mu       sync.Mutex
+	sessions map[string]*streamSession
+}
+
+var streams = &streamSessions{sessions: make(map[string]*streamSession)}
+
+func newStreamSession(uid, machineName string, stream *eventStream) *streamSession {
+	return &streamSession{
+		id:          newID(),
+		uid:         uid,
+		machineName: machineName,
+		stream:      stream,
+		played:      make(chan struct{}, 1),
+		closed:      make(chan struct{}),
+	}
+}
+
+// open lets the next rounds be played on the stream, it is not written by its request anymore
+func (ss *streamSessions) open(session *streamSession) {
+	ss.mu.Lock()
+	ss.sessions[session.id] = session
+	ss.mu.Unlock()
+}
+
+func (ss *streamSessions) get(id string) (*streamSession, bool) {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	session, ok := ss.sessions[id]
+	return session, ok
+}
+
+// end stops the rounds of the stream, the round in play on it finishes first
+func (ss *streamSessions) end(session *streamSession) {
+	ss.mu.Lock()
+	delete(ss.sessions, session.id)
+	ss.mu.Unlock()
+
+	session.mu.Lock()
+	session.ended = true
+	session.mu.Unlock()
+}
+
+// closeAll ends the streams open, for the webserver to shut down
+func (ss *streamSessions) closeAll() {
+	ss.mu.Lock()
+	defer ss.mu.Unlock()
+	for id, session := range ss.sessions {
+		close(session.closed)
+		delete(ss.sessions, id)
+	}
+}
+
+// wait keeps the stream open for the next rounds, until it is idle for _STREAM_IDLE_TIMEOUT,
+// the client goes away or the webserver shuts down
+func (session *streamSession) wait(ctx context.Context) {
+	// The stream outlives the write timeout of the webserver, it ends once idle
+	if rc, ok := ctx.Value(responseControllerKey{}).(*http.ResponseController); ok {
+		if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+			logger.FromContext(ctx).Debug("Stream: Unable to clear the write deadline", "err", err)
+		}
+	}
+	for {
+		select {
+		case <-session.played:
+		case <-time.After(_STREAM_IDLE_TIMEOUT):
+			return
+		case <-ctx.Done():
+			return
+		case <-session.closed:
+			return
+		}
+	}
+}
+
+// playStream plays a round, sending each spin on the stream while the engine plays it.
+// It returns the round with its new JWT, and the spins sent: errors are not sent on the stream.
+func playStream(ctx context.Context, log *logger.Logger, stream *eventStream, machineName string, machine slotmachine.SlotMachine, user userClaims) (round, int, error) {
+	var spins int
+	ctx = slotmachine.WithSpinObserver(ctx, func(result slotmachine.SpinResult) {
+		// A client gone does not stop the round, it is played to the end like any other
+		if err := stream.send(_EVENT_SPIN, streamSpin{Index: spins, spinV2: newSpinV2(machine, result)}); err != nil {
+			log.Debug("Stream: Unable to send spin", "err", err)
+		}
+		spins++
+	})
+	rnd, err := play(ctx, log, machineName, machine, user)
+	if err != nil {
+		return rnd, spins, err
+	}
+
+	// Engines which do not notify their spins have them sent once the round is over
+	for ; spins < len(rnd.results); spins++ {
+		stream.send(_EVENT_SPIN, streamSpin{Index: spins, spinV2: newSpinV2(machine, rnd.results[spins])})
+	}
+
+	user.Chips = rnd.balanceAfter()
+	if rnd.token, err = createToken(user, []byte(apiKey)); err != nil {
+		log.Error("Stream: Unable to create new JWT token", "err", err)
+		return rnd, spins, newAPIError(_ERR_INTERNAL, "Unable to generate new JWT")
+	}
+	return rnd, spins, nil
+}
+
+// SpinStream plays a round and streams each spin as Server-Sent Events while the engine plays it,
+// followed by the totals of the round. Errors before the first spin are sent as plain error responses.
+// The stream is then kept open for the next rounds of the player, sent to SpinStreamNext.
+func SpinStream(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	log := logger.FromContext(r.Context())
+	machineName := ps.ByName(_PARA_SPIN_MACHINE)
+
+	stream, ok := newEventStream(w)
+	if !ok {
+		log.Error("Stream: Response writer cannot flush")
+		respondWithMachineError(w, machineName, newAPIError(_ERR_INTERNAL, "Streaming is not supported"))
+		return
+	}
+	body, err := readBody(w, r)
+	if err != nil {
+		respondWithMachineError(w, machineName, newAPIError(_ERR_INVALID_REQUEST, fmt.Sprintf("Reading body failed. [Error:%s]", err)))
+		return
+	}
+	user, ok := requestUser(w, r, machineName, string(body))
+	if !ok {
+		return
+	}
+	machine, ok := requestMachine(w, machineName)
+	if !ok {
+		return
+	}
+
+	rnd, spins, err := playStream(r.Context(), log, stream, machineName, machine, user)
+	if err != nil {
+		if spins == 0 {
+			respondWithRoundError(w, machineName, err, rnd)
+			return
+		}
+		apiErr := toAPIError(err)
+		observeError(machineName, apiErr.Code)
+		stream.sendError(apiErr)
+		return
+	}
+
+	session := newStreamSession(user.UID, machineName, stream)
+	stream.send(_EVENT_ROUND, streamRound{respSpinV2: computeSpinResponseV2(rnd), Stream: session.id})
+	// The response kept for the Idempotency-Key is the first round, the next ones have their own requests
+	completeResponse(w)
+	streams.open(session)
+	defer streams.end(session)
+	session.wait(r.Context())
+}
+
+// SpinStreamNext plays the next round of a stream opened by SpinStream, for its player and machine.
+// The spins and the totals are sent on the stream, and the totals in the response.
+// Errors before the first spin are sent in the response only.
+func SpinStreamNext(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	log := logger.FromContext(r.Context())
+	machineName := ps.ByName(_PARA_SPIN_MACHINE)
+	streamID := ps.ByName(_PARA_STREAM)
+
+	body, err := readBody(w, r)
+	if err != nil {
+		respondWithMachineError(w, machineName, newAPIError(_ERR_INVALID_REQUEST, fmt.Sprintf("Reading body failed. [Error:%s]", err)))
+		return
+	}
+	user, ok := requestUser(w, r, machineName, string(body))
+	if !ok {
+		return
+	}
+	notFound := newAPIError(_ERR_NOT_FOUND, fmt.Sprintf("Stream:[%s] not found", streamID)).withDetail("stream", streamID)
+	session, found := streams.get(streamID)
+	if !found || session.uid != user.UID || session.machineName != machineName {
+		respondWithMachineError(w, machineName, notFound)
+		return
+	}
+	machine, ok := requestMachine(w, machineName)
+	if !ok {
+		return
+	}
+
+	session.mu.Lock()
+	defer session.mu.Unlock()
+	if session.ended {
+		respondWithMachineError(w, machineName, notFound)
+		return
+	}
+	rnd, spins, err := playStream(r.Context(), log, session.stream, machineName, machine, user)
+	if err != nil {
+		err = roundError(err, rnd)
+		if spins > 0 {
+			session.stream.send(_EVENT_ERROR, respError{Error: toAPIError(err)})
+		}
+		respondWithMachineError(w, machineName, err)
+		return
+	}
+
+	resp := streamRound{respSpinV2: computeSpinResponseV2(rnd), Stream: session.id}
+	session.stream.send(_EVENT_ROUND, resp)
+	select {
+	case session.played <- struct{}{}:
+	default:
+	}
+	writeResponse(w, http.StatusOK, resp)
+}
@@ -0,0 +1,218 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"trippy/slotmachine/engine/atkins"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+type sseEvent struct {
+	name string
+	data string
+}
+
+func readEvents(t *testing.T, body string) []sseEvent {
+	var (
+		events  []sseEvent
+		event   sseEvent
+		scanner = bufio.NewScanner(strings.NewReader(body))
+	)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "":
+			events = append(events, event)
+			event = sseEvent{}
+		case strings.HasPrefix(line, "event: "):
+			event.name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			event.data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+	return events
+}
+
+func streamRequest(t *testing.T, machineName string, user userClaims) *httptest.ResponseRecorder {
+	apiKey = "secret"
+	token, err := createToken(user, []byte(apiKey))
+	if err != nil {
+		t.Fatalf("Unable to create token [Error:%s]", err)
+	}
+	// The client goes away once the round is streamed, the stream is not kept for the next rounds
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	w := &roundRecorder{ResponseRecorder: httptest.NewRecorder(), cancel: cancel}
+	r := httptest.NewRequest(http.MethodPost, "/api/v2/machines/"+machineName+"/spins/stream", strings.NewReader(token)).WithContext(ctx)
+	SpinStream(w, r, httprouter.Params{{Key: _PARA_SPIN_MACHINE, Value: machineName}})
+	return w.ResponseRecorder
+}
+
+// roundRecorder cancels the request once the round event is written
+type roundRecorder struct {
+	*httptest.ResponseRecorder
+	cancel context.CancelFunc
+}
+
+func (rr *roundRecorder) Write(b []byte) (int, error) {
+	if strings.Contains(string(b), "event: "+_EVENT_ROUND+"\n") {
+		rr.cancel()
+	}
+	return rr.ResponseRecorder.Write(b)
+}
+
+func testStream(t *testing.T, machineName string) {
+	w := streamRequest(t, machineName, userClaims{UID: "123", Chips: 1000, Bet: 1})
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "text/event-stream" {
+		t.Fatalf("Expected:[200 text/event-stream] Got:[%d %s]", w.Code, w.Body)
+	}
+	events := readEvents(t, w.Body.String())
+	if len(events) < 2 || events[len(events)-1].name != _EVENT_ROUND {
+		t.Fatalf("Expected spin events and a round event. Got:[%+v]", events)
+	}
+
+	var total int
+	for i, event := range events[:len(events)-1] {
+		var spin streamSpin
+		if err := json.Unmarshal([]byte(event.data), &spin); event.name != _EVENT_SPIN || err != nil || spin.Index != i {
+			t.Fatalf("Expected spin:[%d] Got:[%+v] [Error:%v]", i, event, err)
+		}
+		total = total + spin.Total
+	}
+	var round respSpinV2
+	if err := json.Unmarshal([]byte(events[len(events)-1].data), &round); err != nil {
+		t.Fatalf("Unable to decode round [Error:%s]", err)
+	}
+	if round.Total != total || round.BalanceAfter != 1000-round.Wager+total || round.JWT == "" || len(round.Spins) != 0 {
+		t.Errorf("Expected the totals of the spins streamed. Spins total:[%d] Got:[%+v]", total, round)
+	}
+}
+
+func TestSpinStream(t *testing.T) {
+	machines.set(_ATKINS_DIET_MACHINE, atkins.NewAtkinsDietMachine())
+	testStream(t, _ATKINS_DIET_MACHINE)
+
+	// Spins of the engines which do not notify them are sent at the end
+	machines.set(_FIXED_MACHINE, &fixedMachine{wager: 20, payouts: []int{10}})
+	testStream(t, _FIXED_MACHINE)
+}
+
+func TestSpinStreamError(t *testing.T) {
+	machines.set(_ATKINS_DIET_MACHINE, atkins.NewAtkinsDietMachine())
+	w := streamRequest(t, _ATKINS_DIET_MACHINE, userClaims{UID: "123", Chips: 1, Bet: 1})
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), string(_ERR_INSUFFICIENT_CHIPS)) {
+		t.Errorf("Expected a plain error response. Got:[%d %s]", w.Code, w.Body)
+	}
+}
+
+// readRoundEvents reads the events of the stream up to the end of a round, the round or error event
+func readRoundEvents(t *testing.T, reader *bufio.Reader) []sseEvent {
+	var (
+		events []sseEvent
+		event  sseEvent
+	)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Stream ended before the round. Got:[%+v] [Error:%s]", events, err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case line == "":
+			events = append(events, event)
+			if event.name == _EVENT_ROUND || event.name == _EVENT_ERROR {
+				return events
+			}
+			event = sseEvent{}
+		case strings.HasPrefix(line, "event: "):
+			event.name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			event.data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
+
+func postStream(t *testing.T, url, token, key string) *http.Response {
+	r, _ := http.NewRequest(http.MethodPost, url, strings.NewReader(token))
+	if key != "" {
+		r.Header.Set(_HEADER_IDEMPOTENCY_KEY, key)
+	}
+	resp, err := http.DefaultClient.Do(r)
+	if err != nil {
+		t.Fatalf("Request failed [Error:%s]", err)
+	}
+	return resp
+}
+
+// The stream is kept open for the next rounds of the player, until the webserver shuts down
+func TestSpinStreamRounds(t *testing.T) {
+	apiKey = "secret"
+	machines.set(_ATKINS_DIET_MACHINE, atkins.NewAtkinsDietMachine())
+	s := &Server{idempotency: newIdempotencyStore(time.Hour)}
+	ts := httptest.NewServer(s.Handler())
+	defer ts.Close()
+	url := ts.URL + "/api/v2/machines/" + _ATKINS_DIET_MACHINE + "/spins/stream"
+	opening, _ := createToken(userClaims{UID: "123", Chips: 1000, Bet: 1}, []byte(apiKey))
+
+	resp := postStream(t, url, opening, "open")
+	defer resp.Body.Close()
+	stream := bufio.NewReader(resp.Body)
+	events := readRoundEvents(t, stream)
+	firstRound := events[len(events)-1].data
+	var first streamRound
+	if err := json.Unmarshal([]byte(firstRound), &first); err != nil || first.Stream == "" || first.JWT == "" {
+		t.Fatalf("Expected the round with its stream Got:[%+v] [Error:%v]", events, err)
+	}
+
+	// The next rounds are played on the stream with the new JWT
+	token := first.JWT
+	for i := 0; i < 2; i++ {
+		next := postStream(t, url+"/"+first.Stream, token, "")
+		var played streamRound
+		err := json.NewDecoder(next.Body).Decode(&played)
+		next.Body.Close()
+		if next.StatusCode != http.StatusOK || err != nil {
+			t.Fatalf("Expected:[200] Got:[%d] [Error:%v]", next.StatusCode, err)
+		}
+		events = readRoundEvents(t, stream)
+		var streamed streamRound
+		if err = json.Unmarshal([]byte(events[len(events)-1].data), &streamed); err != nil || events[0].name != _EVENT_SPIN ||
+			streamed.Total != played.Total || streamed.JWT != played.JWT || streamed.BalanceBefore != first.BalanceAfter {
+			t.Fatalf("Expected the round of the response on the stream:[%+v] Got:[%+v] [Error:%v]", played, events, err)
+		}
+		first, token = streamed, streamed.JWT
+	}
+
+	// The stream is the one of its player
+	other, _ := createToken(userClaims{UID: "456", Chips: 1000, Bet: 1}, []byte(apiKey))
+	if next := postStream(t, url+"/"+first.Stream, other, ""); next.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected:[404] Got:[%d]", next.StatusCode)
+	}
+
+	// A retry of the stream with its Idempotency-Key replays its first round only
+	replay := postStream(t, url, opening, "open")
+	body, _ := ioutil.ReadAll(replay.Body)
+	replay.Body.Close()
+	if replayed := readEvents(t, string(body)); replay.Header.Get(_HEADER_IDEMPOTENCY_REPLAYED) != "true" ||
+		len(replayed) == 0 || replayed[len(replayed)-1].data != firstRound {
+		t.Errorf("Expected the first round replayed:[%s] Got:[%d %s]", firstRound, replay.StatusCode, body)
+	}
+
+	streams.closeAll()
+	if _, err := ioutil.ReadAll(stream); err != nil {
+		t.Errorf("Expected the stream to end Got:[%s]", err)
+	}
+	if next := postStream(t, url+"/"+first.Stream, token, ""); next.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected:[404] Got:[%d]", next.StatusCode)
+	}
+}
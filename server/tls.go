@@ -0,0 +1,175 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// certReloader serves the certificate of the key pair files
+// and loads it again whenever one of the files changes
+type certReloader struct {
+	certFile, keyFile string
+
+	mu      sync.RWMutex
+	cert    *tls.Certificate
+	modTime time.Time // Latest modification time of the files loaded
+
+	stop chan struct{}
+}
+
+func newCertReloader(certFile, keyFile string) (*certReloader, error) {
+	cr := &certReloader{
+		certFile: certFile,
+		keyFile:  keyFile,
+		stop:     make(chan struct{}),
+	}
+	if err := cr.load(); err != nil {
+		return nil, err
+	}
+	return cr, nil
+}
+
+func (cr *certReloader) load() error {
+	modTime, err := cr.filesModTime()
+	if err != nil {
+		return err
+	}
+	cert, err := tls.LoadX509KeyPair(cr.certFile, cr.keyFile)
+	if err != nil {
+		return fmt.Errorf("Unable to load TLS key pair [Cert:%s] [Key:%s] [Error:%s]", cr.certFile, cr.keyFile, err)
+	}
+	cr.mu.Lock()
+	cr.cert = &cert
+	cr.modTime = modTime
+	cr.mu.Unlock()
+	return nil
+}
+
+// filesModTime returns the latest modification time of the key pair files
+func (cr *certReloader) filesModTime() (time.Time, error) {
+	var latest time.Time
+	for _, file := range []string{cr.certFile, cr.keyFile} {
+		info, err := os.Stat(file)
+		if err != nil {
+			return latest, fmt.Errorf("Unable to read TLS [File:%s] [Error:%s]", file, err)
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+// reloadIfChanged loads the key pair again if a file changed since the last load.
+// The certificate in use is kept if the new one cannot be loaded.
+func (cr *certReloader) reloadIfChanged() {
+	modTime, err := cr.filesModTime()
+	if err != nil {
+		slog.Warn("TLS certificate check failed", "err", err)
+		return
+	}
+	cr.mu.RLock()
+	changed := !modTime.Equal(cr.modTime)
+	cr.mu.RUnlock()
+	if !changed {
+		return
+	}
+	if err = cr.load(); err != nil {
+		slog.Error("TLS certificate reload failed, keeping the current certificate", "err", err)
+		return
+	}
+	slog.Info("TLS certificate reloaded", "cert", cr.certFile)
+}
+
+// watch checks the files for changes on every interval until close is called
+func (cr *certReloader) watch(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			cr.reloadIfChanged()
+		case <-cr.stop:
+			return
+		}
+	}
+}
+
+func (cr *certReloader) close() {
+	close(cr.stop)
+}
+
+// getCertificate adheres to tls.Config.GetCertificate
+func (cr *certReloader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	cr.mu.RLock()
+	defer cr.mu.RUnlock()
+	return cr.cert, nil
+}
+
+// newTLSConfig builds the TLS config of the webserver.
+// Client certificates are verified if they are sent, and required by clientCertMiddleware.
+func newTLSConfig(cfg TLSConfig, certs *certReloader) (*tls.Config, error) {
+	tlsConfig := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: certs.getCertificate,
+	}
+	if cfg.ClientCAFile == "" {
+		return tlsConfig, nil
+	}
+	caPEM, err := ioutil.ReadFile(cfg.ClientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("Unable to read client CA [File:%s] [Error:%s]", cfg.ClientCAFile, err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caPEM) {
+		return nil, fmt.Errorf("No certificate found in client CA [File:%s]", cfg.ClientCAFile)
+	}
+	tlsConfig.ClientCAs = pool
+	tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	return tlsConfig, nil
+}
+
+// clientCertMiddleware rejects the requests to the operator paths
+// which are not made with a verified client certificate
+func clientCertMiddleware(paths []string) func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	return func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		if !hasPathPrefix(r.URL.Path, paths) || (r.TLS != nil && len(r.TLS.VerifiedChains) > 0) {
+			next(w, r)
+			return
+		}
+		slog.Warn("Request blocked, no verified client certificate", "path", r.URL.Path, "remote", r.RemoteAddr)
+		respondWithError(w, newAPIError(_ERR_CLIENT_CERT_REQUIRED, ""))
+	}
+}
+
+func hasPathPrefix(path string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// httpsRedirect redirects every request to the same URL on the HTTPS address
+func httpsRedirect(httpsListen string) http.Handler {
+	_, port, _ := net.SplitHostPort(httpsListen)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(r.Host); err == nil {
+			host = h
+		}
+		if port != "" && port != "443" {
+			host = net.JoinHostPort(host, port)
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), http.StatusMovedPermanently)
+	})
+}
@@ -0,0 +1,143 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeKeyPair writes a self-signed key pair for the common name
+func writeKeyPair(t *testing.T, dir, commonName string) (certFile, keyFile string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{commonName},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile = filepath.Join(dir, "cert.pem")
+	keyFile = filepath.Join(dir, "key.pem")
+	ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600)
+	ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600)
+	return certFile, keyFile
+}
+
+func commonName(t *testing.T, cr *certReloader) string {
+	cert, _ := cr.getCertificate(nil)
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return leaf.Subject.CommonName
+}
+
+func TestCertReload(t *testing.T) {
+	dir, err := ioutil.TempDir("", "trippy-tls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	certFile, keyFile := writeKeyPair(t, dir, "first.trippy")
+	cr, err := newCertReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatalf("Unable to load key pair [Error:%s]", err)
+	}
+	if name := commonName(t, cr); name != "first.trippy" {
+		t.Fatalf("Expected:[first.trippy] Got:[%s]", name)
+	}
+
+	// Unchanged files are not reloaded
+	cr.reloadIfChanged()
+	if name := commonName(t, cr); name != "first.trippy" {
+		t.Fatalf("Expected:[first.trippy] Got:[%s]", name)
+	}
+
+	writeKeyPair(t, dir, "second.trippy")
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(certFile, later, later)
+	cr.reloadIfChanged()
+	if name := commonName(t, cr); name != "second.trippy" {
+		t.Errorf("Expected:[second.trippy] Got:[%s]", name)
+	}
+
+	// A broken key pair keeps the current certificate
+	ioutil.WriteFile(keyFile, []byte("broken"), 0600)
+	later = later.Add(time.Minute)
+	os.Chtimes(keyFile, later, later)
+	cr.reloadIfChanged()
+	if name := commonName(t, cr); name != "second.trippy" {
+		t.Errorf("Expected:[second.trippy] Got:[%s]", name)
+	}
+}
+
+var (
+	redirectSamples = []redirectSample{
+		{listen: ":443", url: "http://trippy.io/api/machines?x=1", location: "https://trippy.io/api/machines?x=1"},
+		{listen: ":7443", url: "http://trippy.io:8080/", location: "https://trippy.io:7443/"},
+	}
+)
+
+type redirectSample struct {
+	listen, url, location string
+}
+
+func TestHTTPSRedirect(t *testing.T) {
+	for _, sample := range redirectSamples {
+		w := httptest.NewRecorder()
+		httpsRedirect(sample.listen).ServeHTTP(w, httptest.NewRequest(http.MethodGet, sample.url, nil))
+		if w.Code != http.StatusMovedPermanently || w.Header().Get("Location") != sample.location {
+			t.Errorf("URL:[%s] Expected:[%s] Got:[%d %s]", sample.url, sample.location, w.Code, w.Header().Get("Location"))
+		}
+	}
+}
+
+func TestClientCertMiddleware(t *testing.T) {
+	middleware := clientCertMiddleware([]string{"/metrics", "/admin/"})
+	next := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }
+
+	verified := &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{}}}
+	samples := []struct {
+		path   string
+		tls    *tls.ConnectionState
+		status int
+	}{
+		{"/api/machines/atkins-diet/spins", nil, http.StatusOK},
+		{"/metrics", nil, http.StatusForbidden},
+		{"/admin/machines", &tls.ConnectionState{}, http.StatusForbidden},
+		{"/admin/machines", verified, http.StatusOK},
+	}
+	for _, sample := range samples {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, sample.path, nil)
+		r.TLS = sample.tls
+		middleware(w, r, next)
+		if w.Code != sample.status {
+			t.Errorf("Path:[%s] Expected:[%d] Got:[%d]", sample.path, sample.status, w.Code)
+		}
+	}
+}
@@ -1,15 +1,24 @@
 package server
 
 import (
+	"bytes"
 	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io/ioutil"
+	"net"
 	"net/http"
 	"runtime/debug"
+	"strconv"
 	"time"
 
+	"trippy/jackpot"
+	"trippy/logger"
 	"trippy/slotmachine"
+	"trippy/slotmachine/bonus"
 	"trippy/slotmachine/engine/atkins"
 
 	"github.com/dgrijalva/jwt-go"
@@ -18,12 +27,19 @@ import (
 )
 
 const (
+	// Defaults of the config
 	_WEBSERVER_PORT      = "7070"
 	_WS_SHUTDOWN_TIMEOUT = 5 * time.Second
 
 	_ATKINS_DIET_MACHINE = "atkins-diet"
 
 	_PARA_SPIN_MACHINE = "machine"
+
+	_HEADER_REQUEST_ID = "X-Request-ID"
+
+	_REQUEST_ID_MAX_LENGTH = 128 // Longest request ID taken from the client
+
+	_MAX_BODY_BYTES = 1 << 20 // Largest body of the player requests
 )
 
 var (
@@ -32,88 +48,256 @@ var (
 	}
 )
 
-func (s *Server) StartWebServer(stopped chan struct{}) {
-	slog.Println("WebServer starting...")
-
-	router := httprouter.New()
-	router.GET("/", Home)                             // Root
-	router.GET("/hello/:name", Hello)                 // Hello test API
-	router.POST("/api/machines/:machine/spins", Spin) // Spin the respective slot machine
-
-	neg := negroni.Classic()
-	//neg.Use(negroni.HandlerFunc(authMiddleware))
-	neg.UseHandler(router)
+// StartWebServer serves the API in the background.
+// The error it stopped with is sent on stopped, nil if it was shut down or closed.
+func (s *Server) StartWebServer(stopped chan error) error {
+	slog.Info("WebServer starting...", "listen", s.Config.Listen)
 
 	httpsrv := &http.Server{
-		Addr:    ":" + _WEBSERVER_PORT,
-		Handler: neg,
+		Addr:         s.Config.Listen,
+		Handler:      s.Handler(),
+		ReadTimeout:  s.Config.ReadTimeout.Duration,
+		WriteTimeout: s.Config.WriteTimeout.Duration,
+		IdleTimeout:  s.Config.IdleTimeout.Duration,
+		Protocols:    new(http.Protocols),
+	}
+	httpsrv.Protocols.SetHTTP1(true)
+	httpsrv.Protocols.SetHTTP2(true)
+	// HTTP/2 without TLS is accepted for the gRPC clients, with prior knowledge, only if the operator enables it
+	httpsrv.Protocols.SetUnencryptedHTTP2(s.Config.H2C && s.certs == nil)
+	if s.certs != nil {
+		// Checked in Initialize
+		httpsrv.TLSConfig, _ = newTLSConfig(s.Config.TLS, s.certs)
+	}
+
+	listener, err := listen(0, s.Config.Listen)
+	if err != nil {
+		slog.Error("Webserver unable to listen", "listen", s.Config.Listen, "err", err)
+		return err
 	}
 
 	go func() {
+		var err error
 		defer func() {
 			if r := recover(); r != nil {
-				slog.Println("WebServer: Recovered from panic", r)
-				debug.PrintStack()
+				slog.Error("WebServer recovered from panic", "panic", fmt.Sprint(r), "stack", string(debug.Stack()))
+				err = fmt.Errorf("WebServer panic [%v]", r)
 			}
 
-			slog.Println("WebServer exiting...")
-			stopped <- struct{}{}
+			slog.Info("WebServer exiting...")
+			stopped <- err
 		}()
-		if err := httpsrv.ListenAndServe(); err != nil {
-			// ErrServerClosed is returned after every call to Shutdown/Close.
-			// Shutdown/Close of webserver should not be reported as an error
-			if err.Error() != http.ErrServerClosed.Error() {
-				slog.Printf("Error: Webserver: Http Server:ListenAndServe() [E:%s]", err)
-			}
+		if httpsrv.TLSConfig != nil {
+			// The key pair is served by the TLS config
+			err = httpsrv.ServeTLS(listener, "", "")
+		} else {
+			err = httpsrv.Serve(listener)
+		}
+		// ErrServerClosed is returned after every call to Shutdown/Close.
+		// Shutdown/Close of webserver should not be reported as an error
+		if err == http.ErrServerClosed {
+			err = nil
+		} else if err != nil {
+			slog.Error("Webserver Serve failed", "err", err)
 		}
 	}()
 
+	// The streams waiting for their next round end with the shutdown
+	httpsrv.RegisterOnShutdown(streams.closeAll)
 	s.webserver = httpsrv
+	s.apiListener = listener
+	return nil
+}
+
+// Handler routes the requests of the API through its middlewares, as served by the webserver
+func (s *Server) Handler() http.Handler {
+	idempotent := s.idempotency.idempotent
+	router := httprouter.New()
+	router.GET("/", Home)                                                             // Root
+	router.GET("/hello/:name", Hello)                                                 // Hello test API
+	router.GET("/healthz", Healthz)                                                   // Liveness
+	router.GET("/readyz", Readyz)                                                     // Readiness
+	router.POST("/api/machines/:machine/spins", idempotent(Spin))                     // Spin the respective slot machine
+	router.POST("/api/v2/machines/:machine/spins", idempotent(SpinV2))                // Spin with the v2 response schema
+	router.POST("/api/v2/machines/:machine/autoplay", idempotent(Autoplay))           // Play up to 100 rounds in a row
+	router.POST("/api/v2/machines/:machine/spins/stream", idempotent(SpinStream))     // Stream the spins as they are played
+	router.POST("/api/v2/machines/:machine/bonus/:game/picks", idempotent(BonusPick)) // Pick a prize of a bonus game
+	router.GET("/api/v2/jackpots", Jackpots)                                          // Current amount of the progressive jackpots
+	router.Handler(http.MethodPost, _GRPC_ROUTE, GRPC(newGRPCServer(s.limits)))       // gRPC service over HTTP/2
+	// Next round of a stream, played while the stream is open
+	router.POST("/api/v2/machines/:machine/spins/stream/:stream", idempotent(SpinStreamNext))
+	if s.admin != nil {
+		s.admin.register(router) // Operator endpoints under /admin/
+	}
+	router.NotFound = http.HandlerFunc(notFound)
+	router.MethodNotAllowed = http.HandlerFunc(methodNotAllowed)
+	router.PanicHandler = panicHandler
+
+	neg := negroni.New(negroni.NewRecovery(), negroni.HandlerFunc(requestLogger))
+	//neg.Use(negroni.HandlerFunc(authMiddleware))
+	if s.Config.TLS.ClientCAFile != "" {
+		neg.Use(negroni.HandlerFunc(clientCertMiddleware(s.Config.TLS.ClientCertPaths)))
+	}
+	if s.limits != nil {
+		neg.Use(negroni.HandlerFunc(s.limits.middleware))
+	}
+	neg.UseHandler(router)
+	// The writer of the connection is wrapped by the middlewares, the streams reach its deadline from the context
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		neg.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), responseControllerKey{}, http.NewResponseController(w))))
+	})
+}
+
+// responseControllerKey is the context key of the controller of the connection's writer
+type responseControllerKey struct{}
+
+// listen returns the listener at index handed off by the previous process,
+// or a new listener on the address if there is none
+func listen(index int, addr string) (net.Listener, error) {
+	if l, err := inheritedListener(index); l != nil || err != nil {
+		return l, err
+	}
+	return net.Listen("tcp", addr)
 }
 
 // Shutdown is a graceful shutdown of webserver
 func (s *Server) StopWebServer() {
-	ctx, _ := context.WithTimeout(context.Background(), _WS_SHUTDOWN_TIMEOUT)
-	slog.Printf("Webserver: Starting Graceful Shutdown with [Timeout:%s]..", _WS_SHUTDOWN_TIMEOUT)
+	timeout := s.Config.ShutdownTimeout.Duration
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	slog.Info("Webserver starting graceful shutdown", "timeout", timeout)
 	if err := s.webserver.Shutdown(ctx); err != nil {
-		slog.Printf("Error: Webserver Shutdown [E:%s]", err)
+		slog.Error("Webserver shutdown failed", "err", err)
 	} else {
-		slog.Printf("Webserver Shutdown successful")
+		slog.Info("Webserver shutdown successful")
+	}
+}
+
+// startRedirectServer serves the redirects from HTTP to HTTPS, if enabled
+func (s *Server) startRedirectServer() error {
+	if s.certs == nil || s.Config.TLS.RedirectListen == "" {
+		return nil
+	}
+	listener, err := listen(1, s.Config.TLS.RedirectListen)
+	if err != nil {
+		slog.Error("Redirect webserver unable to listen", "listen", s.Config.TLS.RedirectListen, "err", err)
+		return err
+	}
+	s.redirectListener = listener
+	s.redirectServer = &http.Server{
+		Addr:         s.Config.TLS.RedirectListen,
+		Handler:      httpsRedirect(s.Config.Listen),
+		ReadTimeout:  s.Config.ReadTimeout.Duration,
+		WriteTimeout: s.Config.WriteTimeout.Duration,
+	}
+	slog.Info("Redirect webserver starting...", "listen", s.Config.TLS.RedirectListen)
+	go func() {
+		if err := s.redirectServer.Serve(listener); err != nil && err != http.ErrServerClosed {
+			slog.Error("Redirect webserver Serve failed", "err", err)
+		}
+	}()
+	return nil
+}
+
+func (s *Server) stopRedirectServer() {
+	if s.redirectServer == nil {
+		return
+	}
+	if err := s.redirectServer.Close(); err != nil {
+		slog.Error("Redirect webserver close failed", "err", err)
 	}
 }
 
 // Close is an ungraceful shutdown of webserver
 func (s *Server) CloseWebServer() {
-	slog.Printf("Webserver: Closing without waiting for active connections..")
+	slog.Warn("Webserver closing without waiting for active connections..")
 	if err := s.webserver.Close(); err != nil {
-		slog.Printf("Error: Webserver Close [E:%s]", err)
+		slog.Error("Webserver close failed", "err", err)
 	} else {
-		slog.Printf("Webserver Close successful")
+		slog.Info("Webserver close successful")
 	}
 }
 
 /*
 func authMiddleware(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
 	if _, ok := middlewareIgnoreList[r.URL.Path]; ok {
-		slog.Printf("Auth Middleware: [%s] in ignore list", r.URL.Path)
+		slog.Debug("Auth Middleware: path in ignore list", "path", r.URL.Path)
 		next(w, r)
 		return
 	}
 	token := r.Header.Get("Token")
 	if token == "" {
-		slog.Printf("ERR: Request:[%s] blocked, no token in Request", r.URL)
-		respondWithError(w, http.StatusUnauthorized, fmt.Errorf("Please provide a token"))
+		slog.Warn("Request blocked, no token in request", "url", r.URL)
+		respondWithError(w, newAPIError(_ERR_INVALID_TOKEN, "Please provide a token"))
 		return
 	}
 	if token != apiKey {
-		slog.Printf("ERR: Request:[%s] blocked, invalid token [Token:%s]", r.URL, token)
-		respondWithError(w, http.StatusUnauthorized, fmt.Errorf("Invalid token"))
+		slog.Warn("Request blocked, invalid token", "url", r.URL)
+		respondWithError(w, newAPIError(_ERR_INVALID_TOKEN, ""))
 		return
 	}
 	next(w, r)
 }
 */
 
+// requestLogger injects a logger with the request ID in the request context
+// and logs every request once it is served
+func requestLogger(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	start := time.Now()
+	requestID := r.Header.Get(_HEADER_REQUEST_ID)
+	if !validRequestID(requestID) {
+		requestID = newID()
+	}
+	w.Header().Set(_HEADER_REQUEST_ID, requestID)
+
+	log := slog.With("request_id", requestID)
+	next(w, r.WithContext(logger.NewContext(r.Context(), log)))
+
+	status := 0
+	if rw, ok := w.(negroni.ResponseWriter); ok {
+		status = rw.Status()
+	}
+	log.Info("Request served", "method", r.Method, "path", r.URL.Path, "status", status, "duration", time.Since(start))
+}
+
+// validRequestID returns true if the request ID of the client can be logged and sent back as it is:
+// at most _REQUEST_ID_MAX_LENGTH letters, digits, dots, underscores and dashes
+func validRequestID(id string) bool {
+	if id == "" || len(id) > _REQUEST_ID_MAX_LENGTH {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9', c == '.', c == '_', c == '-':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// newID returns a random ID used to correlate logs
+func newID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+	return hex.EncodeToString(b)
+}
+
+func notFound(w http.ResponseWriter, r *http.Request) {
+	respondWithError(w, newAPIError(_ERR_NOT_FOUND, fmt.Sprintf("Path:[%s] not found", r.URL.Path)))
+}
+
+func methodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	respondWithError(w, newAPIError(_ERR_METHOD_NOT_ALLOWED, fmt.Sprintf("Method:[%s] not allowed on Path:[%s]", r.Method, r.URL.Path)))
+}
+
+func panicHandler(w http.ResponseWriter, r *http.Request, rcv interface{}) {
+	logger.FromContext(r.Context()).Error("Request recovered from panic", "panic", fmt.Sprint(rcv), "stack", string(debug.Stack()))
+	respondWithError(w, newAPIError(_ERR_INTERNAL, ""))
+}
+
 func Home(w http.ResponseWriter, r *http.Request, _ httprouter.Params) {
 	fmt.Fprint(w, "Welcome to the slot machine!\n")
 }
@@ -122,59 +306,235 @@ func Hello(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	fmt.Fprintf(w, "Hello, Trippy %s!\n", ps.ByName("name"))
 }
 
+// Spin plays one round and responds with the v1 schema
 func Spin(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
-	machine := ps.ByName(_PARA_SPIN_MACHINE)
-	if machine == "" {
-		slog.Printf("Spin: Parameter:[%s] is empty", _PARA_SPIN_MACHINE)
-		respondWithError(w, http.StatusBadRequest, fmt.Errorf("Parameter:[%s] cannot be empty", _PARA_SPIN_MACHINE))
+	rnd, ok := playRound(w, r, ps)
+	if !ok {
 		return
 	}
+	response := computeSpinResponse(rnd.payout, rnd.results)
+	response.Jackpots = newJackpotWins(rnd.jackpots)
+	response.JWT = rnd.token
+	writeSpinResponse(w, http.StatusOK, response)
+}
 
-	body, err := ioutil.ReadAll(r.Body)
-	if err != nil {
-		slog.Printf("Spin: Unable to read body [Error:%s]", err)
-		respondWithError(w, http.StatusBadRequest, fmt.Errorf("Reading body failed. [Error:%s]", err))
+// SpinV2 plays one round and responds with the v2 schema
+func SpinV2(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	rnd, ok := playRound(w, r, ps)
+	if !ok {
 		return
 	}
-	token := string(body)
-	if token == "" {
-		slog.Println("Spin: Body:[JWT Token] is empty")
-		respondWithError(w, http.StatusBadRequest, errors.New("Body:[JWT token] cannot be empty"))
-		return
+	writeResponse(w, http.StatusOK, computeSpinResponseV2(rnd))
+}
+
+// round is the outcome of a round played by a user
+type round struct {
+	id          string
+	machineName string
+	machine     slotmachine.SlotMachine
+	user        userClaims // Claims received, before the round was played
+	wager       int
+	payout      int
+	results     []slotmachine.SpinResult
+	token       string // New JWT with the chips after the round
+
+	jackpots      []jackpot.Win   // Jackpots won, included in the payout
+	jackpotValues []jackpot.Value // Jackpots of the machine after the round
+	bonus         *bonus.Game     // Bonus game launched or picked by the round
+}
+
+func (rnd round) balanceAfter() int {
+	return rnd.user.Chips - rnd.wager + rnd.payout
+}
+
+// playRound wagers and spins the machine in the request for the user in the JWT.
+// On failure the error is written to the response and ok is false.
+func playRound(w http.ResponseWriter, r *http.Request, ps httprouter.Params) (rnd round, ok bool) {
+	log := logger.FromContext(r.Context())
+	machineName := ps.ByName(_PARA_SPIN_MACHINE)
+	if machineName == "" {
+		log.Warn("Spin: Parameter is empty", "param", _PARA_SPIN_MACHINE)
+		respondWithError(w, newAPIError(_ERR_INVALID_REQUEST, fmt.Sprintf("Parameter:[%s] cannot be empty", _PARA_SPIN_MACHINE)))
+		return rnd, false
 	}
 
-	user, err := parseToken(token, []byte(apiKey))
+	body, err := readBody(w, r)
 	if err != nil {
-		slog.Printf("Spin: Parsing token failed [Error:%s]", err)
-		respondWithError(w, http.StatusBadRequest, errors.New("Invalid token received"))
-		return
+		log.Warn("Spin: Unable to read body", "err", err)
+		respondWithMachineError(w, machineName, newAPIError(_ERR_INVALID_REQUEST, fmt.Sprintf("Reading body failed. [Error:%s]", err)))
+		return rnd, false
+	}
+	user, ok := requestUser(w, r, machineName, string(body))
+	if !ok {
+		return rnd, false
+	}
+	machine, ok := requestMachine(w, machineName)
+	if !ok {
+		return rnd, false
+	}
+
+	rnd, err = play(r.Context(), log, machineName, machine, user)
+	if err != nil {
+		respondWithRoundError(w, machineName, err, rnd)
+		return rnd, false
+	}
+
+	user.Chips = rnd.balanceAfter()
+	rnd.token, err = createToken(user, []byte(apiKey))
+	if err != nil {
+		log.Error("Spin: Unable to create new JWT token", "err", err)
+		respondWithMachineError(w, machineName, newAPIError(_ERR_INTERNAL, "Unable to generate new JWT"))
+		return rnd, false
+	}
+	return rnd, true
+}
+
+// readBody reads the body of a player request, up to _MAX_BODY_BYTES.
+// The body is left to be read again by the next handlers.
+func readBody(w http.ResponseWriter, r *http.Request) ([]byte, error) {
+	if r.Body == nil {
+		return nil, nil
+	}
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, _MAX_BODY_BYTES))
+	r.Body.Close()
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	return body, err
+}
+
+type tokenKey struct{}
+
+// requestToken is the JWT of a request with its user, parsed once for the middlewares and the handler
+type requestToken struct {
+	token string
+	user  userClaims
+	err   error
+}
+
+// withToken parses the token and returns the request with it in its context
+func withToken(r *http.Request, token string) (*http.Request, userClaims, error) {
+	user, err := tokenUser(r.Context(), token)
+	ctx := context.WithValue(r.Context(), tokenKey{}, requestToken{token: token, user: user, err: err})
+	return r.WithContext(ctx), user, err
+}
+
+// tokenUser returns the user of the token, parsed by a middleware if it is the token of the request
+func tokenUser(ctx context.Context, token string) (userClaims, error) {
+	if parsed, ok := ctx.Value(tokenKey{}).(requestToken); ok && parsed.token == token {
+		return parsed.user, parsed.err
 	}
+	return parseToken(token, []byte(apiKey))
+}
 
-	if machine == _ATKINS_DIET_MACHINE {
-		wager, err := atkinsDietMachine.Wager(user.Bet, user.Chips)
-		if err != nil {
-			handleWagerError(w, err, wager, user)
-			return
+// bodyToken returns the JWT of a request body: the body itself,
+// or the jwt field of the JSON object sent to the endpoints with parameters
+func bodyToken(body []byte) string {
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) == 0 || trimmed[0] != '{' {
+		return string(body)
+	}
+	var req struct {
+		JWT string `json:"jwt"`
+	}
+	if err := json.Unmarshal(trimmed, &req); err != nil {
+		return ""
+	}
+	return req.JWT
+}
+
+// requestUser returns the user of the JWT sent by the client.
+// On failure the error is written to the response and ok is false.
+func requestUser(w http.ResponseWriter, r *http.Request, machineName, token string) (userClaims, bool) {
+	log := logger.FromContext(r.Context())
+	if token == "" {
+		log.Warn("Spin: Body [JWT Token] is empty")
+		respondWithMachineError(w, machineName, newAPIError(_ERR_INVALID_TOKEN, "Body:[JWT token] cannot be empty"))
+		return userClaims{}, false
+	}
+	user, err := tokenUser(r.Context(), token)
+	if err != nil {
+		log.Warn("Spin: Parsing token failed", "err", err)
+		if errors.Is(err, errTokenExpired) {
+			respondWithMachineError(w, machineName, err)
+			return user, false
 		}
-		payout, spinResults, err := atkinsDietMachine.Spin(user.Bet)
-		if err != nil {
-			slog.Printf("Spin failed for User:[%s] Error:[%s]", user.UID, err)
-			respondWithError(w, http.StatusInternalServerError, errors.New("Unable to spin"))
-			return
+		respondWithMachineError(w, machineName, newAPIError(_ERR_INVALID_TOKEN, ""))
+		return user, false
+	}
+	return user, true
+}
+
+// requestMachine returns the machine to play, if it is served and the server is not in maintenance.
+// On failure the error is written to the response and ok is false.
+func requestMachine(w http.ResponseWriter, machineName string) (slotmachine.SlotMachine, bool) {
+	machine, err := playableMachine(machineName)
+	if err != nil {
+		respondWithMachineError(w, machineName, err)
+		return nil, false
+	}
+	return machine, true
+}
+
+// playableMachine returns the machine to play, if it is served and the server is not in maintenance
+func playableMachine(machineName string) (slotmachine.SlotMachine, error) {
+	machine, found := getMachine(machineName)
+	if !found {
+		if _, configured := machines.config(machineName); configured {
+			return nil, newAPIError(_ERR_MACHINE_DISABLED, "").withDetail("machine", machineName)
 		}
-		response := computeSpinResponse(payout, spinResults)
-		user.Chips = user.Chips - wager + response.Total
-		token, err = createToken(user, []byte(apiKey))
-		if err != nil {
-			slog.Printf("Spin: Unable to create new JWT token for User:[%s] Error:[%s]", user.UID, err)
-			respondWithError(w, http.StatusInternalServerError, fmt.Errorf("Unable to generate new JWT [Error:%s]", err))
-			return
+		return nil, newAPIError(_ERR_UNKNOWN_MACHINE, fmt.Sprintf("Unknown machine:[%s]", machineName)).
+			withDetail("machine", machineName)
+	}
+
+	if enabled, message := maintenance.active(); enabled {
+		return nil, newAPIError(_ERR_MAINTENANCE, message)
+	}
+	return machine, nil
+}
+
+// play wagers and spins the machine for the user, without a new JWT.
+// The wager is set in the round if the bet was valid, even if the chips are insufficient.
+func play(ctx context.Context, log *logger.Logger, machineName string, machine slotmachine.SlotMachine, user userClaims) (round, error) {
+	// Rounds in play are drained before the server stops
+	if !rounds.begin() {
+		return round{}, newAPIError(_ERR_UNAVAILABLE, "")
+	}
+	defer rounds.end()
+
+	// Every round gets its own ID, added to all its logs
+	rnd := round{
+		id:          newID(),
+		machineName: machineName,
+		machine:     machine,
+		user:        user,
+	}
+	log = log.With("round", rnd.id, "uid", user.UID, "machine", machineName)
+
+	var err error
+	rnd.wager, err = machine.Wager(user.bet(), user.Chips)
+	if err != nil {
+		if errors.Is(err, atkins.ErrChipsInsufficient) {
+			log.Info("Chips not enough", "wager", rnd.wager, "chips", user.Chips)
+		} else {
+			log.Info("Wager rejected", "err", err, "bet", user.Bet, "denom", user.Denom, "lines", user.Lines, "chips", user.Chips)
 		}
-		response.JWT = token
-		writeSpinResponse(w, http.StatusOK, response)
-		return
+		return rnd, wagerError(err)
+	}
+	start := time.Now()
+	rnd.payout, rnd.results, err = machine.Spin(slotmachine.WithWager(logger.NewContext(ctx, log), rnd.wager), user.bet())
+	if err != nil {
+		log.Error("Spin failed", "err", err)
+		return rnd, newAPIError(_ERR_SPIN_FAILED, "")
 	}
-	respondWithError(w, http.StatusBadRequest, fmt.Errorf("Unknown machine:[%s]", machine))
+	// The metrics count the jackpots won in the payout
+	playJackpots(log, &rnd)
+	observeRound(machineName, rnd.wager, rnd.payout, rnd.results, time.Since(start))
+	startBonus(log, &rnd)
+
+	history.add(rnd)
+
+	log.Info("Round played", "wager", rnd.wager, "payout", rnd.payout, "spins", len(rnd.results),
+		"balance_before", user.Chips, "balance_after", rnd.balanceAfter())
+	return rnd, nil
 }
 
 func parseToken(tokenString string, secret []byte) (userClaims, error) {
@@ -187,6 +547,9 @@ func parseToken(tokenString string, secret []byte) (userClaims, error) {
 	})
 
 	if err != nil {
+		if vErr, ok := err.(*jwt.ValidationError); ok && errors.Is(vErr.Inner, errTokenExpired) {
+			return *user, errTokenExpired
+		}
 		return *user, fmt.Errorf("Could not parse JWT token [Error:%s]", err)
 	}
 
@@ -202,21 +565,21 @@ func createToken(claims userClaims, secret []byte) (string, error) {
 	return token.SignedString(secret)
 }
 
-func handleWagerError(w http.ResponseWriter, err error, wager int, user userClaims) {
-	if err == atkins.ErrInvalidBet {
-		slog.Printf("User:[%s] Invalid Bet. Bet:[%d] Chips:[%d]", user.UID, user.Bet, user.Chips)
-		respondWithError(w, http.StatusBadRequest, atkins.ErrInvalidBet)
-		return
-	}
-	if err == atkins.ErrChipsInsufficient {
-		slog.Printf("User:[%s] Chips not enough. Wager:[%d] Chips:[%d]", user.UID, wager, user.Chips)
-		respondWithError(w, http.StatusBadRequest, fmt.Errorf("Chips insufficient. Need [%d] chips more.", wager-user.Chips))
-		return
-	}
-	slog.Printf("User:[%s] Wager Error:[%s] Bet:[%d] Chips:[%d]", user.UID, err, user.Bet, user.Chips)
-	respondWithError(w, http.StatusBadRequest, err)
-	return
+// respondWithRoundError writes the error of a round of the machine which could not be played
+func respondWithRoundError(w http.ResponseWriter, machineName string, err error, rnd round) {
+	respondWithMachineError(w, machineName, roundError(err, rnd))
+}
 
+// roundError adds the chips missing to the errors of the rounds the player cannot afford
+func roundError(err error, rnd round) error {
+	if errors.Is(err, atkins.ErrChipsInsufficient) {
+		chips := rnd.user.Chips
+		return newAPIError(_ERR_INSUFFICIENT_CHIPS, fmt.Sprintf("Chips insufficient. Need [%d] chips more.", rnd.wager-chips)).
+			withDetail("wager", rnd.wager).
+			withDetail("chips", chips).
+			withDetail("required", rnd.wager-chips)
+	}
+	return err
 }
 
 func computeSpinResponse(payout int, spinResults []slotmachine.SpinResult) respSpin {
@@ -231,3 +594,92 @@ func computeSpinResponse(payout int, spinResults []slotmachine.SpinResult) respS
 	}
 	return response
 }
+
+// symbolCatalogue is implemented by the machines which describe their symbols
+type symbolCatalogue interface {
+	SymbolInfo(slotmachine.Symbol) slotmachine.SymbolInfo
+}
+
+func newSymbol(machine slotmachine.SlotMachine, s slotmachine.Symbol) symbol {
+	info := slotmachine.Symbols(nil).Info(s)
+	if catalogue, ok := slotmachine.Capability[symbolCatalogue](machine); ok {
+		info = catalogue.SymbolInfo(s)
+	}
+	return symbol{ID: int(s), Name: info.Name, Code: info.Code, Kind: string(info.Kind)}
+}
+
+func computeSpinResponseV2(rnd round) respSpinV2 {
+	response := respSpinV2{
+		Version:       2,
+		Round:         rnd.id,
+		Machine:       rnd.machineName,
+		Wager:         rnd.wager,
+		Total:         rnd.payout,
+		BalanceBefore: rnd.user.Chips,
+		BalanceAfter:  rnd.balanceAfter(),
+		Spins:         make([]spinV2, len(rnd.results)),
+		JWT:           rnd.token,
+		Jackpots:      newJackpotWins(rnd.jackpots),
+		JackpotValues: newJackpotValues(rnd.jackpotValues),
+		Bonus:         newBonusGame(rnd.bonus),
+	}
+	for i, spinResult := range rnd.results {
+		response.FreeSpins = response.FreeSpins + spinResult.FreeSpins
+		response.Spins[i] = newSpinV2(rnd.machine, spinResult)
+	}
+	return response
+}
+
+func newSpinV2(machine slotmachine.SlotMachine, spinResult slotmachine.SpinResult) spinV2 {
+	spin := spinV2{
+		Type:       spinResult.Type,
+		Total:      spinResult.Pay,
+		Multiplier: spinResult.Multiplier,
+		Stops:      spinResult.Stops,
+		Grid:       make([][]symbol, len(spinResult.Window)),
+		Lines:      make([]winLineV2, len(spinResult.WinLines)),
+		Scatters:   spinResult.ScatterCount,
+		FreeSpins:  spinResult.FreeSpins,
+		Jackpot:    spinResult.Jackpot,
+		Respins:    spinResult.Respins,
+	}
+	for row, symbols := range spinResult.Window {
+		spin.Grid[row] = make([]symbol, len(symbols))
+		for reel, s := range symbols {
+			spin.Grid[row][reel] = newSymbol(machine, s)
+		}
+	}
+	for j, winLine := range spinResult.WinLines {
+		line := winLineV2{
+			Index:          winLine.Index,
+			Symbol:         newSymbol(machine, winLine.Symbol),
+			Count:          winLine.Count,
+			Payout:         winLine.Payout,
+			Multiplier:     winLine.Multiplier,
+			WildMultiplier: winLine.WildMultiplier,
+			Positions:      make([]position, len(winLine.PayLine)),
+			Symbols:        make([]symbol, len(winLine.Line)),
+		}
+		for reel, row := range winLine.PayLine {
+			line.Positions[reel] = position{Reel: reel + 1, Row: row}
+		}
+		for k, s := range winLine.Line {
+			line.Symbols[k] = newSymbol(machine, s)
+		}
+		spin.Lines[j] = line
+	}
+	for _, change := range spinResult.Wilds {
+		wild := wildV2{Behaviour: change.Behaviour, Cells: make([]position, len(change.Cells))}
+		for k, cell := range change.Cells {
+			wild.Cells[k] = position{Reel: cell.Reel, Row: cell.Row}
+		}
+		spin.Wilds = append(spin.Wilds, wild)
+	}
+	for _, m := range spinResult.Multipliers {
+		spin.Multipliers = append(spin.Multipliers, wildMultiplierV2{position{Reel: m.Reel, Row: m.Row}, m.Multiplier})
+	}
+	for _, coin := range spinResult.Coins {
+		spin.Coins = append(spin.Coins, coinV2{position{Reel: coin.Reel, Row: coin.Row}, coin.Value})
+	}
+	return spin
+}
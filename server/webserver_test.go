@@ -1,7 +1,18 @@
 package server
 
 import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
 	"testing"
+
+	"trippy/slotmachine"
+	"trippy/slotmachine/engine/atkins"
+
+	"github.com/julienschmidt/httprouter"
 )
 
 var (
@@ -83,3 +94,132 @@ func testCreateToken(t *testing.T, sample createTokenSample) {
 		t.Errorf("Expected:[%s] Got:[%s]", sample.token, token)
 	}
 }
+
+func spinRequest(t *testing.T, handler httprouter.Handle, user userClaims) *httptest.ResponseRecorder {
+	machines.set(_ATKINS_DIET_MACHINE, atkins.NewAtkinsDietMachine())
+	return playRequest(t, handler, user)
+}
+
+// playRequest plays a round on the machines registered
+func playRequest(t *testing.T, handler httprouter.Handle, user userClaims) *httptest.ResponseRecorder {
+	apiKey = "secret"
+	token, err := createToken(user, []byte(apiKey))
+	if err != nil {
+		t.Fatalf("Unable to create token [Error:%s]", err)
+	}
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "/api/v2/machines/atkins-diet/spins", strings.NewReader(token))
+	handler(w, r, httprouter.Params{{Key: _PARA_SPIN_MACHINE, Value: _ATKINS_DIET_MACHINE}})
+	return w
+}
+
+func TestSpinV2(t *testing.T) {
+	w := spinRequest(t, SpinV2, userClaims{UID: "123", Chips: 1000, Bet: 1})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected:[%d] Got:[%d %s]", http.StatusOK, w.Code, w.Body)
+	}
+	var resp respSpinV2
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("Unable to decode response [Error:%s]", err)
+	}
+	if resp.Version != 2 || resp.BalanceBefore != 1000 || resp.BalanceAfter != 1000-resp.Wager+resp.Total {
+		t.Errorf("Unexpected balance. Got:[%+v]", resp)
+	}
+	if len(resp.Spins) == 0 || len(resp.Spins[0].Grid) != 3 || len(resp.Spins[0].Grid[0]) != 5 {
+		t.Fatalf("Expected a 3x5 grid. Got:[%+v]", resp.Spins)
+	}
+	for _, spin := range resp.Spins {
+		for _, row := range spin.Grid {
+			for _, s := range row {
+				if s.Name == "" || s.Name == strconv.Itoa(s.ID) || s.Code == "" || s.Kind == "" {
+					t.Errorf("Symbol:[%d] is not in the catalogue. Got:[%+v]", s.ID, s)
+				}
+			}
+		}
+		for _, line := range spin.Lines {
+			if len(line.Positions) != 5 || len(line.Symbols) != line.Count {
+				t.Errorf("Unexpected win line. Got:[%+v]", line)
+			}
+		}
+	}
+}
+
+// The cells turned wild and their multipliers are in the spin, the grid showing the wilds
+func TestSpinV2Wilds(t *testing.T) {
+	result := slotmachine.SpinResult{Type: slotmachine.RESPIN, Multiplier: 1,
+		Window:      [][]slotmachine.Symbol{{1, 2, 3}, {4, 5, 6}, {7, 8, 9}},
+		Wilds:       []slotmachine.WildChange{{Behaviour: slotmachine.WALKING_WILD, Cells: []slotmachine.Cell{{Reel: 2, Row: 3}}}},
+		Multipliers: []slotmachine.CellMultiplier{{Cell: slotmachine.Cell{Reel: 2, Row: 3}, Multiplier: 3}},
+		WinLines:    []slotmachine.WinLine{{Index: 1, Count: 2, Multiplier: 3, WildMultiplier: 3, PayLine: slotmachine.PayLine{3, 3, 3}}}}
+	spin := newSpinV2(atkins.NewAtkinsDietMachine(), result)
+	expected := []wildV2{{Behaviour: slotmachine.WALKING_WILD, Cells: []position{{Reel: 2, Row: 3}}}}
+	if !reflect.DeepEqual(spin.Wilds, expected) {
+		t.Errorf("Expected:[%+v] Got:[%+v]", expected, spin.Wilds)
+	}
+	multipliers := []wildMultiplierV2{{position{Reel: 2, Row: 3}, 3}}
+	if !reflect.DeepEqual(spin.Multipliers, multipliers) || spin.Lines[0].WildMultiplier != 3 {
+		t.Errorf("Expected:[%+v] Got:[%+v] [Lines:%+v]", multipliers, spin.Multipliers, spin.Lines)
+	}
+	if spin = newSpinV2(atkins.NewAtkinsDietMachine(), slotmachine.SpinResult{}); spin.Wilds != nil {
+		t.Errorf("Expected no wilds Got:[%+v]", spin.Wilds)
+	}
+}
+
+// The coins held by a hold spin are in the spin with the respins left
+func TestSpinV2Coins(t *testing.T) {
+	result := slotmachine.SpinResult{Type: slotmachine.HOLD_SPIN, Multiplier: 1, Respins: 2,
+		Window: [][]slotmachine.Symbol{{1, 2, 3}, {4, 5, 6}, {7, 8, 9}},
+		Coins:  []slotmachine.Coin{{Cell: slotmachine.Cell{Reel: 1, Row: 2}, Value: 25}}}
+	spin := newSpinV2(atkins.NewAtkinsDietMachine(), result)
+	expected := []coinV2{{position{Reel: 1, Row: 2}, 25}}
+	if !reflect.DeepEqual(spin.Coins, expected) || spin.Respins != 2 || spin.Type != slotmachine.HOLD_SPIN {
+		t.Errorf("Expected:[%+v] with 2 respins Got:[%+v]", expected, spin)
+	}
+}
+
+func TestSpinV1Schema(t *testing.T) {
+	w := spinRequest(t, Spin, userClaims{UID: "123", Chips: 1000, Bet: 1})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected:[%d] Got:[%d %s]", http.StatusOK, w.Code, w.Body)
+	}
+	var resp map[string]interface{}
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatalf("Unable to decode response [Error:%s]", err)
+	}
+	for _, key := range []string{"total", "spins", "jwt"} {
+		if _, ok := resp[key]; !ok {
+			t.Errorf("Key:[%s] missing in v1 response", key)
+		}
+	}
+	if len(resp) != 3 {
+		t.Errorf("Expected:[3 keys] Got:[%v]", resp)
+	}
+}
+
+type requestIDSample struct {
+	id   string
+	kept bool
+}
+
+var requestIDSamples = []requestIDSample{
+	{id: "abc-123_DEF.4", kept: true},
+	{id: strings.Repeat("a", _REQUEST_ID_MAX_LENGTH), kept: true},
+	{id: strings.Repeat("a", _REQUEST_ID_MAX_LENGTH+1)},
+	{id: "abc\nlevel=ERROR"},
+	{id: "abc def"},
+	{id: ""},
+}
+
+// The request ID of the client is sent back if it is safe to log, another one is generated otherwise
+func TestRequestLoggerID(t *testing.T) {
+	for _, sample := range requestIDSamples {
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Set(_HEADER_REQUEST_ID, sample.id)
+		w := httptest.NewRecorder()
+		requestLogger(w, r, func(http.ResponseWriter, *http.Request) {})
+		id := w.Header().Get(_HEADER_REQUEST_ID)
+		if kept := id == sample.id; kept != sample.kept || !validRequestID(id) {
+			t.Errorf("[ID:%q] Expected kept:[%t] Got:[%q]", sample.id, sample.kept, id)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+// Package bonus plays the pick-me games launched by the bonus symbols of a machine, see slotmachine.BonusRules.
+// The hidden prizes are drawn when a game is launched and revealed as the player picks them,
+// over several requests, the games being kept by a Store in between.
+package bonus
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"trippy/slotmachine"
+)
+
+var (
+	ErrGameNotFound = errors.New("Bonus game not found")
+	ErrGameOver     = errors.New("Bonus game has no picks left")
+	ErrInvalidPick  = errors.New("Pick is not a hidden prize of the game")
+	ErrInvalidRules = errors.New("Bonus rules are invalid")
+)
+
+// Game is a pick game launched in a round
+type Game struct {
+	ID      string    `json:"id"`
+	UID     string    `json:"uid"` // Player of the round
+	Machine string    `json:"machine"`
+	Round   string    `json:"round"`  // Round launching the game
+	Wager   int       `json:"wager"`  // Wager of the round, the prizes are multiples of it
+	Prizes  []int     `json:"prizes"` // Chips of the hidden prizes, by position
+	Picked  []bool    `json:"picked"` // Prizes picked, by position
+	Picks   int       `json:"picks"`  // Picks left
+	Won     int       `json:"won"`    // Chips won so far
+	Started time.Time `json:"started"`
+}
+
+// Over returns true once the player has no picks left
+func (g Game) Over() bool {
+	return g.Picks == 0
+}
+
+// Triggered returns true if a main spin of the results shows enough bonus symbols to launch the game
+func Triggered(rules *slotmachine.BonusRules, results []slotmachine.SpinResult) bool {
+	if rules == nil || rules.Count <= 0 {
+		return false
+	}
+	for _, result := range results {
+		if result.Type != slotmachine.MAIN_SPIN {
+			continue
+		}
+		var count int
+		for _, row := range result.Window {
+			for _, symbol := range row {
+				if symbol == rules.Symbol {
+					count++
+				}
+			}
+		}
+		if count >= rules.Count {
+			return true
+		}
+	}
+	return false
+}
+
+// Games launches and plays the games of the players.
+// A game is picked by one request at a time.
+type Games struct {
+	mu    sync.Mutex
+	store Store
+	rand  *rand.Rand
+}
+
+func NewGames(store Store) *Games {
+	return &Games{store: store, rand: rand.New(rand.NewSource(time.Now().UnixNano()))}
+}
+
+// Start draws the hidden prizes of the game from the reveal table and saves it
+func (gs *Games) Start(game Game, rules slotmachine.BonusRules) (Game, error) {
+	var weights int
+	for _, reveal := range rules.Reveals {
+		weights = weights + reveal.Weight
+	}
+	if rules.Prizes < 1 || rules.Picks < 1 || rules.Picks > rules.Prizes || weights <= 0 {
+		return game, ErrInvalidRules
+	}
+
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	game.Prizes = make([]int, rules.Prizes)
+	game.Picked = make([]bool, rules.Prizes)
+	game.Picks = rules.Picks
+	game.Won = 0
+	game.Started = time.Now().UTC()
+	for i := range game.Prizes {
+		draw := gs.rand.Intn(weights)
+		for _, reveal := range rules.Reveals {
+			if draw < reveal.Weight {
+				game.Prizes[i] = reveal.Multiplier * game.Wager
+				break
+			}
+			draw = draw - reveal.Weight
+		}
+	}
+	return game, gs.store.Save(game)
+}
+
+// Get returns the game of the player
+func (gs *Games) Get(id, uid string) (Game, error) {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+	return gs.load(id, uid)
+}
+
+func (gs *Games) load(id, uid string) (Game, error) {
+	game, found, err := gs.store.Load(id)
+	if err != nil {
+		return game, err
+	}
+	// The games of the other players are not found
+	if !found || game.UID != uid {
+		return Game{}, ErrGameNotFound
+	}
+	return game, nil
+}
+
+// Pick reveals the prize at the position, numbered from 1, and returns the chips won.
+// A game over is removed from the store.
+func (gs *Games) Pick(id, uid string, position int) (Game, int, error) {
+	gs.mu.Lock()
+	defer gs.mu.Unlock()
+
+	game, err := gs.load(id, uid)
+	if err != nil {
+		return game, 0, err
+	}
+	if game.Over() {
+		return game, 0, ErrGameOver
+	}
+	i := position - 1
+	if i < 0 || i >= len(game.Prizes) || game.Picked[i] {
+		return game, 0, fmt.Errorf("%w [Pick:%d]", ErrInvalidPick, position)
+	}
+	game.Picked[i] = true
+	game.Picks--
+	game.Won = game.Won + game.Prizes[i]
+	if game.Over() {
+		return game, game.Prizes[i], gs.store.Delete(game.ID)
+	}
+	return game, game.Prizes[i], gs.store.Save(game)
+}
@@ -0,0 +1,115 @@
+package bonus
+
+import (
+	"errors"
+	"testing"
+
+	"trippy/slotmachine"
+)
+
+const _CHEF = slotmachine.Symbol(12)
+
+var rules = slotmachine.BonusRules{
+	Symbol:  _CHEF,
+	Count:   3,
+	Prizes:  6,
+	Picks:   3,
+	Reveals: []slotmachine.BonusReveal{{Multiplier: 1, Weight: 3}, {Multiplier: 5, Weight: 1}},
+}
+
+type triggerSample struct {
+	window [][]slotmachine.Symbol
+	kind   string
+	launch bool
+}
+
+var triggerSamples = []triggerSample{
+	{window: [][]slotmachine.Symbol{{_CHEF, 1, 2}, {3, _CHEF, 4}, {5, 6, _CHEF}}, kind: slotmachine.MAIN_SPIN, launch: true},
+	{window: [][]slotmachine.Symbol{{_CHEF, _CHEF, 2}, {3, 1, 4}, {5, 6, 1}}, kind: slotmachine.MAIN_SPIN},
+	{window: [][]slotmachine.Symbol{{_CHEF, 1, 2}, {3, _CHEF, 4}, {5, 6, _CHEF}}, kind: slotmachine.FREE_SPIN},
+}
+
+func TestTriggered(t *testing.T) {
+	for _, sample := range triggerSamples {
+		results := []slotmachine.SpinResult{{Type: sample.kind, Window: sample.window}}
+		if launch := Triggered(&rules, results); launch != sample.launch {
+			t.Errorf("[Window:%v %s] Expected:[%t] Got:[%t]", sample.window, sample.kind, sample.launch, launch)
+		}
+	}
+	if Triggered(nil, []slotmachine.SpinResult{{Type: slotmachine.MAIN_SPIN, Window: triggerSamples[0].window}}) {
+		t.Errorf("Expected no game without rules")
+	}
+}
+
+func testGames(t *testing.T, games *Games) {
+	game, err := games.Start(Game{ID: "game-1", UID: "123", Machine: "atkins-diet", Wager: 25}, rules)
+	if err != nil {
+		t.Fatalf("Expected:[nil] Got:[%s]", err)
+	}
+	for _, prize := range game.Prizes {
+		if prize != 25 && prize != 125 {
+			t.Errorf("Expected a prize of the reveal table Got:[%d]", prize)
+		}
+	}
+	if _, _, err = games.Pick("game-1", "456", 1); err != ErrGameNotFound {
+		t.Errorf("Expected:[%s] for another player Got:[%v]", ErrGameNotFound, err)
+	}
+
+	var won int
+	for _, pick := range []int{2, 4, 6} {
+		if _, _, err = games.Pick("game-1", "123", 0); !errors.Is(err, ErrInvalidPick) {
+			t.Errorf("Expected:[%s] Got:[%v]", ErrInvalidPick, err)
+		}
+		played, prize, err := games.Pick("game-1", "123", pick)
+		if err != nil {
+			t.Fatalf("Expected:[nil] Got:[%s]", err)
+		}
+		if prize != game.Prizes[pick-1] || !played.Picked[pick-1] {
+			t.Errorf("[Pick:%d] Expected:[%d] Got:[%d]", pick, game.Prizes[pick-1], prize)
+		}
+		won = won + prize
+		if pick == 2 {
+			if _, _, err = games.Pick("game-1", "123", pick); !errors.Is(err, ErrInvalidPick) {
+				t.Errorf("Expected:[%s] for a prize picked Got:[%v]", ErrInvalidPick, err)
+			}
+			if saved, _ := games.Get("game-1", "123"); saved.Picks != 2 || saved.Won != prize {
+				t.Errorf("Expected the pick saved Got:[%+v]", saved)
+			}
+		}
+		if pick == 6 && (!played.Over() || played.Won != won) {
+			t.Errorf("Expected the game over with:[%d] Got:[%+v]", won, played)
+		}
+	}
+	// Games over are removed
+	if _, err = games.Get("game-1", "123"); err != ErrGameNotFound {
+		t.Errorf("Expected:[%s] Got:[%v]", ErrGameNotFound, err)
+	}
+}
+
+func TestMemoryGames(t *testing.T) {
+	testGames(t, NewGames(NewMemoryStore()))
+}
+
+// Games are kept between the servers sharing the directory
+func TestFileGames(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewFileStore(dir)
+	if err != nil {
+		t.Fatalf("Expected:[nil] Got:[%s]", err)
+	}
+	testGames(t, NewGames(store))
+
+	game, _ := NewGames(store).Start(Game{ID: "game-2", UID: "123", Wager: 1}, rules)
+	restarted, _ := NewFileStore(dir)
+	if saved, err := NewGames(restarted).Get("game-2", "123"); err != nil || len(saved.Prizes) != len(game.Prizes) {
+		t.Errorf("Expected the game saved Got:[%+v] [Error:%v]", saved, err)
+	}
+}
+
+func TestInvalidRules(t *testing.T) {
+	invalid := rules
+	invalid.Picks = invalid.Prizes + 1
+	if _, err := NewGames(NewMemoryStore()).Start(Game{ID: "game-1"}, invalid); err != ErrInvalidRules {
+		t.Errorf("Expected:[%s] Got:[%v]", ErrInvalidRules, err)
+	}
+}
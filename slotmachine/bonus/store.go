@@ -0,0 +1,115 @@
+package bonus
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// Store keeps the games in play, by ID
+type Store interface {
+	Load(id string) (Game, bool, error)
+	Save(game Game) error
+	Delete(id string) error
+}
+
+// MemoryStore keeps the games until the server stops
+type MemoryStore struct {
+	mu    sync.RWMutex
+	games map[string]Game
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{games: make(map[string]Game)}
+}
+
+func (ms *MemoryStore) Load(id string) (Game, bool, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+	game, found := ms.games[id]
+	return game, found, nil
+}
+
+func (ms *MemoryStore) Save(game Game) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.games[game.ID] = game
+	return nil
+}
+
+func (ms *MemoryStore) Delete(id string) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	delete(ms.games, id)
+	return nil
+}
+
+// FileStore keeps every game in a JSON file of its directory
+type FileStore struct {
+	dir string
+}
+
+// NewFileStore returns the store of the directory, created if it does not exist
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("Unable to create bonus games [Dir:%s] [Error:%s]", dir, err)
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+// path returns the file of the game, the IDs being generated by the server
+func (fs *FileStore) path(id string) string {
+	return filepath.Join(fs.dir, filepath.Base(id)+".json")
+}
+
+func (fs *FileStore) Load(id string) (Game, bool, error) {
+	var game Game
+	data, err := ioutil.ReadFile(fs.path(id))
+	if os.IsNotExist(err) {
+		return game, false, nil
+	}
+	if err != nil {
+		return game, false, fmt.Errorf("Unable to read bonus game [Game:%s] [Error:%s]", id, err)
+	}
+	if err = json.Unmarshal(data, &game); err != nil {
+		return game, false, fmt.Errorf("Unable to parse bonus game [Game:%s] [Error:%s]", id, err)
+	}
+	return game, true, nil
+}
+
+// Save writes the game to a temporary file renamed over the previous one,
+// a crash never leaves a game half written
+func (fs *FileStore) Save(game Game) error {
+	data, err := json.Marshal(game)
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(fs.dir, ".game-")
+	if err != nil {
+		return fmt.Errorf("Unable to save bonus game [Game:%s] [Error:%s]", game.ID, err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err = tmp.Write(data); err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), fs.path(game.ID))
+	}
+	if err != nil {
+		return fmt.Errorf("Unable to save bonus game [Game:%s] [Error:%s]", game.ID, err)
+	}
+	return nil
+}
+
+func (fs *FileStore) Delete(id string) error {
+	if err := os.Remove(fs.path(id)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("Unable to delete bonus game [Game:%s] [Error:%s]", id, err)
+	}
+	return nil
+}
@@ -11,8 +11,34 @@ import (
 )
 
 const (
-	MAIN_SPIN string = "main"
-	FREE_SPIN string = "free"
+	MAIN_SPIN    string = "main"
+	FREE_SPIN    string = "free"
+	JACKPOT_SPIN string = "jackpot" // Jackpot awarded after the spins, without reels
+	BONUS_SPIN   string = "bonus"   // Prize of a bonus game, without reels
+	RESPIN       string = "respin"  // Spin played again while walking wilds are in view
+	HOLD_SPIN    string = "hold"    // Respin of the hold and spin feature, the coins held in place
+)
+
+// HOLD_GRAND is the jackpot of the hold spin filling the window with coins
+const HOLD_GRAND = "grand"
+
+// Wild behaviours, reported by the changes they make to the window
+const (
+	EXPANDING_WILD = "expanding"
+	STICKY_WILD    = "sticky"
+	WALKING_WILD   = "walking"
+)
+
+// Ways the multipliers of the wilds paid on a line combine
+const (
+	COMBINE_MULTIPLY = "multiply"
+	COMBINE_ADD      = "add"
+)
+
+// Events raising the multiplier of the free spins
+const (
+	STEP_ON_RETRIGGER = "retrigger" // free spins awarded again during the free spins
+	STEP_ON_RESPIN    = "respin"    // respin of walking wilds during the free spins, the engines have no cascades to step on
 )
 
 type Symbol int
@@ -35,23 +61,156 @@ type PayLines []PayLine
 type PayLine []int
 
 type WinLine struct {
-	Index  int      `json:"index"`  //  number of the line
-	Symbol Symbol   `json:"symbol"` // paid symbol, can be code or index
-	Count  int      `json:"count"`  // number of symbols paid
-	Payout int      `json:"payout"` // Payout for this line
-	Line   []Symbol `json:"-"`      // The line of symbols
+	Index          int      `json:"index"`          //  number of the line
+	Symbol         Symbol   `json:"symbol"`         // paid symbol, can be code or index
+	Name           string   `json:"name,omitempty"` // name of the paid symbol in the catalogue of the machine
+	Count          int      `json:"count"`          // number of symbols paid
+	Payout         int      `json:"payout"`         // Payout for this line
+	Line           []Symbol `json:"-"`              // The line of symbols
+	PayLine        PayLine  `json:"-"`              // Row of the line on each reel
+	Multiplier     int      `json:"-"`              // Multiplier applied to the pay table payout, apart from the bet
+	WildMultiplier int      `json:"-"`              // Multiplier of the wilds paid on the line, part of Multiplier
+}
+
+// Bet is the stake placed by a player for one round
+type Bet struct {
+	Coins int // coins bet on each active line
+	Denom int // chip value of a single coin
+	Lines int // number of active pay lines, counted from the first line
+}
+
+// LineBet is the number of chips staked on each active line
+func (b Bet) LineBet() int {
+	return b.Coins * b.Denom
+}
+
+// Total is the number of chips staked on all the active lines
+func (b Bet) Total() int {
+	return b.LineBet() * b.Lines
+}
+
+// BetLimits are the bets a machine accepts
+type BetLimits struct {
+	MinBet        int   `json:"min_bet"`       // minimum coins per line
+	MaxBet        int   `json:"max_bet"`       // maximum coins per line
+	Denominations []int `json:"denominations"` // allowed coin values, the first one is the default
+}
+
+// RTPEstimate is a return to player found by playing rounds at random, within its confidence interval
+type RTPEstimate struct {
+	RTP        float64 `json:"rtp"`
+	Rounds     int     `json:"rounds"`
+	StdDev     float64 `json:"std_dev"`    // Standard deviation of the return of a round
+	Confidence float64 `json:"confidence"` // Confidence of the interval, eg. 0.95 for 95%
+	Low        float64 `json:"low"`
+	High       float64 `json:"high"`
 }
 
 type SpecialSymbols struct {
-	Wildcard Symbol
-	Scatter  Symbol
+	Wildcard Symbol `json:"wildcard"`
+	Scatter  Symbol `json:"scatter"`
+}
+
+// FreeSpinRules are the free spins awarded by scatter symbols
+type FreeSpinRules struct {
+	ScatterCount int `json:"scatter_count"` // scatters needed to award free spins
+	Spins        int `json:"spins"`         // free spins awarded
+	Multiplier   int `json:"multiplier"`    // multiplier applied to the payouts of free spins
+
+	MultiplierStep int    `json:"multiplier_step,omitempty"` // added to the multiplier on each step, it never changes if 0
+	StepOn         string `json:"step_on,omitempty"`         // retrigger or respin, retrigger if empty, see STEP_ON_RESPIN
+	MaxMultiplier  int    `json:"max_multiplier,omitempty"`  // highest multiplier reached by the steps, no limit if 0
+}
+
+// BonusRules are the pick game launched by bonus symbols anywhere in the window of a main spin
+type BonusRules struct {
+	Symbol  Symbol        `json:"symbol"`  // bonus symbol
+	Count   int           `json:"count"`   // bonus symbols launching the game
+	Prizes  int           `json:"prizes"`  // hidden prizes the player picks among
+	Picks   int           `json:"picks"`   // prizes the player picks
+	Reveals []BonusReveal `json:"reveals"` // weighted table each hidden prize is drawn from
+}
+
+// BonusReveal is a prize of the pick game
+type BonusReveal struct {
+	Multiplier int `json:"multiplier"` // prize in multiples of the wager of the round
+	Weight     int `json:"weight"`
+}
+
+// HoldRules are the hold and spin feature launched by coins anywhere in the window of a main spin.
+// The coins lock with their credits and the reels respin for the other cells, every new coin
+// granting the respins again. The last respin pays the credits of the coins.
+type HoldRules struct {
+	Symbol  Symbol      `json:"symbol"`  // coin symbol
+	Count   int         `json:"count"`   // coins launching the feature
+	Respins int         `json:"respins"` // respins granted, again by every new coin
+	Values  []CoinValue `json:"values"`  // weighted table each coin draws its credits from
+	Grand   int         `json:"grand"`   // prize of a window full of coins in multiples of the total bet, added to the coins
+}
+
+// CoinValue are the credits of a coin
+type CoinValue struct {
+	Multiplier int `json:"multiplier"` // credits in multiples of the total bet
+	Weight     int `json:"weight"`
+}
+
+// Coin is a coin held in a cell of the window, with its credits in chips
+type Coin struct {
+	Cell
+	Value int `json:"value"`
+}
+
+// WildRules are the behaviours of the wildcard, it only substitutes in place if none is set
+type WildRules struct {
+	Expanding bool `json:"expanding"` // a wildcard in view fills its whole reel
+	Sticky    bool `json:"sticky"`    // wildcards landing in free spins stay in place until the free spins end
+	Walking   bool `json:"walking"`   // wildcards in view step one reel left on a respin, until they walk off
+
+	Multipliers []WildMultiplier `json:"multipliers,omitempty"` // weighted table each wildcard in view draws its multiplier from
+	Combine     string           `json:"combine,omitempty"`     // multiply or add the multipliers of the wilds of a line, multiply if empty
+}
+
+// Plain is true if the wildcard only substitutes in place, without behaviours nor multipliers
+func (r WildRules) Plain() bool {
+	return !r.Expanding && !r.Sticky && !r.Walking && len(r.Multipliers) == 0
+}
+
+// WildMultiplier is a multiplier carried by the wildcards
+type WildMultiplier struct {
+	Multiplier int `json:"multiplier"`
+	Weight     int `json:"weight"`
+}
+
+// Cell is a position of the window, numbered from 1
+type Cell struct {
+	Reel int `json:"reel"`
+	Row  int `json:"row"`
+}
+
+// WildChange are the cells of the window turned wild by a behaviour before the spin was evaluated
+type WildChange struct {
+	Behaviour string `json:"behaviour"` // expanding, sticky or walking
+	Cells     []Cell `json:"cells"`
+}
+
+// CellMultiplier is the multiplier carried by the wild of a cell
+type CellMultiplier struct {
+	Cell
+	Multiplier int `json:"multiplier"`
 }
 
 type SpinResult struct {
 	Type         string
 	Stops        []int
+	Window       [][]Symbol // Visible symbols, Window[row][reel]
 	Pay          int
+	Multiplier   int // Multiplier applied to the pay table payouts, apart from the bet
 	WinLines     []WinLine
 	ScatterCount int
 	FreeSpins    int
+	Jackpot      string           // Jackpot awarded by a jackpot spin, or grand by a hold spin full of coins
+	Wilds        []WildChange     // Changes of the window by the wild behaviours, the window evaluated has them
+	Multipliers  []CellMultiplier // Multipliers drawn by the wilds of the window
+	Coins        []Coin           // Coins held after a hold spin
+	Respins      int              // Respins left after a hold spin
 }
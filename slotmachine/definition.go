@@ -0,0 +1,167 @@
+package slotmachine
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+)
+
+// Definition describes a machine: its reels, lines and pays, its rules and its symbols.
+// Engines build their machines from a definition loaded from a JSON file.
+type Definition struct {
+	Name      string         `json:"name"`
+	Symbols   Symbols        `json:"symbols,omitempty"`
+	BetLimits BetLimits      `json:"bet_limits"`
+	PayTable  PayTable       `json:"pay_table"`
+	Reels     Reels          `json:"reels"`
+	PayLines  PayLines       `json:"pay_lines"`
+	Special   SpecialSymbols `json:"special"`
+	FreeSpins FreeSpinRules  `json:"free_spins"`
+	Bonus     *BonusRules    `json:"bonus,omitempty"` // Pick game, none if nil
+	Wilds     *WildRules     `json:"wilds,omitempty"` // Behaviours of the wildcard, in place if nil
+	Hold      *HoldRules     `json:"hold,omitempty"`  // Hold and spin feature, none if nil
+}
+
+// plainDefinition has the fields of a definition, without its JSON methods
+type plainDefinition Definition
+
+// definitionJSON is the JSON of a definition, where every symbol is its name in the catalogue or its number.
+// The fields holding symbols override the ones of the definition, the other fields are written as they are.
+type definitionJSON struct {
+	plainDefinition
+	PayTable map[string]Pays            `json:"pay_table"`
+	Reels    [][]json.RawMessage        `json:"reels"`
+	Special  map[string]json.RawMessage `json:"special"`
+	Bonus    *bonusRulesJSON            `json:"bonus,omitempty"`
+	Hold     *holdRulesJSON             `json:"hold,omitempty"`
+}
+
+// bonusRulesJSON are the rules of the pick game with their symbol by name or number
+type bonusRulesJSON struct {
+	BonusRules
+	Symbol json.RawMessage `json:"symbol"`
+}
+
+// holdRulesJSON are the rules of the hold and spin feature with their coin by name or number
+type holdRulesJSON struct {
+	HoldRules
+	Symbol json.RawMessage `json:"symbol"`
+}
+
+// LoadDefinition reads a machine definition from a JSON file
+func LoadDefinition(path string) (Definition, error) {
+	var def Definition
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return def, fmt.Errorf("Unable to read machine definition [File:%s] [Error:%s]", path, err)
+	}
+	if err = json.Unmarshal(data, &def); err != nil {
+		return def, fmt.Errorf("Unable to parse machine definition [File:%s] [Error:%s]", path, err)
+	}
+	return def, nil
+}
+
+// MarshalJSON writes the symbols by their name if the definition has a catalogue
+func (def Definition) MarshalJSON() ([]byte, error) {
+	symbol := func(id Symbol) json.RawMessage {
+		if len(def.Symbols) == 0 {
+			return json.RawMessage(id.String())
+		}
+		name, _ := json.Marshal(def.Symbols.Name(id))
+		return name
+	}
+	out := definitionJSON{
+		plainDefinition: plainDefinition(def),
+		PayTable:        make(map[string]Pays, len(def.PayTable)),
+		Reels:           make([][]json.RawMessage, len(def.Reels)),
+		Special: map[string]json.RawMessage{
+			"wildcard": symbol(def.Special.Wildcard),
+			"scatter":  symbol(def.Special.Scatter),
+		},
+	}
+	if def.Bonus != nil {
+		out.Bonus = &bonusRulesJSON{BonusRules: *def.Bonus, Symbol: symbol(def.Bonus.Symbol)}
+	}
+	if def.Hold != nil {
+		out.Hold = &holdRulesJSON{HoldRules: *def.Hold, Symbol: symbol(def.Hold.Symbol)}
+	}
+	for id, pays := range def.PayTable {
+		out.PayTable[def.Symbols.Name(id)] = pays
+	}
+	for i, reelLine := range def.Reels {
+		out.Reels[i] = make([]json.RawMessage, len(reelLine))
+		for j, id := range reelLine {
+			out.Reels[i][j] = symbol(id)
+		}
+	}
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON reads the symbols by their name in the catalogue or by their number
+func (def *Definition) UnmarshalJSON(data []byte) error {
+	var in definitionJSON
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	*def = Definition(in.plainDefinition)
+
+	var err error
+	if in.PayTable != nil {
+		def.PayTable = make(PayTable, len(in.PayTable))
+	}
+	for name, pays := range in.PayTable {
+		id, err := def.Symbols.Lookup(name)
+		if err != nil {
+			return fmt.Errorf("pay_table: %s", err)
+		}
+		def.PayTable[id] = pays
+	}
+	if in.Reels != nil {
+		def.Reels = make(Reels, len(in.Reels))
+	}
+	for i, reelLine := range in.Reels {
+		def.Reels[i] = make(ReelLine, len(reelLine))
+		for j, raw := range reelLine {
+			if def.Reels[i][j], err = def.Symbols.decode(raw); err != nil {
+				return fmt.Errorf("reels[%d][%d]: %s", i, j, err)
+			}
+		}
+	}
+	if raw, ok := in.Special["wildcard"]; ok {
+		if def.Special.Wildcard, err = def.Symbols.decode(raw); err != nil {
+			return fmt.Errorf("special.wildcard: %s", err)
+		}
+	}
+	if raw, ok := in.Special["scatter"]; ok {
+		if def.Special.Scatter, err = def.Symbols.decode(raw); err != nil {
+			return fmt.Errorf("special.scatter: %s", err)
+		}
+	}
+	if in.Bonus != nil {
+		def.Bonus = &in.Bonus.BonusRules
+		if def.Bonus.Symbol, err = def.Symbols.decode(in.Bonus.Symbol); err != nil {
+			return fmt.Errorf("bonus.symbol: %s", err)
+		}
+	}
+	if in.Hold != nil {
+		def.Hold = &in.Hold.HoldRules
+		if def.Hold.Symbol, err = def.Symbols.decode(in.Hold.Symbol); err != nil {
+			return fmt.Errorf("hold.symbol: %s", err)
+		}
+	}
+	return nil
+}
+
+// decode reads a symbol written as its name or its number
+func (s Symbols) decode(raw json.RawMessage) (Symbol, error) {
+	var name string
+	if err := json.Unmarshal(raw, &name); err == nil {
+		return s.Lookup(name)
+	}
+	n, err := strconv.Atoi(string(raw))
+	if err != nil {
+		return 0, fmt.Errorf("Symbol:[%s] is neither a name nor a number", raw)
+	}
+	return Symbol(n), nil
+}
@@ -0,0 +1,126 @@
+// Package display draws the reel strips and pay lines of a machine, and the visible window
+// of a spin with its winning lines, as text, SVG or HTML.
+package display
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"trippy/slotmachine"
+	"trippy/spinner"
+)
+
+const _WINDOW_ROWS = 3 // Rows of the visible window, as in the spinner
+
+type Format string
+
+const (
+	TEXT Format = "text"
+	SVG  Format = "svg"
+	HTML Format = "html"
+)
+
+// ParseFormat returns the format for its name, "text", "svg" or "html"
+func ParseFormat(name string) (Format, error) {
+	switch Format(strings.ToLower(name)) {
+	case TEXT:
+		return TEXT, nil
+	case SVG:
+		return SVG, nil
+	case HTML:
+		return HTML, nil
+	}
+	return TEXT, fmt.Errorf("Unknown display format:[%s]", name)
+}
+
+// Outcome is a spin stopped at given stops
+type Outcome struct {
+	Stops    []int                  // Stop of each reel, numbered from 1 as in the responses
+	Window   [][]slotmachine.Symbol // Visible symbols, Window[row][reel]
+	WinLines []slotmachine.WinLine  // Lines paying, payouts for a line bet of 1
+	Pay      int                    // Pay table payout of all the lines, for a line bet of 1
+	Scatters int
+}
+
+// Evaluate finds the outcome of the stops on the first lines pay lines of the definition, all of them if 0
+func Evaluate(def slotmachine.Definition, stops []int, lines int) (Outcome, error) {
+	outcome := Outcome{Stops: stops}
+	if len(def.Reels) == 0 || len(stops) != len(def.Reels[0]) {
+		return outcome, fmt.Errorf("Expected:[%d] stops, one per reel Got:[%d]", width(def), len(stops))
+	}
+	if lines == 0 || lines > len(def.PayLines) {
+		lines = len(def.PayLines)
+	}
+	payLines := def.PayLines[:lines]
+
+	offsets := make([]int, len(stops))
+	for i, stop := range stops {
+		if stop < 1 || stop > len(def.Reels) {
+			return outcome, fmt.Errorf("Stop:[%d] of reel %d is outside the reel, stops are 1 to %d", stop, i+1, len(def.Reels))
+		}
+		offsets[i] = stop - 1
+	}
+	winLines, err := spinner.FindWins(offsets, def.Reels, payLines, def.Special)
+	if err != nil {
+		return outcome, err
+	}
+	for i := range winLines {
+		winLines[i].PayLine = payLines[winLines[i].Index-1]
+		winLines[i].Name = def.Symbols.Name(winLines[i].Symbol)
+	}
+	result, err := spinner.CalculatePay(winLines, def.PayTable, def.Special)
+	if err != nil {
+		return outcome, err
+	}
+	// The lines of symbols without pays are not wins, they are neither listed nor highlighted
+	for _, winLine := range result.WinLines {
+		if winLine.Payout > 0 {
+			outcome.WinLines = append(outcome.WinLines, winLine)
+		}
+	}
+	outcome.Pay = result.Pay
+	outcome.Window = spinner.Window(offsets, def.Reels)
+	outcome.Scatters = spinner.CountScatter(offsets, def.Reels, def.Special.Scatter)
+	return outcome, nil
+}
+
+// paid returns the cells of the window on the paid part of a winning line, paid[row][reel]
+func (o Outcome) paid() [][]bool {
+	paid := make([][]bool, len(o.Window))
+	for row := range paid {
+		paid[row] = make([]bool, len(o.Window[row]))
+	}
+	for _, winLine := range o.WinLines {
+		for reel := 0; reel < winLine.Count; reel++ {
+			paid[winLine.PayLine[reel]-1][reel] = true
+		}
+	}
+	return paid
+}
+
+// Page is what is drawn of a machine
+type Page struct {
+	Definition slotmachine.Definition
+	Reels      bool     // Draws the reel strips
+	PayLines   bool     // Draws the shape of every pay line
+	Outcome    *Outcome // Draws the visible window and the winning lines, if set
+}
+
+// Render draws the page in the format
+func (p Page) Render(w io.Writer, format Format) error {
+	switch format {
+	case SVG:
+		return p.SVG(w)
+	case HTML:
+		return p.HTML(w)
+	}
+	return p.Text(w)
+}
+
+func width(def slotmachine.Definition) int {
+	if len(def.Reels) == 0 {
+		return 0
+	}
+	return len(def.Reels[0])
+}
@@ -0,0 +1,168 @@
+package display
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+
+	"trippy/slotmachine"
+	"trippy/slotmachine/engine/atkins"
+)
+
+// Stops of the default machine paying 2 STEAK on the lines 5, 13 and 15
+var steakStops = []int{14, 3, 20, 19, 26}
+
+type evaluateSample struct {
+	stops []int
+	lines int
+	wins  []int // Index of the winning lines
+	pay   int
+	err   bool
+}
+
+var evaluateSamples = []evaluateSample{
+	{stops: steakStops, wins: []int{5, 13, 15}, pay: 9},
+	{stops: steakStops, lines: 13, wins: []int{5, 13}, pay: 6},
+	// 2 MAYONNAISE on the lines 5, 13 and 15 pay nothing
+	{stops: []int{1, 1, 1, 1, 1}},
+	{stops: []int{1, 2}, err: true},
+	{stops: []int{0, 3, 20, 19, 26}, err: true},
+	{stops: []int{14, 3, 20, 19, 33}, err: true},
+}
+
+func TestEvaluate(t *testing.T) {
+	for _, sample := range evaluateSamples {
+		testEvaluate(t, sample)
+	}
+}
+
+func testEvaluate(t *testing.T, sample evaluateSample) {
+	outcome, err := Evaluate(atkins.DefaultDefinition(), sample.stops, sample.lines)
+	if (err != nil) != sample.err {
+		t.Errorf("[Stops:%v] Expected error:[%t] Got:[%v]", sample.stops, sample.err, err)
+		return
+	}
+	if err != nil {
+		return
+	}
+	var wins []int
+	for _, winLine := range outcome.WinLines {
+		wins = append(wins, winLine.Index)
+		if winLine.Name != "STEAK" {
+			t.Errorf("Expected:[STEAK] Got:[%s]", winLine.Name)
+		}
+	}
+	if outcome.Pay != sample.pay || len(wins) != len(sample.wins) {
+		t.Errorf("Expected:[%v %d] Got:[%v %d]", sample.wins, sample.pay, wins, outcome.Pay)
+	}
+	if len(outcome.Window) != _WINDOW_ROWS {
+		t.Errorf("Expected:[%d] rows Got:[%d]", _WINDOW_ROWS, len(outcome.Window))
+	}
+	var highlighted int
+	for _, row := range outcome.paid() {
+		for _, paid := range row {
+			if paid {
+				highlighted++
+			}
+		}
+	}
+	if (highlighted > 0) != (len(sample.wins) > 0) {
+		t.Errorf("[Stops:%v] Expected highlights:[%t] Got:[%d]", sample.stops, len(sample.wins) > 0, highlighted)
+	}
+}
+
+func steakPage(t *testing.T) Page {
+	def := atkins.DefaultDefinition()
+	outcome, err := Evaluate(def, steakStops, 0)
+	if err != nil {
+		t.Fatalf("Expected:[nil] Got:[%s]", err)
+	}
+	return Page{Definition: def, Reels: true, PayLines: true, Outcome: &outcome}
+}
+
+func TestText(t *testing.T) {
+	var buf bytes.Buffer
+	if err := steakPage(t).Render(&buf, TEXT); err != nil {
+		t.Fatalf("Expected:[nil] Got:[%s]", err)
+	}
+	text := buf.String()
+	for _, expected := range []string{
+		"  32  BUTTER         BACON          STEAK          BACON          BUFFALO_WINGS\n",
+		"Line 16     Line 17     Line 18     Line 19     Line 20\n",
+		" MAYONNAISE     [STEAK]          BUFFALO_WINGS   BUFFALO_WINGS   BUTTER\n",
+		"Line 13: 2 STEAK pays 3, rows 3 2 2 2 3\n",
+	} {
+		if !strings.Contains(text, expected) {
+			t.Errorf("Expected:[%s] Got:\n%s", expected, text)
+		}
+	}
+}
+
+func TestSVG(t *testing.T) {
+	var buf bytes.Buffer
+	if err := steakPage(t).Render(&buf, SVG); err != nil {
+		t.Fatalf("Expected:[nil] Got:[%s]", err)
+	}
+	var (
+		decoder   = xml.NewDecoder(&buf)
+		polylines int
+		paid      int
+	)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Expected valid XML Got:[%s]", err)
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			for _, attr := range start.Attr {
+				if attr.Name.Local == "class" && attr.Value == "cell paid" {
+					paid++
+				}
+			}
+			if start.Name.Local == "polyline" {
+				polylines++
+			}
+		}
+	}
+	if polylines != 3 || paid != 2 {
+		t.Errorf("Expected:[3] lines and [2] paid cells Got:[%d %d]", polylines, paid)
+	}
+}
+
+// Names of the catalogue are escaped
+func TestHTML(t *testing.T) {
+	page := steakPage(t)
+	page.Definition.Symbols = append(slotmachine.Symbols{}, page.Definition.Symbols...)
+	for i := range page.Definition.Symbols {
+		if page.Definition.Symbols[i].Name == "STEAK" {
+			page.Definition.Symbols[i].Name = "<STEAK>"
+		}
+	}
+	var buf bytes.Buffer
+	if err := page.Render(&buf, HTML); err != nil {
+		t.Fatalf("Expected:[nil] Got:[%s]", err)
+	}
+	html := buf.String()
+	if strings.Contains(html, "<STEAK>") || !strings.Contains(html, `<td class="paid">&lt;STEAK&gt;</td>`) {
+		t.Errorf("Expected the paid symbols escaped Got:\n%s", html)
+	}
+	if strings.Count(html, `<table class="shape">`) != len(page.Definition.PayLines) {
+		t.Errorf("Expected:[%d] pay lines", len(page.Definition.PayLines))
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	for name, expected := range map[string]Format{"text": TEXT, "SVG": SVG, "html": HTML} {
+		if format, err := ParseFormat(name); err != nil || format != expected {
+			t.Errorf("Expected:[%s] Got:[%s] [Error:%v]", expected, format, err)
+		}
+	}
+	if _, err := ParseFormat("pdf"); err == nil {
+		t.Errorf("Expected an error for an unknown format")
+	}
+}
@@ -0,0 +1,149 @@
+package display
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+)
+
+var htmlPage = template.Must(template.New("page").Funcs(template.FuncMap{
+	"inc": func(i int) int { return i + 1 },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Name}}</title>
+<style>
+body { font-family: sans-serif; }
+table { border-collapse: collapse; margin-bottom: 1em; }
+td, th { font-family: monospace; padding: 4px 8px; text-align: center; }
+table.grid td { border: 1px solid #999; }
+td.paid { background: #ffe680; font-weight: bold; }
+table.shape td { border: 1px solid #ccc; width: 10px; height: 10px; padding: 0; background: #eee; }
+table.shape td.line { background: #333; }
+.shapes { display: flex; flex-wrap: wrap; gap: 0 24px; }
+.swatch { display: inline-block; width: 12px; height: 12px; margin-right: 6px; }
+</style>
+</head>
+<body>
+<h1>{{.Name}}</h1>
+{{- if .Reels}}
+<h2>Reel strips</h2>
+<table class="grid">
+<tr><th>Stop</th>{{range .ReelNames}}<th>{{.}}</th>{{end}}</tr>
+{{- range $i, $stop := .Reels}}
+<tr><th>{{inc $i}}</th>{{range $stop}}<td>{{.}}</td>{{end}}</tr>
+{{- end}}
+</table>
+{{- end}}
+{{- if .PayLines}}
+<h2>Pay lines</h2>
+<div class="shapes">
+{{- range $i, $shape := .PayLines}}
+<div><p>Line {{inc $i}}</p><table class="shape">
+{{- range $shape}}
+<tr>{{range .}}<td{{if .}} class="line"{{end}}></td>{{end}}</tr>
+{{- end}}
+</table></div>
+{{- end}}
+</div>
+{{- end}}
+{{- with .Outcome}}
+<h2>Window, stops {{.Stops}}</h2>
+<table class="grid">
+{{- range .Window}}
+<tr>{{range .}}<td{{if .Paid}} class="paid"{{end}}>{{.Name}}</td>{{end}}</tr>
+{{- end}}
+</table>
+<ul>
+{{- range .WinLines}}
+<li><span class="swatch" style="background: {{.Color}}"></span>Line {{.Index}}: {{.Count}} {{.Name}} pays {{.Payout}}, rows {{.Rows}}</li>
+{{- end}}
+<li>Scatters: {{.Scatters}}</li>
+<li>Pay: {{.Pay}} for a line bet of 1</li>
+</ul>
+{{- end}}
+</body>
+</html>
+`))
+
+type htmlCell struct {
+	Name string
+	Paid bool
+}
+
+type htmlWinLine struct {
+	Index, Count, Payout int
+	Name, Rows           string
+	Color                template.CSS
+}
+
+type htmlOutcome struct {
+	Stops    string
+	Window   [][]htmlCell
+	WinLines []htmlWinLine
+	Scatters int
+	Pay      int
+}
+
+type htmlData struct {
+	Name      string
+	ReelNames []string
+	Reels     [][]string // Names of the symbols, Reels[stop][reel]
+	PayLines  [][][]bool // Cells of the line, PayLines[line][row][reel]
+	Outcome   *htmlOutcome
+}
+
+// HTML draws the page as an HTML document. Paid symbols of the window are highlighted.
+func (p Page) HTML(w io.Writer) error {
+	var (
+		def  = p.Definition
+		data = htmlData{Name: def.Name}
+	)
+	if p.Reels {
+		for reel := 0; reel < width(def); reel++ {
+			data.ReelNames = append(data.ReelNames, fmt.Sprintf("Reel %d", reel+1))
+		}
+		data.Reels = make([][]string, len(def.Reels))
+		for i, stop := range def.Reels {
+			data.Reels[i] = def.Symbols.Names(stop)
+		}
+	}
+	if p.PayLines {
+		data.PayLines = make([][][]bool, len(def.PayLines))
+		for i, line := range def.PayLines {
+			data.PayLines[i] = make([][]bool, _WINDOW_ROWS)
+			for row := range data.PayLines[i] {
+				data.PayLines[i][row] = make([]bool, len(line))
+				for reel, lineRow := range line {
+					data.PayLines[i][row][reel] = lineRow == row+1
+				}
+			}
+		}
+	}
+	if outcome := p.Outcome; outcome != nil {
+		var (
+			paid = outcome.paid()
+			out  = &htmlOutcome{Stops: fmt.Sprint(outcome.Stops), Scatters: outcome.Scatters, Pay: outcome.Pay}
+		)
+		out.Window = make([][]htmlCell, len(outcome.Window))
+		for row, symbols := range outcome.Window {
+			out.Window[row] = make([]htmlCell, len(symbols))
+			for reel, symbol := range symbols {
+				out.Window[row][reel] = htmlCell{Name: def.Symbols.Name(symbol), Paid: paid[row][reel]}
+			}
+		}
+		for i, winLine := range outcome.WinLines {
+			out.WinLines = append(out.WinLines, htmlWinLine{
+				Index:  winLine.Index,
+				Count:  winLine.Count,
+				Payout: winLine.Payout,
+				Name:   winLine.Name,
+				Rows:   fmt.Sprint(winLine.PayLine),
+				Color:  template.CSS(lineColors[i%len(lineColors)]),
+			})
+		}
+		data.Outcome = out
+	}
+	return htmlPage.Execute(w, data)
+}
@@ -0,0 +1,168 @@
+package display
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+)
+
+const (
+	_SVG_MARGIN      = 20
+	_SVG_TITLE       = 32 // Height of a section title
+	_SVG_CELL_WIDTH  = 120
+	_SVG_CELL_HEIGHT = 32
+	_SVG_STOP_HEIGHT = 20 // Height of a stop of the reel strips
+	_SVG_LINE_CELL   = 14 // Size of a cell of a pay line shape
+	_SVG_LINE_GAP    = 24 // Space between the pay line shapes
+)
+
+// lineColors are the colors of the winning lines, in turn
+var lineColors = []string{"#d62728", "#1f77b4", "#2ca02c", "#ff7f0e", "#9467bd", "#8c564b", "#e377c2", "#17becf"}
+
+const _SVG_STYLE = `text{font-family:monospace;font-size:12px}` +
+	`.title{font-size:16px;font-weight:bold}` +
+	`.cell{fill:#fff;stroke:#999}` +
+	`.paid{fill:#ffe680}` +
+	`.line{fill:#333}` +
+	`.empty{fill:#eee;stroke:#ccc}` +
+	`.win{fill:none;stroke-width:3;stroke-opacity:0.8}`
+
+// svgWriter draws the sections of a page one below the other
+type svgWriter struct {
+	buf   bytes.Buffer
+	y     int // Top of the next section
+	width int // Right of the widest section
+}
+
+// SVG draws the page as an SVG image. Paid symbols of the window are highlighted
+// and the winning lines drawn across it.
+func (p Page) SVG(w io.Writer) error {
+	sw := &svgWriter{y: _SVG_MARGIN}
+	if p.Reels {
+		p.svgReels(sw)
+	}
+	if p.PayLines {
+		p.svgPayLines(sw)
+	}
+	if p.Outcome != nil {
+		p.svgWindow(sw)
+	}
+	_, err := fmt.Fprintf(w, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d"><style>%s</style>%s</svg>`+"\n",
+		sw.width+_SVG_MARGIN, sw.y, _SVG_STYLE, sw.buf.String())
+	return err
+}
+
+func (sw *svgWriter) title(title string) {
+	sw.text(_SVG_MARGIN, sw.y+16, "title", title)
+	sw.y = sw.y + _SVG_TITLE
+}
+
+func (sw *svgWriter) rect(x, y, width, height int, class string) {
+	fmt.Fprintf(&sw.buf, `<rect x="%d" y="%d" width="%d" height="%d" class="%s"/>`, x, y, width, height, class)
+	if x+width > sw.width {
+		sw.width = x + width
+	}
+}
+
+func (sw *svgWriter) text(x, y int, class, text string) {
+	fmt.Fprintf(&sw.buf, `<text x="%d" y="%d" class="%s">`, x, y, class)
+	xml.EscapeText(&sw.buf, []byte(text))
+	sw.buf.WriteString(`</text>`)
+}
+
+// centred writes the text in the middle of a cell
+func (sw *svgWriter) centred(x, y, width, height int, class, text string) {
+	fmt.Fprintf(&sw.buf, `<text x="%d" y="%d" text-anchor="middle" dominant-baseline="central" class="%s">`,
+		x+width/2, y+height/2, class)
+	xml.EscapeText(&sw.buf, []byte(text))
+	sw.buf.WriteString(`</text>`)
+}
+
+// end moves below a section of the height
+func (sw *svgWriter) end(height int) {
+	sw.y = sw.y + height + _SVG_MARGIN
+}
+
+func (p Page) svgReels(sw *svgWriter) {
+	def := p.Definition
+	sw.title("Reel strips")
+	for reel := 0; reel < width(def); reel++ {
+		sw.centred(_SVG_MARGIN+_SVG_CELL_WIDTH/2*(2*reel+1), sw.y, _SVG_CELL_WIDTH, _SVG_STOP_HEIGHT, "", fmt.Sprintf("Reel %d", reel+1))
+	}
+	for i, stop := range def.Reels {
+		y := sw.y + (i+1)*_SVG_STOP_HEIGHT
+		sw.centred(_SVG_MARGIN, y, _SVG_CELL_WIDTH/2, _SVG_STOP_HEIGHT, "", fmt.Sprint(i+1))
+		for reel, symbol := range stop {
+			x := _SVG_MARGIN + _SVG_CELL_WIDTH/2*(2*reel+1)
+			sw.rect(x, y, _SVG_CELL_WIDTH, _SVG_STOP_HEIGHT, "cell")
+			sw.centred(x, y, _SVG_CELL_WIDTH, _SVG_STOP_HEIGHT, "", def.Symbols.Name(symbol))
+		}
+	}
+	sw.end((len(def.Reels) + 1) * _SVG_STOP_HEIGHT)
+}
+
+func (p Page) svgPayLines(sw *svgWriter) {
+	var (
+		lines = p.Definition.PayLines
+		shape = width(p.Definition)*_SVG_LINE_CELL + _SVG_LINE_GAP
+		block = _SVG_TITLE/2 + _WINDOW_ROWS*_SVG_LINE_CELL + _SVG_LINE_GAP
+	)
+	sw.title("Pay lines")
+	for i, line := range lines {
+		x := _SVG_MARGIN + i%_LINES_PER_ROW*shape
+		y := sw.y + i/_LINES_PER_ROW*block
+		sw.text(x, y+12, "", fmt.Sprintf("Line %d", i+1))
+		for row := 1; row <= _WINDOW_ROWS; row++ {
+			for reel, lineRow := range line {
+				class := "empty"
+				if lineRow == row {
+					class = "line"
+				}
+				sw.rect(x+reel*_SVG_LINE_CELL, y+_SVG_TITLE/2+(row-1)*_SVG_LINE_CELL, _SVG_LINE_CELL, _SVG_LINE_CELL, class)
+			}
+		}
+	}
+	sw.end((len(lines)+_LINES_PER_ROW-1)/_LINES_PER_ROW*block - _SVG_LINE_GAP)
+}
+
+func (p Page) svgWindow(sw *svgWriter) {
+	var (
+		def     = p.Definition
+		outcome = p.Outcome
+		paid    = outcome.paid()
+		top     = sw.y + _SVG_TITLE
+	)
+	sw.title(fmt.Sprintf("Window, stops %v", outcome.Stops))
+	for row, symbols := range outcome.Window {
+		for reel, symbol := range symbols {
+			x, y := _SVG_MARGIN+reel*_SVG_CELL_WIDTH, top+row*_SVG_CELL_HEIGHT
+			class := "cell"
+			if paid[row][reel] {
+				class = "cell paid"
+			}
+			sw.rect(x, y, _SVG_CELL_WIDTH, _SVG_CELL_HEIGHT, class)
+			sw.centred(x, y, _SVG_CELL_WIDTH, _SVG_CELL_HEIGHT, "", def.Symbols.Name(symbol))
+		}
+	}
+	// Winning lines are drawn across the paid symbols
+	for i, winLine := range outcome.WinLines {
+		fmt.Fprintf(&sw.buf, `<polyline class="win" stroke="%s" points="`, lineColors[i%len(lineColors)])
+		for reel := 0; reel < winLine.Count; reel++ {
+			fmt.Fprintf(&sw.buf, "%d,%d ", _SVG_MARGIN+reel*_SVG_CELL_WIDTH+_SVG_CELL_WIDTH/2,
+				top+(winLine.PayLine[reel]-1)*_SVG_CELL_HEIGHT+_SVG_CELL_HEIGHT/2)
+		}
+		sw.buf.WriteString(`"/>`)
+	}
+
+	y := top + len(outcome.Window)*_SVG_CELL_HEIGHT + _SVG_STOP_HEIGHT
+	for i, winLine := range outcome.WinLines {
+		fmt.Fprintf(&sw.buf, `<rect x="%d" y="%d" width="12" height="4" fill="%s"/>`, _SVG_MARGIN, y+i*_SVG_STOP_HEIGHT-4, lineColors[i%len(lineColors)])
+		sw.text(_SVG_MARGIN+20, y+i*_SVG_STOP_HEIGHT, "", fmt.Sprintf("Line %d: %d %s pays %d",
+			winLine.Index, winLine.Count, winLine.Name, winLine.Payout))
+	}
+	y = y + len(outcome.WinLines)*_SVG_STOP_HEIGHT
+	sw.text(_SVG_MARGIN, y, "", fmt.Sprintf("Scatters: %d", outcome.Scatters))
+	sw.text(_SVG_MARGIN, y+_SVG_STOP_HEIGHT, "", fmt.Sprintf("Pay: %d for a line bet of 1", outcome.Pay))
+	sw.y = y + _SVG_STOP_HEIGHT + _SVG_MARGIN
+}
@@ -0,0 +1,152 @@
+package display
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"trippy/slotmachine"
+)
+
+const (
+	_LINES_PER_ROW = 5 // Pay line shapes drawn side by side
+
+	_TEXT_LINE  = "#"
+	_TEXT_EMPTY = "."
+)
+
+// Text draws the page as plain text. Paid symbols of the window are in brackets.
+func (p Page) Text(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	sections := 0
+	section := func(title string) {
+		if sections > 0 {
+			fmt.Fprintln(bw)
+		}
+		fmt.Fprintf(bw, "%s\n%s\n", title, strings.Repeat("=", len(title)))
+		sections++
+	}
+	if p.Reels {
+		section("Reel strips")
+		p.textReels(bw)
+	}
+	if p.PayLines {
+		section("Pay lines")
+		p.textPayLines(bw)
+	}
+	if p.Outcome != nil {
+		section("Window")
+		p.textWindow(bw)
+	}
+	return bw.Flush()
+}
+
+// nameWidth is the width of the longest symbol name on the reels
+func (p Page) nameWidth() int {
+	max := len("Reel 10")
+	for _, stop := range p.Definition.Reels {
+		for _, symbol := range stop {
+			if n := len(p.Definition.Symbols.Name(symbol)); n > max {
+				max = n
+			}
+		}
+	}
+	return max
+}
+
+func (p Page) textReels(w io.Writer) {
+	var (
+		def   = p.Definition
+		cell  = p.nameWidth()
+		cells = make([]string, width(def))
+	)
+	for reel := range cells {
+		cells[reel] = pad(fmt.Sprintf("Reel %d", reel+1), cell)
+	}
+	fmt.Fprintf(w, "Stop  %s\n", strings.TrimRight(strings.Join(cells, "  "), " "))
+	for i, stop := range def.Reels {
+		for reel, symbol := range stop {
+			cells[reel] = pad(def.Symbols.Name(symbol), cell)
+		}
+		fmt.Fprintf(w, "%4d  %s\n", i+1, strings.TrimRight(strings.Join(cells, "  "), " "))
+	}
+}
+
+func (p Page) textPayLines(w io.Writer) {
+	var (
+		lines = p.Definition.PayLines
+		cell  = len(fmt.Sprintf("Line %d", len(lines)))
+	)
+	if n := 2*width(p.Definition) - 1; n > cell {
+		cell = n
+	}
+	for first := 0; first < len(lines); first += _LINES_PER_ROW {
+		last := first + _LINES_PER_ROW
+		if last > len(lines) {
+			last = len(lines)
+		}
+		if first > 0 {
+			fmt.Fprintln(w)
+		}
+		titles := make([]string, 0, _LINES_PER_ROW)
+		for i := first; i < last; i++ {
+			titles = append(titles, pad(fmt.Sprintf("Line %d", i+1), cell))
+		}
+		fmt.Fprintln(w, strings.TrimRight(strings.Join(titles, "   "), " "))
+		for row := 1; row <= _WINDOW_ROWS; row++ {
+			shapes := make([]string, 0, _LINES_PER_ROW)
+			for i := first; i < last; i++ {
+				shapes = append(shapes, pad(lineRow(lines[i], row), cell))
+			}
+			fmt.Fprintln(w, strings.TrimRight(strings.Join(shapes, "   "), " "))
+		}
+	}
+}
+
+// lineRow draws the cells of the row of the window, marked on the reels the line crosses it
+func lineRow(line slotmachine.PayLine, row int) string {
+	cells := make([]string, len(line))
+	for reel, lineRow := range line {
+		cells[reel] = _TEXT_EMPTY
+		if lineRow == row {
+			cells[reel] = _TEXT_LINE
+		}
+	}
+	return strings.Join(cells, " ")
+}
+
+func (p Page) textWindow(w io.Writer) {
+	var (
+		def     = p.Definition
+		outcome = p.Outcome
+		paid    = outcome.paid()
+		cell    = p.nameWidth() + 2
+	)
+	fmt.Fprintf(w, "Stops: %s\n\n", strings.Trim(fmt.Sprint(outcome.Stops), "[]"))
+	for row, symbols := range outcome.Window {
+		cells := make([]string, len(symbols))
+		for reel, symbol := range symbols {
+			name := " " + def.Symbols.Name(symbol) + " "
+			if paid[row][reel] {
+				name = "[" + def.Symbols.Name(symbol) + "]"
+			}
+			cells[reel] = pad(name, cell)
+		}
+		fmt.Fprintln(w, strings.TrimRight(strings.Join(cells, " "), " "))
+	}
+	fmt.Fprintln(w)
+	for _, winLine := range outcome.WinLines {
+		fmt.Fprintf(w, "Line %d: %d %s pays %d, rows %s\n", winLine.Index, winLine.Count, winLine.Name,
+			winLine.Payout, strings.Trim(fmt.Sprint(winLine.PayLine), "[]"))
+	}
+	fmt.Fprintf(w, "Scatters: %d\n", outcome.Scatters)
+	fmt.Fprintf(w, "Pay: %d for a line bet of 1\n", outcome.Pay)
+}
+
+func pad(s string, width int) string {
+	if len(s) >= width {
+		return s
+	}
+	return s + strings.Repeat(" ", width-len(s))
+}
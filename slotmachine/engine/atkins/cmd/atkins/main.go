@@ -1,11 +1,119 @@
 package main
 
 import (
+	"encoding/json"
+	"flag"
 	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"trippy/slotmachine"
+	"trippy/slotmachine/display"
 	"trippy/slotmachine/engine/atkins"
 )
 
+const _USAGE = `Usage: atkins [flags] [reels] [lines] [window] [definition]
+
+Draws the reel strips, the pay lines and the window of -stops, all of them if none is given.
+definition prints the definition as JSON, with the symbols by name.
+
+`
+
 func main() {
-	fmt.Println(atkins.PayTable)
-	fmt.Println(atkins.Reels)
+	var (
+		definitionPath = flag.String("definition", "", "Machine definition file, the default Atkins Diet machine if empty")
+		formatName     = flag.String("format", string(display.TEXT), "Output format: text, svg or html")
+		stopsList      = flag.String("stops", "", "Comma separated stop of each reel, numbered from 1 as in the responses, eg. 3,17,8,1,22")
+		lines          = flag.Int("lines", 0, "Active pay lines of the window, all lines if 0")
+		outputPath     = flag.String("o", "", "Output file, stdout if empty")
+	)
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), _USAGE)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	def := atkins.DefaultDefinition()
+	if *definitionPath != "" {
+		var err error
+		if def, err = slotmachine.LoadDefinition(*definitionPath); err != nil {
+			exit(err)
+		}
+	}
+	format, err := display.ParseFormat(*formatName)
+	if err != nil {
+		exit(err)
+	}
+
+	var w io.Writer = os.Stdout
+	if *outputPath != "" {
+		f, err := os.Create(*outputPath)
+		if err != nil {
+			exit(fmt.Errorf("Unable to create output [File:%s] [Error:%s]", *outputPath, err))
+		}
+		defer f.Close()
+		w = f
+	}
+
+	page := display.Page{Definition: def}
+	views := flag.Args()
+	if len(views) == 0 {
+		views = []string{"reels", "lines"}
+		if *stopsList != "" {
+			views = append(views, "window")
+		}
+	}
+	for _, view := range views {
+		switch view {
+		case "reels":
+			page.Reels = true
+		case "lines":
+			page.PayLines = true
+		case "window":
+			stops, err := parseStops(*stopsList)
+			if err != nil {
+				exit(err)
+			}
+			outcome, err := display.Evaluate(def, stops, *lines)
+			if err != nil {
+				exit(err)
+			}
+			page.Outcome = &outcome
+		case "definition":
+			data, err := json.MarshalIndent(def, "", "  ")
+			if err != nil {
+				exit(fmt.Errorf("Unable to marshal definition [Error:%s]", err))
+			}
+			fmt.Fprintln(w, string(data))
+			return
+		default:
+			flag.Usage()
+			os.Exit(2)
+		}
+	}
+	if err = page.Render(w, format); err != nil {
+		exit(err)
+	}
+}
+
+func parseStops(list string) ([]int, error) {
+	if list == "" {
+		return nil, fmt.Errorf("The window needs the -stops of the reels")
+	}
+	var stops []int
+	for _, s := range strings.Split(list, ",") {
+		stop, err := strconv.Atoi(strings.TrimSpace(s))
+		if err != nil {
+			return nil, fmt.Errorf("Stop:[%s] is not a number", s)
+		}
+		stops = append(stops, stop)
+	}
+	return stops, nil
+}
+
+func exit(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
 }
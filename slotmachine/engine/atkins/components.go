@@ -17,12 +17,42 @@ const (
 	_BACON
 	_MAYONNAISE
 	_SCALE
+	_COIN
 
 	_SCATTER_COUNT_FOR_FREE_SPIN = 3
 	_FREE_SPINS                  = 10
+	_FREE_SPIN_MULTIPLIER        = 3
+
+	_COUNT_FOR_HOLD = 6
+	_HOLD_RESPINS   = 3
+	_HOLD_GRAND     = 500
+
+	_MIN_BET = 1
+	_MAX_BET = 500
 )
 
 var (
+	Symbols = slotmachine.Symbols{
+		{ID: _EMPTY, Name: "EMPTY", Code: "---", Kind: slotmachine.BLANK},
+		{ID: _ATKINS, Name: "ATKINS", Code: "ATK", Kind: slotmachine.WILD, Description: "Dr. Atkins, substitutes every symbol but the scale"},
+		{ID: _STEAK, Name: "STEAK", Code: "STK", Kind: slotmachine.REGULAR},
+		{ID: _HAM, Name: "HAM", Code: "HAM", Kind: slotmachine.REGULAR},
+		{ID: _BUFFALO_WINGS, Name: "BUFFALO_WINGS", Code: "BFW", Kind: slotmachine.REGULAR},
+		{ID: _SAUSAGE, Name: "SAUSAGE", Code: "SSG", Kind: slotmachine.REGULAR},
+		{ID: _EGGS, Name: "EGGS", Code: "EGG", Kind: slotmachine.REGULAR},
+		{ID: _BUTTER, Name: "BUTTER", Code: "BTR", Kind: slotmachine.REGULAR},
+		{ID: _CHEESE, Name: "CHEESE", Code: "CHS", Kind: slotmachine.REGULAR},
+		{ID: _BACON, Name: "BACON", Code: "BCN", Kind: slotmachine.REGULAR},
+		{ID: _MAYONNAISE, Name: "MAYONNAISE", Code: "MAY", Kind: slotmachine.REGULAR},
+		{ID: _SCALE, Name: "SCALE", Code: "SCL", Kind: slotmachine.SCATTER, Description: "Awards free spins when 3 or more are in view"},
+	}
+
+	BetLimits = slotmachine.BetLimits{
+		MinBet:        _MIN_BET,
+		MaxBet:        _MAX_BET,
+		Denominations: []int{1, 2, 5, 10, 25, 50, 100},
+	}
+
 	PayTable = slotmachine.PayTable{
 		_ATKINS: slotmachine.Pays{
 			5: 5000,
@@ -115,6 +145,56 @@ var (
 		{_BUTTER, _BACON, _STEAK, _BACON, _BUFFALO_WINGS},
 	}
 
+	// HoldSymbols is the catalogue of the hold and spin variant, with its coin
+	HoldSymbols = append(Symbols[:len(Symbols):len(Symbols)],
+		slotmachine.SymbolInfo{ID: _COIN, Name: "COIN", Code: "CON", Kind: slotmachine.COIN, Description: "Holds a credit value, 6 or more launch hold and spin"},
+	)
+
+	// HoldValues are the credits of the coins, in multiples of the total bet
+	HoldValues = []slotmachine.CoinValue{
+		{Multiplier: 1, Weight: 50},
+		{Multiplier: 2, Weight: 25},
+		{Multiplier: 5, Weight: 15},
+		{Multiplier: 10, Weight: 7},
+		{Multiplier: 25, Weight: 3},
+	}
+
+	// HoldReels are the reels of the hold and spin variant, coins in place of some symbols
+	HoldReels = slotmachine.Reels{
+		{_SCALE, _MAYONNAISE, _COIN, _HAM, _BACON},
+		{_MAYONNAISE, _BUFFALO_WINGS, _BUTTER, _CHEESE, _SCALE},
+		{_COIN, _STEAK, _EGGS, _ATKINS, _STEAK},
+		{_SAUSAGE, _COIN, _SCALE, _SCALE, _COIN},
+		{_COIN, _CHEESE, _CHEESE, _BUTTER, _CHEESE},
+		{_EGGS, _MAYONNAISE, _MAYONNAISE, _BACON, _SAUSAGE},
+		{_CHEESE, _HAM, _BUTTER, _CHEESE, _BUTTER},
+		{_MAYONNAISE, _BUTTER, _HAM, _SAUSAGE, _COIN},
+		{_SAUSAGE, _BACON, _COIN, _STEAK, _BUFFALO_WINGS},
+		{_BUTTER, _STEAK, _BACON, _EGGS, _CHEESE},
+		{_BUFFALO_WINGS, _SAUSAGE, _STEAK, _BACON, _SAUSAGE},
+		{_BACON, _MAYONNAISE, _BUFFALO_WINGS, _COIN, _HAM},
+		{_EGGS, _HAM, _BUTTER, _COIN, _BUTTER},
+		{_MAYONNAISE, _ATKINS, _MAYONNAISE, _CHEESE, _STEAK},
+		{_STEAK, _BUTTER, _CHEESE, _BUTTER, _MAYONNAISE},
+		{_BUFFALO_WINGS, _EGGS, _SAUSAGE, _HAM, _EGGS},
+		{_BUTTER, _CHEESE, _EGGS, _MAYONNAISE, _SAUSAGE},
+		{_CHEESE, _COIN, _BACON, _COIN, _HAM},
+		{_EGGS, _SAUSAGE, _COIN, _BUFFALO_WINGS, _ATKINS},
+		{_ATKINS, _BUFFALO_WINGS, _BUFFALO_WINGS, _SAUSAGE, _COIN},
+		{_BACON, _SCALE, _HAM, _CHEESE, _BUFFALO_WINGS},
+		{_COIN, _MAYONNAISE, _SAUSAGE, _EGGS, _MAYONNAISE},
+		{_HAM, _BUTTER, _BACON, _BUTTER, _EGGS},
+		{_CHEESE, _CHEESE, _COIN, _BUFFALO_WINGS, _HAM},
+		{_EGGS, _BACON, _EGGS, _BACON, _BACON},
+		{_SCALE, _EGGS, _ATKINS, _MAYONNAISE, _BUTTER},
+		{_BUTTER, _BUFFALO_WINGS, _BUFFALO_WINGS, _COIN, _STEAK},
+		{_BACON, _MAYONNAISE, _BACON, _HAM, _MAYONNAISE},
+		{_COIN, _STEAK, _BUTTER, _SAUSAGE, _SAUSAGE},
+		{_BUFFALO_WINGS, _COIN, _CHEESE, _STEAK, _EGGS},
+		{_STEAK, _CHEESE, _MAYONNAISE, _MAYONNAISE, _COIN},
+		{_BUTTER, _COIN, _STEAK, _BACON, _BUFFALO_WINGS},
+	}
+
 	PayLines = slotmachine.PayLines{
 		{2, 2, 2, 2, 2},
 		{1, 1, 1, 1, 1},
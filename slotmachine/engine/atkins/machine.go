@@ -1,144 +1,425 @@
 package atkins
 
 import (
+	"context"
 	"errors"
-	"log"
-	"os"
+	"fmt"
 	"time"
 
+	"trippy/logger"
 	"trippy/slotmachine"
+	"trippy/slotmachine/hold"
 	"trippy/spinner"
 )
 
-var (
-	slog *log.Logger
-)
-
-func init() {
-	// Log to stdout
-	slog = log.New(os.Stdout, "", 0)
-	slog.SetFlags(log.Lshortfile | log.Ldate | log.Ltime | log.Lmicroseconds)
-	slog.SetPrefix("ATKINS:")
-}
-
 type AtkinsDietMachine struct {
-	PayTable slotmachine.PayTable
-	Reels    slotmachine.Reels
-	PayLines slotmachine.PayLines
+	Symbols   slotmachine.Symbols
+	BetLimits slotmachine.BetLimits
+	PayTable  slotmachine.PayTable
+	Reels     slotmachine.Reels
+	PayLines  slotmachine.PayLines
 
 	slotmachine.SpecialSymbols
+	FreeSpinRules slotmachine.FreeSpinRules
+	Bonus         *slotmachine.BonusRules // Pick game, none if nil
+	Wilds         slotmachine.WildRules   // Behaviours of the wildcard
+	Hold          *slotmachine.HoldRules  // Hold and spin feature, none if nil
+
+	// LogWinLines logs every paid line of a spin at debug level
+	LogWinLines bool
 }
 
 func NewAtkinsDietMachine() *AtkinsDietMachine {
+	return NewAtkinsDietMachineFromDefinition(DefaultDefinition())
+}
+
+// NewAtkinsDietMachineFromDefinition creates a machine with the reels, lines and rules of the definition
+func NewAtkinsDietMachineFromDefinition(def slotmachine.Definition) *AtkinsDietMachine {
 	return &AtkinsDietMachine{
-		PayTable: PayTable,
-		Reels:    Reels,
-		PayLines: PayLines,
-		SpecialSymbols: slotmachine.SpecialSymbols{
+		Symbols:        def.Symbols,
+		BetLimits:      def.BetLimits,
+		PayTable:       def.PayTable,
+		Reels:          def.Reels,
+		PayLines:       def.PayLines,
+		SpecialSymbols: def.Special,
+		FreeSpinRules:  def.FreeSpins,
+		Bonus:          def.Bonus,
+		Wilds:          wildRules(def.Wilds),
+		Hold:           def.Hold,
+	}
+}
+
+func wildRules(rules *slotmachine.WildRules) slotmachine.WildRules {
+	if rules == nil {
+		return slotmachine.WildRules{}
+	}
+	return *rules
+}
+
+// DefaultDefinition is the definition of the original Atkins Diet machine
+func DefaultDefinition() slotmachine.Definition {
+	return slotmachine.Definition{
+		Name:      "atkins-diet",
+		Symbols:   Symbols,
+		BetLimits: BetLimits,
+		PayTable:  PayTable,
+		Reels:     Reels,
+		PayLines:  PayLines,
+		Special: slotmachine.SpecialSymbols{
 			Wildcard: _ATKINS,
 			Scatter:  _SCALE,
 		},
+		FreeSpins: slotmachine.FreeSpinRules{
+			ScatterCount: _SCATTER_COUNT_FOR_FREE_SPIN,
+			Spins:        _FREE_SPINS,
+			Multiplier:   _FREE_SPIN_MULTIPLIER,
+		},
+	}
+}
+
+// HoldDefinition is the variant of the Atkins Diet machine with coins launching hold and spin
+func HoldDefinition() slotmachine.Definition {
+	def := DefaultDefinition()
+	def.Name = "atkins-hold"
+	def.Symbols = HoldSymbols
+	def.Reels = HoldReels
+	def.Hold = &slotmachine.HoldRules{
+		Symbol:  _COIN,
+		Count:   _COUNT_FOR_HOLD,
+		Respins: _HOLD_RESPINS,
+		Values:  HoldValues,
+		Grand:   _HOLD_GRAND,
 	}
+	return def
 }
 
 var (
 	ErrChipsInsufficient = errors.New("Chips insufficient")
 	ErrInvalidBet        = errors.New("Bet is not greater than 0")
+	ErrBetBelowMin       = errors.New("Bet is below the minimum bet per line")
+	ErrBetAboveMax       = errors.New("Bet is above the maximum bet per line")
+	ErrInvalidDenom      = errors.New("Denomination is not allowed")
+	ErrInvalidLines      = errors.New("Number of lines is out of range")
 )
 
-func (ad *AtkinsDietMachine) Wager(bet, chips int) (int, error) {
-	if bet <= 0 {
-		return 0, ErrInvalidBet
+// LimitError is returned when a bet breaks one of the machine's BetLimits.
+// errors.Is matches it against the Err it wraps.
+type LimitError struct {
+	Err     error // ErrBetBelowMin, ErrBetAboveMax, ErrInvalidDenom or ErrInvalidLines
+	Value   int   // Value received in the bet
+	Min     int   // Lowest value allowed, if it is a range
+	Max     int   // Highest value allowed, if it is a range
+	Allowed []int // Values allowed, if it is a list
+}
+
+func (e *LimitError) Error() string {
+	if len(e.Allowed) > 0 {
+		return fmt.Sprintf("%s. Got:[%d] Allowed:%v", e.Err, e.Value, e.Allowed)
 	}
-	wager := bet * len(ad.PayLines)
+	return fmt.Sprintf("%s. Got:[%d] Min:[%d] Max:[%d]", e.Err, e.Value, e.Min, e.Max)
+}
+
+func (e *LimitError) Unwrap() error {
+	return e.Err
+}
+
+// Wager validates the bet against the machine limits and returns the chips it costs.
+// Denom and Lines default to the first denomination and all the pay lines when 0.
+func (ad *AtkinsDietMachine) Wager(bet slotmachine.Bet, chips int) (int, error) {
+	bet = ad.withDefaults(bet)
+	if err := ad.validate(bet); err != nil {
+		return 0, err
+	}
+	wager := bet.Total()
 	if wager > chips {
 		return wager, ErrChipsInsufficient
 	}
 	return wager, nil
 }
 
-func (ad *AtkinsDietMachine) Spin(bet int) (int, []slotmachine.SpinResult, error) {
+func (ad *AtkinsDietMachine) withDefaults(bet slotmachine.Bet) slotmachine.Bet {
+	if bet.Denom == 0 && len(ad.BetLimits.Denominations) > 0 {
+		bet.Denom = ad.BetLimits.Denominations[0]
+	}
+	if bet.Lines == 0 {
+		bet.Lines = len(ad.PayLines)
+	}
+	return bet
+}
+
+func (ad *AtkinsDietMachine) validate(bet slotmachine.Bet) error {
+	limits := ad.BetLimits
+	if bet.Coins <= 0 {
+		return ErrInvalidBet
+	}
+	if bet.Coins < limits.MinBet {
+		return &LimitError{Err: ErrBetBelowMin, Value: bet.Coins, Min: limits.MinBet, Max: limits.MaxBet}
+	}
+	if limits.MaxBet > 0 && bet.Coins > limits.MaxBet {
+		return &LimitError{Err: ErrBetAboveMax, Value: bet.Coins, Min: limits.MinBet, Max: limits.MaxBet}
+	}
+	if !allowedDenom(bet.Denom, limits.Denominations) {
+		return &LimitError{Err: ErrInvalidDenom, Value: bet.Denom, Allowed: limits.Denominations}
+	}
+	if bet.Lines < 1 || bet.Lines > len(ad.PayLines) {
+		return &LimitError{Err: ErrInvalidLines, Value: bet.Lines, Min: 1, Max: len(ad.PayLines)}
+	}
+	return nil
+}
+
+func allowedDenom(denom int, denominations []int) bool {
+	// No list of denominations means that any coin value is allowed
+	if len(denominations) == 0 {
+		return denom > 0
+	}
+	for _, d := range denominations {
+		if d == denom {
+			return true
+		}
+	}
+	return false
+}
 
+// Spin plays the main spin and the free spins it triggers, each followed by the respins of its walking wilds.
+// Logs are written to the logger in the context.
+func (ad *AtkinsDietMachine) Spin(ctx context.Context, bet slotmachine.Bet) (int, []slotmachine.SpinResult, error) {
+	return ad.round(ctx, bet, true)
+}
+
+// round plays a round of Spin, pausing the long runs of free spins if asked
+func (ad *AtkinsDietMachine) round(ctx context.Context, bet slotmachine.Bet, pause bool) (int, []slotmachine.SpinResult, error) {
 	var (
-		spinResults []slotmachine.SpinResult
-		freeSpin    = true
-		mainSpin    = false
+		state spinState
+		log   = logger.FromContext(ctx).With("engine", "atkins")
 	)
 
+	bet = ad.withDefaults(bet)
+	if err := ad.validate(bet); err != nil {
+		return 0, nil, err
+	}
+
 	// Main Spin
-	spinResult, err := ad.spin(bet, mainSpin)
+	totalPayout, freeSpins, spinResults, err := ad.play(ctx, log, bet, &state, slotmachine.MAIN_SPIN)
 	if err != nil {
 		return 0, spinResults, err
 	}
 
-	spinResult.Type = slotmachine.MAIN_SPIN
-	spinResults = append(spinResults, spinResult)
-
-	if spinResult.FreeSpins == 0 {
-		return spinResult.Pay, spinResults, nil
+	// Hold and Spin - if the main spin shows the coins
+	if hold.Triggered(ad.Hold, spinResults) {
+		log.Debug("Hold and spin", "coins", spinner.CountWindow(spinResults[0].Window, ad.Hold.Symbol))
+		pay, results, err := hold.Play(ctx, *ad.Hold, ad.Reels, spinResults[0].Window, bet.Total())
+		spinResults = append(spinResults, results...)
+		if err != nil {
+			return totalPayout, spinResults, err
+		}
+		totalPayout = totalPayout + pay
 	}
 
 	// Free Spins - if any
-	var (
-		freeSpins   = spinResult.FreeSpins
-		totalPayout = spinResult.Pay
-	)
-
+	state.free = true
+	state.multiplier = ad.FreeSpinRules.Multiplier
+	if state.multiplier <= 0 {
+		state.multiplier = 1
+	}
 	for i := 0; freeSpins > 0; i++ {
 		freeSpins--
-		slog.Println("Remaining free spins:", freeSpins)
+		log.Debug("Free spin", "remaining", freeSpins)
 
 		// Incase we are stuck in an infinite loop of free spins
 		// We slow down the free spins to allow other goroutines to work
 		// Sleeping on every 16th iteration
-		if i&16 == 16 {
+		if pause && i&16 == 16 {
 			i = 1
 			time.Sleep(500 * time.Millisecond)
 		}
 
-		spinResult, err = ad.spin(bet, freeSpin)
+		pay, awarded, results, err := ad.play(ctx, log, bet, &state, slotmachine.FREE_SPIN)
+		spinResults = append(spinResults, results...)
 		if err != nil {
 			return totalPayout, spinResults, err
 		}
-
-		spinResult.Type = slotmachine.FREE_SPIN
-		spinResults = append(spinResults, spinResult)
-		freeSpins = freeSpins + spinResult.FreeSpins
-		totalPayout = totalPayout + spinResult.Pay
+		freeSpins = freeSpins + awarded
+		totalPayout = totalPayout + pay
+		if awarded > 0 && ad.FreeSpinRules.StepOn != slotmachine.STEP_ON_RESPIN {
+			ad.stepMultiplier(&state)
+		}
 	}
 
 	return totalPayout, spinResults, nil
 }
 
-func (ad *AtkinsDietMachine) spin(bet int, freeSpin bool) (slotmachine.SpinResult, error) {
-	spinResult, err := spinner.SpinNPay(
-		ad.Reels,
-		ad.PayLines,
-		ad.PayTable,
-		ad.SpecialSymbols,
+// spinState carries the wilds and the multiplier of a round from a spin to the next ones
+type spinState struct {
+	free       bool               // Free spins are played
+	multiplier int                // Multiplier of the free spins
+	sticky     []slotmachine.Cell // Wilds held until the free spins end
+	walking    []slotmachine.Cell // Wilds in view on the next respin, once walked
+}
+
+// stepMultiplier raises the multiplier of the free spins by the step of the rules, up to their maximum
+func (ad *AtkinsDietMachine) stepMultiplier(state *spinState) {
+	rules := ad.FreeSpinRules
+	if rules.MultiplierStep <= 0 {
+		return
+	}
+	state.multiplier = state.multiplier + rules.MultiplierStep
+	if rules.MaxMultiplier > 0 && state.multiplier > rules.MaxMultiplier {
+		state.multiplier = rules.MaxMultiplier
+	}
+}
+
+// _MAX_RESPINS is the most respins a spin plays for its walking wilds. The wilds walk off in as many
+// respins as the reels, unless new ones land: the respins are capped should they keep landing.
+const _MAX_RESPINS = 50
+
+// play plays a spin of the kind, then respins while walking wilds are in view, up to _MAX_RESPINS.
+// It returns the pay of the spins and the free spins they award.
+func (ad *AtkinsDietMachine) play(ctx context.Context, log *logger.Logger, bet slotmachine.Bet, state *spinState, kind string) (int, int, []slotmachine.SpinResult, error) {
+	var (
+		pay, freeSpins int
+		spinResults    []slotmachine.SpinResult
 	)
+	for respins := 0; ; respins++ {
+		spinResult, err := ad.spin(log, bet, state)
+		if err != nil {
+			return pay, freeSpins, spinResults, err
+		}
+		spinResult.Type = kind
+		spinResults = append(spinResults, spinResult)
+		slotmachine.NotifySpin(ctx, spinResult)
+		pay = pay + spinResult.Pay
+		freeSpins = freeSpins + spinResult.FreeSpins
+		if len(state.walking) == 0 {
+			return pay, freeSpins, spinResults, nil
+		}
+		if respins == _MAX_RESPINS {
+			log.Warn("Respins of the walking wilds capped", "respins", respins)
+			state.walking = nil
+			return pay, freeSpins, spinResults, nil
+		}
+		kind = slotmachine.RESPIN
+		if state.free && ad.FreeSpinRules.StepOn == slotmachine.STEP_ON_RESPIN {
+			ad.stepMultiplier(state)
+		}
+	}
+}
+
+func (ad *AtkinsDietMachine) spin(log *logger.Logger, bet slotmachine.Bet, state *spinState) (slotmachine.SpinResult, error) {
+	var spinResult slotmachine.SpinResult
+	stops, err := spinner.Spin(ad.Reels)
+	if err != nil {
+		return spinResult, err
+	}
+	window := spinner.Window(stops, ad.Reels)
+	wilds := ad.placeWilds(window, state)
+
+	// Only the active lines are evaluated, on the window changed by the wilds
+	payLines := ad.PayLines[:bet.Lines]
+	winLines, err := spinner.FindWindowWins(window, payLines, ad.SpecialSymbols)
+	if err != nil {
+		return spinResult, err
+	}
+	for i := range winLines {
+		winLines[i].PayLine = payLines[winLines[i].Index-1]
+	}
+	spinResult, err = spinner.CalculatePay(winLines, ad.PayTable, ad.SpecialSymbols)
 	if err != nil {
 		return spinResult, err
 	}
+	spinResult.ScatterCount = spinner.CountWindow(window, ad.Scatter)
+	spinResult.Window = window
+	spinResult.Wilds = wilds
+
+	// Changing stops to Human-friendly numbering (starts from 1)
+	for i := range stops {
+		stops[i]++
+	}
+	spinResult.Stops = stops
 
 	spinResult.FreeSpins = ad.getFreeSpins(spinResult.ScatterCount)
-	slog.Println("Got FreeSpins:", spinResult.FreeSpins)
+	spinResult.Multipliers = spinner.DrawMultipliers(spinner.WildCells(window, ad.Wildcard), ad.Wilds.Multipliers)
 
-	// Multiplying payout by wager
-	if freeSpin {
-		bet = bet * 3
+	// Multiplying payout by the chips bet on each line, and the multipliers of the wilds paid on it
+	spinResult.Multiplier = 1
+	if state.free {
+		spinResult.Multiplier = state.multiplier
 	}
+	lineBet := bet.LineBet() * spinResult.Multiplier
+	spinResult.Pay = 0
 	for i := 0; i < len(spinResult.WinLines); i++ {
-		spinResult.WinLines[i].Payout = spinResult.WinLines[i].Payout * bet
+		winLine := &spinResult.WinLines[i]
+		winLine.Name = ad.SymbolName(winLine.Symbol)
+		winLine.WildMultiplier = spinner.LineMultiplier(*winLine, spinResult.Multipliers, ad.Wilds.Combine)
+		winLine.Multiplier = spinResult.Multiplier * winLine.WildMultiplier
+		winLine.Payout = winLine.Payout * lineBet * winLine.WildMultiplier
+		spinResult.Pay = spinResult.Pay + winLine.Payout
 	}
-	spinResult.Pay = spinResult.Pay * bet
-	slog.Printf("Spin Result %#v [Bet:%d]", spinResult, bet)
+
+	if ad.LogWinLines && log.Enabled(logger.DEBUG) {
+		for _, winLine := range spinResult.WinLines {
+			log.Debug("Win line", "line", winLine.Index, "symbol", ad.SymbolName(winLine.Symbol),
+				"count", winLine.Count, "multiplier", winLine.Multiplier, "payout", winLine.Payout)
+		}
+	}
+	log.Debug("Spin", "free", state.free, "stops", fmt.Sprint(spinResult.Stops), "pay", spinResult.Pay,
+		"line_bet", lineBet, "scatters", spinResult.ScatterCount, "free_spins", spinResult.FreeSpins,
+		"wilds", len(wilds))
 	return spinResult, nil
 }
 
+// placeWilds changes the window by the wild behaviours of the machine and returns the changes.
+// The wilds landed or walked in view walk on the next respin. Sticky wilds are held
+// once expanded, until the free spins end.
+func (ad *AtkinsDietMachine) placeWilds(window [][]slotmachine.Symbol, state *spinState) []slotmachine.WildChange {
+	var (
+		rules   = ad.Wilds
+		changes []slotmachine.WildChange
+	)
+	add := func(behaviour string, cells []slotmachine.Cell) {
+		if len(cells) > 0 {
+			changes = append(changes, slotmachine.WildChange{Behaviour: behaviour, Cells: cells})
+		}
+	}
+	if rules.Walking {
+		add(slotmachine.WALKING_WILD, spinner.PlaceWilds(window, state.walking, ad.Wildcard))
+		state.walking = spinner.WalkWilds(spinner.WildCells(window, ad.Wildcard))
+	}
+	sticky := rules.Sticky && state.free
+	if sticky {
+		add(slotmachine.STICKY_WILD, spinner.PlaceWilds(window, state.sticky, ad.Wildcard))
+	}
+	if rules.Expanding {
+		add(slotmachine.EXPANDING_WILD, spinner.ExpandWilds(window, ad.Wildcard))
+	}
+	if sticky {
+		state.sticky = spinner.WildCells(window, ad.Wildcard)
+	}
+	return changes
+}
+
+// SymbolName returns the name of the symbol, or its number if it is unknown
+func (ad *AtkinsDietMachine) SymbolName(symbol slotmachine.Symbol) string {
+	return ad.Symbols.Name(symbol)
+}
+
+// Limits returns the bets the machine accepts
+func (ad *AtkinsDietMachine) Limits() slotmachine.BetLimits {
+	return ad.BetLimits
+}
+
+// BonusGame returns the rules of the pick game launched by the main spins, nil if the machine has none
+func (ad *AtkinsDietMachine) BonusGame() *slotmachine.BonusRules {
+	return ad.Bonus
+}
+
+// SymbolInfo returns the description of the symbol in the catalogue of the machine
+func (ad *AtkinsDietMachine) SymbolInfo(symbol slotmachine.Symbol) slotmachine.SymbolInfo {
+	return ad.Symbols.Info(symbol)
+}
+
 func (ad *AtkinsDietMachine) getFreeSpins(scatterCount int) int {
-	if scatterCount >= _SCATTER_COUNT_FOR_FREE_SPIN {
-		return _FREE_SPINS
+	if ad.FreeSpinRules.ScatterCount > 0 && scatterCount >= ad.FreeSpinRules.ScatterCount {
+		return ad.FreeSpinRules.Spins
 	}
 	return 0
 }
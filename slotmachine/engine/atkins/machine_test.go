@@ -1,7 +1,15 @@
 package atkins
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
 	"testing"
+
+	"trippy/slotmachine"
+	"trippy/spinner"
 )
 
 var (
@@ -12,12 +20,21 @@ var (
 		{bet: 0, chips: 200, err: ErrInvalidBet, wager: 0},
 		{bet: 200, chips: 100, err: ErrChipsInsufficient, wager: 200 * len(adm.PayLines)},
 		{bet: -2, chips: 100, err: ErrInvalidBet, wager: 0},
+
+		// Bet limits, denominations and lines
+		{bet: _MAX_BET + 1, chips: 1000000, err: ErrBetAboveMax, wager: 0},
+		{bet: 10, denom: 3, chips: 1000, err: ErrInvalidDenom, wager: 0},
+		{bet: 10, denom: 5, chips: 1000, err: nil, wager: 10 * 5 * len(adm.PayLines)},
+		{bet: 10, lines: 5, chips: 100, err: nil, wager: 10 * 5},
+		{bet: 10, denom: 2, lines: 1, chips: 100, err: nil, wager: 10 * 2},
+		{bet: 10, lines: len(adm.PayLines) + 1, chips: 1000, err: ErrInvalidLines, wager: 0},
+		{bet: 10, lines: -1, chips: 1000, err: ErrInvalidLines, wager: 0},
 	}
 )
 
 type wagerSample struct {
-	bet, chips, wager int
-	err               error
+	bet, denom, lines, chips, wager int
+	err                             error
 }
 
 func TestWager(t *testing.T) {
@@ -27,17 +44,386 @@ func TestWager(t *testing.T) {
 }
 
 func testWager(t *testing.T, sample wagerSample) {
-	wager, err := adm.Wager(sample.bet, sample.chips)
-	if err != sample.err {
-		t.Errorf("Wager sufficient or not. Bet:[%d] Chips:[%d] Expected:[%s] Got:[%s]",
-			sample.bet, sample.chips, sample.err, err)
+	bet := slotmachine.Bet{Coins: sample.bet, Denom: sample.denom, Lines: sample.lines}
+	wager, err := adm.Wager(bet, sample.chips)
+	if !errors.Is(err, sample.err) || (err == nil) != (sample.err == nil) {
+		t.Errorf("Wager sufficient or not. Bet:[%+v] Chips:[%d] Expected:[%s] Got:[%s]",
+			bet, sample.chips, sample.err, err)
 		return
 	}
 	if err != nil {
 		return
 	}
 	if wager != sample.wager {
-		t.Errorf("Bet:[%d] Chips:[%d] Expected:[%d] Got:[%d]",
-			sample.bet, sample.chips, sample.wager, wager)
+		t.Errorf("Bet:[%+v] Chips:[%d] Expected:[%d] Got:[%d]",
+			bet, sample.chips, sample.wager, wager)
+	}
+}
+
+func TestBetBelowMin(t *testing.T) {
+	machine := NewAtkinsDietMachine()
+	machine.BetLimits.MinBet = 5
+	_, err := machine.Wager(slotmachine.Bet{Coins: 2}, 1000)
+	var limitErr *LimitError
+	if !errors.As(err, &limitErr) || !errors.Is(err, ErrBetBelowMin) {
+		t.Fatalf("Expected:[%s] Got:[%s]", ErrBetBelowMin, err)
+	}
+	if limitErr.Min != 5 || limitErr.Value != 2 {
+		t.Errorf("Expected:[Min:5 Value:2] Got:[Min:%d Value:%d]", limitErr.Min, limitErr.Value)
+	}
+}
+
+func TestSpinActiveLines(t *testing.T) {
+	for i := 0; i < 50; i++ {
+		_, results, err := adm.Spin(context.Background(), slotmachine.Bet{Coins: 1, Lines: 3})
+		if err != nil {
+			t.Fatalf("Expected:[nil] Got:[%s]", err)
+		}
+		for _, result := range results {
+			for _, line := range result.WinLines {
+				if line.Index > 3 {
+					t.Fatalf("Win on inactive line. Lines:[3] Got:[%d]", line.Index)
+				}
+			}
+		}
+	}
+}
+
+func TestDefinitionFile(t *testing.T) {
+	def, err := slotmachine.LoadDefinition("atkins-diet.json")
+	if err != nil {
+		t.Fatalf("Unable to load definition [Error:%s]", err)
+	}
+	if !reflect.DeepEqual(def, DefaultDefinition()) {
+		t.Errorf("atkins-diet.json differs from the default definition. Got:[%+v]", def)
+	}
+	if def, err = slotmachine.LoadDefinition("atkins-hold.json"); err != nil || !reflect.DeepEqual(def, HoldDefinition()) {
+		t.Errorf("atkins-hold.json differs from the hold definition. Got:[%+v] [Error:%v]", def, err)
+	}
+}
+
+// Symbols are written by name and read by name or by number
+func TestDefinitionSymbolNames(t *testing.T) {
+	data, err := json.Marshal(DefaultDefinition())
+	if err != nil {
+		t.Fatalf("Unable to marshal definition [Error:%s]", err)
+	}
+	if !strings.Contains(string(data), `["SCALE","MAYONNAISE","HAM","HAM","BACON"]`) ||
+		!strings.Contains(string(data), `"ATKINS":{`) {
+		t.Errorf("Expected the symbols by name. Got:[%s]", data)
+	}
+	var def slotmachine.Definition
+	if err = json.Unmarshal(data, &def); err != nil || !reflect.DeepEqual(def, DefaultDefinition()) {
+		t.Errorf("Expected the default definition back. Got:[%+v] [Error:%v]", def, err)
+	}
+
+	// Definitions without a catalogue use numbers
+	numbered := DefaultDefinition()
+	numbered.Symbols = nil
+	if data, err = json.Marshal(numbered); err != nil || !strings.Contains(string(data), `[11,10,3,3,9]`) {
+		t.Errorf("Expected the symbols by number. Got:[%s] [Error:%v]", data, err)
+	}
+	def = slotmachine.Definition{}
+	if err = json.Unmarshal(data, &def); err != nil || !reflect.DeepEqual(def, numbered) {
+		t.Errorf("Expected the numbered definition back. Got:[%+v] [Error:%v]", def, err)
+	}
+
+	if err = json.Unmarshal([]byte(`{"reels":[["STEAK","LOBSTER"]]}`), &def); err == nil {
+		t.Errorf("Expected an error for a symbol missing from the catalogue")
+	}
+}
+
+func TestSymbolCatalogue(t *testing.T) {
+	if info := adm.SymbolInfo(adm.Wildcard); info.Kind != slotmachine.WILD || info.Name != "ATKINS" {
+		t.Errorf("Expected the wildcard to be wild. Got:[%+v]", info)
+	}
+	if info := adm.SymbolInfo(adm.Scatter); info.Kind != slotmachine.SCATTER || info.Code != "SCL" {
+		t.Errorf("Expected the scatter to be a scatter. Got:[%+v]", info)
+	}
+	if info := adm.SymbolInfo(99); info.Name != "99" || info.Kind != slotmachine.REGULAR {
+		t.Errorf("Expected unknown symbols to be named by their number. Got:[%+v]", info)
+	}
+
+	for i := 0; i < 200; i++ {
+		_, results, err := adm.Spin(context.Background(), slotmachine.Bet{Coins: 1})
+		if err != nil {
+			t.Fatalf("Expected:[nil] Got:[%s]", err)
+		}
+		for _, result := range results {
+			for _, line := range result.WinLines {
+				if line.Name != adm.SymbolName(line.Symbol) {
+					t.Fatalf("Expected win line of:[%s] Got:[%s]", adm.SymbolName(line.Symbol), line.Name)
+				}
+			}
+		}
+	}
+}
+
+func TestSpinObserver(t *testing.T) {
+	for i := 0; i < 50; i++ {
+		var observed []slotmachine.SpinResult
+		ctx := slotmachine.WithSpinObserver(context.Background(), func(result slotmachine.SpinResult) {
+			observed = append(observed, result)
+		})
+		_, results, err := adm.Spin(ctx, slotmachine.Bet{Coins: 1})
+		if err != nil {
+			t.Fatalf("Expected:[nil] Got:[%s]", err)
+		}
+		if !reflect.DeepEqual(observed, results) {
+			t.Fatalf("Expected every spin to be observed in order. Expected:[%d] Got:[%d]", len(results), len(observed))
+		}
+	}
+}
+
+// wildMachine is the default machine with the wild behaviours
+func wildMachine(rules slotmachine.WildRules) *AtkinsDietMachine {
+	ad := NewAtkinsDietMachine()
+	ad.Wilds = rules
+	return ad
+}
+
+// isWild returns true if the cell of the window shows the wildcard
+func isWild(result slotmachine.SpinResult, cell slotmachine.Cell) bool {
+	return result.Window[cell.Row-1][cell.Reel-1] == _ATKINS
+}
+
+// Walking wilds landing on every respin stop respinning at the cap
+func TestRespinLimit(t *testing.T) {
+	ad := wildMachine(slotmachine.WildRules{Walking: true})
+	ad.Reels = slotmachine.Reels{
+		{_ATKINS, _ATKINS, _ATKINS, _ATKINS, _ATKINS},
+		{_ATKINS, _ATKINS, _ATKINS, _ATKINS, _ATKINS},
+		{_ATKINS, _ATKINS, _ATKINS, _ATKINS, _ATKINS},
+	}
+	_, results, err := ad.Spin(context.Background(), slotmachine.Bet{Coins: 1})
+	if err != nil {
+		t.Fatalf("Expected:[nil] Got:[%s]", err)
+	}
+	if len(results) != _MAX_RESPINS+1 || results[0].Type != slotmachine.MAIN_SPIN || results[_MAX_RESPINS].Type != slotmachine.RESPIN {
+		t.Errorf("Expected the main spin and:[%d] respins Got:[%d]", _MAX_RESPINS, len(results))
+	}
+}
+
+type wildSample struct {
+	rules slotmachine.WildRules
+	check func(t *testing.T, results []slotmachine.SpinResult)
+}
+
+var wildSamples = []wildSample{
+	// Every reel showing a wild is filled with it
+	{rules: slotmachine.WildRules{Expanding: true}, check: func(t *testing.T, results []slotmachine.SpinResult) {
+		for _, result := range results {
+			for _, cell := range spinner.WildCells(result.Window, _ATKINS) {
+				for row := 1; row <= 3; row++ {
+					if !isWild(result, slotmachine.Cell{Reel: cell.Reel, Row: row}) {
+						t.Fatalf("Expected reel:[%d] wild Got:[%v]", cell.Reel, result.Window)
+					}
+				}
+			}
+		}
+	}},
+	// The wilds of a spin are one reel left on the respin following it
+	{rules: slotmachine.WildRules{Walking: true}, check: func(t *testing.T, results []slotmachine.SpinResult) {
+		for i, result := range results {
+			walked := spinner.WalkWilds(spinner.WildCells(result.Window, _ATKINS))
+			if len(walked) == 0 {
+				if i+1 < len(results) && results[i+1].Type == slotmachine.RESPIN {
+					t.Fatalf("Expected no respin without wilds to walk Got:[%v]", result.Window)
+				}
+				continue
+			}
+			if i+1 == len(results) || results[i+1].Type != slotmachine.RESPIN {
+				t.Fatalf("Expected a respin for the wilds:[%v]", walked)
+			}
+			for _, cell := range walked {
+				if !isWild(results[i+1], cell) {
+					t.Fatalf("Expected the wild walked to:[%+v] Got:[%v]", cell, results[i+1].Window)
+				}
+			}
+		}
+	}},
+	// The wilds of a free spin stay in place in the next ones
+	{rules: slotmachine.WildRules{Sticky: true}, check: func(t *testing.T, results []slotmachine.SpinResult) {
+		var held []slotmachine.Cell
+		for _, result := range results {
+			if result.Type != slotmachine.FREE_SPIN {
+				continue
+			}
+			for _, cell := range held {
+				if !isWild(result, cell) {
+					t.Fatalf("Expected the sticky wild:[%+v] Got:[%v]", cell, result.Window)
+				}
+			}
+			held = spinner.WildCells(result.Window, _ATKINS)
+		}
+	}},
+}
+
+func TestWildBehaviours(t *testing.T) {
+	for _, sample := range wildSamples {
+		testWildBehaviours(t, sample)
+	}
+}
+
+func testWildBehaviours(t *testing.T, sample wildSample) {
+	ad := wildMachine(sample.rules)
+	var changed bool
+	for i := 0; i < 1000; i++ {
+		payout, results, err := ad.Spin(context.Background(), slotmachine.Bet{Coins: 1})
+		if err != nil {
+			t.Fatalf("Expected:[nil] Got:[%s]", err)
+		}
+		var paid int
+		for _, result := range results {
+			paid = paid + result.Pay
+			for _, change := range result.Wilds {
+				changed = true
+				for _, cell := range change.Cells {
+					if !isWild(result, cell) {
+						t.Fatalf("[%s] Expected:[%+v] wild Got:[%v]", change.Behaviour, cell, result.Window)
+					}
+				}
+			}
+		}
+		if paid != payout {
+			t.Fatalf("Expected the pays of the spins:[%d] Got:[%d]", paid, payout)
+		}
+		sample.check(t, results)
+	}
+	if !changed {
+		t.Errorf("[Wilds:%+v] Expected the window changed by the wilds", sample.rules)
+	}
+}
+
+// Every wild carries a multiplier, combined on the lines paying it, and the free spins
+// are multiplied by one more on each retrigger up to 5
+func TestWinMultipliers(t *testing.T) {
+	ad := NewAtkinsDietMachine()
+	ad.Wilds.Multipliers = []slotmachine.WildMultiplier{{Multiplier: 1, Weight: 1}, {Multiplier: 2, Weight: 1}, {Multiplier: 3, Weight: 1}}
+	ad.FreeSpinRules = slotmachine.FreeSpinRules{ScatterCount: 2, Spins: 2, Multiplier: 3, MultiplierStep: 1, MaxMultiplier: 5}
+
+	var multiplied, stepped bool
+	for i := 0; i < 1000; i++ {
+		payout, results, err := ad.Spin(context.Background(), slotmachine.Bet{Coins: 1})
+		if err != nil {
+			t.Fatalf("Expected:[nil] Got:[%s]", err)
+		}
+		var paid int
+		multiplier := ad.FreeSpinRules.Multiplier
+		for j, result := range results {
+			switch {
+			case result.Type == slotmachine.MAIN_SPIN && result.Multiplier != 1:
+				t.Fatalf("Expected:[1] for the main spin Got:[%d]", result.Multiplier)
+			case result.Type == slotmachine.FREE_SPIN && result.Multiplier != multiplier:
+				t.Fatalf("[Spin:%d] Expected the free spin multiplier:[%d] Got:[%d]", j, multiplier, result.Multiplier)
+			}
+			if result.Type == slotmachine.FREE_SPIN && result.FreeSpins > 0 && multiplier < 5 {
+				multiplier++
+				stepped = true
+			}
+			if wilds := spinner.CountWindow(result.Window, _ATKINS); len(result.Multipliers) != wilds {
+				t.Fatalf("Expected a multiplier for each of the:[%d] wilds Got:[%+v]", wilds, result.Multipliers)
+			}
+			var pay int
+			for _, winLine := range result.WinLines {
+				wild := spinner.LineMultiplier(winLine, result.Multipliers, ad.Wilds.Combine)
+				if winLine.WildMultiplier != wild || winLine.Multiplier != result.Multiplier*wild {
+					t.Fatalf("Expected the multipliers:[%d x %d] Got:[%+v]", result.Multiplier, wild, winLine)
+				}
+				multiplied = multiplied || wild > 1
+				pay = pay + winLine.Payout
+			}
+			if pay != result.Pay {
+				t.Fatalf("Expected the pay of the lines:[%d] Got:[%d]", pay, result.Pay)
+			}
+			paid = paid + result.Pay
+		}
+		if paid != payout {
+			t.Fatalf("Expected the pays of the spins:[%d] Got:[%d]", paid, payout)
+		}
+	}
+	if !multiplied || !stepped {
+		t.Errorf("Expected lines multiplied by wilds:[%t] and free spins retriggered:[%t]", multiplied, stepped)
+	}
+}
+
+// The free spins are multiplied by one more on each respin of the walking wilds
+func TestRespinMultiplier(t *testing.T) {
+	ad := wildMachine(slotmachine.WildRules{Walking: true})
+	ad.FreeSpinRules = slotmachine.FreeSpinRules{ScatterCount: 2, Spins: 2, Multiplier: 1, MultiplierStep: 1, StepOn: slotmachine.STEP_ON_RESPIN}
+
+	var stepped bool
+	for i := 0; i < 1000; i++ {
+		_, results, err := ad.Spin(context.Background(), slotmachine.Bet{Coins: 1})
+		if err != nil {
+			t.Fatalf("Expected:[nil] Got:[%s]", err)
+		}
+		// The main spin and its respins are not multiplied
+		var free bool
+		multiplier := 1
+		for j, result := range results {
+			free = free || result.Type == slotmachine.FREE_SPIN
+			if free && result.Type == slotmachine.RESPIN {
+				multiplier++
+				stepped = true
+			}
+			if result.Multiplier != multiplier {
+				t.Fatalf("[Spin:%d %s] Expected:[%d] Got:[%d]", j, result.Type, multiplier, result.Multiplier)
+			}
+		}
+	}
+	if !stepped {
+		t.Errorf("Expected respins in the free spins")
+	}
+}
+
+// Hold and spin follows the main spins showing the coins, the last hold spin paying them
+func TestHoldAndSpin(t *testing.T) {
+	ad := NewAtkinsDietMachineFromDefinition(HoldDefinition())
+	ad.Hold.Count = 2
+
+	var launched bool
+	for i := 0; i < 500; i++ {
+		payout, results, err := ad.Spin(context.Background(), slotmachine.Bet{Coins: 1})
+		if err != nil {
+			t.Fatalf("Expected:[nil] Got:[%s]", err)
+		}
+		var (
+			paid int
+			last *slotmachine.SpinResult
+		)
+		for j := range results {
+			paid = paid + results[j].Pay
+			if results[j].Type != slotmachine.HOLD_SPIN {
+				continue
+			}
+			if previous := results[j-1].Type; previous != slotmachine.MAIN_SPIN && previous != slotmachine.HOLD_SPIN {
+				t.Fatalf("[Spin:%d] Expected the hold spins after the main spin Got:[%s]", j, previous)
+			}
+			last = &results[j]
+		}
+		if paid != payout {
+			t.Fatalf("Expected the pays of the spins:[%d] Got:[%d]", paid, payout)
+		}
+		if triggered := spinner.CountWindow(results[0].Window, _COIN) >= ad.Hold.Count; triggered != (last != nil) {
+			t.Fatalf("Expected hold and spin:[%t] for the window:[%v]", triggered, results[0].Window)
+		}
+		if last == nil {
+			continue
+		}
+		launched = true
+		var coins int
+		for _, coin := range last.Coins {
+			coins = coins + coin.Value
+		}
+		if last.Jackpot == slotmachine.HOLD_GRAND {
+			coins = coins + _HOLD_GRAND*len(ad.PayLines)
+		}
+		if last.Pay != coins {
+			t.Fatalf("Expected the coins paid:[%d] Got:[%+v]", coins, *last)
+		}
+	}
+	if !launched {
+		t.Errorf("Expected hold and spin launched")
 	}
 }
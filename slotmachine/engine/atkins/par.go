@@ -0,0 +1,229 @@
+package atkins
+
+import (
+	"errors"
+	"math"
+	"sort"
+
+	"trippy/slotmachine"
+	"trippy/slotmachine/parsheet"
+	"trippy/spinner"
+)
+
+const (
+	_VOLATILITY_CONFIDENCE = 0.9   // Confidence of the volatility index
+	_VOLATILITY_Z          = 1.645 // z of the 90% confidence
+)
+
+// ErrParSheetWilds is returned for the machines whose wilds expand or carry multipliers, the lines of a spin
+// are not tabled
+var ErrParSheetWilds = errors.New("PAR sheet does not table the lines of expanding wilds and multipliers")
+
+// payKey is an entry of the pay table
+type payKey struct {
+	symbol slotmachine.Symbol
+	count  int
+}
+
+// ParSheet computes the PAR sheet of the machine.
+// Every combination of the symbols of a line is evaluated by the spinner, weighted by the stops showing it.
+// The lines are the ones of a spin without the wilds kept from the spins before, sticky or walking.
+// The rounds are simulated for the variance of a round, and for the RTP of those wilds and of the multipliers
+// raised by steps: the sheet then has the confidence interval of the simulation.
+func (ad *AtkinsDietMachine) ParSheet(name string, rounds int) (parsheet.Sheet, error) {
+	if ad.Wilds.Expanding || len(ad.Wilds.Multipliers) > 0 {
+		return parsheet.Sheet{}, ErrParSheetWilds
+	}
+	rtp, err := ad.TheoreticalRTP()
+	modelled := err == nil
+	if !modelled && !errors.Is(err, ErrWildsNotModelled) && !errors.Is(err, ErrStepNotModelled) {
+		return parsheet.Sheet{}, err
+	}
+	estimate, err := ad.SimulatedRTP(rounds)
+	if err != nil {
+		return parsheet.Sheet{}, err
+	}
+	var simulation *parsheet.Simulation
+	if !modelled {
+		rtp = estimate.RTP
+		simulation = &parsheet.Simulation{
+			Confidence: estimate.Confidence,
+			Low:        estimate.Low,
+			High:       estimate.High,
+		}
+	}
+	var (
+		reels = len(ad.Reels[0])
+		stops = len(ad.Reels)
+		sheet = parsheet.Sheet{
+			Machine:    name,
+			Reels:      reels,
+			Stops:      stops,
+			Lines:      len(ad.PayLines),
+			Cycle:      int64(math.Pow(float64(stops), float64(reels))),
+			Symbols:    ad.symbolCounts(),
+			RTP:        rtp,
+			Simulation: simulation,
+
+			Rounds:               estimate.Rounds,
+			Variance:             estimate.StdDev * estimate.StdDev,
+			VolatilityIndex:      _VOLATILITY_Z * estimate.StdDev,
+			VolatilityConfidence: _VOLATILITY_CONFIDENCE,
+		}
+		counts = ad.reelSymbolCounts()
+
+		// The line is evaluated as the middle row of a single stop
+		line      = make(slotmachine.ReelLine, reels)
+		lineReels = slotmachine.Reels{line}
+		middle    = make(slotmachine.PayLines, 1)
+		zeros     = make([]int, reels)
+		hits      = make(map[payKey]int64)
+		paying    int64
+		sum       float64
+		walk      func(reel int, combinations int64)
+	)
+	middle[0] = make(slotmachine.PayLine, reels)
+	for i := range middle[0] {
+		middle[0][i] = 2
+	}
+	walk = func(reel int, combinations int64) {
+		if reel < reels {
+			for _, sc := range counts[reel] {
+				line[reel] = sc.symbol
+				walk(reel+1, combinations*int64(sc.count))
+			}
+			return
+		}
+		wins, _ := spinner.FindWins(zeros, lineReels, middle, ad.SpecialSymbols)
+		result, _ := spinner.CalculatePay(wins, ad.PayTable, ad.SpecialSymbols)
+		if result.Pay == 0 {
+			return
+		}
+		hits[payKey{wins[0].Symbol, wins[0].Count}] += combinations
+		paying = paying + combinations
+		sum = sum + float64(combinations)*float64(result.Pay)
+	}
+	walk(0, 1)
+
+	cycle := float64(sheet.Cycle)
+	for _, key := range ad.payKeys() {
+		pay := parsheet.Pay{
+			Symbol:      ad.SymbolName(key.symbol),
+			Count:       key.count,
+			Payout:      ad.PayTable[key.symbol][key.count],
+			Hits:        hits[key],
+			Probability: float64(hits[key]) / cycle,
+		}
+		if pay.Hits > 0 {
+			pay.HitCycle = cycle / float64(pay.Hits)
+		}
+		pay.RTP = pay.Probability * float64(pay.Payout)
+		sheet.Pays = append(sheet.Pays, pay)
+	}
+	sheet.HitFrequency = float64(paying) / cycle
+	sheet.LineRTP = sum / cycle
+
+	sheet.FreeSpins = parsheet.FreeSpins{
+		ScatterCount:       ad.FreeSpinRules.ScatterCount,
+		Spins:              ad.FreeSpinRules.Spins,
+		Multiplier:         ad.FreeSpinRules.Multiplier,
+		TriggerProbability: ad.FreeSpinProbability(),
+		RTP:                rtp - sheet.LineRTP - ad.BonusRTP() - ad.HoldRTP(),
+	}
+	if ad.FreeSpinRules.Spins > 0 {
+		retrigger := sheet.FreeSpins.TriggerProbability * float64(ad.FreeSpinRules.Spins)
+		sheet.FreeSpins.SpinsPerTrigger = float64(ad.FreeSpinRules.Spins) / (1 - retrigger)
+	}
+	if ad.Bonus != nil {
+		sheet.Bonus = &parsheet.Bonus{
+			Symbol:             ad.SymbolInfo(ad.Bonus.Symbol).Name,
+			Count:              ad.Bonus.Count,
+			Prizes:             ad.Bonus.Prizes,
+			Picks:              ad.Bonus.Picks,
+			TriggerProbability: ad.BonusProbability(),
+			RTP:                ad.BonusRTP(),
+		}
+		if sheet.Bonus.TriggerProbability > 0 && ad.Bonus.Picks > 0 {
+			sheet.Bonus.MeanMultiplier = sheet.Bonus.RTP / sheet.Bonus.TriggerProbability / float64(ad.Bonus.Picks)
+		}
+	}
+	if ad.Hold != nil {
+		_, grand := ad.holdReturns()
+		sheet.Hold = &parsheet.Hold{
+			Symbol:             ad.SymbolInfo(ad.Hold.Symbol).Name,
+			Count:              ad.Hold.Count,
+			Respins:            ad.Hold.Respins,
+			Grand:              ad.Hold.Grand,
+			MeanValue:          ad.meanCoinValue(),
+			TriggerProbability: ad.HoldProbability(),
+			GrandProbability:   grand,
+			RTP:                ad.HoldRTP(),
+		}
+	}
+	return sheet, nil
+}
+
+type symbolCount struct {
+	symbol slotmachine.Symbol
+	count  int
+}
+
+// reelSymbolCounts returns the stops of every symbol of each reel strip, ordered by symbol
+func (ad *AtkinsDietMachine) reelSymbolCounts() [][]symbolCount {
+	reels := make([][]symbolCount, len(ad.Reels[0]))
+	for reel := range reels {
+		counts := make(map[slotmachine.Symbol]int)
+		for _, stop := range ad.Reels {
+			counts[stop[reel]]++
+		}
+		for symbol, count := range counts {
+			reels[reel] = append(reels[reel], symbolCount{symbol, count})
+		}
+		sort.Slice(reels[reel], func(i, j int) bool { return reels[reel][i].symbol < reels[reel][j].symbol })
+	}
+	return reels
+}
+
+// symbolCounts returns the stops of every symbol on each reel, ordered by symbol
+func (ad *AtkinsDietMachine) symbolCounts() []parsheet.SymbolCount {
+	var (
+		reels    = len(ad.Reels[0])
+		bySymbol = make(map[slotmachine.Symbol]*parsheet.SymbolCount)
+		symbols  []slotmachine.Symbol
+	)
+	for _, stop := range ad.Reels {
+		for reel, symbol := range stop {
+			sc, ok := bySymbol[symbol]
+			if !ok {
+				sc = &parsheet.SymbolCount{Symbol: ad.SymbolName(symbol), Counts: make([]int, reels)}
+				bySymbol[symbol] = sc
+				symbols = append(symbols, symbol)
+			}
+			sc.Counts[reel]++
+			sc.Total++
+		}
+	}
+	sort.Slice(symbols, func(i, j int) bool { return symbols[i] < symbols[j] })
+	counts := make([]parsheet.SymbolCount, len(symbols))
+	for i, symbol := range symbols {
+		counts[i] = *bySymbol[symbol]
+	}
+	return counts
+}
+
+// payKeys returns the entries of the pay table, ordered by symbol and by count from the highest
+func (ad *AtkinsDietMachine) payKeys() []payKey {
+	var keys []payKey
+	for symbol, pays := range ad.PayTable {
+		for count := range pays {
+			keys = append(keys, payKey{symbol, count})
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].symbol != keys[j].symbol {
+			return keys[i].symbol < keys[j].symbol
+		}
+		return keys[i].count > keys[j].count
+	})
+	return keys
+}
@@ -0,0 +1,81 @@
+package atkins
+
+import (
+	"math"
+	"testing"
+
+	"trippy/slotmachine"
+)
+
+// The PAR sheet evaluates the lines with the spinner, its returns match the ones of rtp.go
+func TestParSheet(t *testing.T) {
+	sheet, err := adm.ParSheet("atkins-diet", 1000)
+	if err != nil {
+		t.Fatalf("Expected:[nil] Got:[%s]", err)
+	}
+	rtp, _ := adm.TheoreticalRTP()
+	if math.Abs(sheet.LineRTP-adm.LineRTP()) > 1e-9 || math.Abs(sheet.RTP-rtp) > 1e-9 {
+		t.Errorf("Expected:[%f %f] Got:[%f %f]", adm.LineRTP(), rtp, sheet.LineRTP, sheet.RTP)
+	}
+	if math.Abs(sheet.LineRTP+sheet.FreeSpins.RTP-sheet.RTP) > 1e-9 {
+		t.Errorf("Expected the line and free spins returns to add up to:[%f] Got:[%f %f]", sheet.RTP, sheet.LineRTP, sheet.FreeSpins.RTP)
+	}
+
+	var (
+		paysRTP float64
+		hits    int64
+		stops   int
+	)
+	for _, pay := range sheet.Pays {
+		paysRTP = paysRTP + pay.RTP
+		hits = hits + pay.Hits
+	}
+	if math.Abs(paysRTP-sheet.LineRTP) > 1e-9 || math.Abs(float64(hits)/float64(sheet.Cycle)-sheet.HitFrequency) > 1e-9 {
+		t.Errorf("Expected the pays to add up to:[%f %f] Got:[%f %d]", sheet.LineRTP, sheet.HitFrequency, paysRTP, hits)
+	}
+	if len(sheet.Pays) != 34 || sheet.Pays[0].Symbol != "ATKINS" || sheet.Pays[0].Count != 5 || sheet.Pays[0].Hits != 1 {
+		t.Errorf("Expected the 34 pays from 5 ATKINS Got:[%d %+v]", len(sheet.Pays), sheet.Pays[0])
+	}
+	for _, symbol := range sheet.Symbols {
+		stops = stops + symbol.Total
+	}
+	if stops != sheet.Stops*sheet.Reels || sheet.Cycle != 33554432 {
+		t.Errorf("Expected:[%d] stops Got:[%d] Cycle:[%d]", sheet.Stops*sheet.Reels, stops, sheet.Cycle)
+	}
+	if sheet.Rounds != 1000 || sheet.Variance <= 0 || math.Abs(sheet.VolatilityIndex-_VOLATILITY_Z*math.Sqrt(sheet.Variance)) > 1e-9 ||
+		sheet.VolatilityConfidence != 0.9 {
+		t.Errorf("Unexpected volatility [Rounds:%d Variance:%f Index:%f Confidence:%f]", sheet.Rounds, sheet.Variance,
+			sheet.VolatilityIndex, sheet.VolatilityConfidence)
+	}
+
+	if _, err = smallMachine().ParSheet("small", 1000); err != ErrEndlessFreeSpins {
+		t.Errorf("Expected:[%s] Got:[%v]", ErrEndlessFreeSpins, err)
+	}
+	expanding := NewAtkinsDietMachine()
+	expanding.Wilds.Expanding = true
+	multiplied := NewAtkinsDietMachine()
+	multiplied.Wilds.Multipliers = []slotmachine.WildMultiplier{{Multiplier: 2, Weight: 1}}
+	for _, ad := range []*AtkinsDietMachine{expanding, multiplied} {
+		if _, err = ad.ParSheet("wilds", 1000); err != ErrParSheetWilds {
+			t.Errorf("[Wilds:%+v] Expected:[%s] Got:[%v]", ad.Wilds, ErrParSheetWilds, err)
+		}
+	}
+}
+
+// Hold and spin has its section, the returns adding up to the RTP
+func TestHoldParSheet(t *testing.T) {
+	ad := NewAtkinsDietMachineFromDefinition(HoldDefinition())
+	sheet, err := ad.ParSheet("atkins-hold", 1000)
+	if err != nil {
+		t.Fatalf("Expected:[nil] Got:[%s]", err)
+	}
+	if sheet.Hold == nil || sheet.Hold.Symbol != "COIN" || math.Abs(sheet.Hold.RTP-ad.HoldRTP()) > 1e-9 {
+		t.Fatalf("Expected the hold section of COIN Got:[%+v]", sheet.Hold)
+	}
+	if sheet.Hold.GrandProbability <= 0 || sheet.Hold.GrandProbability >= sheet.Hold.TriggerProbability {
+		t.Errorf("Expected a full window in some holds Got:[%f %f]", sheet.Hold.GrandProbability, sheet.Hold.TriggerProbability)
+	}
+	if math.Abs(sheet.LineRTP+sheet.FreeSpins.RTP+sheet.Hold.RTP-sheet.RTP) > 1e-9 || sheet.FreeSpins.RTP < 0 {
+		t.Errorf("Expected the returns to add up to:[%f] Got:[%f %f %f]", sheet.RTP, sheet.LineRTP, sheet.FreeSpins.RTP, sheet.Hold.RTP)
+	}
+}
@@ -0,0 +1,437 @@
+package atkins
+
+import (
+	"errors"
+	"math"
+	"math/bits"
+	"sort"
+
+	"trippy/slotmachine"
+)
+
+var (
+	ErrNoReels          = errors.New("Reels are empty")
+	ErrEndlessFreeSpins = errors.New("Free spins are retriggered endlessly")
+	ErrWildsNotModelled = errors.New("Sticky and walking wilds are not modelled by the theoretical RTP")
+	ErrStepNotModelled  = errors.New("Free spin multipliers raised by steps are not modelled by the theoretical RTP")
+	visibleRowOffsets   = []int{-1, 0, 1} // Rows 1, 2 and 3 of the window around the stop
+)
+
+const _MIDDLE_ROW = 2 // Row of the window showing the stop
+
+type maskProbability struct {
+	mask        int
+	probability float64
+}
+
+type symbolProbability struct {
+	symbol      slotmachine.Symbol
+	probability float64
+}
+
+// TheoreticalRTP is the return to player of the machine: the chips paid over the chips wagered in the long run.
+// It is computed exactly from the reel strips, whatever the bet and the number of lines.
+// Expanding wilds, the multipliers of the wilds and hold and spin are part of it, the spins carrying
+// sticky or walking wilds and the free spins whose multiplier is raised by steps are not.
+func (ad *AtkinsDietMachine) TheoreticalRTP() (float64, error) {
+	if len(ad.Reels) == 0 || len(ad.Reels[0]) == 0 {
+		return 0, ErrNoReels
+	}
+	if ad.Wilds.Sticky || ad.Wilds.Walking {
+		return 0, ErrWildsNotModelled
+	}
+	if ad.FreeSpinRules.MultiplierStep > 0 {
+		return 0, ErrStepNotModelled
+	}
+	rtp := ad.LineRTP()
+
+	// Free spins are awarded S at a time and retriggered with probability p on every free spin,
+	// so a trigger plays S + pS + (pS)^2 + ... = S / (1 - pS) free spins
+	trigger := ad.FreeSpinProbability()
+	if trigger > 0 && ad.FreeSpinRules.Spins > 0 {
+		retrigger := trigger * float64(ad.FreeSpinRules.Spins)
+		if retrigger >= 1 {
+			return 0, ErrEndlessFreeSpins
+		}
+		freeSpins := float64(ad.FreeSpinRules.Spins) / (1 - retrigger)
+		multiplier := 1
+		if ad.FreeSpinRules.Multiplier > 0 {
+			multiplier = ad.FreeSpinRules.Multiplier
+		}
+		rtp = rtp * (1 + trigger*freeSpins*float64(multiplier))
+	}
+	return rtp + ad.BonusRTP() + ad.HoldRTP(), nil
+}
+
+// BonusRTP is the return of the pick game. The game is launched by the main spins only,
+// and every pick reveals a prize drawn from the reveal table, in multiples of the wager.
+func (ad *AtkinsDietMachine) BonusRTP() float64 {
+	if ad.Bonus == nil || len(ad.Reels) == 0 {
+		return 0
+	}
+	var weights, expected float64
+	for _, reveal := range ad.Bonus.Reveals {
+		weights = weights + float64(reveal.Weight)
+		expected = expected + float64(reveal.Multiplier*reveal.Weight)
+	}
+	if weights == 0 {
+		return 0
+	}
+	return ad.BonusProbability() * float64(ad.Bonus.Picks) * expected / weights
+}
+
+// BonusProbability is the probability of a main spin to launch the pick game
+func (ad *AtkinsDietMachine) BonusProbability() float64 {
+	if ad.Bonus == nil {
+		return 0
+	}
+	return ad.windowProbability(ad.Bonus.Symbol, ad.Bonus.Count)
+}
+
+// HoldRTP is the return of hold and spin, launched by the main spins only.
+// The coins are paid their mean credits, and the grand prize is paid for the windows filled with coins.
+func (ad *AtkinsDietMachine) HoldRTP() float64 {
+	if ad.Hold == nil {
+		return 0
+	}
+	coins, full := ad.holdReturns()
+	return coins*ad.meanCoinValue() + full*float64(ad.Hold.Grand)
+}
+
+// HoldProbability is the probability of a main spin to launch hold and spin
+func (ad *AtkinsDietMachine) HoldProbability() float64 {
+	if ad.Hold == nil {
+		return 0
+	}
+	return ad.windowProbability(ad.Hold.Symbol, ad.Hold.Count)
+}
+
+// meanCoinValue is the credits of a coin on average, in multiples of the total bet
+func (ad *AtkinsDietMachine) meanCoinValue() float64 {
+	var weights, expected float64
+	for _, value := range ad.Hold.Values {
+		if value.Weight > 0 {
+			weights = weights + float64(value.Weight)
+			expected = expected + float64(value.Multiplier*value.Weight)
+		}
+	}
+	if weights == 0 {
+		return 0
+	}
+	return expected / weights
+}
+
+// holdReturns returns the coins held at the end of hold and spin and the probability of a window
+// full of coins, expected on a main spin.
+//
+// The cells holding coins are a mask of rows on every reel, the reels respinning independently:
+// a respin adds to the mask of each reel the coins of its new window. The expectations of a mask are
+// found from the ones of the masks holding more coins, so the masks are walked from the fullest.
+func (ad *AtkinsDietMachine) holdReturns() (float64, float64) {
+	rules := ad.Hold
+	if rules == nil || rules.Count <= 0 || rules.Respins < 1 || len(ad.Reels) == 0 {
+		return 0, 0
+	}
+	var (
+		reels   = len(ad.Reels[0])
+		rows    = len(visibleRowOffsets)
+		masks   = 1 << rows
+		full    = 1<<(reels*rows) - 1
+		stops   = float64(len(ad.Reels))
+		trigger = make([][]float64, reels) // trigger[reel][mask] is the probability of the main spin to show the coins of mask
+		respin  = make([][]float64, reels) // respin[reel][mask] is the probability of a respin to show the coins of mask
+		moves   = make([][][]maskProbability, reels)
+	)
+	for reel := 0; reel < reels; reel++ {
+		trigger[reel] = make([]float64, masks)
+		respin[reel] = make([]float64, masks)
+		for stop := range ad.Reels {
+			// The main spin is changed by the expanding wilds, the respins are not
+			var triggerMask, respinMask int
+			for row, symbol := range ad.reelWindow(stop, reel) {
+				if symbol == rules.Symbol {
+					triggerMask |= 1 << row
+				}
+			}
+			for row, offset := range visibleRowOffsets {
+				if ad.Reels[((stop+offset)%len(ad.Reels)+len(ad.Reels))%len(ad.Reels)][reel] == rules.Symbol {
+					respinMask |= 1 << row
+				}
+			}
+			trigger[reel][triggerMask] += 1 / stops
+			respin[reel][respinMask] += 1 / stops
+		}
+	}
+	// moves[reel][mask] are the masks a respin of the reel holding the mask leads to
+	for reel := range moves {
+		moves[reel] = make([][]maskProbability, masks)
+		for mask := range moves[reel] {
+			next := make([]float64, masks)
+			for shown, p := range respin[reel] {
+				next[mask|shown] += p
+			}
+			for to, p := range next {
+				if p > 0 {
+					moves[reel][mask] = append(moves[reel][mask], maskProbability{to, p})
+				}
+			}
+		}
+	}
+	reelMask := func(state, reel int) int {
+		return state >> (reel * rows) & (masks - 1)
+	}
+
+	// coins[state] and grand[state] are the coins held at the end and the probability of a full window,
+	// once the state is held with every respin left
+	var (
+		coins  = make([]float64, full+1)
+		grand  = make([]float64, full+1)
+		states = make([]int, full+1)
+	)
+	for state := range states {
+		states[state] = state
+	}
+	sort.Slice(states, func(i, j int) bool { return bits.OnesCount(uint(states[i])) > bits.OnesCount(uint(states[j])) })
+	for _, state := range states {
+		held := float64(bits.OnesCount(uint(state)))
+		if state == full {
+			coins[state], grand[state] = held, 1
+			continue
+		}
+		// The respins showing new coins start again from the state they lead to
+		var (
+			stay, moveCoins, moveGrand float64
+			walk                       func(reel, next int, probability float64)
+		)
+		walk = func(reel, next int, probability float64) {
+			if reel == reels {
+				if next == state {
+					stay = stay + probability
+				} else {
+					moveCoins = moveCoins + probability*coins[next]
+					moveGrand = moveGrand + probability*grand[next]
+				}
+				return
+			}
+			for _, move := range moves[reel][reelMask(state, reel)] {
+				walk(reel+1, next|move.mask<<(reel*rows), probability*move.probability)
+			}
+		}
+		walk(0, 0, 1)
+
+		// Without new coins, the respins left run out one by one
+		stateCoins, stateGrand := held, 0.0
+		for left := 1; left <= rules.Respins; left++ {
+			stateCoins = moveCoins + stay*stateCoins
+			stateGrand = moveGrand + stay*stateGrand
+		}
+		coins[state], grand[state] = stateCoins, stateGrand
+	}
+
+	// The main spins showing enough coins launch the feature
+	var expectedCoins, expectedGrand float64
+	for state := 0; state <= full; state++ {
+		if bits.OnesCount(uint(state)) < rules.Count {
+			continue
+		}
+		probability := 1.0
+		for reel := 0; reel < reels && probability > 0; reel++ {
+			probability = probability * trigger[reel][reelMask(state, reel)]
+		}
+		expectedCoins = expectedCoins + probability*coins[state]
+		expectedGrand = expectedGrand + probability*grand[state]
+	}
+	return expectedCoins, expectedGrand
+}
+
+// LineRTP is the pay table payout expected on a line for a bet of 1, without free spins.
+// The reels stop independently, so the symbols of a line are drawn from each reel strip independently,
+// and every wild draws its multiplier independently.
+// Every row shows the symbols of a strip as often, unless wilds expand: it is then the mean of all the pay lines.
+func (ad *AtkinsDietMachine) LineRTP() float64 {
+	if !ad.Wilds.Expanding {
+		return ad.lineRTP(ad.symbolProbabilities(_MIDDLE_ROW))
+	}
+	return ad.payLinesRTP(ad.PayLines)
+}
+
+// payLinesRTP is the mean pay table payout of the pay lines for a bet of 1
+func (ad *AtkinsDietMachine) payLinesRTP(payLines slotmachine.PayLines) float64 {
+	if len(payLines) == 0 {
+		return 0
+	}
+	var (
+		rows = make(map[int][][]symbolProbability)
+		rtp  float64
+	)
+	for _, payLine := range payLines {
+		reels := make([][]symbolProbability, len(payLine))
+		for reel, row := range payLine {
+			if _, ok := rows[row]; !ok {
+				rows[row] = ad.symbolProbabilities(row)
+			}
+			reels[reel] = rows[row][reel]
+		}
+		rtp = rtp + ad.lineRTP(reels)
+	}
+	return rtp / float64(len(payLines))
+}
+
+// lineRTP is the pay table payout expected on a line whose symbols are drawn from the probabilities of each reel
+func (ad *AtkinsDietMachine) lineRTP(reels [][]symbolProbability) float64 {
+	var (
+		line = make([]slotmachine.Symbol, len(reels))
+		rtp  float64
+		walk func(reel int, probability float64)
+	)
+	walk = func(reel int, probability float64) {
+		if reel == len(reels) {
+			pay, wilds := ad.linePay(line)
+			rtp = rtp + probability*float64(pay)*ad.wildMultiplier(wilds)
+			return
+		}
+		for _, sp := range reels[reel] {
+			line[reel] = sp.symbol
+			walk(reel+1, probability*sp.probability)
+		}
+	}
+	walk(0, 1)
+	return rtp
+}
+
+// FreeSpinProbability is the probability of a spin to award free spins
+func (ad *AtkinsDietMachine) FreeSpinProbability() float64 {
+	return ad.windowProbability(ad.Scatter, ad.FreeSpinRules.ScatterCount)
+}
+
+// windowProbability is the probability of a spin to show count symbols or more anywhere in the window
+func (ad *AtkinsDietMachine) windowProbability(symbol slotmachine.Symbol, count int) float64 {
+	if count <= 0 || len(ad.Reels) == 0 {
+		return 0
+	}
+	// counts[n] is the probability of n symbols in the window of the reels seen so far
+	counts := []float64{1}
+	for reel := range ad.Reels[0] {
+		reelCounts := ad.reelWindowProbabilities(reel, symbol)
+		next := make([]float64, len(counts)+len(reelCounts)-1)
+		for n, p := range counts {
+			for m, q := range reelCounts {
+				next[n+m] = next[n+m] + p*q
+			}
+		}
+		counts = next
+	}
+	var probability float64
+	for n := count; n < len(counts); n++ {
+		probability = probability + counts[n]
+	}
+	return probability
+}
+
+// reelWindowProbabilities returns the probability of every number of the symbol in the window of the reel
+func (ad *AtkinsDietMachine) reelWindowProbabilities(reel int, symbol slotmachine.Symbol) []float64 {
+	var (
+		stops         = len(ad.Reels)
+		probabilities = make([]float64, len(visibleRowOffsets)+1)
+	)
+	for stop := 0; stop < stops; stop++ {
+		var count int
+		for _, s := range ad.reelWindow(stop, reel) {
+			if s == symbol {
+				count++
+			}
+		}
+		probabilities[count] = probabilities[count] + 1/float64(stops)
+	}
+	return probabilities
+}
+
+// reelWindow returns the symbols of the reel in the window around the stop, filled by an expanding wild
+func (ad *AtkinsDietMachine) reelWindow(stop, reel int) []slotmachine.Symbol {
+	var (
+		stops  = len(ad.Reels)
+		window = make([]slotmachine.Symbol, len(visibleRowOffsets))
+		wild   bool
+	)
+	for i, offset := range visibleRowOffsets {
+		window[i] = ad.Reels[((stop+offset)%stops+stops)%stops][reel]
+		wild = wild || window[i] == ad.Wildcard
+	}
+	if wild && ad.Wilds.Expanding {
+		for i := range window {
+			window[i] = ad.Wildcard
+		}
+	}
+	return window
+}
+
+// lineSymbol is the symbol of the reel at the row, from 1, of the window around the stop
+func (ad *AtkinsDietMachine) lineSymbol(stop, reel, row int) slotmachine.Symbol {
+	if !ad.Wilds.Expanding {
+		stops := len(ad.Reels)
+		return ad.Reels[((stop+row-_MIDDLE_ROW)%stops+stops)%stops][reel]
+	}
+	return ad.reelWindow(stop, reel)[row-1]
+}
+
+// symbolProbabilities returns the probability of every symbol of each reel strip at the row, ordered by symbol
+func (ad *AtkinsDietMachine) symbolProbabilities(row int) [][]symbolProbability {
+	stops := len(ad.Reels)
+	reels := make([][]symbolProbability, len(ad.Reels[0]))
+	for reel := range reels {
+		counts := make(map[slotmachine.Symbol]int)
+		for stop := range ad.Reels {
+			counts[ad.lineSymbol(stop, reel, row)]++
+		}
+		for symbol, count := range counts {
+			reels[reel] = append(reels[reel], symbolProbability{symbol, float64(count) / float64(stops)})
+		}
+		sort.Slice(reels[reel], func(i, j int) bool { return reels[reel][i].symbol < reels[reel][j].symbol })
+	}
+	return reels
+}
+
+// linePay is the pay table payout of a line of symbols, matched like spinner.FindWins does,
+// and the number of wilds paid
+func (ad *AtkinsDietMachine) linePay(line []slotmachine.Symbol) (int, int) {
+	prime := line[0]
+	if prime == ad.Wildcard {
+		prime = line[1]
+	}
+	count := 1
+	for _, symbol := range line[1:] {
+		if symbol != ad.Wildcard && symbol != prime {
+			break
+		}
+		count++
+	}
+	if count < 2 {
+		return 0, 0
+	}
+	var wilds int
+	for _, symbol := range line[:count] {
+		if symbol == ad.Wildcard {
+			wilds++
+		}
+	}
+	return ad.PayTable[prime][count], wilds
+}
+
+// wildMultiplier is the multiplier expected on a line paying the wilds, each drawing its own from the table
+func (ad *AtkinsDietMachine) wildMultiplier(wilds int) float64 {
+	var weights, expected float64
+	for _, m := range ad.Wilds.Multipliers {
+		if m.Weight > 0 {
+			weights = weights + float64(m.Weight)
+			expected = expected + float64(m.Multiplier*m.Weight)
+		}
+	}
+	if wilds == 0 || weights == 0 {
+		return 1
+	}
+	expected = expected / weights
+	if ad.Wilds.Combine == slotmachine.COMBINE_ADD {
+		return expected * float64(wilds)
+	}
+	return math.Pow(expected, float64(wilds))
+}
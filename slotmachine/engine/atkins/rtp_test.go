@@ -0,0 +1,216 @@
+package atkins
+
+import (
+	"math"
+	"testing"
+
+	"trippy/slotmachine"
+	"trippy/spinner"
+)
+
+// smallMachine has few enough stops to play every one of them
+func smallMachine() *AtkinsDietMachine {
+	def := DefaultDefinition()
+	def.Reels = slotmachine.Reels{
+		{_ATKINS, _STEAK, _SCALE, _HAM, _STEAK},
+		{_STEAK, _ATKINS, _STEAK, _SCALE, _HAM},
+		{_HAM, _STEAK, _ATKINS, _STEAK, _SCALE},
+		{_SCALE, _HAM, _HAM, _ATKINS, _STEAK},
+	}
+	return NewAtkinsDietMachineFromDefinition(def)
+}
+
+// playAllStops returns the average line payout and the probability of free spins over all the stops.
+// Every wild paid on a line multiplies its payout by the mean of the multipliers, or adds it.
+func playAllStops(t *testing.T, ad *AtkinsDietMachine) (float64, float64) {
+	var (
+		stops     = make([]int, len(ad.Reels[0]))
+		pay       float64
+		triggered int
+		plays     int
+		play      func(reel int)
+		mean      float64
+		weights   int
+	)
+	for _, m := range ad.Wilds.Multipliers {
+		mean = mean + float64(m.Multiplier*m.Weight)
+		weights = weights + m.Weight
+	}
+	if weights > 0 {
+		mean = mean / float64(weights)
+	}
+	play = func(reel int) {
+		if reel == len(stops) {
+			window := spinner.Window(stops, ad.Reels)
+			if ad.Wilds.Expanding {
+				spinner.ExpandWilds(window, ad.Wildcard)
+			}
+			wins, err := spinner.FindWindowWins(window, ad.PayLines[:3], ad.SpecialSymbols)
+			if err != nil {
+				t.Fatalf("Expected:[nil] Got:[%s]", err)
+			}
+			result, _ := spinner.CalculatePay(wins, ad.PayTable, ad.SpecialSymbols)
+			for _, win := range result.WinLines {
+				var wilds int
+				for reel, row := range ad.PayLines[win.Index-1][:win.Count] {
+					if window[row-1][reel] == ad.Wildcard {
+						wilds++
+					}
+				}
+				multiplier := 1.0
+				switch {
+				case wilds == 0 || weights == 0:
+				case ad.Wilds.Combine == slotmachine.COMBINE_ADD:
+					multiplier = mean * float64(wilds)
+				default:
+					multiplier = math.Pow(mean, float64(wilds))
+				}
+				pay = pay + float64(win.Payout)*multiplier
+			}
+			if ad.getFreeSpins(spinner.CountWindow(window, ad.Scatter)) > 0 {
+				triggered++
+			}
+			plays++
+			return
+		}
+		for stop := range ad.Reels {
+			stops[reel] = stop
+			play(reel + 1)
+		}
+	}
+	play(0)
+	return pay / float64(plays*3), float64(triggered) / float64(plays)
+}
+
+func TestLineRTP(t *testing.T) {
+	expanding := smallMachine()
+	expanding.Wilds.Expanding = true
+	multiplied := smallMachine()
+	multiplied.Wilds.Multipliers = []slotmachine.WildMultiplier{{Multiplier: 2, Weight: 3}, {Multiplier: 3, Weight: 1}}
+	added := smallMachine()
+	added.Wilds = slotmachine.WildRules{Expanding: true, Combine: slotmachine.COMBINE_ADD, Multipliers: multiplied.Wilds.Multipliers}
+	for _, ad := range []*AtkinsDietMachine{smallMachine(), expanding, multiplied, added} {
+		// The stops are played on the first 3 lines
+		linePay, trigger := playAllStops(t, ad)
+		if rtp := ad.payLinesRTP(ad.PayLines[:3]); math.Abs(rtp-linePay) > 1e-9 {
+			t.Errorf("[Wilds:%+v] Expected:[%f] Got:[%f]", ad.Wilds, linePay, rtp)
+		}
+		if !ad.Wilds.Expanding && math.Abs(ad.LineRTP()-linePay) > 1e-9 {
+			t.Errorf("Expected:[%f] Got:[%f]", linePay, ad.LineRTP())
+		}
+		if math.Abs(ad.FreeSpinProbability()-trigger) > 1e-9 {
+			t.Errorf("[Wilds:%+v] Expected:[%f] Got:[%f]", ad.Wilds, trigger, ad.FreeSpinProbability())
+		}
+	}
+}
+
+func TestTheoreticalRTP(t *testing.T) {
+	rtp, err := adm.TheoreticalRTP()
+	if err != nil {
+		t.Fatalf("Expected:[nil] Got:[%s]", err)
+	}
+	if rtp <= adm.LineRTP() || rtp >= 1 {
+		t.Errorf("Expected the free spins to add to the line RTP, below 1. Got:[%f] Line:[%f]", rtp, adm.LineRTP())
+	}
+
+	if _, err = smallMachine().TheoreticalRTP(); err != ErrEndlessFreeSpins {
+		t.Errorf("Expected:[%s] Got:[%v]", ErrEndlessFreeSpins, err)
+	}
+	walking := NewAtkinsDietMachine()
+	walking.Wilds.Walking = true
+	if _, err = walking.TheoreticalRTP(); err != ErrWildsNotModelled {
+		t.Errorf("Expected:[%s] Got:[%v]", ErrWildsNotModelled, err)
+	}
+	stepped := NewAtkinsDietMachine()
+	stepped.FreeSpinRules.MultiplierStep = 1
+	if _, err = stepped.TheoreticalRTP(); err != ErrStepNotModelled {
+		t.Errorf("Expected:[%s] Got:[%v]", ErrStepNotModelled, err)
+	}
+	held := NewAtkinsDietMachineFromDefinition(HoldDefinition())
+	if rtp, err := held.TheoreticalRTP(); err != nil || held.HoldRTP() <= 0 || rtp < held.HoldRTP()+held.LineRTP() {
+		t.Errorf("Expected hold and spin to add to the RTP Got:[%f] Hold:[%f] [Error:%v]", rtp, held.HoldRTP(), err)
+	}
+
+	// Wilds ×2 paid on a line pay twice as much
+	doubled := NewAtkinsDietMachine()
+	doubled.Wilds.Multipliers = []slotmachine.WildMultiplier{{Multiplier: 2, Weight: 1}}
+	if rtp, err := doubled.TheoreticalRTP(); err != nil || rtp <= adm.LineRTP() || doubled.LineRTP() <= adm.LineRTP() {
+		t.Errorf("Expected the wild multipliers to add to the RTP Got:[%f] [Error:%v]", rtp, err)
+	}
+}
+
+// The pick game pays the mean prize of its picks every time it is launched
+func TestBonusRTP(t *testing.T) {
+	ad := smallMachine()
+	ad.Bonus = &slotmachine.BonusRules{Symbol: _HAM, Count: 3, Prizes: 5, Picks: 2,
+		Reveals: []slotmachine.BonusReveal{{Multiplier: 2, Weight: 3}, {Multiplier: 10, Weight: 1}}}
+
+	var (
+		stops     = make([]int, len(ad.Reels[0]))
+		triggered int
+		plays     int
+		play      func(reel int)
+	)
+	play = func(reel int) {
+		if reel == len(stops) {
+			if spinner.CountScatter(stops, ad.Reels, _HAM) >= ad.Bonus.Count {
+				triggered++
+			}
+			plays++
+			return
+		}
+		for stop := range ad.Reels {
+			stops[reel] = stop
+			play(reel + 1)
+		}
+	}
+	play(0)
+
+	trigger := float64(triggered) / float64(plays)
+	if math.Abs(ad.BonusProbability()-trigger) > 1e-9 {
+		t.Errorf("Expected:[%f] Got:[%f]", trigger, ad.BonusProbability())
+	}
+	if expected := trigger * 2 * 4; math.Abs(ad.BonusRTP()-expected) > 1e-9 {
+		t.Errorf("Expected:[%f] Got:[%f]", expected, ad.BonusRTP())
+	}
+	if smallMachine().BonusRTP() != 0 {
+		t.Errorf("Expected no bonus return without a bonus game")
+	}
+}
+
+// The first reel always shows 3 coins and the second one a coin on one of its rows, so the feature
+// is launched by every spin. A respin shows a new coin on the second reel with probability 2/3, then 1/3.
+func TestHoldRTP(t *testing.T) {
+	ad := NewAtkinsDietMachineFromDefinition(HoldDefinition())
+	ad.Reels = slotmachine.Reels{
+		{_COIN, _COIN},
+		{_COIN, _HAM},
+		{_COIN, _HAM},
+	}
+	ad.Hold = &slotmachine.HoldRules{
+		Symbol:  _COIN,
+		Count:   4,
+		Respins: 1,
+		Values:  []slotmachine.CoinValue{{Multiplier: 1, Weight: 1}, {Multiplier: 3, Weight: 0}},
+		Grand:   10,
+	}
+	coins, grand := ad.holdReturns()
+	if math.Abs(coins-44.0/9) > 1e-9 || math.Abs(grand-2.0/9) > 1e-9 {
+		t.Errorf("Expected:[%f %f] Got:[%f %f]", 44.0/9, 2.0/9, coins, grand)
+	}
+	if rtp := ad.HoldRTP(); math.Abs(rtp-64.0/9) > 1e-9 || ad.HoldProbability() != 1 {
+		t.Errorf("Expected:[%f 1] Got:[%f %f]", 64.0/9, rtp, ad.HoldProbability())
+	}
+
+	ad.Hold.Respins = 2
+	if coins, grand = ad.holdReturns(); coins <= 44.0/9 || grand <= 2.0/9 {
+		t.Errorf("Expected more coins with more respins Got:[%f %f]", coins, grand)
+	}
+	ad.Hold.Count = 5
+	if rtp := ad.HoldRTP(); rtp != 0 {
+		t.Errorf("Expected:[0] Got:[%f]", rtp)
+	}
+	if rtp := adm.HoldRTP(); rtp != 0 {
+		t.Errorf("Expected:[0] Got:[%f]", rtp)
+	}
+}
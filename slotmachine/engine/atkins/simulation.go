@@ -0,0 +1,110 @@
+package atkins
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"runtime"
+	"sync"
+
+	"trippy/slotmachine"
+	"trippy/slotmachine/bonus"
+)
+
+const (
+	_SIMULATION_CONFIDENCE = 0.95 // Confidence of the interval of the simulated RTP
+	_SIMULATION_Z          = 1.96 // z of the 95% confidence
+)
+
+var ErrNoRounds = errors.New("No rounds to simulate")
+
+// SimulatedRTP estimates the return to player by playing rounds at random, for the machines whose
+// features TheoreticalRTP does not model: sticky and walking wilds, and free spin multipliers raised by steps.
+// The rounds bet a coin on all the lines, the player of the pick game picking at random,
+// and are shared among the processors. The RTP is within the interval at 95% confidence.
+func (ad *AtkinsDietMachine) SimulatedRTP(rounds int) (slotmachine.RTPEstimate, error) {
+	var estimate slotmachine.RTPEstimate
+	if len(ad.Reels) == 0 || len(ad.Reels[0]) == 0 {
+		return estimate, ErrNoReels
+	}
+	if rounds <= 0 {
+		return estimate, ErrNoRounds
+	}
+	// Free spins retriggered endlessly would keep the round playing
+	if ad.FreeSpinRules.Spins > 0 && ad.FreeSpinProbability()*float64(ad.FreeSpinRules.Spins) >= 1 {
+		return estimate, ErrEndlessFreeSpins
+	}
+	var (
+		bet     = ad.withDefaults(slotmachine.Bet{Coins: max(1, ad.BetLimits.MinBet)})
+		workers = min(runtime.GOMAXPROCS(0), rounds)
+		totals  = make([]simulationTotal, workers)
+		wg      sync.WaitGroup
+	)
+	for i := range totals {
+		played := rounds / workers
+		if i < rounds%workers {
+			played++
+		}
+		wg.Add(1)
+		go func(total *simulationTotal) {
+			defer wg.Done()
+			for ; played > 0 && total.err == nil; played-- {
+				var ret float64
+				ret, total.err = ad.simulateRound(bet)
+				total.sum = total.sum + ret
+				total.sumSq = total.sumSq + ret*ret
+			}
+		}(&totals[i])
+	}
+	wg.Wait()
+
+	var sum, sumSq float64
+	for _, total := range totals {
+		if total.err != nil {
+			return estimate, total.err
+		}
+		sum, sumSq = sum+total.sum, sumSq+total.sumSq
+	}
+	n := float64(rounds)
+	estimate.RTP = sum / n
+	estimate.Rounds = rounds
+	estimate.StdDev = math.Sqrt(math.Max(sumSq/n-estimate.RTP*estimate.RTP, 0))
+	estimate.Confidence = _SIMULATION_CONFIDENCE
+	margin := _SIMULATION_Z * estimate.StdDev / math.Sqrt(n)
+	estimate.Low, estimate.High = estimate.RTP-margin, estimate.RTP+margin
+	return estimate, nil
+}
+
+// simulationTotal adds up the returns of the rounds played by a processor
+type simulationTotal struct {
+	sum, sumSq float64
+	err        error
+}
+
+// simulateRound plays a round without pausing the free spins, then its pick game,
+// and returns the chips paid over the chips wagered
+func (ad *AtkinsDietMachine) simulateRound(bet slotmachine.Bet) (float64, error) {
+	pay, results, err := ad.round(context.Background(), bet, false)
+	if err != nil {
+		return 0, err
+	}
+	if bonus.Triggered(ad.Bonus, results) {
+		var weights int
+		for _, reveal := range ad.Bonus.Reveals {
+			weights = weights + reveal.Weight
+		}
+		// Every pick reveals a prize drawn from the table on its own
+		for pick := 0; pick < ad.Bonus.Picks && weights > 0; pick++ {
+			draw := rand.Intn(weights)
+			for _, reveal := range ad.Bonus.Reveals {
+				if draw < reveal.Weight {
+					pay = pay + reveal.Multiplier*bet.Total()
+					break
+				}
+				draw = draw - reveal.Weight
+			}
+		}
+	}
+	return float64(pay) / float64(bet.Total()), nil
+}
@@ -0,0 +1,60 @@
+package atkins
+
+import (
+	"math"
+	"testing"
+
+	"trippy/slotmachine"
+)
+
+// The simulated RTP of the machines modelled finds their theoretical RTP, within a few margins of error
+func TestSimulatedRTP(t *testing.T) {
+	for _, ad := range []*AtkinsDietMachine{adm, NewAtkinsDietMachineFromDefinition(HoldDefinition())} {
+		rtp, err := ad.TheoreticalRTP()
+		if err != nil {
+			t.Fatalf("Expected:[nil] Got:[%s]", err)
+		}
+		estimate, err := ad.SimulatedRTP(100000)
+		if err != nil {
+			t.Fatalf("Expected:[nil] Got:[%s]", err)
+		}
+		margin := (estimate.High - estimate.Low) / 2
+		if estimate.Rounds != 100000 || estimate.Confidence != _SIMULATION_CONFIDENCE || margin <= 0 {
+			t.Errorf("Unexpected estimate:[%+v]", estimate)
+		}
+		if math.Abs(estimate.RTP-rtp) > 3*margin {
+			t.Errorf("Expected:[%f] Got:[%+v]", rtp, estimate)
+		}
+	}
+
+	if _, err := adm.SimulatedRTP(0); err != ErrNoRounds {
+		t.Errorf("Expected:[%s] Got:[%v]", ErrNoRounds, err)
+	}
+	walking := smallMachine()
+	walking.Wilds.Walking = true
+	if _, err := walking.SimulatedRTP(10); err != ErrEndlessFreeSpins {
+		t.Errorf("Expected:[%s] Got:[%v]", ErrEndlessFreeSpins, err)
+	}
+}
+
+// The PAR sheet of the wilds kept from a spin to the next ones, or of the multipliers raised by steps,
+// has the RTP simulated
+func TestSimulatedParSheet(t *testing.T) {
+	stepped := NewAtkinsDietMachine()
+	stepped.FreeSpinRules.MultiplierStep = 1
+	for _, ad := range []*AtkinsDietMachine{wildMachine(slotmachine.WildRules{Sticky: true}), stepped} {
+		sheet, err := ad.ParSheet("simulated", 20000)
+		if err != nil {
+			t.Fatalf("Expected:[nil] Got:[%s]", err)
+		}
+		if sheet.Simulation == nil || sheet.Rounds != 20000 || sheet.RTP < sheet.Simulation.Low || sheet.RTP > sheet.Simulation.High {
+			t.Fatalf("Expected the simulated RTP Got:[%f %+v]", sheet.RTP, sheet.Simulation)
+		}
+		if math.Abs(sheet.LineRTP-adm.LineRTP()) > 1e-9 {
+			t.Errorf("Expected the lines of a spin:[%f] Got:[%f]", adm.LineRTP(), sheet.LineRTP)
+		}
+	}
+	if sheet, _ := adm.ParSheet("atkins-diet", 20000); sheet.Simulation != nil {
+		t.Errorf("Expected the theoretical RTP Got:[%+v]", sheet.Simulation)
+	}
+}
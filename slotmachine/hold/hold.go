@@ -0,0 +1,138 @@
+// Package hold plays the hold and spin feature of any machine, see slotmachine.HoldRules.
+// The engines launch it after a main spin showing enough coins, on their own reel strips.
+package hold
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+
+	"trippy/slotmachine"
+	"trippy/spinner"
+)
+
+var ErrInvalidRules = errors.New("Hold and spin rules are invalid")
+
+// Triggered returns true if a main spin of the results shows enough coins to launch the feature
+func Triggered(rules *slotmachine.HoldRules, results []slotmachine.SpinResult) bool {
+	if rules == nil || rules.Count <= 0 {
+		return false
+	}
+	for _, result := range results {
+		if result.Type == slotmachine.MAIN_SPIN && spinner.CountWindow(result.Window, rules.Symbol) >= rules.Count {
+			return true
+		}
+	}
+	return false
+}
+
+// Play plays the feature launched by the window, Window[row][reel], for a total bet in chips.
+// The coins of the window are held, then the reels respin for the other cells until no respin is left
+// or every cell holds a coin. Every spin is notified as it is played, the last one pays the credits
+// of the coins with the grand prize if the window is full. It returns the pay and the hold spins.
+func Play(ctx context.Context, rules slotmachine.HoldRules, reels slotmachine.Reels, window [][]slotmachine.Symbol, totalBet int) (int, []slotmachine.SpinResult, error) {
+	var weights int
+	for _, value := range rules.Values {
+		if value.Weight > 0 {
+			weights = weights + value.Weight
+		}
+	}
+	if weights == 0 || rules.Respins < 1 || len(window) == 0 {
+		return 0, nil, ErrInvalidRules
+	}
+
+	var (
+		coins   []slotmachine.Coin
+		held    = make(map[slotmachine.Cell]bool)
+		cells   = len(window) * len(window[0])
+		respins = rules.Respins
+		results []slotmachine.SpinResult
+	)
+	// hold keeps the new coins of the window and returns their number
+	hold := func(window [][]slotmachine.Symbol) int {
+		var found int
+		for _, cell := range spinner.SymbolCells(window, rules.Symbol) {
+			if !held[cell] {
+				held[cell] = true
+				coins = append(coins, slotmachine.Coin{Cell: cell, Value: drawValue(rules.Values, weights) * totalBet})
+				found++
+			}
+		}
+		return found
+	}
+	hold(window)
+	// pay pays the coins held to the last spin, with the grand prize if the window is full
+	pay := func(last *slotmachine.SpinResult) {
+		for _, coin := range coins {
+			last.Pay = last.Pay + coin.Value
+		}
+		if len(coins) == cells {
+			last.Pay = last.Pay + rules.Grand*totalBet
+			last.Jackpot = slotmachine.HOLD_GRAND
+		}
+	}
+
+	for respins > 0 && len(coins) < cells {
+		respins--
+		stops, err := spinner.Spin(reels)
+		if err != nil {
+			return 0, results, err
+		}
+		window := spinner.Window(stops, reels)
+		for _, coin := range coins {
+			window[coin.Row-1][coin.Reel-1] = rules.Symbol
+		}
+		if hold(window) > 0 {
+			respins = rules.Respins
+		}
+		for i := range stops {
+			stops[i]++
+		}
+		result := slotmachine.SpinResult{
+			Type:       slotmachine.HOLD_SPIN,
+			Stops:      stops,
+			Window:     window,
+			Multiplier: 1,
+			Coins:      append([]slotmachine.Coin(nil), coins...),
+			Respins:    respins,
+		}
+		if respins == 0 || len(coins) == cells {
+			pay(&result)
+		}
+		results = append(results, result)
+		slotmachine.NotifySpin(ctx, result)
+	}
+	// A window full of coins from the start is paid without respins
+	if len(results) == 0 {
+		full := make([][]slotmachine.Symbol, len(window))
+		for row := range window {
+			full[row] = append([]slotmachine.Symbol(nil), window[row]...)
+		}
+		result := slotmachine.SpinResult{
+			Type:       slotmachine.HOLD_SPIN,
+			Stops:      []int{},
+			Window:     full,
+			Multiplier: 1,
+			Coins:      coins,
+		}
+		pay(&result)
+		results = append(results, result)
+		slotmachine.NotifySpin(ctx, result)
+	}
+	return results[len(results)-1].Pay, results, nil
+}
+
+// drawValue draws the credits of a coin from the values, in multiples of the total bet
+func drawValue(values []slotmachine.CoinValue, weights int) int {
+	draw := rand.Intn(weights)
+	for _, value := range values {
+		if value.Weight <= 0 {
+			continue
+		}
+		if draw < value.Weight {
+			return value.Multiplier
+		}
+		draw = draw - value.Weight
+	}
+	return 0
+}
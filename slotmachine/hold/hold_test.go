@@ -0,0 +1,150 @@
+package hold
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"trippy/slotmachine"
+)
+
+const _COIN = slotmachine.Symbol(12)
+
+var rules = slotmachine.HoldRules{
+	Symbol:  _COIN,
+	Count:   6,
+	Respins: 3,
+	Values:  []slotmachine.CoinValue{{Multiplier: 2, Weight: 1}, {Multiplier: 50, Weight: 0}},
+	Grand:   100,
+}
+
+// sixCoins is a window of 3 rows and 5 reels showing 6 coins
+var sixCoins = [][]slotmachine.Symbol{
+	{_COIN, 1, 2, _COIN, 3},
+	{4, _COIN, 5, 6, _COIN},
+	{_COIN, 7, 8, _COIN, 9},
+}
+
+// reels returns reel strips of 5 reels and 4 stops, the stops showing the coin on the reels given
+func reels(coins ...int) slotmachine.Reels {
+	strips := make(slotmachine.Reels, 4)
+	for stop := range strips {
+		strips[stop] = slotmachine.ReelLine{1, 2, 3, 4, 5}
+		for _, reel := range coins {
+			strips[stop][reel] = _COIN
+		}
+	}
+	return strips
+}
+
+type triggerSample struct {
+	window [][]slotmachine.Symbol
+	kind   string
+	launch bool
+}
+
+var triggerSamples = []triggerSample{
+	{window: sixCoins, kind: slotmachine.MAIN_SPIN, launch: true},
+	{window: sixCoins, kind: slotmachine.FREE_SPIN},
+	{window: [][]slotmachine.Symbol{{_COIN, _COIN, _COIN, _COIN, _COIN}, {1, 2, 3, 4, 5}, {1, 2, 3, 4, 5}}, kind: slotmachine.MAIN_SPIN},
+}
+
+func TestTriggered(t *testing.T) {
+	for _, sample := range triggerSamples {
+		results := []slotmachine.SpinResult{{Type: sample.kind, Window: sample.window}}
+		if launch := Triggered(&rules, results); launch != sample.launch {
+			t.Errorf("[Window:%v %s] Expected:[%t] Got:[%t]", sample.window, sample.kind, sample.launch, launch)
+		}
+	}
+	if Triggered(nil, []slotmachine.SpinResult{{Type: slotmachine.MAIN_SPIN, Window: sixCoins}}) {
+		t.Errorf("Expected no feature without rules")
+	}
+}
+
+type playSample struct {
+	name    string
+	reels   slotmachine.Reels
+	spins   int
+	coins   int
+	pay     int
+	jackpot string
+}
+
+var playSamples = []playSample{
+	// No coin lands, the respins run out
+	{name: "no new coin", reels: reels(), spins: 3, coins: 6, pay: 6 * 2 * 10},
+	// The coins of the 2 reels fill their 5 empty cells, the respins are granted again
+	{name: "new coins", reels: reels(1, 2), spins: 4, coins: 11, pay: 11 * 2 * 10},
+	// The window is filled on the first respin
+	{name: "full window", reels: reels(0, 1, 2, 3, 4), spins: 1, coins: 15, pay: 15*2*10 + 100*10, jackpot: slotmachine.HOLD_GRAND},
+}
+
+func TestPlay(t *testing.T) {
+	for _, sample := range playSamples {
+		testPlay(t, sample)
+	}
+}
+
+func testPlay(t *testing.T, sample playSample) {
+	var notified []slotmachine.SpinResult
+	ctx := slotmachine.WithSpinObserver(context.Background(), func(result slotmachine.SpinResult) {
+		notified = append(notified, result)
+	})
+	pay, results, err := Play(ctx, rules, sample.reels, sixCoins, 10)
+	if err != nil {
+		t.Fatalf("[%s] Expected:[nil] Got:[%s]", sample.name, err)
+	}
+	if !reflect.DeepEqual(notified, results) {
+		t.Errorf("[%s] Expected the spins notified:[%+v] Got:[%+v]", sample.name, results, notified)
+	}
+	if len(results) != sample.spins || pay != sample.pay {
+		t.Fatalf("[%s] Expected:[%d spins paying %d] Got:[%d spins paying %d]", sample.name, sample.spins, sample.pay, len(results), pay)
+	}
+	coins, respins := 6, rules.Respins
+	for i, result := range results {
+		if result.Type != slotmachine.HOLD_SPIN {
+			t.Errorf("[%s] Expected:[%s] Got:[%s]", sample.name, slotmachine.HOLD_SPIN, result.Type)
+		}
+		// New coins grant the respins again
+		respins--
+		if len(result.Coins) > coins {
+			respins = rules.Respins
+		}
+		coins = len(result.Coins)
+		if result.Respins != respins {
+			t.Errorf("[%s Spin:%d] Expected:[%d] respins Got:[%d]", sample.name, i, respins, result.Respins)
+		}
+		for _, coin := range result.Coins {
+			if result.Window[coin.Row-1][coin.Reel-1] != _COIN || coin.Value != 20 {
+				t.Errorf("[%s Spin:%d] Expected a coin of 20 held at:[%+v] Got:[%v]", sample.name, i, coin, result.Window)
+			}
+		}
+		if i < len(results)-1 && result.Pay != 0 {
+			t.Errorf("[%s Spin:%d] Expected the last spin to pay Got:[%d]", sample.name, i, result.Pay)
+		}
+	}
+	if coins != sample.coins || results[len(results)-1].Jackpot != sample.jackpot {
+		t.Errorf("[%s] Expected:[%d coins %q] Got:[%+v]", sample.name, sample.coins, sample.jackpot, results[len(results)-1])
+	}
+}
+
+// A window full of coins is paid at once
+func TestPlayFullWindow(t *testing.T) {
+	full := [][]slotmachine.Symbol{
+		{_COIN, _COIN, _COIN, _COIN, _COIN},
+		{_COIN, _COIN, _COIN, _COIN, _COIN},
+		{_COIN, _COIN, _COIN, _COIN, _COIN},
+	}
+	pay, results, err := Play(context.Background(), rules, reels(), full, 1)
+	if err != nil || len(results) != 1 || pay != 15*2+100 || results[0].Jackpot != slotmachine.HOLD_GRAND {
+		t.Errorf("Expected:[1 spin paying 130] Got:[%+v %d] [Error:%v]", results, pay, err)
+	}
+}
+
+func TestInvalidRules(t *testing.T) {
+	invalid := rules
+	invalid.Values = invalid.Values[1:]
+	if _, _, err := Play(context.Background(), invalid, reels(), sixCoins, 1); err != ErrInvalidRules {
+		t.Errorf("Expected:[%s] Got:[%v]", ErrInvalidRules, err)
+	}
+}
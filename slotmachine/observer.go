@@ -0,0 +1,25 @@
+package slotmachine
+
+import "context"
+
+// SpinObserver is called with every spin of a round as soon as the engine plays it,
+// before the round is over
+type SpinObserver func(result SpinResult)
+
+type observerKey struct{}
+
+// WithSpinObserver returns a context carrying the observer, which the engines call through NotifySpin
+func WithSpinObserver(ctx context.Context, observer SpinObserver) context.Context {
+	return context.WithValue(ctx, observerKey{}, observer)
+}
+
+// NotifySpin calls the observer carried by the context, if any.
+// Engines call it with each spin once its type is set.
+func NotifySpin(ctx context.Context, result SpinResult) {
+	if ctx == nil {
+		return
+	}
+	if observer, ok := ctx.Value(observerKey{}).(SpinObserver); ok && observer != nil {
+		observer(result)
+	}
+}
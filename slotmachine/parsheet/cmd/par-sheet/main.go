@@ -0,0 +1,59 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"trippy/slotmachine"
+	"trippy/slotmachine/engine/atkins"
+	"trippy/slotmachine/parsheet"
+)
+
+// Writes the PAR sheet of a machine definition.
+//
+// Usage: par-sheet [-definition atkins-diet.json] [-format csv|markdown|json] [-o par.csv] [-rounds 1000000]
+func main() {
+	var (
+		definitionPath = flag.String("definition", "", "Machine definition file, the default Atkins Diet machine if empty")
+		formatName     = flag.String("format", string(parsheet.CSV), "Output format: csv, markdown or json")
+		outputPath     = flag.String("o", "", "Output file, stdout if empty")
+		rounds         = flag.Int("rounds", 1000000, "Rounds simulated for the RTP of sticky and walking wilds and stepped multipliers")
+	)
+	flag.Parse()
+
+	def := atkins.DefaultDefinition()
+	if *definitionPath != "" {
+		var err error
+		if def, err = slotmachine.LoadDefinition(*definitionPath); err != nil {
+			exit(err)
+		}
+	}
+	format, err := parsheet.ParseFormat(*formatName)
+	if err != nil {
+		exit(err)
+	}
+	sheet, err := atkins.NewAtkinsDietMachineFromDefinition(def).ParSheet(def.Name, *rounds)
+	if err != nil {
+		exit(fmt.Errorf("Unable to compute PAR sheet [Machine:%s] [Error:%s]", def.Name, err))
+	}
+
+	var w io.Writer = os.Stdout
+	if *outputPath != "" {
+		f, err := os.Create(*outputPath)
+		if err != nil {
+			exit(fmt.Errorf("Unable to create output [File:%s] [Error:%s]", *outputPath, err))
+		}
+		defer f.Close()
+		w = f
+	}
+	if err = sheet.Write(w, format); err != nil {
+		exit(err)
+	}
+}
+
+func exit(err error) {
+	fmt.Fprintln(os.Stderr, err)
+	os.Exit(1)
+}
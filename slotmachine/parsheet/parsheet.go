@@ -0,0 +1,273 @@
+// Package parsheet writes the PAR sheet of a machine: the symbols of its reels, the probability
+// and return of every pay, the return of the features and the volatility of a round, as certification labs ask.
+// Engines compute the sheet of their machines, see atkins.ParSheet.
+package parsheet
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+type Format string
+
+const (
+	CSV      Format = "csv"
+	MARKDOWN Format = "markdown"
+	JSON     Format = "json"
+)
+
+// ParseFormat returns the format for its name, "csv", "markdown" or "json"
+func ParseFormat(name string) (Format, error) {
+	switch Format(strings.ToLower(name)) {
+	case CSV:
+		return CSV, nil
+	case MARKDOWN, "md":
+		return MARKDOWN, nil
+	case JSON:
+		return JSON, nil
+	}
+	return CSV, fmt.Errorf("Unknown PAR sheet format:[%s]", name)
+}
+
+// Sheet is the PAR sheet of a machine. Payouts are for a bet of 1 on a line,
+// probabilities and returns are fractions, eg. 0.95 for 95%.
+type Sheet struct {
+	Machine      string        `json:"machine"`
+	Reels        int           `json:"reels"`
+	Stops        int           `json:"stops"` // Stops of each reel
+	Lines        int           `json:"lines"`
+	Cycle        int64         `json:"cycle"` // Combinations of the stops of all the reels
+	Symbols      []SymbolCount `json:"symbols"`
+	Pays         []Pay         `json:"pays"`
+	HitFrequency float64       `json:"hit_frequency"` // Probability of a line to pay
+	LineRTP      float64       `json:"line_rtp"`      // Return of the pay table, without free spins
+	FreeSpins    FreeSpins     `json:"free_spins"`
+	Bonus        *Bonus        `json:"bonus,omitempty"` // Pick game, none if nil
+	Hold         *Hold         `json:"hold,omitempty"`  // Hold and spin, none if nil
+	RTP          float64       `json:"rtp"`
+	Simulation   *Simulation   `json:"simulation,omitempty"` // Interval of the RTP found by the rounds, exact if nil
+
+	// The variance is the one of the return of a round, in total bets, with the free spins, hold and spin
+	// and the pick game, found by playing rounds at random
+	Rounds               int     `json:"rounds"`
+	Variance             float64 `json:"variance"`
+	VolatilityIndex      float64 `json:"volatility_index"`      // Standard deviation of a round at the confidence
+	VolatilityConfidence float64 `json:"volatility_confidence"` // eg. 0.9 for 90%
+}
+
+// Simulation is the confidence interval of an RTP found by playing the rounds of the sheet,
+// for the features the engine does not model
+type Simulation struct {
+	Confidence float64 `json:"confidence"` // eg. 0.95 for 95%
+	Low        float64 `json:"low"`
+	High       float64 `json:"high"`
+}
+
+// SymbolCount is the number of stops of a symbol on each reel
+type SymbolCount struct {
+	Symbol string `json:"symbol"`
+	Counts []int  `json:"counts"`
+	Total  int    `json:"total"`
+}
+
+// Pay is an entry of the pay table
+type Pay struct {
+	Symbol      string  `json:"symbol"`
+	Count       int     `json:"count"`
+	Payout      int     `json:"payout"`
+	Hits        int64   `json:"hits"` // Combinations of the cycle paying the entry on a line
+	Probability float64 `json:"probability"`
+	HitCycle    float64 `json:"hit_cycle"` // Lines played for a hit on average, 0 if never
+	RTP         float64 `json:"rtp"`       // Contribution to the return
+}
+
+// FreeSpins is the contribution of the free spins
+type FreeSpins struct {
+	ScatterCount       int     `json:"scatter_count"`
+	Spins              int     `json:"spins"` // Free spins awarded by a trigger
+	Multiplier         int     `json:"multiplier"`
+	TriggerProbability float64 `json:"trigger_probability"` // Probability of a spin to award free spins
+	SpinsPerTrigger    float64 `json:"spins_per_trigger"`   // Free spins played for a trigger, retriggers included
+	RTP                float64 `json:"rtp"`                 // Contribution to the return, with the respins of walking wilds
+}
+
+// Bonus is the contribution of the pick game
+type Bonus struct {
+	Symbol             string  `json:"symbol"`
+	Count              int     `json:"count"` // Symbols in the window launching the game
+	Prizes             int     `json:"prizes"`
+	Picks              int     `json:"picks"`
+	MeanMultiplier     float64 `json:"mean_multiplier"`     // Wagers won by a pick on average
+	TriggerProbability float64 `json:"trigger_probability"` // Probability of a spin to launch the game
+	RTP                float64 `json:"rtp"`                 // Contribution to the return
+}
+
+// Hold is the contribution of hold and spin
+type Hold struct {
+	Symbol             string  `json:"symbol"`
+	Count              int     `json:"count"` // Coins in the window launching the feature
+	Respins            int     `json:"respins"`
+	Grand              int     `json:"grand"`
+	MeanValue          float64 `json:"mean_value"`          // Total bets credited by a coin on average
+	TriggerProbability float64 `json:"trigger_probability"` // Probability of a spin to launch the feature
+	GrandProbability   float64 `json:"grand_probability"`   // Probability of a spin to fill the window with coins
+	RTP                float64 `json:"rtp"`                 // Contribution to the return
+}
+
+// Write writes the sheet in the format
+func (s Sheet) Write(w io.Writer, format Format) error {
+	switch format {
+	case JSON:
+		data, err := json.MarshalIndent(s, "", "  ")
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintln(w, string(data))
+		return err
+	case MARKDOWN:
+		return s.writeMarkdown(w)
+	}
+	return s.writeCSV(w)
+}
+
+// table is a section of the sheet
+type table struct {
+	title  string
+	header []string
+	rows   [][]string
+}
+
+// tables returns the sections of the sheet, with the numbers formatted by percent and number
+func (s Sheet) tables(percent func(float64) string, number func(float64) string) []table {
+	summary := table{title: "Summary", header: []string{"Item", "Value"}, rows: [][]string{
+		{"Machine", s.Machine},
+		{"Reels", strconv.Itoa(s.Reels)},
+		{"Stops per reel", strconv.Itoa(s.Stops)},
+		{"Pay lines", strconv.Itoa(s.Lines)},
+		{"Cycle", strconv.FormatInt(s.Cycle, 10)},
+		{"Hit frequency", percent(s.HitFrequency)},
+		{"Line RTP", percent(s.LineRTP)},
+		{"Free spins RTP", percent(s.FreeSpins.RTP)},
+	}}
+	if s.Bonus != nil {
+		summary.rows = append(summary.rows, []string{"Bonus RTP", percent(s.Bonus.RTP)})
+	}
+	if s.Hold != nil {
+		summary.rows = append(summary.rows, []string{"Hold and spin RTP", percent(s.Hold.RTP)})
+	}
+	summary.rows = append(summary.rows, []string{"RTP", percent(s.RTP)})
+	if sim := s.Simulation; sim != nil {
+		summary.rows = append(summary.rows, [][]string{
+			{fmt.Sprintf("RTP low at %g%% confidence", sim.Confidence*100), percent(sim.Low)},
+			{fmt.Sprintf("RTP high at %g%% confidence", sim.Confidence*100), percent(sim.High)},
+		}...)
+	}
+	summary.rows = append(summary.rows, [][]string{
+		{"Simulated rounds", strconv.Itoa(s.Rounds)},
+		{"Variance of a round", number(s.Variance)},
+		{fmt.Sprintf("Volatility index at %g%% confidence", s.VolatilityConfidence*100), number(s.VolatilityIndex)},
+	}...)
+
+	symbols := table{title: "Symbols", header: []string{"Symbol"}}
+	for reel := 1; reel <= s.Reels; reel++ {
+		symbols.header = append(symbols.header, fmt.Sprintf("Reel %d", reel))
+	}
+	symbols.header = append(symbols.header, "Total")
+	for _, symbol := range s.Symbols {
+		row := []string{symbol.Symbol}
+		for _, count := range symbol.Counts {
+			row = append(row, strconv.Itoa(count))
+		}
+		symbols.rows = append(symbols.rows, append(row, strconv.Itoa(symbol.Total)))
+	}
+
+	pays := table{title: "Pays", header: []string{"Symbol", "Count", "Payout", "Hits", "Probability", "Hit cycle", "RTP"}}
+	for _, pay := range s.Pays {
+		pays.rows = append(pays.rows, []string{pay.Symbol, strconv.Itoa(pay.Count), strconv.Itoa(pay.Payout),
+			strconv.FormatInt(pay.Hits, 10), number(pay.Probability), number(pay.HitCycle), percent(pay.RTP)})
+	}
+
+	free := s.FreeSpins
+	freeSpins := table{title: "Free spins", header: []string{"Item", "Value"}, rows: [][]string{
+		{"Scatters to trigger", strconv.Itoa(free.ScatterCount)},
+		{"Spins awarded", strconv.Itoa(free.Spins)},
+		{"Multiplier", strconv.Itoa(free.Multiplier)},
+		{"Trigger probability", number(free.TriggerProbability)},
+		{"Spins per trigger", number(free.SpinsPerTrigger)},
+		{"RTP", percent(free.RTP)},
+	}}
+	tables := []table{summary, symbols, pays, freeSpins}
+
+	if bonus := s.Bonus; bonus != nil {
+		tables = append(tables, table{title: "Bonus", header: []string{"Item", "Value"}, rows: [][]string{
+			{"Symbol", bonus.Symbol},
+			{"Symbols to trigger", strconv.Itoa(bonus.Count)},
+			{"Prizes", strconv.Itoa(bonus.Prizes)},
+			{"Picks", strconv.Itoa(bonus.Picks)},
+			{"Mean multiplier", number(bonus.MeanMultiplier)},
+			{"Trigger probability", number(bonus.TriggerProbability)},
+			{"RTP", percent(bonus.RTP)},
+		}})
+	}
+	if hold := s.Hold; hold != nil {
+		tables = append(tables, table{title: "Hold and spin", header: []string{"Item", "Value"}, rows: [][]string{
+			{"Symbol", hold.Symbol},
+			{"Coins to trigger", strconv.Itoa(hold.Count)},
+			{"Respins", strconv.Itoa(hold.Respins)},
+			{"Grand", strconv.Itoa(hold.Grand)},
+			{"Mean value", number(hold.MeanValue)},
+			{"Trigger probability", number(hold.TriggerProbability)},
+			{"Grand probability", number(hold.GrandProbability)},
+			{"RTP", percent(hold.RTP)},
+		}})
+	}
+	return tables
+}
+
+// writeCSV writes the sections one after the other, each with its title and header rows
+func (s Sheet) writeCSV(w io.Writer) error {
+	var (
+		cw      = csv.NewWriter(w)
+		percent = func(f float64) string { return strconv.FormatFloat(f, 'f', 8, 64) }
+		number  = func(f float64) string { return strconv.FormatFloat(f, 'g', 10, 64) }
+	)
+	for i, t := range s.tables(percent, number) {
+		if i > 0 {
+			cw.Write(nil)
+		}
+		cw.Write([]string{t.title})
+		cw.Write(t.header)
+		cw.WriteAll(t.rows)
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func (s Sheet) writeMarkdown(w io.Writer) error {
+	var (
+		b       strings.Builder
+		percent = func(f float64) string { return strconv.FormatFloat(f*100, 'f', 4, 64) + "%" }
+		number  = func(f float64) string {
+			// Small probabilities keep their significant digits
+			if f < 1 {
+				return strconv.FormatFloat(f, 'g', 4, 64)
+			}
+			return strconv.FormatFloat(f, 'f', 2, 64)
+		}
+	)
+	fmt.Fprintf(&b, "# PAR sheet: %s\n", s.Machine)
+	for _, t := range s.tables(percent, number) {
+		fmt.Fprintf(&b, "\n## %s\n\n", t.title)
+		fmt.Fprintf(&b, "| %s |\n", strings.Join(t.header, " | "))
+		fmt.Fprintf(&b, "|%s\n", strings.Repeat(" --- |", len(t.header)))
+		for _, row := range t.rows {
+			fmt.Fprintf(&b, "| %s |\n", strings.Join(row, " | "))
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
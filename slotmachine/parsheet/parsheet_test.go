@@ -0,0 +1,102 @@
+package parsheet
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var sheet = Sheet{
+	Machine: "small",
+	Reels:   3,
+	Stops:   4,
+	Lines:   1,
+	Cycle:   64,
+	Symbols: []SymbolCount{
+		{Symbol: "CHERRY", Counts: []int{2, 2, 1}, Total: 5},
+		{Symbol: "BELL", Counts: []int{2, 2, 3}, Total: 7},
+	},
+	Pays: []Pay{
+		{Symbol: "CHERRY", Count: 3, Payout: 10, Hits: 4, Probability: 0.0625, HitCycle: 16, RTP: 0.625},
+		{Symbol: "BELL", Count: 3, Payout: 5, Hits: 12, Probability: 0.1875, HitCycle: 5.333333333333333, RTP: 0.9375},
+	},
+	HitFrequency: 0.25,
+	LineRTP:      1.5625,
+	RTP:          1.5625,
+	Simulation:   &Simulation{Confidence: 0.95, Low: 1.55, High: 1.575},
+
+	Rounds:               1000,
+	Variance:             4,
+	VolatilityIndex:      3.29,
+	VolatilityConfidence: 0.9,
+}
+
+func TestCSV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := sheet.Write(&buf, CSV); err != nil {
+		t.Fatalf("Expected:[nil] Got:[%s]", err)
+	}
+	r := csv.NewReader(&buf)
+	r.FieldsPerRecord = -1
+	records, err := r.ReadAll()
+	if err != nil {
+		t.Fatalf("Expected:[nil] Got:[%s]", err)
+	}
+	expected := [][]string{
+		{"Pays"},
+		{"Symbol", "Count", "Payout", "Hits", "Probability", "Hit cycle", "RTP"},
+		{"CHERRY", "3", "10", "4", "0.0625", "16", "0.62500000"},
+	}
+	for i, record := range records {
+		if reflect.DeepEqual(record, expected[0]) {
+			if !reflect.DeepEqual(records[i:i+3], expected) {
+				t.Errorf("Expected:%v Got:%v", expected, records[i:i+3])
+			}
+			return
+		}
+	}
+	t.Errorf("Expected the Pays section Got:%v", records)
+}
+
+func TestMarkdown(t *testing.T) {
+	var buf bytes.Buffer
+	if err := sheet.Write(&buf, MARKDOWN); err != nil {
+		t.Fatalf("Expected:[nil] Got:[%s]", err)
+	}
+	for _, expected := range []string{
+		"# PAR sheet: small\n",
+		"| Symbol | Reel 1 | Reel 2 | Reel 3 | Total |\n| --- | --- | --- | --- | --- |\n| CHERRY | 2 | 2 | 1 | 5 |\n",
+		"| BELL | 3 | 5 | 12 | 0.1875 | 5.33 | 93.7500% |\n",
+		"| RTP | 156.2500% |\n| RTP low at 95% confidence | 155.0000% |\n",
+		"| Volatility index at 90% confidence | 3.29 |\n",
+	} {
+		if !strings.Contains(buf.String(), expected) {
+			t.Errorf("Expected:[%s] Got:\n%s", expected, buf.String())
+		}
+	}
+}
+
+func TestJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := sheet.Write(&buf, JSON); err != nil {
+		t.Fatalf("Expected:[nil] Got:[%s]", err)
+	}
+	var decoded Sheet
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil || !reflect.DeepEqual(decoded, sheet) {
+		t.Errorf("Expected:[%+v] Got:[%+v] [Error:%v]", sheet, decoded, err)
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	for name, expected := range map[string]Format{"csv": CSV, "md": MARKDOWN, "Markdown": MARKDOWN, "json": JSON} {
+		if format, err := ParseFormat(name); err != nil || format != expected {
+			t.Errorf("Expected:[%s] Got:[%s] [Error:%v]", expected, format, err)
+		}
+	}
+	if _, err := ParseFormat("xlsx"); err == nil {
+		t.Errorf("Expected an error for an unknown format")
+	}
+}
@@ -1,6 +1,49 @@
 package slotmachine
 
+import "context"
+
 type SlotMachine interface {
-	Wager(bet, balance int) (wager int, err error)
-	Spin(bet int) (payout int, results []SpinResult, err error)
+	Wager(bet Bet, balance int) (wager int, err error)
+	// Spin plays a round. The context carries the logger of the round, its wager, see WithWager,
+	// and the observer notified of every spin, see NotifySpin.
+	Spin(ctx context.Context, bet Bet) (payout int, results []SpinResult, err error)
+}
+
+// Wrapper is implemented by the machines adding a feature to another machine.
+// The capabilities of the machine wrapped are found through it, see Capability.
+type Wrapper interface {
+	Unwrap() SlotMachine
+}
+
+// Capability returns the first machine of the chain of wrappers implementing T, starting with the machine itself
+func Capability[T any](machine SlotMachine) (T, bool) {
+	for machine != nil {
+		if capable, ok := machine.(T); ok {
+			return capable, true
+		}
+		wrapper, ok := machine.(Wrapper)
+		if !ok {
+			break
+		}
+		machine = wrapper.Unwrap()
+	}
+	var none T
+	return none, false
+}
+
+type wagerKey struct{}
+
+// WithWager returns a context carrying the chips wagered on the round, as returned by Wager
+func WithWager(ctx context.Context, wager int) context.Context {
+	return context.WithValue(ctx, wagerKey{}, wager)
+}
+
+// RoundWager returns the wager carried by the context, or else the total of the bet
+func RoundWager(ctx context.Context, bet Bet) int {
+	if ctx != nil {
+		if wager, ok := ctx.Value(wagerKey{}).(int); ok {
+			return wager
+		}
+	}
+	return bet.Total()
 }
@@ -0,0 +1,69 @@
+package slotmachine
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// SymbolKind is the role of a symbol on the reels
+type SymbolKind string
+
+const (
+	REGULAR SymbolKind = "regular" // Paid on the lines
+	WILD    SymbolKind = "wild"    // Substitutes the regular symbols on the lines
+	SCATTER SymbolKind = "scatter" // Paid anywhere in the window, triggers free spins
+	BONUS   SymbolKind = "bonus"   // Triggers a bonus game
+	COIN    SymbolKind = "coin"    // Held with a credit value by the hold and spin feature
+	BLANK   SymbolKind = "blank"   // Empty position, never paid
+)
+
+// SymbolInfo describes a symbol of a machine
+type SymbolInfo struct {
+	ID          Symbol     `json:"id"`
+	Name        string     `json:"name"` // Unique name, used for the symbol in JSON, eg. STEAK
+	Code        string     `json:"code"` // Short code displaying the symbol, eg. STK
+	Kind        SymbolKind `json:"kind"`
+	Description string     `json:"description,omitempty"`
+}
+
+// Symbols is the catalogue of the symbols of a machine
+type Symbols []SymbolInfo
+
+// Info returns the description of the symbol.
+// Symbols missing from the catalogue are regular, named and coded by their number.
+func (s Symbols) Info(id Symbol) SymbolInfo {
+	for _, info := range s {
+		if info.ID == id {
+			return info
+		}
+	}
+	return SymbolInfo{ID: id, Name: id.String(), Code: id.String(), Kind: REGULAR}
+}
+
+// Name returns the name of the symbol, or its number if it is not in the catalogue
+func (s Symbols) Name(id Symbol) string {
+	return s.Info(id).Name
+}
+
+// Lookup returns the symbol of the name, or of the number if it is not a name of the catalogue
+func (s Symbols) Lookup(name string) (Symbol, error) {
+	for _, info := range s {
+		if info.Name == name {
+			return info.ID, nil
+		}
+	}
+	n, err := strconv.Atoi(name)
+	if err != nil {
+		return 0, fmt.Errorf("Symbol:[%s] is not in the catalogue", name)
+	}
+	return Symbol(n), nil
+}
+
+// Names returns the names of the symbols, eg. for the logs
+func (s Symbols) Names(ids []Symbol) []string {
+	names := make([]string, len(ids))
+	for i, id := range ids {
+		names[i] = s.Name(id)
+	}
+	return names
+}
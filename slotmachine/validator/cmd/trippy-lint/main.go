@@ -0,0 +1,102 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"trippy/server"
+	"trippy/slotmachine"
+	"trippy/slotmachine/engine/atkins"
+	"trippy/slotmachine/validator"
+)
+
+// Checks machine definitions and reports all their problems.
+//
+// Usage: trippy-lint [-strict] [-config trippy.json] [definition.json...]
+// The definitions of the machines of the config are checked with the files given,
+// the default definition of the engine if a machine has none.
+// Exits with 1 if a definition has errors, or warnings with -strict.
+func main() {
+	var (
+		configPath = flag.String("config", "", "Server config, whose machine definitions are checked")
+		strict     = flag.Bool("strict", false, "Fail on warnings too")
+		failed     bool
+	)
+	flag.Parse()
+
+	if *configPath == "" && flag.NArg() == 0 {
+		fmt.Println("Usage: trippy-lint [-strict] [-config trippy.json] [definition.json...]")
+		os.Exit(2)
+	}
+
+	var targets []target
+	if *configPath != "" {
+		config, err := server.LoadConfig(*configPath)
+		if err != nil {
+			fmt.Println(err)
+			os.Exit(1)
+		}
+		for _, machine := range config.Machines {
+			targets = append(targets, target{name: machine.Name, engine: machine.Engine, path: machine.Definition})
+		}
+	}
+	for _, path := range flag.Args() {
+		targets = append(targets, target{name: path, engine: "atkins", path: path})
+	}
+
+	for _, t := range targets {
+		problems, err := t.lint()
+		if err != nil {
+			fmt.Printf("%s: error: %s\n", t.name, err)
+			failed = true
+			continue
+		}
+		for _, p := range problems {
+			fmt.Printf("%s: %s\n", t.name, p)
+		}
+		if len(problems.Errors()) > 0 || (*strict && len(problems) > 0) {
+			failed = true
+		}
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// target is a machine definition to check
+type target struct {
+	name   string // Machine name or file
+	engine string
+	path   string // Definition file, the default of the engine if empty
+}
+
+func (t target) lint() (validator.Problems, error) {
+	if t.engine != "atkins" {
+		return nil, fmt.Errorf("Engine:[%s] is unknown", t.engine)
+	}
+	def := atkins.DefaultDefinition()
+	if t.path != "" {
+		var err error
+		if def, err = slotmachine.LoadDefinition(t.path); err != nil {
+			return nil, err
+		}
+	}
+	problems := validator.Validate(def)
+	if len(problems.Errors()) > 0 {
+		return problems, nil
+	}
+
+	// Free spins retriggered endlessly are only found by the engine
+	_, err := atkins.NewAtkinsDietMachineFromDefinition(def).TheoreticalRTP()
+	switch {
+	case errors.Is(err, atkins.ErrWildsNotModelled):
+		problems = append(problems, validator.Problem{Severity: validator.WARNING, Field: "wilds", Message: err.Error()})
+	case errors.Is(err, atkins.ErrStepNotModelled):
+		problems = append(problems, validator.Problem{Severity: validator.WARNING, Field: "free_spins.multiplier_step", Message: err.Error()})
+	case err != nil:
+		problems = append(problems, validator.Problem{Severity: validator.ERROR, Field: "free_spins", Message: err.Error()})
+	}
+	return problems, nil
+}
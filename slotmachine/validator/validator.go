@@ -0,0 +1,587 @@
+// Package validator checks that a machine definition can be played as the spinner evaluates it.
+//
+// Validate reports every problem of a definition at once: errors make the machine unplayable
+// or its pays wrong, warnings are parts of the definition which can never take effect.
+package validator
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"trippy/slotmachine"
+)
+
+const (
+	_WINDOW_ROWS = 3 // Rows of the visible window, as in the spinner
+	_MIN_COUNT   = 2 // Symbols in a row paid at least, as in the spinner
+)
+
+// Severity tells whether a problem prevents the definition from being used
+type Severity string
+
+const (
+	ERROR   Severity = "error"
+	WARNING Severity = "warning"
+)
+
+// Problem is a problem found in a part of a definition
+type Problem struct {
+	Severity Severity
+	Field    string // Path of the part in the JSON of the definition, eg. pay_lines[3]
+	Message  string
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("%s: %s: %s", p.Severity, p.Field, p.Message)
+}
+
+// Problems are all the problems of a definition, grouped by the part of the definition
+type Problems []Problem
+
+// Errors returns the problems of severity error
+func (ps Problems) Errors() Problems {
+	var errs Problems
+	for _, p := range ps {
+		if p.Severity == ERROR {
+			errs = append(errs, p)
+		}
+	}
+	return errs
+}
+
+// Error joins the problems, one per line
+func (ps Problems) Error() string {
+	lines := make([]string, len(ps))
+	for i, p := range ps {
+		lines[i] = p.String()
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Validate checks the symbols, bet limits, pay table, reels, pay lines, special symbols,
+// free spins, bonus game, wild behaviours and hold and spin of the definition
+func Validate(def slotmachine.Definition) Problems {
+	v := &validation{def: def}
+	v.symbols()
+	v.betLimits()
+	if v.reels() {
+		v.payTable()
+		v.payLines()
+		v.special()
+		v.freeSpins()
+		v.bonus()
+		v.wilds()
+		v.hold()
+	}
+	return v.problems
+}
+
+// validation collects the problems of a definition
+type validation struct {
+	def      slotmachine.Definition
+	problems Problems
+	width    int                           // Number of reels
+	onReel   []map[slotmachine.Symbol]bool // Symbols of each reel strip
+	used     map[slotmachine.Symbol]bool   // Symbols of all the reel strips
+}
+
+func (v *validation) errorf(field, format string, args ...interface{}) {
+	v.problems = append(v.problems, Problem{ERROR, field, fmt.Sprintf(format, args...)})
+}
+
+func (v *validation) warnf(field, format string, args ...interface{}) {
+	v.problems = append(v.problems, Problem{WARNING, field, fmt.Sprintf(format, args...)})
+}
+
+func (v *validation) name(symbol slotmachine.Symbol) string {
+	return v.def.Symbols.Name(symbol)
+}
+
+// kind is the kind of the symbol in the catalogue, empty if it is not in the catalogue
+func (v *validation) kind(symbol slotmachine.Symbol) slotmachine.SymbolKind {
+	for _, info := range v.def.Symbols {
+		if info.ID == symbol {
+			return info.Kind
+		}
+	}
+	return ""
+}
+
+// symbols checks that the catalogue identifies every symbol once
+func (v *validation) symbols() {
+	var (
+		ids   = make(map[slotmachine.Symbol]int)
+		names = make(map[string]int)
+		codes = make(map[string]int)
+	)
+	for i, info := range v.def.Symbols {
+		field := fmt.Sprintf("symbols[%d]", i)
+		if j, ok := ids[info.ID]; ok {
+			v.errorf(field, "ID:[%d] is already the ID of symbols[%d]", info.ID, j)
+		}
+		ids[info.ID] = i
+		if info.Name == "" {
+			v.errorf(field, "Symbol:[%d] has no name", info.ID)
+		} else if j, ok := names[info.Name]; ok {
+			v.errorf(field, "Name:[%s] is already the name of symbols[%d]", info.Name, j)
+		}
+		names[info.Name] = i
+		if info.Code == "" {
+			v.warnf(field, "Symbol:[%s] has no code to display it", info.Name)
+		} else if j, ok := codes[info.Code]; ok {
+			v.warnf(field, "Code:[%s] is already the code of symbols[%d]", info.Code, j)
+		}
+		codes[info.Code] = i
+		switch info.Kind {
+		case slotmachine.REGULAR, slotmachine.WILD, slotmachine.SCATTER, slotmachine.BONUS, slotmachine.COIN, slotmachine.BLANK:
+		default:
+			v.errorf(field, "Kind:[%s] of Symbol:[%s] is unknown", info.Kind, info.Name)
+		}
+	}
+}
+
+func (v *validation) betLimits() {
+	limits := v.def.BetLimits
+	if limits.MinBet < 1 {
+		v.errorf("bet_limits.min_bet", "Minimum bet:[%d] is not greater than 0", limits.MinBet)
+	}
+	if limits.MaxBet > 0 && limits.MaxBet < limits.MinBet {
+		v.errorf("bet_limits.max_bet", "Maximum bet:[%d] is below the minimum bet:[%d]", limits.MaxBet, limits.MinBet)
+	}
+	seen := make(map[int]bool)
+	for i, denom := range limits.Denominations {
+		field := fmt.Sprintf("bet_limits.denominations[%d]", i)
+		if denom < 1 {
+			v.errorf(field, "Denomination:[%d] is not greater than 0", denom)
+		}
+		if seen[denom] {
+			v.warnf(field, "Denomination:[%d] is listed twice", denom)
+		}
+		seen[denom] = true
+	}
+}
+
+// reels checks that every stop is as wide as the first one.
+// The other checks need the reels, they are skipped if it fails.
+func (v *validation) reels() bool {
+	reels := v.def.Reels
+	if len(reels) == 0 || len(reels[0]) == 0 {
+		v.errorf("reels", "Reels are empty")
+		return false
+	}
+	v.width = len(reels[0])
+	if v.width < _MIN_COUNT {
+		v.errorf("reels", "Reels:[%d] are fewer than the %d needed to pay a line", v.width, _MIN_COUNT)
+		return false
+	}
+	if len(reels) < _WINDOW_ROWS {
+		v.errorf("reels", "Stops:[%d] are fewer than the %d rows of the window", len(reels), _WINDOW_ROWS)
+		return false
+	}
+	ok := true
+	for i, stop := range reels {
+		if len(stop) != v.width {
+			v.errorf(fmt.Sprintf("reels[%d]", i), "Expected:[%d] symbols, one per reel Got:[%d]", v.width, len(stop))
+			ok = false
+		}
+	}
+	if !ok {
+		return false
+	}
+
+	v.onReel = make([]map[slotmachine.Symbol]bool, v.width)
+	v.used = make(map[slotmachine.Symbol]bool)
+	for reel := range v.onReel {
+		v.onReel[reel] = make(map[slotmachine.Symbol]bool)
+		for _, stop := range reels {
+			v.onReel[reel][stop[reel]] = true
+			v.used[stop[reel]] = true
+		}
+	}
+	for _, symbol := range sortedSymbols(v.used) {
+		if len(v.def.Symbols) > 0 && v.kind(symbol) == "" {
+			v.warnf("reels", "Symbol:[%d] is not in the catalogue", symbol)
+		}
+	}
+	for i, info := range v.def.Symbols {
+		if !v.used[info.ID] && info.Kind != slotmachine.BLANK {
+			v.warnf(fmt.Sprintf("symbols[%d]", i), "Symbol:[%s] is on none of the reels", info.Name)
+		}
+	}
+	return true
+}
+
+// payTable checks that every pay can be won, and that every symbol on the reels is paid or special
+func (v *validation) payTable() {
+	special := v.def.Special
+	for _, symbol := range sortedSymbols(v.payTableSymbols()) {
+		var (
+			pays  = v.def.PayTable[symbol]
+			field = "pay_table." + v.name(symbol)
+		)
+		if !v.used[symbol] {
+			v.warnf(field, "Symbol:[%s] is on none of the reels, its pays are never won", v.name(symbol))
+			continue
+		}
+		switch {
+		case symbol == special.Scatter && symbol != special.Wildcard:
+			v.warnf(field, "Symbol:[%s] is the scatter, it is paid on the lines and not anywhere in the window", v.name(symbol))
+		case v.kind(symbol) == slotmachine.BLANK:
+			v.warnf(field, "Symbol:[%s] is blank and paid", v.name(symbol))
+		}
+
+		counts := make([]int, 0, len(pays))
+		for count := range pays {
+			counts = append(counts, count)
+		}
+		sort.Ints(counts)
+		last := 0
+		for _, count := range counts {
+			countField := fmt.Sprintf("%s.%d", field, count)
+			switch {
+			case count < _MIN_COUNT || count > v.width:
+				v.errorf(countField, "Count:[%d] is never paid, lines pay %d to %d symbols", count, _MIN_COUNT, v.width)
+				continue
+			case pays[count] < 0:
+				v.errorf(countField, "Payout:[%d] is negative", pays[count])
+			case pays[count] == 0:
+				v.warnf(countField, "Payout is 0")
+			case pays[count] < last:
+				v.warnf(countField, "Payout:[%d] is less than the payout of fewer symbols:[%d]", pays[count], last)
+			}
+			if pays[count] > last {
+				last = pays[count]
+			}
+			if reason := v.unreachable(symbol, count); reason != "" {
+				v.warnf(countField, "Count:[%d] of Symbol:[%s] is never paid, %s", count, v.name(symbol), reason)
+			}
+		}
+	}
+
+	for _, symbol := range sortedSymbols(v.used) {
+		if _, ok := v.def.PayTable[symbol]; ok || symbol == special.Wildcard || symbol == special.Scatter {
+			continue
+		}
+		switch v.kind(symbol) {
+		case slotmachine.BLANK, slotmachine.BONUS, slotmachine.COIN:
+			continue
+		}
+		v.warnf("pay_table", "Symbol:[%s] is on the reels but never paid", v.name(symbol))
+	}
+}
+
+func (v *validation) payTableSymbols() map[slotmachine.Symbol]bool {
+	symbols := make(map[slotmachine.Symbol]bool, len(v.def.PayTable))
+	for symbol := range v.def.PayTable {
+		symbols[symbol] = true
+	}
+	return symbols
+}
+
+// unreachable tells why count symbols in a row are never paid for the symbol, empty if they can be.
+// A line pays the first symbol which is not a wildcard, followed by the same symbols or wildcards.
+func (v *validation) unreachable(symbol slotmachine.Symbol, count int) string {
+	wild := v.def.Special.Wildcard
+	if symbol != wild && !v.onReel[0][symbol] && !(v.onReel[0][wild] && v.onReel[1][symbol]) {
+		return "it starts neither reel 1 nor reel 2 after a wildcard"
+	}
+	for reel := 0; reel < count; reel++ {
+		if !v.onReel[reel][symbol] && !v.onReel[reel][wild] {
+			return fmt.Sprintf("reel %d has neither the symbol nor a wildcard", reel+1)
+		}
+	}
+	if count == v.width {
+		return ""
+	}
+	for other := range v.onReel[count] {
+		if other != symbol && other != wild {
+			return ""
+		}
+	}
+	return fmt.Sprintf("reel %d always continues the line", count+1)
+}
+
+// payLines checks that the lines are as wide as the reels and stay in the window
+func (v *validation) payLines() {
+	if len(v.def.PayLines) == 0 {
+		v.errorf("pay_lines", "Pay lines are empty")
+		return
+	}
+	seen := make(map[string]int)
+	for i, line := range v.def.PayLines {
+		field := fmt.Sprintf("pay_lines[%d]", i)
+		if len(line) != v.width {
+			v.errorf(field, "Expected:[%d] rows, one per reel Got:[%d]", v.width, len(line))
+		}
+		for j, row := range line {
+			if row < 1 || row > _WINDOW_ROWS {
+				v.errorf(fmt.Sprintf("%s[%d]", field, j), "Row:[%d] is outside the window, rows are 1 to %d", row, _WINDOW_ROWS)
+			}
+		}
+		key := fmt.Sprint(line)
+		if j, ok := seen[key]; ok {
+			v.warnf(field, "Pay line is the same as pay_lines[%d], it pays twice", j)
+		}
+		seen[key] = i
+	}
+}
+
+// special checks the wildcard and the scatter against the catalogue and the reels
+func (v *validation) special() {
+	special := v.def.Special
+	if special.Wildcard == special.Scatter {
+		v.errorf("special", "Symbol:[%s] is both the wildcard and the scatter", v.name(special.Wildcard))
+	}
+	if kind := v.kind(special.Wildcard); kind != "" && kind != slotmachine.WILD {
+		v.errorf("special.wildcard", "Symbol:[%s] is of kind:[%s] in the catalogue, not %s", v.name(special.Wildcard), kind, slotmachine.WILD)
+	}
+	if kind := v.kind(special.Scatter); kind != "" && kind != slotmachine.SCATTER {
+		v.errorf("special.scatter", "Symbol:[%s] is of kind:[%s] in the catalogue, not %s", v.name(special.Scatter), kind, slotmachine.SCATTER)
+	}
+	for i, info := range v.def.Symbols {
+		if info.Kind == slotmachine.WILD && info.ID != special.Wildcard {
+			v.errorf(fmt.Sprintf("symbols[%d]", i), "Symbol:[%s] is a wild but the wildcard is %s, only one is substituted", info.Name, v.name(special.Wildcard))
+		}
+		if info.Kind == slotmachine.SCATTER && info.ID != special.Scatter {
+			v.errorf(fmt.Sprintf("symbols[%d]", i), "Symbol:[%s] is a scatter but the scatter is %s, only one is counted", info.Name, v.name(special.Scatter))
+		}
+	}
+	if !v.used[special.Wildcard] {
+		v.warnf("special.wildcard", "Symbol:[%s] is on none of the reels", v.name(special.Wildcard))
+	}
+}
+
+// freeSpins checks that the scatters needed for free spins can be in the window
+func (v *validation) freeSpins() {
+	rules := v.def.FreeSpins
+	switch {
+	case rules.ScatterCount < 0:
+		v.errorf("free_spins.scatter_count", "Scatter count:[%d] is negative", rules.ScatterCount)
+	case rules.Spins < 0:
+		v.errorf("free_spins.spins", "Free spins:[%d] is negative", rules.Spins)
+	case rules.Multiplier < 0:
+		v.errorf("free_spins.multiplier", "Multiplier:[%d] is negative", rules.Multiplier)
+	}
+	v.multiplierSteps()
+	if rules.ScatterCount == 0 {
+		if rules.Spins > 0 {
+			v.warnf("free_spins.scatter_count", "Scatter count is 0, the free spins are never awarded")
+		}
+		return
+	}
+	if rules.Spins == 0 {
+		v.warnf("free_spins.spins", "Free spins are 0, the scatters award nothing")
+		return
+	}
+	if max := v.maxInView(v.def.Special.Scatter); rules.ScatterCount > max {
+		v.errorf("free_spins.scatter_count", "Scatter count:[%d] is never in view, the window holds at most %d scatters", rules.ScatterCount, max)
+	}
+}
+
+// multiplierSteps checks that the steps raise the multiplier of the free spins
+func (v *validation) multiplierSteps() {
+	rules := v.def.FreeSpins
+	if rules.MultiplierStep < 0 {
+		v.errorf("free_spins.multiplier_step", "Multiplier step:[%d] is negative", rules.MultiplierStep)
+	}
+	if rules.MaxMultiplier < 0 {
+		v.errorf("free_spins.max_multiplier", "Max multiplier:[%d] is negative", rules.MaxMultiplier)
+	}
+	switch rules.StepOn {
+	case "", slotmachine.STEP_ON_RETRIGGER:
+	case slotmachine.STEP_ON_RESPIN:
+		if v.def.Wilds == nil || !v.def.Wilds.Walking {
+			v.warnf("free_spins.step_on", "Wilds are not walking, there is no respin to step on")
+		}
+	default:
+		v.errorf("free_spins.step_on", "Step on:[%s] is not %s or %s", rules.StepOn, slotmachine.STEP_ON_RETRIGGER, slotmachine.STEP_ON_RESPIN)
+	}
+	base := rules.Multiplier
+	if base < 1 {
+		base = 1
+	}
+	if rules.MultiplierStep > 0 && rules.MaxMultiplier > 0 && rules.MaxMultiplier <= base {
+		v.warnf("free_spins.max_multiplier", "Max multiplier:[%d] is not above the multiplier:[%d], the steps never raise it", rules.MaxMultiplier, base)
+	}
+	if rules.MultiplierStep == 0 && (rules.StepOn != "" || rules.MaxMultiplier > 0) {
+		v.warnf("free_spins.multiplier_step", "Multiplier step is 0, the multiplier is never raised")
+	}
+}
+
+// bonus checks that the pick game can be launched and pays its prizes
+func (v *validation) bonus() {
+	rules := v.def.Bonus
+	if rules == nil {
+		for i, info := range v.def.Symbols {
+			if info.Kind == slotmachine.BONUS && v.used[info.ID] {
+				v.warnf(fmt.Sprintf("symbols[%d]", i), "Symbol:[%s] is a bonus but the definition has no bonus game", info.Name)
+			}
+		}
+		return
+	}
+	if kind := v.kind(rules.Symbol); kind != "" && kind != slotmachine.BONUS {
+		v.errorf("bonus.symbol", "Symbol:[%s] is of kind:[%s] in the catalogue, not %s", v.name(rules.Symbol), kind, slotmachine.BONUS)
+	}
+	if rules.Symbol == v.def.Special.Wildcard || rules.Symbol == v.def.Special.Scatter {
+		v.errorf("bonus.symbol", "Symbol:[%s] is already the wildcard or the scatter", v.name(rules.Symbol))
+	}
+	if rules.Count < 1 {
+		v.errorf("bonus.count", "Count:[%d] is not greater than 0", rules.Count)
+	} else if max := v.maxInView(rules.Symbol); rules.Count > max {
+		v.errorf("bonus.count", "Count:[%d] is never in view, the window holds at most %d symbols:[%s]", rules.Count, max, v.name(rules.Symbol))
+	}
+	if rules.Prizes < 1 {
+		v.errorf("bonus.prizes", "Prizes:[%d] is not greater than 0", rules.Prizes)
+	}
+	if rules.Picks < 1 || rules.Picks > rules.Prizes {
+		v.errorf("bonus.picks", "Picks:[%d] must be between 1 and the prizes:[%d]", rules.Picks, rules.Prizes)
+	}
+	if len(rules.Reveals) == 0 {
+		v.errorf("bonus.reveals", "Reveals are empty, no prize can be drawn")
+	}
+	var weights int
+	for i, reveal := range rules.Reveals {
+		field := fmt.Sprintf("bonus.reveals[%d]", i)
+		if reveal.Multiplier < 0 {
+			v.errorf(field, "Multiplier:[%d] is negative", reveal.Multiplier)
+		}
+		if reveal.Weight < 0 {
+			v.errorf(field, "Weight:[%d] is negative", reveal.Weight)
+		} else if reveal.Weight == 0 {
+			v.warnf(field, "Weight is 0, the prize is never drawn")
+		}
+		weights = weights + reveal.Weight
+	}
+	if len(rules.Reveals) > 0 && weights <= 0 {
+		v.errorf("bonus.reveals", "Reveals have no weight, no prize can be drawn")
+	}
+}
+
+// wilds checks that the wild behaviours change the window and the multipliers can be drawn
+func (v *validation) wilds() {
+	rules := v.def.Wilds
+	if rules == nil || rules.Plain() {
+		if rules != nil && rules.Combine != "" {
+			v.warnf("wilds.combine", "Multipliers are empty, there is nothing to combine")
+		}
+		return
+	}
+	switch rules.Combine {
+	case "", slotmachine.COMBINE_MULTIPLY, slotmachine.COMBINE_ADD:
+	default:
+		v.errorf("wilds.combine", "Combine:[%s] is not %s or %s", rules.Combine, slotmachine.COMBINE_MULTIPLY, slotmachine.COMBINE_ADD)
+	}
+	var weights int
+	for i, m := range rules.Multipliers {
+		field := fmt.Sprintf("wilds.multipliers[%d]", i)
+		if m.Multiplier < 1 {
+			v.errorf(field, "Multiplier:[%d] is not greater than 0", m.Multiplier)
+		}
+		if m.Weight < 0 {
+			v.errorf(field, "Weight:[%d] is negative", m.Weight)
+		} else if m.Weight == 0 {
+			v.warnf(field, "Weight is 0, the multiplier is never drawn")
+		}
+		weights = weights + m.Weight
+	}
+	if len(rules.Multipliers) > 0 && weights <= 0 {
+		v.errorf("wilds.multipliers", "Multipliers have no weight, none can be drawn")
+	}
+	if !v.used[v.def.Special.Wildcard] {
+		v.warnf("wilds", "Wildcard:[%s] is on none of the reels, the wild behaviours never play", v.name(v.def.Special.Wildcard))
+	}
+	if rules.Sticky && (v.def.FreeSpins.ScatterCount == 0 || v.def.FreeSpins.Spins == 0) {
+		v.warnf("wilds.sticky", "Free spins are never awarded, the sticky wilds are never held")
+	}
+}
+
+// hold checks that the hold and spin feature can be launched and pays its coins
+func (v *validation) hold() {
+	rules := v.def.Hold
+	if rules == nil {
+		for i, info := range v.def.Symbols {
+			if info.Kind == slotmachine.COIN && v.used[info.ID] {
+				v.warnf(fmt.Sprintf("symbols[%d]", i), "Symbol:[%s] is a coin but the definition has no hold and spin", info.Name)
+			}
+		}
+		return
+	}
+	if kind := v.kind(rules.Symbol); kind != "" && kind != slotmachine.COIN {
+		v.errorf("hold.symbol", "Symbol:[%s] is of kind:[%s] in the catalogue, not %s", v.name(rules.Symbol), kind, slotmachine.COIN)
+	}
+	if rules.Symbol == v.def.Special.Wildcard || rules.Symbol == v.def.Special.Scatter ||
+		(v.def.Bonus != nil && rules.Symbol == v.def.Bonus.Symbol) {
+		v.errorf("hold.symbol", "Symbol:[%s] is already the wildcard, the scatter or the bonus symbol", v.name(rules.Symbol))
+	}
+	if rules.Count < 1 {
+		v.errorf("hold.count", "Count:[%d] is not greater than 0", rules.Count)
+	} else if max := v.maxInView(rules.Symbol); rules.Count > max {
+		v.errorf("hold.count", "Count:[%d] is never in view, the window holds at most %d symbols:[%s]", rules.Count, max, v.name(rules.Symbol))
+	}
+	if rules.Respins < 1 {
+		v.errorf("hold.respins", "Respins:[%d] is not greater than 0", rules.Respins)
+	}
+	if rules.Grand < 0 {
+		v.errorf("hold.grand", "Grand:[%d] is negative", rules.Grand)
+	}
+	for reel := 0; reel < v.width; reel++ {
+		if !v.onReel[reel][rules.Symbol] {
+			v.warnf("hold.symbol", "Symbol:[%s] is not on reel:[%d], the window is never full of coins", v.name(rules.Symbol), reel+1)
+			break
+		}
+	}
+	if len(rules.Values) == 0 {
+		v.errorf("hold.values", "Values are empty, no coin can be drawn")
+	}
+	var weights int
+	for i, value := range rules.Values {
+		field := fmt.Sprintf("hold.values[%d]", i)
+		if value.Multiplier < 1 {
+			v.errorf(field, "Multiplier:[%d] is not greater than 0", value.Multiplier)
+		}
+		if value.Weight < 0 {
+			v.errorf(field, "Weight:[%d] is negative", value.Weight)
+		} else if value.Weight == 0 {
+			v.warnf(field, "Weight is 0, the value is never drawn")
+		}
+		weights = weights + value.Weight
+	}
+	if len(rules.Values) > 0 && weights <= 0 {
+		v.errorf("hold.values", "Values have no weight, no coin can be drawn")
+	}
+}
+
+// maxInView is the highest number of the symbol in the window
+func (v *validation) maxInView(symbol slotmachine.Symbol) int {
+	var (
+		reels = v.def.Reels
+		stops = len(reels)
+		total int
+	)
+	for reel := 0; reel < v.width; reel++ {
+		var max int
+		for stop := 0; stop < stops; stop++ {
+			var count int
+			for row := -1; row < _WINDOW_ROWS-1; row++ {
+				if reels[((stop+row)%stops+stops)%stops][reel] == symbol {
+					count++
+				}
+			}
+			if count > max {
+				max = count
+			}
+		}
+		total = total + max
+	}
+	return total
+}
+
+func sortedSymbols(set map[slotmachine.Symbol]bool) []slotmachine.Symbol {
+	symbols := make([]slotmachine.Symbol, 0, len(set))
+	for symbol := range set {
+		symbols = append(symbols, symbol)
+	}
+	sort.Slice(symbols, func(i, j int) bool { return symbols[i] < symbols[j] })
+	return symbols
+}
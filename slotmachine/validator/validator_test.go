@@ -0,0 +1,190 @@
+package validator
+
+import (
+	"testing"
+
+	"trippy/slotmachine"
+	"trippy/slotmachine/engine/atkins"
+)
+
+// The blank EMPTY symbol of atkins is on none of the reels, without a warning
+func TestValidDefinitions(t *testing.T) {
+	def, err := slotmachine.LoadDefinition("../engine/atkins/atkins-diet.json")
+	if err != nil {
+		t.Fatalf("Expected:[nil] Got:[%s]", err)
+	}
+	for _, def := range []slotmachine.Definition{atkins.DefaultDefinition(), def, atkins.HoldDefinition()} {
+		if problems := Validate(def); len(problems) > 0 {
+			t.Errorf("Expected no problems [Definition:%s] Got:\n%s", def.Name, problems)
+		}
+	}
+}
+
+type problemSample struct {
+	name     string
+	change   func(def *slotmachine.Definition)
+	severity Severity
+	field    string
+}
+
+// smallDefinition has 3 reels of 4 stops, every pay of the pay table can be won
+func smallDefinition() slotmachine.Definition {
+	return slotmachine.Definition{
+		Name: "small",
+		Symbols: slotmachine.Symbols{
+			{ID: 0, Name: "EMPTY", Code: "---", Kind: slotmachine.BLANK},
+			{ID: 1, Name: "WILD", Code: "WLD", Kind: slotmachine.WILD},
+			{ID: 2, Name: "CHERRY", Code: "CHR", Kind: slotmachine.REGULAR},
+			{ID: 3, Name: "BELL", Code: "BEL", Kind: slotmachine.REGULAR},
+			{ID: 4, Name: "STAR", Code: "STR", Kind: slotmachine.SCATTER},
+		},
+		BetLimits: slotmachine.BetLimits{MinBet: 1, MaxBet: 10, Denominations: []int{1, 5}},
+		PayTable: slotmachine.PayTable{
+			1: {2: 5},
+			2: {2: 2, 3: 10},
+			3: {3: 20},
+		},
+		Reels: slotmachine.Reels{
+			{1, 2, 3},
+			{2, 1, 4},
+			{3, 4, 2},
+			{4, 3, 0},
+		},
+		PayLines:  slotmachine.PayLines{{2, 2, 2}, {1, 1, 1}, {3, 3, 3}},
+		Special:   slotmachine.SpecialSymbols{Wildcard: 1, Scatter: 4},
+		FreeSpins: slotmachine.FreeSpinRules{ScatterCount: 3, Spins: 5, Multiplier: 2},
+	}
+}
+
+// withBonus adds a pick game launched by a CHEF on the third reel
+func withBonus(def *slotmachine.Definition) {
+	def.Symbols = append(def.Symbols, slotmachine.SymbolInfo{ID: 5, Name: "CHEF", Code: "CHF", Kind: slotmachine.BONUS})
+	def.Reels[3][2] = 5
+	def.Bonus = &slotmachine.BonusRules{Symbol: 5, Count: 1, Prizes: 5, Picks: 2,
+		Reveals: []slotmachine.BonusReveal{{Multiplier: 1, Weight: 4}, {Multiplier: 10, Weight: 1}}}
+}
+
+// withHold adds a hold and spin launched by 3 COINs, on a new stop of every reel
+func withHold(def *slotmachine.Definition) {
+	def.Symbols = append(def.Symbols, slotmachine.SymbolInfo{ID: 6, Name: "COIN", Code: "CON", Kind: slotmachine.COIN})
+	def.Reels = append(def.Reels, slotmachine.ReelLine{6, 6, 6})
+	def.Hold = &slotmachine.HoldRules{Symbol: 6, Count: 3, Respins: 3, Grand: 50,
+		Values: []slotmachine.CoinValue{{Multiplier: 1, Weight: 3}, {Multiplier: 5, Weight: 1}}}
+}
+
+var problemSamples = []problemSample{
+	{"duplicate name", func(def *slotmachine.Definition) { def.Symbols[3].Name = "CHERRY" }, ERROR, "symbols[3]"},
+	{"unknown kind", func(def *slotmachine.Definition) { def.Symbols[3].Kind = "joker" }, ERROR, "symbols[3]"},
+	{"unused symbol", func(def *slotmachine.Definition) {
+		def.Symbols = append(def.Symbols, slotmachine.SymbolInfo{ID: 5, Name: "LEMON", Code: "LMN", Kind: slotmachine.REGULAR})
+	}, WARNING, "symbols[5]"},
+	{"max below min", func(def *slotmachine.Definition) { def.BetLimits.MaxBet = 0; def.BetLimits.MinBet = 0 }, ERROR, "bet_limits.min_bet"},
+	{"zero denomination", func(def *slotmachine.Definition) { def.BetLimits.Denominations = []int{1, 0} }, ERROR, "bet_limits.denominations[1]"},
+	{"empty reels", func(def *slotmachine.Definition) { def.Reels = nil }, ERROR, "reels"},
+	{"ragged reels", func(def *slotmachine.Definition) { def.Reels[2] = def.Reels[2][:2] }, ERROR, "reels[2]"},
+	{"too few stops", func(def *slotmachine.Definition) { def.Reels = def.Reels[:2] }, ERROR, "reels"},
+	{"symbol not in catalogue", func(def *slotmachine.Definition) { def.Reels[3][2] = 9 }, WARNING, "reels"},
+	{"count too high", func(def *slotmachine.Definition) { def.PayTable[2][4] = 100 }, ERROR, "pay_table.CHERRY.4"},
+	{"count too low", func(def *slotmachine.Definition) { def.PayTable[2][1] = 1 }, ERROR, "pay_table.CHERRY.1"},
+	{"decreasing pays", func(def *slotmachine.Definition) { def.PayTable[2][3] = 1 }, WARNING, "pay_table.CHERRY.3"},
+	{"negative pay", func(def *slotmachine.Definition) { def.PayTable[3][3] = -1 }, ERROR, "pay_table.BELL.3"},
+	{"symbol off the reels", func(def *slotmachine.Definition) { def.Reels[0][1] = 3; def.Reels[1][0] = 3; def.Reels[2][2] = 3 }, WARNING, "pay_table.CHERRY"},
+	{"never starts a line", func(def *slotmachine.Definition) { def.Reels[1][0] = 3; def.Reels[0][1] = 3 }, WARNING, "pay_table.CHERRY.2"},
+	{"missing on a reel", func(def *slotmachine.Definition) { def.Reels[0][2] = 0 }, WARNING, "pay_table.BELL.3"},
+	{"line always continues", func(def *slotmachine.Definition) {
+		def.Reels = slotmachine.Reels{{2, 2, 2}, {3, 1, 2}, {2, 4, 1}, {4, 3, 2}}
+	}, WARNING, "pay_table.CHERRY.2"},
+	{"scatter paid", func(def *slotmachine.Definition) { def.PayTable[4] = slotmachine.Pays{3: 5} }, WARNING, "pay_table.STAR"},
+	{"never paid", func(def *slotmachine.Definition) { delete(def.PayTable, 3) }, WARNING, "pay_table"},
+	{"line too short", func(def *slotmachine.Definition) { def.PayLines[1] = slotmachine.PayLine{1, 1} }, ERROR, "pay_lines[1]"},
+	{"row outside window", func(def *slotmachine.Definition) { def.PayLines[2][1] = 4 }, ERROR, "pay_lines[2][1]"},
+	{"duplicate line", func(def *slotmachine.Definition) { def.PayLines = append(def.PayLines, slotmachine.PayLine{1, 1, 1}) }, WARNING, "pay_lines[3]"},
+	{"no pay lines", func(def *slotmachine.Definition) { def.PayLines = nil }, ERROR, "pay_lines"},
+	{"wildcard is scatter", func(def *slotmachine.Definition) { def.Special.Scatter = 1 }, ERROR, "special"},
+	{"wildcard of regular kind", func(def *slotmachine.Definition) { def.Special.Wildcard = 3 }, ERROR, "special.wildcard"},
+	{"second wild", func(def *slotmachine.Definition) { def.Symbols[3].Kind = slotmachine.WILD }, ERROR, "symbols[3]"},
+	{"scatter count unreachable", func(def *slotmachine.Definition) { def.FreeSpins.ScatterCount = 4 }, ERROR, "free_spins.scatter_count"},
+	{"no free spins", func(def *slotmachine.Definition) { def.FreeSpins.Spins = 0 }, WARNING, "free_spins.spins"},
+	{"bonus symbol without game", func(def *slotmachine.Definition) { withBonus(def); def.Bonus = nil }, WARNING, "symbols[5]"},
+	{"bonus of regular kind", func(def *slotmachine.Definition) { withBonus(def); def.Bonus.Symbol = 3 }, ERROR, "bonus.symbol"},
+	{"bonus count unreachable", func(def *slotmachine.Definition) { withBonus(def); def.Bonus.Count = 2 }, ERROR, "bonus.count"},
+	{"more picks than prizes", func(def *slotmachine.Definition) { withBonus(def); def.Bonus.Picks = 6 }, ERROR, "bonus.picks"},
+	{"negative multiplier", func(def *slotmachine.Definition) { withBonus(def); def.Bonus.Reveals[1].Multiplier = -1 }, ERROR, "bonus.reveals[1]"},
+	{"no reveals", func(def *slotmachine.Definition) { withBonus(def); def.Bonus.Reveals = nil }, ERROR, "bonus.reveals"},
+	{"wild behaviours without wildcard", func(def *slotmachine.Definition) {
+		def.Wilds = &slotmachine.WildRules{Expanding: true}
+		def.Reels[0][0] = 0
+		def.Reels[1][1] = 0
+	}, WARNING, "wilds"},
+	{"sticky without free spins", func(def *slotmachine.Definition) {
+		def.Wilds = &slotmachine.WildRules{Sticky: true}
+		def.FreeSpins.Spins = 0
+	}, WARNING, "wilds.sticky"},
+	{"wild multiplier of 0", func(def *slotmachine.Definition) {
+		def.Wilds = &slotmachine.WildRules{Multipliers: []slotmachine.WildMultiplier{{Multiplier: 2, Weight: 1}, {Multiplier: 0, Weight: 1}}}
+	}, ERROR, "wilds.multipliers[1]"},
+	{"wild multipliers without weight", func(def *slotmachine.Definition) {
+		def.Wilds = &slotmachine.WildRules{Multipliers: []slotmachine.WildMultiplier{{Multiplier: 2}}}
+	}, ERROR, "wilds.multipliers"},
+	{"unknown combine", func(def *slotmachine.Definition) {
+		def.Wilds = &slotmachine.WildRules{Combine: "max", Multipliers: []slotmachine.WildMultiplier{{Multiplier: 2, Weight: 1}}}
+	}, ERROR, "wilds.combine"},
+	{"combine without multipliers", func(def *slotmachine.Definition) { def.Wilds = &slotmachine.WildRules{Combine: "add"} }, WARNING, "wilds.combine"},
+	{"negative multiplier step", func(def *slotmachine.Definition) { def.FreeSpins.MultiplierStep = -1 }, ERROR, "free_spins.multiplier_step"},
+	{"unknown step", func(def *slotmachine.Definition) { def.FreeSpins.MultiplierStep = 1; def.FreeSpins.StepOn = "cascade" }, ERROR, "free_spins.step_on"},
+	{"respin step without walking wilds", func(def *slotmachine.Definition) {
+		def.FreeSpins.MultiplierStep = 1
+		def.FreeSpins.StepOn = slotmachine.STEP_ON_RESPIN
+	}, WARNING, "free_spins.step_on"},
+	{"max multiplier reached", func(def *slotmachine.Definition) { def.FreeSpins.MultiplierStep = 1; def.FreeSpins.MaxMultiplier = 2 }, WARNING, "free_spins.max_multiplier"},
+	{"max multiplier without step", func(def *slotmachine.Definition) { def.FreeSpins.MaxMultiplier = 5 }, WARNING, "free_spins.multiplier_step"},
+	{"coin without hold", func(def *slotmachine.Definition) { withHold(def); def.Hold = nil }, WARNING, "symbols[5]"},
+	{"hold of scatter", func(def *slotmachine.Definition) { withHold(def); def.Hold.Symbol = 4 }, ERROR, "hold.symbol"},
+	{"hold count unreachable", func(def *slotmachine.Definition) { withHold(def); def.Hold.Count = 10 }, ERROR, "hold.count"},
+	{"no respins", func(def *slotmachine.Definition) { withHold(def); def.Hold.Respins = 0 }, ERROR, "hold.respins"},
+	{"coin missing on a reel", func(def *slotmachine.Definition) { withHold(def); def.Reels[4][1] = 0 }, WARNING, "hold.symbol"},
+	{"coin value of 0", func(def *slotmachine.Definition) { withHold(def); def.Hold.Values[0].Multiplier = 0 }, ERROR, "hold.values[0]"},
+	{"no coin values", func(def *slotmachine.Definition) { withHold(def); def.Hold.Values = nil }, ERROR, "hold.values"},
+}
+
+func TestProblems(t *testing.T) {
+	if problems := Validate(smallDefinition()); len(problems) > 0 {
+		t.Fatalf("Expected no problems Got:\n%s", problems)
+	}
+	bonus := smallDefinition()
+	withBonus(&bonus)
+	if problems := Validate(bonus); len(problems) > 0 {
+		t.Fatalf("Expected no problems with the bonus game Got:\n%s", problems)
+	}
+	hold := smallDefinition()
+	withHold(&hold)
+	if problems := Validate(hold); len(problems) > 0 {
+		t.Fatalf("Expected no problems with the hold and spin Got:\n%s", problems)
+	}
+	for _, sample := range problemSamples {
+		testProblem(t, sample)
+	}
+}
+
+func testProblem(t *testing.T, sample problemSample) {
+	def := smallDefinition()
+	sample.change(&def)
+	problems := Validate(def)
+	for _, p := range problems {
+		if p.Severity == sample.severity && p.Field == sample.field {
+			return
+		}
+	}
+	t.Errorf("[Sample:%s] Expected:[%s: %s] Got:\n%s", sample.name, sample.severity, sample.field, problems)
+}
+
+// All the problems are reported at once
+func TestAllProblems(t *testing.T) {
+	def := smallDefinition()
+	def.BetLimits.MinBet = 0
+	def.PayLines[0][0] = 0
+	def.FreeSpins.ScatterCount = 7
+	if errs := Validate(def).Errors(); len(errs) != 3 {
+		t.Errorf("Expected:[3] errors Got:\n%s", errs)
+	}
+}
@@ -2,10 +2,11 @@ package spinner
 
 import (
 	"errors"
-	"fmt"
 	"math/rand"
 	"os"
 	"time"
+
+	"trippy/logger"
 )
 
 func init() {
@@ -16,7 +17,7 @@ func init() {
 			seed = seed + int(n)
 		}
 	} else {
-		fmt.Println("Error in getting host name:", err)
+		logger.Default().Warn("Unable to get host name for the random seed", "err", err)
 	}
 	// Randomizing the seed using machine hostname & current time
 	// So that all machines don't have the same seed if started at the same time
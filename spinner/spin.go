@@ -3,28 +3,16 @@ package spinner
 import (
 	"errors"
 	"fmt"
-	"log"
-	"os"
 
 	"trippy/slotmachine"
 )
 
 var (
-	slog *log.Logger
-)
-
-func init() {
-	// Log to stdout
-	slog = log.New(os.Stdout, "", 0)
-	slog.SetFlags(log.Lshortfile | log.Ldate | log.Ltime | log.Lmicroseconds)
-	slog.SetPrefix("SPIN:")
-}
-
-var (
-	errEmptyReel           = errors.New("Reel strips are empty")
-	errEmptyPayLine        = errors.New("Pay lines are empty")
-	errReelPayLineMismatch = errors.New("Reel width and Pay line width do not match")
-	errOnlyOneReelStrip    = errors.New("Only one reel strip present")
+	errEmptyReel            = errors.New("Reel strips are empty")
+	errEmptyPayLine         = errors.New("Pay lines are empty")
+	errReelPayLineMismatch  = errors.New("Reel width and Pay line width do not match")
+	errOnlyOneReelStrip     = errors.New("Only one reel strip present")
+	errPayLineOutsideWindow = errors.New("Pay line has a row outside the window")
 )
 
 func SpinNPay(
@@ -44,6 +32,9 @@ func SpinNPay(
 	if err != nil {
 		return spinResult, err
 	}
+	for i := range winLines {
+		winLines[i].PayLine = payLines[winLines[i].Index-1]
+	}
 
 	spinResult, err = CalculatePay(winLines, payTable, special)
 	if err != nil {
@@ -51,6 +42,7 @@ func SpinNPay(
 	}
 
 	spinResult.ScatterCount = CountScatter(stops, reels, special.Scatter)
+	spinResult.Window = Window(stops, reels)
 
 	// Changing stops to Human-friendly numbering (starts from 1)
 	for i := 0; i < len(stops); i++ {
@@ -68,7 +60,6 @@ func Spin(reels slotmachine.Reels) (stops []int, err error) {
 	}
 
 	stops = make([]int, len(reels[0]))
-	//slog.Println("Min:", 0, "Max:", len(reels)-1)
 
 	// Spinning the reels
 	for i := range stops {
@@ -87,6 +78,45 @@ func FindWins(
 	payLines slotmachine.PayLines,
 	special slotmachine.SpecialSymbols) ([]slotmachine.WinLine, error) {
 
+	if len(reels) == 0 {
+		return make([]slotmachine.WinLine, 0), errEmptyReel
+	}
+	symbolAt := func(row, reel int) slotmachine.Symbol {
+		return getSymbol(reels, stops[reel], row, reel)
+	}
+	return findWins(len(reels[0]), payLines, special, symbolAt)
+}
+
+// FindWindowWins finds the wins of the pay lines in a window, Window[row][reel],
+// such as a window whose symbols were changed by wilds
+func FindWindowWins(
+	window [][]slotmachine.Symbol,
+	payLines slotmachine.PayLines,
+	special slotmachine.SpecialSymbols) ([]slotmachine.WinLine, error) {
+
+	if len(window) == 0 {
+		return make([]slotmachine.WinLine, 0), errEmptyReel
+	}
+	for _, line := range payLines {
+		for _, row := range line {
+			if row < 1 || row > len(window) {
+				return make([]slotmachine.WinLine, 0), errPayLineOutsideWindow
+			}
+		}
+	}
+	symbolAt := func(row, reel int) slotmachine.Symbol {
+		return window[row-1][reel]
+	}
+	return findWins(len(window[0]), payLines, special, symbolAt)
+}
+
+// findWins matches the pay lines on reels of the width, symbolAt returning the symbol at a row, from 1, of a reel
+func findWins(
+	width int,
+	payLines slotmachine.PayLines,
+	special slotmachine.SpecialSymbols,
+	symbolAt func(row, reel int) slotmachine.Symbol) ([]slotmachine.WinLine, error) {
+
 	var (
 		j                      int
 		primeSymbol, curSymbol slotmachine.Symbol
@@ -94,14 +124,12 @@ func FindWins(
 		payLineSymbols         []slotmachine.Symbol
 		winLine                slotmachine.WinLine
 	)
-	if len(reels) == 0 {
-		return payLineSymbolsTable, errEmptyReel
-	}
 	if len(payLines) == 0 {
 		return payLineSymbolsTable, errEmptyPayLine
 	}
 	for i, line := range payLines {
-		if len(reels[i]) != len(line) {
+		// Every stop of the reels is as wide as the first one, see the validator package
+		if width != len(line) {
 			return payLineSymbolsTable, errReelPayLineMismatch
 		}
 
@@ -110,7 +138,7 @@ func FindWins(
 		}
 
 		// Keeping track of the prime symbol and comparing each symbol in line with it
-		primeSymbol = getSymbol(reels, stops[0], line[0], 0)
+		primeSymbol = symbolAt(line[0], 0)
 
 		// If first symbol was wildcard, we take the second symbol as prime
 		// If second symbol,
@@ -121,14 +149,13 @@ func FindWins(
 		//     WC WC 31 41 51 - two WCs in a row
 		// Handles the special case where WC WC WC 1 1 -> three WC in a row, not five 1s in a row
 		if primeSymbol == special.Wildcard {
-			primeSymbol = getSymbol(reels, stops[1], line[1], 1)
+			primeSymbol = symbolAt(line[1], 1)
 		}
-		//slog.Printf("Prime Symbol:%s", primeSymbol)
-		payLineSymbols = make([]slotmachine.Symbol, 0, len(stops))
+		payLineSymbols = make([]slotmachine.Symbol, 0, len(line))
 		payLineSymbols = append(payLineSymbols, primeSymbol)
 
 		for j = 1; j < len(line); j++ {
-			curSymbol = getSymbol(reels, stops[j], line[j], j)
+			curSymbol = symbolAt(line[j], j)
 
 			// Any wildcard symbol or a symbol equal to the firstSymbol is a win
 			if curSymbol == special.Wildcard || curSymbol == primeSymbol {
@@ -137,7 +164,6 @@ func FindWins(
 				break
 			}
 		}
-		//slog.Printf("PayLine Symbols:%v", payLineSymbols)
 		if len(payLineSymbols) > 1 {
 			winLine = slotmachine.WinLine{
 				Index:  i + 1, // Starting human-friendly indexing (starts from 1)
@@ -173,13 +199,29 @@ func CountScatter(stops []int, reels slotmachine.Reels, scatterSymbol slotmachin
 	return scatter
 }
 
+// Window returns the visible symbols for the stops as Window[row][reel]
+func Window(stops []int, reels slotmachine.Reels) [][]slotmachine.Symbol {
+	if len(reels) == 0 {
+		return nil
+	}
+	reelStrips := len(reels[0])
+	// Only the 3 slots 1,2 and 3 are visible
+	window := make([][]slotmachine.Symbol, 3)
+	for i := range window {
+		window[i] = make([]slotmachine.Symbol, reelStrips)
+		for j := 0; j < reelStrips; j++ {
+			window[i][j] = getSymbol(reels, stops[j], i+1, j)
+		}
+	}
+	return window
+}
+
 func getSymbol(reels slotmachine.Reels, stop, payLineSpot, stripNumber int) slotmachine.Symbol {
 	// payLines are numbered from 1 to n where n is the number of slots
 	// Here, we assume that only 3 slots are present
 	// Note: To allow multislots, subtract paylineSpot by center i.e. ((n/2) + 1)
 	payLineOffset := payLineSpot - 2
 	offset := rotateOverflow(len(reels)-1, stop+payLineOffset)
-	//slog.Println("Got offset:", offset)
 	return reels[offset][stripNumber]
 }
 
@@ -207,15 +249,15 @@ func CalculatePay(wins []slotmachine.WinLine, payTable slotmachine.PayTable, spe
 		spinResult  slotmachine.SpinResult
 	)
 	for i := 0; i < len(wins); i++ {
+		// Symbols without pays, such as scatters, pay nothing on a line
+		linePayout = 0
 		if pays, ok = payTable[wins[i].Symbol]; ok {
 			linePayout = pays[wins[i].Count]
 		}
 		totalPayout = totalPayout + linePayout
 		wins[i].Payout = linePayout
-		slog.Printf("Win Line:%v CurrentPay:%d", wins[i].Line, totalPayout)
 	}
 	spinResult.Pay = totalPayout
 	spinResult.WinLines = wins
-	//slog.Printf("22 %v", spinResult)
 	return spinResult, nil
 }
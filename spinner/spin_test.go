@@ -106,6 +106,18 @@ var (
 				SM.WinLine{Index: 2, Symbol: 4, Count: 3, Line: []SM.Symbol{SM.Symbol(4), SM.Symbol(4), SM.Symbol(4)}},
 			},
 		},
+
+		// More pay lines than stops, the width of the lines is checked against the reels
+		{stops: []int{0, 0}, reels: SM.Reels{{1, 1}, {2, 2}}, payLines: []SM.PayLine{{1, 1}, {2, 2}, {3, 3}}, special: SM.SpecialSymbols{},
+			err: nil,
+			wins: []SM.WinLine{
+				SM.WinLine{Index: 1, Symbol: 2, Count: 2, Line: []SM.Symbol{SM.Symbol(2), SM.Symbol(2)}},
+				SM.WinLine{Index: 2, Symbol: 1, Count: 2, Line: []SM.Symbol{SM.Symbol(1), SM.Symbol(1)}},
+				SM.WinLine{Index: 3, Symbol: 2, Count: 2, Line: []SM.Symbol{SM.Symbol(2), SM.Symbol(2)}},
+			},
+		},
+		{stops: []int{0, 0}, reels: SM.Reels{{1, 1}, {2, 2}}, payLines: []SM.PayLine{{1, 1}, {2, 2, 2}}, special: SM.SpecialSymbols{},
+			err: errReelPayLineMismatch},
 	}
 )
 
@@ -267,6 +279,16 @@ var (
 			err:     nil,
 			pay:     40,
 		},
+		{
+			// A symbol without pays does not pay the payout of the previous line
+			wins: []SM.WinLine{
+				SM.WinLine{Symbol: 2, Count: 3, Line: []SM.Symbol{SM.Symbol(2), SM.Symbol(2), SM.Symbol(2)}},
+				SM.WinLine{Symbol: 9, Count: 2, Line: []SM.Symbol{SM.Symbol(9), SM.Symbol(9)}},
+			},
+			special: SM.SpecialSymbols{Wildcard: 1, Scatter: 9},
+			err:     nil,
+			pay:     40,
+		},
 	}
 
 	samplePayTable = SM.PayTable{
@@ -320,3 +342,12 @@ func testCalculatePay(t *testing.T, sample paySample) {
 	}
 	//t.Logf("Pay: %d", pay)
 }
+
+func TestWindow(t *testing.T) {
+	reels := SM.Reels{{1, 2}, {3, 4}, {5, 6}, {7, 8}}
+	window := Window([]int{0, 2}, reels)
+	expected := [][]SM.Symbol{{7, 4}, {1, 6}, {3, 8}}
+	if !reflect.DeepEqual(window, expected) {
+		t.Errorf("Expected:[%v] Got:[%v]", expected, window)
+	}
+}
@@ -0,0 +1,140 @@
+package spinner
+
+import (
+	"math/rand"
+
+	"trippy/slotmachine"
+)
+
+// CountWindow counts the symbol in the window, Window[row][reel]
+func CountWindow(window [][]slotmachine.Symbol, symbol slotmachine.Symbol) int {
+	var count int
+	for _, row := range window {
+		for _, s := range row {
+			if s == symbol {
+				count++
+			}
+		}
+	}
+	return count
+}
+
+// WildCells returns the cells of the window showing the wildcard, reel by reel
+func WildCells(window [][]slotmachine.Symbol, wild slotmachine.Symbol) []slotmachine.Cell {
+	return SymbolCells(window, wild)
+}
+
+// SymbolCells returns the cells of the window showing the symbol, reel by reel
+func SymbolCells(window [][]slotmachine.Symbol, symbol slotmachine.Symbol) []slotmachine.Cell {
+	var cells []slotmachine.Cell
+	if len(window) == 0 {
+		return cells
+	}
+	for reel := range window[0] {
+		for row := range window {
+			if window[row][reel] == symbol {
+				cells = append(cells, slotmachine.Cell{Reel: reel + 1, Row: row + 1})
+			}
+		}
+	}
+	return cells
+}
+
+// PlaceWilds sets the wildcard on the cells of the window and returns those which were not wild.
+// Cells outside the window are ignored.
+func PlaceWilds(window [][]slotmachine.Symbol, cells []slotmachine.Cell, wild slotmachine.Symbol) []slotmachine.Cell {
+	var placed []slotmachine.Cell
+	for _, cell := range cells {
+		if cell.Row < 1 || cell.Row > len(window) || cell.Reel < 1 || cell.Reel > len(window[cell.Row-1]) {
+			continue
+		}
+		if window[cell.Row-1][cell.Reel-1] != wild {
+			window[cell.Row-1][cell.Reel-1] = wild
+			placed = append(placed, cell)
+		}
+	}
+	return placed
+}
+
+// ExpandWilds fills every reel showing the wildcard with it and returns the cells turned wild
+func ExpandWilds(window [][]slotmachine.Symbol, wild slotmachine.Symbol) []slotmachine.Cell {
+	var expanded []slotmachine.Cell
+	if len(window) == 0 {
+		return expanded
+	}
+	for reel := range window[0] {
+		var (
+			column []slotmachine.Cell
+			shown  bool
+		)
+		for row := range window {
+			column = append(column, slotmachine.Cell{Reel: reel + 1, Row: row + 1})
+			shown = shown || window[row][reel] == wild
+		}
+		if shown {
+			expanded = append(expanded, PlaceWilds(window, column, wild)...)
+		}
+	}
+	return expanded
+}
+
+// WalkWilds moves the cells one reel to the left, the cells of the first reel walk off the window
+func WalkWilds(cells []slotmachine.Cell) []slotmachine.Cell {
+	var walked []slotmachine.Cell
+	for _, cell := range cells {
+		if cell.Reel > 1 {
+			walked = append(walked, slotmachine.Cell{Reel: cell.Reel - 1, Row: cell.Row})
+		}
+	}
+	return walked
+}
+
+// DrawMultipliers draws the multiplier of each cell from the weighted table, none if the table has no weight
+func DrawMultipliers(cells []slotmachine.Cell, table []slotmachine.WildMultiplier) []slotmachine.CellMultiplier {
+	var (
+		multipliers []slotmachine.CellMultiplier
+		weights     int
+	)
+	for _, m := range table {
+		if m.Weight > 0 {
+			weights = weights + m.Weight
+		}
+	}
+	if weights == 0 {
+		return multipliers
+	}
+	for _, cell := range cells {
+		draw := rand.Intn(weights)
+		for _, m := range table {
+			if m.Weight <= 0 {
+				continue
+			}
+			if draw < m.Weight {
+				multipliers = append(multipliers, slotmachine.CellMultiplier{Cell: cell, Multiplier: m.Multiplier})
+				break
+			}
+			draw = draw - m.Weight
+		}
+	}
+	return multipliers
+}
+
+// LineMultiplier combines the multipliers of the cells paid on the win line, 1 if it pays none of them.
+// They are multiplied together unless combine is add.
+func LineMultiplier(winLine slotmachine.WinLine, multipliers []slotmachine.CellMultiplier, combine string) int {
+	var (
+		product = 1
+		sum     int
+	)
+	for _, m := range multipliers {
+		if m.Reel < 1 || m.Reel > winLine.Count || m.Reel > len(winLine.PayLine) || winLine.PayLine[m.Reel-1] != m.Row {
+			continue
+		}
+		product = product * m.Multiplier
+		sum = sum + m.Multiplier
+	}
+	if combine == slotmachine.COMBINE_ADD && sum > 0 {
+		return sum
+	}
+	return product
+}
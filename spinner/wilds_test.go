@@ -0,0 +1,130 @@
+package spinner
+
+import (
+	"reflect"
+	"testing"
+
+	SM "trippy/slotmachine"
+)
+
+const _WILD = SM.Symbol(888)
+
+type wildSample struct {
+	window   [][]SM.Symbol
+	cells    []SM.Cell // Cells placed
+	placed   []SM.Cell
+	expanded []SM.Cell
+	result   [][]SM.Symbol
+}
+
+var wildSamples = []wildSample{
+	// A wild landed on the second reel fills it
+	{
+		window:   [][]SM.Symbol{{1, 2, 3}, {4, _WILD, 6}, {7, 8, 9}},
+		expanded: []SM.Cell{{Reel: 2, Row: 1}, {Reel: 2, Row: 3}},
+		result:   [][]SM.Symbol{{1, _WILD, 3}, {4, _WILD, 6}, {7, _WILD, 9}},
+	},
+	// Wilds placed on the window expand, the cells already wild or outside the window are ignored
+	{
+		window:   [][]SM.Symbol{{1, 2, 3}, {4, _WILD, 6}, {7, 8, 9}},
+		cells:    []SM.Cell{{Reel: 2, Row: 2}, {Reel: 3, Row: 1}, {Reel: 4, Row: 1}},
+		placed:   []SM.Cell{{Reel: 3, Row: 1}},
+		expanded: []SM.Cell{{Reel: 2, Row: 1}, {Reel: 2, Row: 3}, {Reel: 3, Row: 2}, {Reel: 3, Row: 3}},
+		result:   [][]SM.Symbol{{1, _WILD, _WILD}, {4, _WILD, _WILD}, {7, _WILD, _WILD}},
+	},
+	{
+		window: [][]SM.Symbol{{1, 2, 3}, {4, 5, 6}, {7, 8, 9}},
+		result: [][]SM.Symbol{{1, 2, 3}, {4, 5, 6}, {7, 8, 9}},
+	},
+}
+
+func TestWilds(t *testing.T) {
+	for _, sample := range wildSamples {
+		testWilds(t, sample)
+	}
+}
+
+func testWilds(t *testing.T, sample wildSample) {
+	if placed := PlaceWilds(sample.window, sample.cells, _WILD); !reflect.DeepEqual(placed, sample.placed) {
+		t.Errorf("Expected placed:[%v] Got:[%v]", sample.placed, placed)
+	}
+	if expanded := ExpandWilds(sample.window, _WILD); !reflect.DeepEqual(expanded, sample.expanded) {
+		t.Errorf("Expected expanded:[%v] Got:[%v]", sample.expanded, expanded)
+	}
+	if !reflect.DeepEqual(sample.window, sample.result) {
+		t.Errorf("Expected:[%v] Got:[%v]", sample.result, sample.window)
+	}
+	if count := CountWindow(sample.window, _WILD); count != len(WildCells(sample.window, _WILD)) {
+		t.Errorf("Expected the wild cells counted Got:[%d] [%v]", count, WildCells(sample.window, _WILD))
+	}
+}
+
+func TestWalkWilds(t *testing.T) {
+	walked := WalkWilds([]SM.Cell{{Reel: 1, Row: 2}, {Reel: 3, Row: 1}})
+	if expected := []SM.Cell{{Reel: 2, Row: 1}}; !reflect.DeepEqual(walked, expected) {
+		t.Errorf("Expected:[%v] Got:[%v]", expected, walked)
+	}
+}
+
+// The window evaluated matches the stops it shows
+func TestFindWindowWins(t *testing.T) {
+	for _, sample := range winSamples {
+		if sample.err != nil {
+			continue
+		}
+		wins, err := FindWindowWins(Window(sample.stops, sample.reels), sample.payLines, sample.special)
+		if err != nil || !reflect.DeepEqual(wins, sample.wins) {
+			t.Errorf("Expected:[%v] Got:[%v] [Error:%v]", sample.wins, wins, err)
+		}
+	}
+	window := [][]SM.Symbol{{1, 1}, {2, 2}, {3, 3}}
+	if _, err := FindWindowWins(window, SM.PayLines{{1, 4}}, SM.SpecialSymbols{}); err != errPayLineOutsideWindow {
+		t.Errorf("Expected:[%s] Got:[%v]", errPayLineOutsideWindow, err)
+	}
+}
+
+type lineMultiplierSample struct {
+	count    int
+	combine  string
+	expected int
+}
+
+// Wilds ×2 on reel 1 row 2 and ×3 on reel 3 row 2 of the middle line, ×5 off the line on reel 2 row 1
+var (
+	cellMultipliers = []SM.CellMultiplier{
+		{Cell: SM.Cell{Reel: 1, Row: 2}, Multiplier: 2},
+		{Cell: SM.Cell{Reel: 2, Row: 1}, Multiplier: 5},
+		{Cell: SM.Cell{Reel: 3, Row: 2}, Multiplier: 3},
+	}
+	lineMultiplierSamples = []lineMultiplierSample{
+		{count: 3, combine: SM.COMBINE_MULTIPLY, expected: 6},
+		{count: 3, combine: "", expected: 6},
+		{count: 3, combine: SM.COMBINE_ADD, expected: 5},
+		{count: 2, combine: SM.COMBINE_ADD, expected: 2},
+		{count: 2, combine: SM.COMBINE_MULTIPLY, expected: 2},
+	}
+)
+
+func TestLineMultiplier(t *testing.T) {
+	for _, sample := range lineMultiplierSamples {
+		winLine := SM.WinLine{Count: sample.count, PayLine: SM.PayLine{2, 2, 2}}
+		if m := LineMultiplier(winLine, cellMultipliers, sample.combine); m != sample.expected {
+			t.Errorf("[Count:%d Combine:%s] Expected:[%d] Got:[%d]", sample.count, sample.combine, sample.expected, m)
+		}
+	}
+	if m := LineMultiplier(SM.WinLine{Count: 3, PayLine: SM.PayLine{1, 1, 1}}, cellMultipliers[:1], SM.COMBINE_ADD); m != 1 {
+		t.Errorf("Expected:[1] for a line without wilds Got:[%d]", m)
+	}
+}
+
+func TestDrawMultipliers(t *testing.T) {
+	cells := []SM.Cell{{Reel: 1, Row: 1}, {Reel: 2, Row: 3}}
+	table := []SM.WildMultiplier{{Multiplier: 2, Weight: 0}, {Multiplier: 3, Weight: 1}}
+	expected := []SM.CellMultiplier{{Cell: cells[0], Multiplier: 3}, {Cell: cells[1], Multiplier: 3}}
+	if drawn := DrawMultipliers(cells, table); !reflect.DeepEqual(drawn, expected) {
+		t.Errorf("Expected:[%+v] Got:[%+v]", expected, drawn)
+	}
+	if drawn := DrawMultipliers(cells, table[:1]); len(drawn) != 0 {
+		t.Errorf("Expected no multipliers without weights Got:[%+v]", drawn)
+	}
+}